@@ -2,10 +2,18 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/otherjamesbrown/context-palace/cp/internal/embedding"
 	"github.com/spf13/cobra"
 )
 
@@ -19,96 +27,638 @@ var adminEmbedBackfillCmd = &cobra.Command{
 	Use:   "embed-backfill",
 	Short: "Generate embeddings for shards that don't have them",
 	Long: `Fetches shards without embeddings and generates them using the configured
-embedding provider. Rate-limited to ~50 requests/minute.`,
+embedding provider.
+
+Shards are embedded in batches (one API call per batch when the provider has a
+batch endpoint) by a pool of workers. Requests are rate-limited to --rate API
+calls per minute across all workers (0 = unlimited).
+
+Progress is checkpointed to ~/.cp/backfill-<project>.json. Embedded shards are
+never refetched, so an interrupted run (Ctrl-C, crash) resumes where it stopped
+by re-running the command. Shards that failed in an earlier run are skipped on
+resume unless --retry-failed is given. The checkpoint is removed once a run
+finishes with no failures.`,
 	Example: `  cp admin embed-backfill
   cp admin embed-backfill --dry-run
-  cp admin embed-backfill --batch-size 20 --type task`,
+  cp admin embed-backfill --concurrency 8 --batch-size 50 --rate 0
+  cp admin embed-backfill --type task --retry-failed`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if cpClient.EmbedProvider == nil {
 			return fmt.Errorf("embedding provider not configured.\nAdd an `embedding:` section to ~/.cp/config.yaml")
 		}
 
-		ctx := context.Background()
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-		batchSize, _ := cmd.Flags().GetInt("batch-size")
-		typeFilter, _ := cmd.Flags().GetString("type")
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
 
-		// Fetch all shards needing embedding (large limit for backfill)
-		shards, err := cpClient.GetShardsNeedingEmbedding(ctx, 10000)
+		job := newEmbedJob(cmd, cpClient.EmbedProvider, "backfill")
+
+		limit, _ := cmd.Flags().GetInt("limit")
+		shards, err := cpClient.GetShardsNeedingEmbedding(ctx, limit)
 		if err != nil {
 			return err
 		}
 
-		// Apply type filter if specified
-		if typeFilter != "" {
-			filtered := shards[:0]
-			for _, s := range shards {
-				if s.Type == typeFilter {
-					filtered = append(filtered, s)
-				}
+		return job.run(ctx, shards, "No shards need embedding.")
+	},
+}
+
+var adminReembedCmd = &cobra.Command{
+	Use:   "reembed",
+	Short: "Re-embed shards whose embedding came from a different model",
+	Long: `Every stored embedding is tagged with the provider, model and dimensions that
+produced it. Vectors from different models are not comparable, so after a model
+change search quality silently degrades until old embeddings are replaced.
+
+reembed finds shards whose embedding was produced by anything other than the
+target model (including embeddings stored before model tracking) and re-embeds
+them. The target is the configured embedding model, or --model to override it.
+
+It runs like embed-backfill (workers, batches, rate limit) and is incremental:
+each shard is tagged as soon as it is re-embedded, so an interrupted run resumes
+where it stopped. Once it finishes, set embedding.model in your config to the
+target so new shards and queries use it too.`,
+	Example: `  cp admin reembed --dry-run
+  cp admin reembed --model gemini-embedding-001
+  cp admin reembed --model nomic-embed-text --concurrency 2 --rate 0`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cpClient.Config.Embedding == nil {
+			return fmt.Errorf("embedding provider not configured.\nAdd an `embedding:` section to ~/.cp/config.yaml")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		provider := cpClient.EmbedProvider
+		if model, _ := cmd.Flags().GetString("model"); model != "" {
+			embCfg := *cpClient.Config.Embedding
+			embCfg.Model = model
+			p, err := embedding.NewProvider(&embCfg)
+			if err != nil {
+				return err
 			}
-			shards = filtered
+			provider = p
+		}
+		if provider == nil {
+			return fmt.Errorf("embedding provider could not be initialized (run with --debug for details)")
 		}
 
-		total := len(shards)
-		if total == 0 {
-			fmt.Println("No shards need embedding.")
-			return nil
+		job := newEmbedJob(cmd, provider, "reembed")
+
+		limit, _ := cmd.Flags().GetInt("limit")
+		shards, err := cpClient.GetShardsWithStaleEmbedding(ctx, provider.Info(), limit)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Target model: %s\n", provider.Info())
+		if err := job.run(ctx, shards, "No stale embeddings."); err != nil {
+			return err
+		}
+
+		if cpClient.EmbedProvider == nil || cpClient.EmbedProvider.Info() != provider.Info() {
+			fmt.Printf("Set embedding.model to %q in your config so new shards and queries use it.\n", provider.Info().Model)
+		}
+		return nil
+	},
+}
+
+var adminEmbedResetCmd = &cobra.Command{
+	Use:   "embed-reset [dimensions]",
+	Short: "Resize the embedding columns for a provider with other dimensions",
+	Long: `The shards.embedding and shard_chunks.embedding columns hold vectors of one
+fixed size, 768 unless resized. A provider that returns another size (set by
+embedding.dimensions or implied by the model) cannot store or search until the
+columns match it.
+
+embed-reset resizes both columns to the given dimensions, or to the configured
+provider's. Vectors cannot be converted between sizes, so every stored
+embedding and chunk is cleared, in every project: the columns are shared.
+Run cp admin embed-backfill in each project afterwards. Without arguments or
+changes it prints the current size. Requires migration 021.`,
+	Example: `  cp admin embed-reset
+  cp admin embed-reset 1536
+  cp admin embed-reset 1536 --force && cp admin embed-backfill`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		force, _ := cmd.Flags().GetBool("force")
+
+		current, err := cpClient.EmbeddingDimensions(ctx)
+		if err != nil {
+			return err
 		}
 
-		if dryRun {
-			fmt.Printf("%d shards to embed.\n", total)
+		dims := 0
+		switch {
+		case len(args) == 1:
+			dims, err = strconv.Atoi(args[0])
+			if err != nil || dims < 1 {
+				return fmt.Errorf("invalid dimensions %q: want a positive number", args[0])
+			}
+		case cpClient.EmbedProvider != nil:
+			dims = cpClient.EmbedProvider.Dimensions()
+		default:
+			return fmt.Errorf("embedding provider not configured; pass the dimensions to resize to")
+		}
+
+		if dims == current {
 			if outputFormat == "json" {
-				s, _ := client.FormatJSON(shards)
+				s, _ := client.FormatJSON(map[string]any{"dimensions": current, "cleared": 0})
 				fmt.Println(s)
+				return nil
 			}
+			fmt.Printf("Embedding columns are already vector(%d).\n", current)
 			return nil
 		}
 
-		fmt.Printf("Embedding %d shards...\n", total)
+		if !force {
+			fmt.Printf("Resize embedding columns from vector(%d) to vector(%d)? This clears every stored embedding in every project. (y/n) ", current, dims)
+			var answer string
+			fmt.Scanln(&answer)
+			if answer != "y" && answer != "Y" {
+				fmt.Println("Cancelled.")
+				return nil
+			}
+		}
+
+		cleared, err := cpClient.ResizeEmbeddings(ctx, dims)
+		if err != nil {
+			return err
+		}
 
-		var embedded, failed int
-		for i, s := range shards {
-			fmt.Printf("Embedding shard %d/%d: %s (%s)\n", i+1, total, s.ID, s.Type)
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(map[string]any{"dimensions": dims, "previous": current, "cleared": cleared})
+			fmt.Println(s)
+			return nil
+		}
+		fmt.Printf("Embedding columns resized to vector(%d); %d embeddings cleared.\n", dims, cleared)
+		fmt.Println("Run cp admin embed-backfill in each project to re-embed.")
+		return nil
+	},
+}
 
-			// Fetch full content for embedding
-			shardType, title, content, err := cpClient.GetShardContentForEmbedding(ctx, s.ID)
-			if err != nil {
-				fmt.Printf("  Error fetching content: %v\n", err)
-				failed++
-				continue
+// embedJob is a batched, concurrent, resumable embedding run shared by
+// embed-backfill and reembed
+type embedJob struct {
+	name           string // checkpoint name: "backfill" or "reembed"
+	provider       embedding.Provider
+	checkpointPath string
+	typeFilter     string
+	batchSize      int
+	concurrency    int
+	rate           int
+	dryRun         bool
+	retryFailed    bool
+}
+
+// newEmbedJob reads the shared embedding job flags
+func newEmbedJob(cmd *cobra.Command, provider embedding.Provider, name string) *embedJob {
+	j := &embedJob{name: name, provider: provider}
+	j.dryRun, _ = cmd.Flags().GetBool("dry-run")
+	j.batchSize, _ = cmd.Flags().GetInt("batch-size")
+	j.typeFilter, _ = cmd.Flags().GetString("type")
+	j.concurrency, _ = cmd.Flags().GetInt("concurrency")
+	j.rate, _ = cmd.Flags().GetInt("rate")
+	j.checkpointPath, _ = cmd.Flags().GetString("checkpoint")
+	j.retryFailed, _ = cmd.Flags().GetBool("retry-failed")
+
+	if j.batchSize < 1 {
+		j.batchSize = 1
+	}
+	if j.concurrency < 1 {
+		j.concurrency = 1
+	}
+	if j.checkpointPath == "" {
+		j.checkpointPath = defaultCheckpointPath(name, cpClient.Config.Project)
+	}
+	return j
+}
+
+// run embeds shards, reporting progress and failures and keeping the checkpoint current
+func (j *embedJob) run(ctx context.Context, shards []client.ShardForEmbedding, emptyMsg string) error {
+	model := j.provider.Info().String()
+	checkpoint, err := loadBackfillCheckpoint(j.checkpointPath, cpClient.Config.Project, model)
+	if err != nil {
+		return err
+	}
+
+	// Apply type filter and skip shards that failed in an earlier run
+	var skippedFailed int
+	filtered := shards[:0]
+	for _, s := range shards {
+		if j.typeFilter != "" && s.Type != j.typeFilter {
+			continue
+		}
+		if _, failedBefore := checkpoint.Failed[s.ID]; failedBefore && !j.retryFailed {
+			skippedFailed++
+			continue
+		}
+		filtered = append(filtered, s)
+	}
+	shards = filtered
+
+	total := len(shards)
+	if total == 0 {
+		fmt.Println(emptyMsg)
+		if skippedFailed > 0 {
+			fmt.Printf("%d shards failed in an earlier run; use --retry-failed to retry them.\n", skippedFailed)
+		}
+		return nil
+	}
+
+	if j.dryRun {
+		fmt.Printf("%d shards to embed.\n", total)
+		if skippedFailed > 0 {
+			fmt.Printf("%d previously failed shards skipped (--retry-failed to include).\n", skippedFailed)
+		}
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(shards)
+			fmt.Println(s)
+		}
+		return nil
+	}
+
+	if checkpoint.Embedded > 0 {
+		fmt.Printf("Resuming: %d shards embedded in earlier runs.\n", checkpoint.Embedded)
+	}
+	if skippedFailed > 0 {
+		fmt.Printf("Skipping %d shards that failed in an earlier run (--retry-failed to include).\n", skippedFailed)
+	}
+	fmt.Printf("Embedding %d shards (%d workers, batch size %d)...\n", total, j.concurrency, j.batchSize)
+
+	b := &backfiller{
+		client:   cpClient,
+		provider: j.provider,
+	}
+	if j.rate > 0 {
+		ticker := time.NewTicker(time.Minute / time.Duration(j.rate))
+		defer ticker.Stop()
+		b.limiter = ticker.C
+	}
+
+	batches := make(chan []client.ShardForEmbedding)
+	results := make(chan backfillResult)
+
+	var wg sync.WaitGroup
+	for w := 0; w < j.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				for _, r := range b.embedBatch(ctx, batch) {
+					results <- r
+				}
 			}
+		}()
+	}
 
-			err = embedShard(ctx, cpClient, cpClient.EmbedProvider, s.ID, shardType, title, content)
-			if err != nil {
-				fmt.Printf("  Error embedding: %v\n", err)
-				failed++
-				continue
+	go func() {
+		defer close(batches)
+		for i := 0; i < total; i += j.batchSize {
+			end := i + j.batchSize
+			if end > total {
+				end = total
 			}
+			select {
+			case batches <- shards[i:end]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	progress := newBackfillProgress(total)
+	var embedded, skipped int
+	var failures []backfillResult
+	for r := range results {
+		if r.Err != nil && (ctx.Err() != nil || errors.Is(r.Err, context.Canceled)) {
+			// Interrupted, not failed: the shard stays pending for the next run
+			continue
+		}
+		switch {
+		case r.Err != nil:
+			failures = append(failures, r)
+			checkpoint.Failed[r.ID] = r.Err.Error()
+		case r.Skipped:
+			skipped++
+		default:
 			embedded++
+			checkpoint.Embedded++
+			delete(checkpoint.Failed, r.ID)
+		}
+		progress.update(embedded+skipped+len(failures), len(failures))
+		// Cheap relative to an API round-trip; keeps resume state current
+		if err := checkpoint.save(j.checkpointPath); err != nil {
+			progress.finish()
+			return err
+		}
+	}
+	progress.finish()
 
-			// Rate limit: ~50/min = 1.2s between calls
-			if i < total-1 {
-				time.Sleep(1200 * time.Millisecond)
-			}
+	interrupted := ctx.Err() != nil
+	done := embedded + skipped + len(failures)
 
-			// Batch boundary logging
-			if batchSize > 0 && (i+1)%batchSize == 0 {
-				fmt.Printf("  Batch complete: %d/%d\n", i+1, total)
-			}
+	if !interrupted && len(checkpoint.Failed) == 0 {
+		os.Remove(j.checkpointPath)
+	}
+
+	if outputFormat == "json" {
+		type failureOut struct {
+			ID    string `json:"id"`
+			Type  string `json:"type"`
+			Error string `json:"error"`
+		}
+		out := struct {
+			Model       string       `json:"model"`
+			Total       int          `json:"total"`
+			Embedded    int          `json:"embedded"`
+			Skipped     int          `json:"skipped"`
+			Failed      []failureOut `json:"failed"`
+			Interrupted bool         `json:"interrupted"`
+			Checkpoint  string       `json:"checkpoint,omitempty"`
+		}{Model: model, Total: total, Embedded: embedded, Skipped: skipped, Interrupted: interrupted, Failed: []failureOut{}}
+		for _, f := range failures {
+			out.Failed = append(out.Failed, failureOut{ID: f.ID, Type: f.Type, Error: f.Err.Error()})
+		}
+		if interrupted || len(checkpoint.Failed) > 0 {
+			out.Checkpoint = j.checkpointPath
+		}
+		s, _ := client.FormatJSON(out)
+		fmt.Println(s)
+		return nil
+	}
+
+	if len(failures) > 0 {
+		fmt.Println("\nFailures:")
+		table := client.NewTable("ID", "TYPE", "ERROR")
+		for _, f := range failures {
+			table.AddRow(f.ID, f.Type, client.Truncate(f.Err.Error(), 80))
 		}
+		fmt.Print(table.String())
+	}
 
-		fmt.Printf("Done: %d embedded, %d failed\n", embedded, failed)
+	if interrupted {
+		fmt.Printf("Interrupted after %d/%d: %d embedded, %d failed. Re-run to resume.\n",
+			done, total, embedded, len(failures))
 		return nil
-	},
+	}
+	if skipped > 0 {
+		fmt.Printf("Done: %d embedded, %d failed, %d skipped (no content)\n", embedded, len(failures), skipped)
+	} else {
+		fmt.Printf("Done: %d embedded, %d failed\n", embedded, len(failures))
+	}
+	if len(failures) > 0 {
+		fmt.Printf("Failed shards recorded in %s (re-run with --retry-failed).\n", j.checkpointPath)
+	}
+	return nil
+}
+
+// backfillResult is the outcome of embedding one shard
+type backfillResult struct {
+	ID      string
+	Type    string
+	Skipped bool // no content to embed
+	Err     error
+}
+
+// backfiller embeds batches of shards; safe for concurrent use
+type backfiller struct {
+	client   *client.Client
+	provider embedding.Provider
+	limiter  <-chan time.Time // nil = unlimited
+}
+
+// wait blocks until the rate limiter allows another API call
+func (b *backfiller) wait(ctx context.Context) error {
+	if b.limiter == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-b.limiter:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// embedBatch embeds a batch with one provider call. If the batch call fails,
+// each shard is retried on its own so one bad shard doesn't fail the batch.
+// Long shards additionally get their chunks embedded.
+func (b *backfiller) embedBatch(ctx context.Context, batch []client.ShardForEmbedding) []backfillResult {
+	results := make([]backfillResult, len(batch))
+	docs := make([]backfillDoc, len(batch))
+	var texts []string
+	var idx []int
+
+	for i, s := range batch {
+		results[i] = backfillResult{ID: s.ID, Type: s.Type}
+		shardType, title, content, err := b.client.GetShardContentForEmbedding(ctx, s.ID)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		docs[i] = backfillDoc{shardType, title, content}
+		text := embedding.BuildEmbeddingText(shardType, title, content)
+		if text == "" {
+			results[i].Skipped = true
+			continue
+		}
+		texts = append(texts, text)
+		idx = append(idx, i)
+	}
+	if len(texts) == 0 {
+		return results
+	}
+
+	if err := b.wait(ctx); err != nil {
+		for _, i := range idx {
+			results[i].Err = err
+		}
+		return results
+	}
+
+	vecs, err := embedding.EmbedBatch(ctx, b.provider, texts)
+	if err != nil && len(texts) > 1 && ctx.Err() == nil {
+		for j, i := range idx {
+			if err := b.wait(ctx); err != nil {
+				results[i].Err = err
+				continue
+			}
+			vec, err := b.provider.Embed(ctx, texts[j])
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			results[i].Err = b.store(ctx, batch[i].ID, docs[i], vec)
+		}
+		return results
+	}
+	if err != nil {
+		for _, i := range idx {
+			results[i].Err = err
+		}
+		return results
+	}
+
+	for j, i := range idx {
+		results[i].Err = b.store(ctx, batch[i].ID, docs[i], vecs[j])
+	}
+	return results
+}
+
+// backfillDoc is the shard text fetched for embedding
+type backfillDoc struct {
+	shardType, title, content string
+}
+
+// store saves a shard's embedding, then embeds its chunks if it is long
+func (b *backfiller) store(ctx context.Context, id string, doc backfillDoc, vec []float32) error {
+	if err := b.client.UpdateEmbedding(ctx, id, vec, b.provider.Info()); err != nil {
+		return err
+	}
+	if embedding.NeedsChunking(doc.content) {
+		if err := b.wait(ctx); err != nil {
+			return err
+		}
+	}
+	return b.client.EmbedChunks(ctx, b.provider, id, doc.shardType, doc.title, doc.content)
+}
+
+// backfillCheckpoint records progress across embed-backfill/reembed runs
+type backfillCheckpoint struct {
+	Project   string            `json:"project"`
+	Model     string            `json:"model"` // ModelInfo.String() of the provider used
+	StartedAt time.Time         `json:"started_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Embedded  int               `json:"embedded"`
+	Failed    map[string]string `json:"failed"` // shard ID -> last error
+}
+
+func defaultCheckpointPath(name, project string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".cp", fmt.Sprintf("%s-%s.json", name, project))
+}
+
+// loadBackfillCheckpoint reads the checkpoint, starting fresh if none exists
+func loadBackfillCheckpoint(path, project, model string) (*backfillCheckpoint, error) {
+	cp := &backfillCheckpoint{Project: project, Model: model, StartedAt: time.Now(), Failed: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read checkpoint %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %v (delete it to start over)", path, err)
+	}
+	if cp.Project != project {
+		return nil, fmt.Errorf("checkpoint %s belongs to project %q, not %q", path, cp.Project, project)
+	}
+	if cp.Model != "" && cp.Model != model {
+		return nil, fmt.Errorf("checkpoint %s was written for model %s, not %s (delete it or pass --checkpoint)", path, cp.Model, model)
+	}
+	cp.Model = model
+	if cp.Failed == nil {
+		cp.Failed = map[string]string{}
+	}
+	return cp, nil
+}
+
+func (cp *backfillCheckpoint) save(path string) error {
+	cp.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cannot write checkpoint: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("cannot write checkpoint: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// backfillProgress renders a live progress line on a terminal, or a line
+// every 10 seconds when output is redirected
+type backfillProgress struct {
+	total     int
+	start     time.Time
+	lastPrint time.Time
+	live      bool
+}
+
+func newBackfillProgress(total int) *backfillProgress {
+	live := false
+	if fi, err := os.Stderr.Stat(); err == nil {
+		live = fi.Mode()&os.ModeCharDevice != 0
+	}
+	return &backfillProgress{total: total, start: time.Now(), live: live}
+}
+
+func (p *backfillProgress) update(done, failed int) {
+	now := time.Now()
+	if !p.live && now.Sub(p.lastPrint) < 10*time.Second && done < p.total {
+		return
+	}
+	p.lastPrint = now
+
+	elapsed := now.Sub(p.start)
+	perMin := float64(done) / elapsed.Minutes()
+	eta := "--"
+	if done > 0 && done < p.total {
+		remaining := time.Duration(float64(elapsed) / float64(done) * float64(p.total-done))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	line := fmt.Sprintf("%s %d/%d | Rate: %.0f/min | ETA: %s | Failed: %d",
+		renderProgressBar(done, p.total, 20), done, p.total, perMin, eta, failed)
+	if p.live {
+		fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+	} else {
+		fmt.Fprintln(os.Stderr, line)
+	}
+}
+
+func (p *backfillProgress) finish() {
+	if p.live {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// addEmbedJobFlags registers the flags shared by embed-backfill and reembed
+func addEmbedJobFlags(cmd *cobra.Command, name string) {
+	cmd.Flags().Bool("dry-run", false, "Show count without embedding")
+	cmd.Flags().Int("batch-size", 20, "Shards per provider API call")
+	cmd.Flags().Int("concurrency", 4, "Number of concurrent workers")
+	cmd.Flags().Int("rate", 50, "Max provider API calls per minute (0 = unlimited)")
+	cmd.Flags().Int("limit", 100000, "Max shards to process in this run")
+	cmd.Flags().String("type", "", "Only embed shards of this type")
+	cmd.Flags().String("checkpoint", "", fmt.Sprintf("Checkpoint file (default: ~/.cp/%s-<project>.json)", name))
+	cmd.Flags().Bool("retry-failed", false, "Retry shards that failed in an earlier run")
 }
 
 func init() {
-	adminEmbedBackfillCmd.Flags().Bool("dry-run", false, "Show count without embedding")
-	adminEmbedBackfillCmd.Flags().Int("batch-size", 10, "Log progress every N shards")
-	adminEmbedBackfillCmd.Flags().String("type", "", "Only embed shards of this type")
+	addEmbedJobFlags(adminEmbedBackfillCmd, "backfill")
+	addEmbedJobFlags(adminReembedCmd, "reembed")
+	adminReembedCmd.Flags().String("model", "", "Target embedding model (default: configured model)")
 
 	adminCmd.AddCommand(adminEmbedBackfillCmd)
+	adminCmd.AddCommand(adminReembedCmd)
+
+	adminEmbedResetCmd.Flags().Bool("force", false, "Skip confirmation prompt")
+	adminCmd.AddCommand(adminEmbedResetCmd)
+	postgresOnly(adminEmbedResetCmd)
 	rootCmd.AddCommand(adminCmd)
 }
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var adminExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export the project to a portable archive",
+	Long: `Write every shard of the current project to a versioned archive: a gzipped tar
+of JSONL files with shards, labels, edges, read receipts and focus rows.
+Knowledge document versions and memory telemetry are part of the shards and
+edges, so they are included.
+
+Embeddings are left out unless --embeddings is given (they are large and can be
+regenerated with 'cp admin embed-backfill'). The export reads from a single
+snapshot, so it is consistent even while agents are writing.
+
+The default file is <project>-<date>.cpa.tar.gz; use - for stdout.`,
+	Example: `  cp admin export
+  cp admin export backup.cpa.tar.gz --embeddings
+  cp admin export - | ssh backup-host 'cat > penfold.cpa.tar.gz'`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		withEmbeddings, _ := cmd.Flags().GetBool("embeddings")
+
+		path := fmt.Sprintf("%s-%s.cpa.tar.gz", cpClient.Config.Project, time.Now().Format("20060102"))
+		if len(args) == 1 {
+			path = args[0]
+		}
+
+		var w io.Writer = os.Stdout
+		var f *os.File
+		if path != "-" {
+			var err error
+			// Write to a temp file so a failed export never leaves a truncated archive
+			f, err = os.CreateTemp(filepath.Dir(path), ".cp-export-*")
+			if err != nil {
+				return fmt.Errorf("cannot create %s: %v", path, err)
+			}
+			defer os.Remove(f.Name())
+			defer f.Close()
+			w = f
+		}
+
+		m, err := cpClient.ExportArchive(context.Background(), w, client.ExportOptions{Embeddings: withEmbeddings})
+		if err != nil {
+			return err
+		}
+		if f != nil {
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("cannot write %s: %v", path, err)
+			}
+			if err := os.Rename(f.Name(), path); err != nil {
+				return fmt.Errorf("cannot write %s: %v", path, err)
+			}
+		}
+
+		// Keep stdout clean when it carries the archive
+		out := os.Stdout
+		if path == "-" {
+			out = os.Stderr
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(struct {
+				File     string                  `json:"file"`
+				Manifest *client.ArchiveManifest `json:"manifest"`
+			}{path, m})
+			fmt.Fprintln(out, s)
+			return nil
+		}
+
+		fmt.Fprintf(out, "Exported %s (prefix %s) to %s\n", m.Project, m.Prefix, path)
+		for _, mem := range client.ArchiveMembers {
+			if n, ok := m.Counts[mem]; ok {
+				fmt.Fprintf(out, "  %-14s %d\n", strings.TrimSuffix(mem, ".jsonl")+":", n)
+			}
+		}
+		return nil
+	},
+}
+
+var adminImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a project archive",
+	Long: `Load an archive written by 'cp admin export' into the current project (use
+--project to pick another). The import runs in one transaction: it either
+loads everything or nothing.
+
+When the target project's ID prefix differs from the archive's, shard IDs are
+rewritten to the target prefix, along with every reference to them in parent
+IDs, edges, labels, read receipts, focus and metadata. Shard content is not
+rewritten. A target project that doesn't exist yet is created with --prefix
+(default: the archive's prefix).
+
+The import fails if any shard ID already exists, unless --skip-existing is
+given. Edges to shards that exist in neither the archive nor the target
+database are skipped. Use - to read the archive from stdin.`,
+	Example: `  cp admin import penfold-20261016.cpa.tar.gz --dry-run
+  cp admin import penfold-20261016.cpa.tar.gz --project penfold-test --prefix pt
+  cp admin import backup.cpa.tar.gz --skip-existing`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prefix, _ := cmd.Flags().GetString("prefix")
+		skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("cannot open archive: %v", err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		archive, err := client.ReadArchive(r)
+		if err != nil {
+			return err
+		}
+
+		res, err := cpClient.ImportArchive(context.Background(), archive, client.ImportOptions{
+			Prefix:       prefix,
+			SkipExisting: skipExisting,
+			DryRun:       dryRun,
+		})
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(res)
+			fmt.Println(s)
+			return nil
+		}
+
+		verb := "Imported"
+		if res.DryRun {
+			verb = "Would import"
+		}
+		fmt.Printf("%s %s (exported %s) into %s\n", verb, res.SourceProject,
+			archive.Manifest.ExportedAt.Format("2006-01-02 15:04"), res.Project)
+		if res.CreatedProject {
+			fmt.Printf("  Created project %s with prefix %s\n", res.Project, res.ToPrefix)
+		}
+		if res.Remapped {
+			fmt.Printf("  IDs remapped: %s-* -> %s-*\n", res.FromPrefix, res.ToPrefix)
+		}
+		for _, mem := range client.ArchiveMembers {
+			n, skipped := res.Imported[mem], res.Skipped[mem]
+			name := strings.TrimSuffix(mem, ".jsonl")
+			if n == 0 && skipped == 0 {
+				continue
+			}
+			if skipped > 0 {
+				fmt.Printf("  %-14s %d (%d skipped)\n", name+":", n, skipped)
+			} else {
+				fmt.Printf("  %-14s %d\n", name+":", n)
+			}
+		}
+		if res.DryRun {
+			fmt.Println("Dry run: nothing was written.")
+		} else if !archive.Manifest.Embeddings {
+			fmt.Println("Archive has no embeddings; run 'cp admin embed-backfill' to generate them.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	adminExportCmd.Flags().Bool("embeddings", false, "Include shard and chunk embeddings")
+
+	adminImportCmd.Flags().String("prefix", "", "ID prefix for a new target project (default: archive's prefix)")
+	adminImportCmd.Flags().Bool("skip-existing", false, "Leave shards that already exist untouched instead of failing")
+	adminImportCmd.Flags().Bool("dry-run", false, "Validate and count without writing")
+
+	adminCmd.AddCommand(adminExportCmd)
+	adminCmd.AddCommand(adminImportCmd)
+	postgresOnly(adminExportCmd, adminImportCmd)
+}
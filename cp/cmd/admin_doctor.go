@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var adminDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check schema, indexes and data integrity",
+	Long: `Check that the database matches what this cp expects, then check the
+project's data for integrity problems:
+
+  migrations       every embedded migration is applied
+  functions        every SQL function cp calls exists with the expected signature
+  pgvector         the vector extension and vector indexes are installed
+  edges            no edges point at shards that no longer exist
+  parent cycles    no parent_id chain loops back on itself
+  leases           no in_progress shard is held past its lease
+  memory pointers  memory pointer blocks match their children (cp memory sync)
+  embeddings       the embedding columns fit the provider; no shards are
+                   missing embeddings or chunks
+
+--fix repairs what is safe to repair automatically: dangling edges are deleted,
+expired claims are returned to open and pointer blocks are re-synced.
+Everything else prints the command to run. Exits non-zero when any check fails.`,
+	Example: `  cp admin doctor
+  cp admin doctor --fix
+  cp admin doctor -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		report, err := cpClient.Doctor(context.Background(), fix)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(report)
+			fmt.Println(s)
+		} else {
+			printDoctorReport(report)
+		}
+
+		if !report.Healthy() {
+			return fmt.Errorf("doctor found problems")
+		}
+		return nil
+	},
+}
+
+func printDoctorReport(report *client.DoctorReport) {
+	fmt.Printf("Context Palace doctor: %s\n\n", report.Project)
+	for _, c := range report.Checks {
+		fmt.Printf("  [%-4s] %-16s %s\n", strings.ToUpper(c.Status), c.Name, c.Summary)
+		for _, p := range c.Problems {
+			fmt.Printf("         %-16s - %s\n", "", p)
+		}
+		if c.Hint != "" && c.Status != client.DoctorOK {
+			fmt.Printf("         %-16s → %s\n", "", c.Hint)
+		}
+	}
+
+	var fails, warns, fixed int
+	for _, c := range report.Checks {
+		switch c.Status {
+		case client.DoctorFail:
+			fails++
+		case client.DoctorWarn:
+			warns++
+		}
+		fixed += c.Fixed
+	}
+	fmt.Println()
+	switch {
+	case fails == 0 && warns == 0:
+		fmt.Println("All checks passed.")
+	default:
+		fmt.Printf("%d failed, %d warnings.\n", fails, warns)
+	}
+	if fixed > 0 {
+		fmt.Printf("Fixed %d problems.\n", fixed)
+	}
+}
+
+func init() {
+	adminDoctorCmd.Flags().Bool("fix", false, "Repair problems that are safe to fix automatically")
+	adminCmd.AddCommand(adminDoctorCmd)
+	postgresOnly(adminDoctorCmd)
+}
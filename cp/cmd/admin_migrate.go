@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var adminMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply or inspect database schema migrations",
+	Long: `The SQL schema cp depends on (tables, functions, triggers) ships inside the
+binary as numbered migrations. Applied versions are recorded in the
+schema_migrations table, so 'cp admin migrate up' only runs what is missing.
+
+A fresh database needs only 'cp admin migrate up'. A database whose schema was
+installed by hand should first be recorded with 'cp admin migrate baseline
+<version>' (the last migration it already has), then brought up to date.`,
+}
+
+var adminMigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	Example: `  cp admin migrate status
+  cp admin migrate status -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, err := cpClient.GetMigrationStatus(context.Background())
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(status)
+			fmt.Println(s)
+			return nil
+		}
+
+		table := client.NewTable("VERSION", "NAME", "STATUS", "APPLIED", "DOWN")
+		pending, modified := 0, 0
+		for _, s := range status {
+			state, at := "pending", "-"
+			if s.Applied {
+				state = "applied"
+				at = s.AppliedAt.Local().Format("2006-01-02 15:04")
+				if s.Modified {
+					state = "modified"
+					modified++
+				}
+			} else {
+				pending++
+			}
+			down := "no"
+			if s.Reversible() {
+				down = "yes"
+			}
+			table.AddRow(fmt.Sprintf("%03d", s.Version), s.Name, state, at, down)
+		}
+		fmt.Print(table.String())
+
+		fmt.Printf("\n%d applied, %d pending\n", len(status)-pending, pending)
+		if pending == len(status) && pending > 0 {
+			fmt.Println("No migrations recorded. For a database set up by hand, run 'cp admin migrate baseline <version>' first.")
+		} else if pending > 0 {
+			fmt.Println("Run 'cp admin migrate up' to apply pending migrations.")
+		}
+		if modified > 0 {
+			fmt.Printf("%d applied migrations differ from the embedded SQL (edited after applying).\n", modified)
+		}
+		return nil
+	},
+}
+
+var adminMigrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply pending migrations",
+	Long: `Apply pending migrations in version order, each in its own transaction. A
+failing migration is rolled back and stops the run; earlier ones stay applied.
+Concurrent runs against the same database wait for each other.`,
+	Example: `  cp admin migrate up
+  cp admin migrate up --dry-run
+  cp admin migrate up --to 8`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, _ := cmd.Flags().GetInt("to")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		applied, err := cpClient.MigrateUp(context.Background(), to, dryRun, func(m client.Migration, took time.Duration) {
+			if outputFormat != "json" {
+				fmt.Printf("Applied %03d_%s (%s)\n", m.Version, m.Name, took.Round(time.Millisecond))
+			}
+		})
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(migrateResult(applied, dryRun, err))
+			fmt.Println(s)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(applied) == 0:
+			fmt.Println("Schema is up to date.")
+		case dryRun:
+			fmt.Printf("%d migrations would be applied:\n", len(applied))
+			for _, m := range applied {
+				fmt.Printf("  %03d_%s\n", m.Version, m.Name)
+			}
+		default:
+			fmt.Printf("Applied %d migrations.\n", len(applied))
+		}
+		return nil
+	},
+}
+
+var adminMigrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the latest applied migrations",
+	Long: `Revert the most recently applied migrations, newest first, using their
+.down.sql scripts. Migrations without one are irreversible: the command refuses
+to start if any migration in range lacks a down script.`,
+	Example: `  cp admin migrate down --dry-run
+  cp admin migrate down --steps 2`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, _ := cmd.Flags().GetInt("steps")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if steps < 1 {
+			return fmt.Errorf("--steps must be at least 1")
+		}
+
+		reverted, err := cpClient.MigrateDown(context.Background(), steps, dryRun, func(m client.Migration, took time.Duration) {
+			if outputFormat != "json" {
+				fmt.Printf("Reverted %03d_%s (%s)\n", m.Version, m.Name, took.Round(time.Millisecond))
+			}
+		})
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(migrateResult(reverted, dryRun, err))
+			fmt.Println(s)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(reverted) == 0:
+			fmt.Println("No applied migrations.")
+		case dryRun:
+			fmt.Printf("%d migrations would be reverted:\n", len(reverted))
+			for _, m := range reverted {
+				fmt.Printf("  %03d_%s\n", m.Version, m.Name)
+			}
+		default:
+			fmt.Printf("Reverted %d migrations.\n", len(reverted))
+		}
+		return nil
+	},
+}
+
+var adminMigrateBaselineCmd = &cobra.Command{
+	Use:   "baseline <version>",
+	Short: "Record migrations as applied without running them",
+	Long: `Mark every migration up to and including <version> as applied, without
+running any SQL. Use this once on a database whose schema was installed by
+hand, so 'cp admin migrate up' only applies what it is missing.`,
+	Example: `  cp admin migrate baseline 12`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid version: %s", args[0])
+		}
+
+		recorded, err := cpClient.MigrateBaseline(context.Background(), version)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(migrateResult(recorded, false, nil))
+			fmt.Println(s)
+			return nil
+		}
+		if len(recorded) == 0 {
+			fmt.Printf("Migrations up to %03d were already recorded.\n", version)
+			return nil
+		}
+		fmt.Printf("Recorded %d migrations as applied (%03d-%03d).\n",
+			len(recorded), recorded[0].Version, recorded[len(recorded)-1].Version)
+		return nil
+	},
+}
+
+// migrateOutput is the JSON output of migrate up/down/baseline
+type migrateOutput struct {
+	Migrations []client.Migration `json:"migrations"`
+	DryRun     bool               `json:"dry_run"`
+	Error      string             `json:"error,omitempty"`
+}
+
+func migrateResult(ms []client.Migration, dryRun bool, err error) migrateOutput {
+	out := migrateOutput{Migrations: ms, DryRun: dryRun}
+	if out.Migrations == nil {
+		out.Migrations = []client.Migration{}
+	}
+	if err != nil {
+		out.Error = err.Error()
+	}
+	return out
+}
+
+func init() {
+	adminMigrateUpCmd.Flags().Int("to", 0, "Stop after this version (default: latest)")
+	adminMigrateUpCmd.Flags().Bool("dry-run", false, "List pending migrations without applying them")
+	adminMigrateDownCmd.Flags().Int("steps", 1, "Number of migrations to revert")
+	adminMigrateDownCmd.Flags().Bool("dry-run", false, "List migrations that would be reverted")
+
+	adminMigrateCmd.AddCommand(adminMigrateStatusCmd)
+	adminMigrateCmd.AddCommand(adminMigrateUpCmd)
+	adminMigrateCmd.AddCommand(adminMigrateDownCmd)
+	adminMigrateCmd.AddCommand(adminMigrateBaselineCmd)
+	adminCmd.AddCommand(adminMigrateCmd)
+	postgresOnly(adminMigrateCmd)
+}
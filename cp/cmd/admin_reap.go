@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var adminReapCmd = &cobra.Command{
+	Use:   "reap",
+	Short: "Return shards with expired leases to open",
+	Long: `Find in_progress shards whose claim lease has run out, because the owner
+stopped sending heartbeats (cp task heartbeat / cp task progress), and return
+them to open with no owner so another agent can pick them up. A note naming
+the previous owner is appended to each shard's content.
+
+Shards claimed before leases existed have none and are never reaped. 'cp serve
+--reap-interval' runs the same sweep periodically.`,
+	Example: `  cp admin reap
+  cp admin reap --dry-run
+  cp admin reap -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		reaped, err := cpClient.ReapLeases(context.Background(), dryRun)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(map[string]any{"dry_run": dryRun, "reaped": reaped})
+			fmt.Println(s)
+			return nil
+		}
+
+		if len(reaped) == 0 {
+			fmt.Println("No expired leases.")
+			return nil
+		}
+
+		tbl := client.NewTable("ID", "TITLE", "OWNER", "LEASE EXPIRED")
+		for _, r := range reaped {
+			owner := "-"
+			if r.Owner != nil {
+				owner = *r.Owner
+			}
+			tbl.AddRow(r.ID, client.Truncate(r.Title, 40), owner, timeAgo(r.ExpiredAt))
+		}
+		fmt.Print(tbl.String())
+
+		if dryRun {
+			fmt.Printf("\n%d shards would be returned to open (dry run).\n", len(reaped))
+		} else {
+			fmt.Printf("\nReturned %d shards to open.\n", len(reaped))
+		}
+		return nil
+	},
+}
+
+func init() {
+	adminReapCmd.Flags().Bool("dry-run", false, "List expired claims without releasing them")
+	adminCmd.AddCommand(adminReapCmd)
+}
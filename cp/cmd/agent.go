@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Agent registry",
+	Long: `Register agents with their display name, capabilities and the projects they
+serve, and see who is around.
+
+Presence comes from the last-seen time, which register and heartbeat update:
+online within 5 minutes, idle within an hour, otherwise offline. Capabilities
+match needs:<capability> labels on shards (cp shard next --capable). Groups
+are message groups: cp message send @implementers reaches every member, and
+@all every registered agent serving the project.`,
+}
+
+// -- agent register --
+
+var agentRegisterCmd = &cobra.Command{
+	Use:   "register [agent]",
+	Short: "Register or update an agent (default: yourself)",
+	Long: `Create or update an agent's registry entry. Flags that are not given keep
+their current value; --capability, --group and --project replace the whole
+list.
+Omitting --project on a new agent means it serves every project.`,
+	Args: cobra.MaximumNArgs(1),
+	Example: `  cp agent register --name Mycroft --capability go,sql,review --project penfold
+  cp agent register agent-worker-3 --capability go --group implementers
+  cp agent register --project penfold,palace`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := cpClient.Config.Agent
+		if len(args) == 1 {
+			id = args[0]
+		}
+
+		var u client.AgentUpdate
+		if cmd.Flags().Changed("name") {
+			v, _ := cmd.Flags().GetString("name")
+			u.DisplayName = &v
+		}
+		if cmd.Flags().Changed("description") {
+			v, _ := cmd.Flags().GetString("description")
+			u.Description = &v
+		}
+		if cmd.Flags().Changed("capability") {
+			u.Capabilities, _ = cmd.Flags().GetStringSlice("capability")
+			if u.Capabilities == nil {
+				u.Capabilities = []string{}
+			}
+		}
+		if cmd.Flags().Changed("group") {
+			u.Groups, _ = cmd.Flags().GetStringSlice("group")
+			if u.Groups == nil {
+				u.Groups = []string{}
+			}
+		}
+		if cmd.Flags().Changed("project") {
+			u.Projects, _ = cmd.Flags().GetStringSlice("project")
+			if u.Projects == nil {
+				u.Projects = []string{}
+			}
+		}
+
+		a, err := cpClient.RegisterAgent(context.Background(), id, u)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(a)
+			fmt.Println(s)
+			return nil
+		}
+
+		fmt.Printf("Registered %s (capabilities: %s; groups: %s; projects: %s)\n", a.ID,
+			orDash(strings.Join(a.Capabilities, ", ")), orDash(strings.Join(a.Groups, ", ")), agentProjects(a))
+		return nil
+	},
+}
+
+// -- agent list --
+
+var agentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered agents",
+	Example: `  cp agent list
+  cp agent list --capability review
+  cp agent list --group implementers
+  cp agent list --all-projects -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all-projects")
+		capability, _ := cmd.Flags().GetString("capability")
+		group, _ := cmd.Flags().GetString("group")
+
+		agents, err := cpClient.ListAgents(context.Background(), all, capability, strings.TrimPrefix(group, client.GroupPrefix))
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(agents)
+			fmt.Println(s)
+			return nil
+		}
+
+		if len(agents) == 0 {
+			fmt.Println("No agents registered. Use 'cp agent register' to add one.")
+			return nil
+		}
+
+		tbl := client.NewTable("AGENT", "NAME", "PRESENCE", "LAST SEEN", "CAPABILITIES", "GROUPS", "PROJECTS")
+		for _, a := range agents {
+			seen := "never"
+			if a.LastSeen != nil {
+				seen = timeAgo(*a.LastSeen)
+			}
+			tbl.AddRow(a.ID, orDash(a.DisplayName), a.Presence, seen,
+				orDash(strings.Join(a.Capabilities, ",")), orDash(strings.Join(a.Groups, ",")), agentProjects(&a))
+		}
+		fmt.Print(tbl.String())
+		return nil
+	},
+}
+
+// -- agent show --
+
+var agentShowCmd = &cobra.Command{
+	Use:     "show [agent]",
+	Short:   "Show an agent and its current work (default: yourself)",
+	Args:    cobra.MaximumNArgs(1),
+	Example: "  cp agent show agent-mycroft",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		id := cpClient.Config.Agent
+		if len(args) == 1 {
+			id = args[0]
+		}
+
+		a, err := cpClient.GetAgent(ctx, id)
+		if err != nil {
+			return err
+		}
+		board, err := cpClient.GetShardBoard(ctx, nil, &id)
+		if err != nil {
+			return err
+		}
+		var working []client.BoardShard
+		for _, s := range board {
+			if s.Status == "in_progress" {
+				working = append(working, s)
+			}
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(map[string]any{"agent": a, "in_progress": nonNil(working)})
+			fmt.Println(s)
+			return nil
+		}
+
+		fmt.Printf("Agent:        %s\n", a.ID)
+		if a.DisplayName != "" {
+			fmt.Printf("Name:         %s\n", a.DisplayName)
+		}
+		seen := "never"
+		if a.LastSeen != nil {
+			seen = fmt.Sprintf("%s (%s)", timeAgo(*a.LastSeen), a.LastSeen.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Printf("Presence:     %s, last seen %s\n", a.Presence, seen)
+		fmt.Printf("Capabilities: %s\n", orDash(strings.Join(a.Capabilities, ", ")))
+		fmt.Printf("Groups:       %s\n", orDash(strings.Join(a.Groups, ", ")))
+		fmt.Printf("Projects:     %s\n", agentProjects(a))
+		fmt.Printf("Registered:   %s\n", a.RegisteredAt.Local().Format("2006-01-02 15:04"))
+		if a.Description != "" {
+			fmt.Printf("\n%s\n", a.Description)
+		}
+
+		if len(working) > 0 {
+			fmt.Printf("\nIn progress in %s (%d):\n", cpClient.Config.Project, len(working))
+			for _, s := range working {
+				lease := ""
+				if s.LeaseExpiresAt != nil {
+					lease = "  (" + leaseStatus(s.HeartbeatAt, *s.LeaseExpiresAt) + ")"
+				}
+				fmt.Printf("  %-10s %-8s %s%s\n", s.ID, s.Kind, client.Truncate(s.Title, 40), lease)
+			}
+		}
+		return nil
+	},
+}
+
+// -- agent heartbeat --
+
+var agentHeartbeatCmd = &cobra.Command{
+	Use:   "heartbeat [agent]",
+	Short: "Mark an agent as alive (default: yourself)",
+	Long: `Update the agent's last-seen time. Long-running agents should call this
+every few minutes to stay online. This does not renew shard leases; use
+'cp task heartbeat <id>' for those.`,
+	Args:    cobra.MaximumNArgs(1),
+	Example: "  cp agent heartbeat",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := cpClient.Config.Agent
+		if len(args) == 1 {
+			id = args[0]
+		}
+
+		a, err := cpClient.AgentHeartbeat(context.Background(), id)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(a)
+			fmt.Println(s)
+			return nil
+		}
+
+		fmt.Printf("%s is %s\n", a.ID, a.Presence)
+		return nil
+	},
+}
+
+// agentProjects renders the projects an agent serves
+func agentProjects(a *client.Agent) string {
+	if len(a.Projects) == 0 {
+		return "all"
+	}
+	return strings.Join(a.Projects, ",")
+}
+
+func init() {
+	agentRegisterCmd.Flags().String("name", "", "Display name")
+	agentRegisterCmd.Flags().StringSlice("capability", nil, "Capabilities/skills, e.g. go,sql,review (replaces the list)")
+	agentRegisterCmd.Flags().StringSlice("group", nil, "Message groups, e.g. implementers (replaces the list)")
+	agentRegisterCmd.Flags().StringSlice("project", nil, "Projects served (replaces the list; empty = all)")
+	agentRegisterCmd.Flags().String("description", "", "What the agent does")
+
+	agentListCmd.Flags().Bool("all-projects", false, "Include agents that do not serve this project")
+	agentListCmd.Flags().String("capability", "", "Only agents with this capability")
+	agentListCmd.Flags().String("group", "", "Only members of this message group")
+
+	agentCmd.AddCommand(agentRegisterCmd)
+	agentCmd.AddCommand(agentListCmd)
+	agentCmd.AddCommand(agentShowCmd)
+	agentCmd.AddCommand(agentHeartbeatCmd)
+	rootCmd.AddCommand(agentCmd)
+}
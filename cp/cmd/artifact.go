@@ -49,4 +49,5 @@ Artifact types: commit, file, pr, url, deploy, or any custom type.`,
 func init() {
 	rootCmd.AddCommand(artifactCmd)
 	artifactCmd.AddCommand(artifactAddCmd)
+	postgresOnly(artifactCmd)
 }
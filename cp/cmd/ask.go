@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/answer"
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask <question>",
+	Short: "Answer a question from project memory, with shard citations",
+	Long: `Answer a question grounded in Context Palace.
+
+Retrieves relevant shards with the same search as cp recall (hybrid by
+default), packs their content into a token budget, and asks the generation
+model to answer using only those sources, citing shard IDs inline like
+[pf-c74eea]. Replaces the recall → shard show → summarise loop with one call.
+
+Requires generation config. Without embedding config, retrieval falls back to
+keyword search.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  cp ask "why do allocations not restart after deploy?"
+  cp ask "what did we decide about entity resolution?" --type decision,requirement
+  cp ask "how is TLS terminated?" --budget 12000 --sources 12
+  cp ask "open auth bugs?" -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		question := args[0]
+
+		typeFlag, _ := cmd.Flags().GetString("type")
+		labelFlag, _ := cmd.Flags().GetString("label")
+		includeClosed, _ := cmd.Flags().GetBool("include-closed")
+		minSim, _ := cmd.Flags().GetFloat64("min-similarity")
+		mode, _ := cmd.Flags().GetString("mode")
+		sources, _ := cmd.Flags().GetInt("sources")
+		budget, _ := cmd.Flags().GetInt("budget")
+
+		if cpClient.Generator == nil {
+			return fmt.Errorf("cp ask requires generation config. Use `cp recall` to search without it")
+		}
+		switch mode {
+		case "hybrid", "semantic", "keyword":
+		default:
+			return fmt.Errorf("invalid --mode %q: must be hybrid, semantic or keyword", mode)
+		}
+		if sources < 1 || sources > 50 {
+			return fmt.Errorf("sources must be 1-50")
+		}
+		if budget < 500 {
+			return fmt.Errorf("budget must be at least 500 tokens")
+		}
+		if cpClient.EmbedProvider == nil {
+			if mode == "semantic" {
+				return fmt.Errorf("semantic retrieval requires embedding config. Use `--mode keyword`")
+			}
+			mode = "keyword"
+		}
+
+		ctx := context.Background()
+
+		opts := client.RecallOptions{
+			Mode:          mode,
+			MinSimilarity: minSim,
+			Limit:         sources,
+			Weights:       client.HybridWeights{Semantic: 1, Keyword: 1},
+		}
+		if typeFlag != "" {
+			opts.Types = strings.Split(typeFlag, ",")
+		}
+		if labelFlag != "" {
+			opts.Labels = strings.Split(labelFlag, ",")
+		}
+		if !includeClosed {
+			opts.Status = []string{"open"}
+		}
+
+		if mode != "keyword" {
+			if err := checkEmbeddingModel(ctx, cmd, cpClient.EmbedProvider.Info(), false); err != nil {
+				return err
+			}
+		}
+
+		results, err := cpClient.Recall(ctx, question, opts)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return fmt.Errorf("no shards match the question; nothing to answer from")
+		}
+
+		candidates := make([]answer.Source, 0, len(results))
+		byID := make(map[string]client.RecallResult, len(results))
+		for _, r := range results {
+			_, _, content, err := cpClient.GetShardContentForEmbedding(ctx, r.ID)
+			if err != nil {
+				return err
+			}
+			src := answer.Source{ID: r.ID, Type: r.Type, Title: r.Title, Content: content, Similarity: r.Similarity}
+			if r.ChunkHeading != nil {
+				src.Heading = *r.ChunkHeading
+			}
+			candidates = append(candidates, src)
+			byID[r.ID] = r
+		}
+		packed := answer.Pack(candidates, budget)
+		if len(packed) == 0 {
+			return fmt.Errorf("budget of %d tokens is too small for any source", budget)
+		}
+
+		response, err := cpClient.Generator.Generate(ctx, answer.BuildAskPrompt(question, packed))
+		if err != nil {
+			return fmt.Errorf("answer generation failed: %v", err)
+		}
+		response = strings.TrimSpace(response)
+
+		cited := answer.Citations(response, packed)
+		citedSet := make(map[string]bool, len(cited))
+		for _, id := range cited {
+			citedSet[id] = true
+		}
+
+		result := askResult{Question: question, Answer: response}
+		for _, id := range cited {
+			result.Citations = append(result.Citations, newAskSource(byID[id], true))
+		}
+		for _, s := range packed {
+			if !citedSet[s.ID] {
+				result.Uncited = append(result.Uncited, newAskSource(byID[s.ID], false))
+			}
+			if s.Truncated {
+				result.Truncated = append(result.Truncated, s.ID)
+			}
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(result)
+			fmt.Println(s)
+			return nil
+		}
+
+		fmt.Println(response)
+		fmt.Println()
+		if len(result.Citations) == 0 {
+			fmt.Println("(no sources cited)")
+		} else {
+			tbl := client.NewTable("SIMILARITY", "TYPE", "ID", "TITLE")
+			for _, c := range result.Citations {
+				tbl.AddRow(fmt.Sprintf("%.2f", c.Similarity), c.Type, c.ID, client.Truncate(c.Title, 50))
+			}
+			fmt.Print(tbl.String())
+		}
+		fmt.Printf("\n%d of %d sources cited (budget: %d tokens)\n", len(result.Citations), len(packed), budget)
+		return nil
+	},
+}
+
+// askResult is the JSON shape of cp ask
+type askResult struct {
+	Question  string      `json:"question"`
+	Answer    string      `json:"answer"`
+	Citations []askSource `json:"citations"`
+	Uncited   []askSource `json:"uncited,omitempty"`
+	Truncated []string    `json:"truncated,omitempty"`
+}
+
+// askSource is a shard given to the model as context
+type askSource struct {
+	ID         string                 `json:"id"`
+	Title      string                 `json:"title"`
+	Type       string                 `json:"type"`
+	Status     string                 `json:"status"`
+	Similarity float64                `json:"similarity"`
+	Scores     *client.ScoreBreakdown `json:"scores,omitempty"`
+	Cited      bool                   `json:"cited"`
+}
+
+func newAskSource(r client.RecallResult, cited bool) askSource {
+	return askSource{
+		ID:         r.ID,
+		Title:      r.Title,
+		Type:       r.Type,
+		Status:     r.Status,
+		Similarity: r.Similarity,
+		Scores:     r.Scores,
+		Cited:      cited,
+	}
+}
+
+func init() {
+	askCmd.Flags().String("type", "", "Filter sources by shard type (comma-separated)")
+	askCmd.Flags().String("label", "", "Filter sources by label (comma-separated)")
+	askCmd.Flags().Bool("include-closed", false, "Also use closed shards as sources")
+	askCmd.Flags().Float64("min-similarity", 0.3, "Minimum similarity for semantic matches (0.0-1.0)")
+	askCmd.Flags().String("mode", "hybrid", "Retrieval mode: hybrid, semantic, keyword")
+	askCmd.Flags().Int("sources", 8, "Maximum number of shards to retrieve (1-50)")
+	askCmd.Flags().Int("budget", 6000, "Token budget for source content in the prompt")
+
+	rootCmd.AddCommand(askCmd)
+}
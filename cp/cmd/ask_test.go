@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useMockGenerator configures the mock generation provider with a fixtures
+// file in HOME, which useLocalStore points at a temp dir
+func useMockGenerator(t *testing.T, fixtures string) {
+	t.Helper()
+	dir := filepath.Join(os.Getenv("HOME"), ".cp")
+	path := filepath.Join(dir, "fixtures.yaml")
+	config := fmt.Sprintf("generation:\n  provider: mock\n  fixtures: %s\n", path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(fixtures), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAskWithMockGenerator(t *testing.T) {
+	useLocalStore(t)
+
+	var created struct{ ID string }
+	mustCP(t, &created, "shard", "create", "--type", "memory",
+		"--title", "Pipeline timeouts", "--body", "Pipeline timeouts come from the retry budget being too small.")
+
+	useMockGenerator(t, fmt.Sprintf(`responses:
+  - match: "Pipeline timeouts come from the retry budget"
+    response: "Raise the retry budget [%s]."
+`, created.ID))
+
+	var result struct {
+		Answer    string
+		Citations []struct{ ID string }
+	}
+	mustCP(t, &result, "ask", "pipeline timeouts")
+	if want := fmt.Sprintf("Raise the retry budget [%s].", created.ID); result.Answer != want {
+		t.Errorf("answer = %q, want %q", result.Answer, want)
+	}
+	if len(result.Citations) != 1 || result.Citations[0].ID != created.ID {
+		t.Errorf("citations = %+v, want %s", result.Citations, created.ID)
+	}
+
+	// No fixture for the prompt: the generator's error surfaces
+	useMockGenerator(t, "responses: []\n")
+	_, err := runCP(t, "ask", "pipeline timeouts")
+	if err == nil || !strings.Contains(err.Error(), "answer generation failed") {
+		t.Errorf("err = %v, want the generation failure", err)
+	}
+}
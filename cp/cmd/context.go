@@ -24,7 +24,7 @@ var contextStatusCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		defer conn.Close(ctx)
+		defer conn.Release()
 
 		project := cpClient.Config.Project
 		agent := cpClient.Config.Agent
@@ -88,7 +88,7 @@ var contextHistoryCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		defer conn.Close(ctx)
+		defer conn.Release()
 
 		rows, err := conn.Query(ctx, `
 			SELECT id, type, title, creator, created_at
@@ -98,7 +98,7 @@ var contextHistoryCmd = &cobra.Command{
 			LIMIT $2
 		`, cpClient.Config.Project, limitFlag)
 		if err != nil {
-			return fmt.Errorf("failed to get history: %v", err)
+			return fmt.Errorf("failed to get history: %w", err)
 		}
 		defer rows.Close()
 
@@ -166,7 +166,7 @@ var contextMorningCmd = &cobra.Command{
 			}
 			fmt.Println()
 		} else {
-			fmt.Println("--- No unread messages ---\n")
+			fmt.Print("--- No unread messages ---\n\n")
 		}
 
 		// Open tasks
@@ -174,7 +174,7 @@ var contextMorningCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		defer conn.Close(ctx)
+		defer conn.Release()
 
 		rows, err := conn.Query(ctx, `
 			SELECT id, title, priority, status FROM shards
@@ -217,7 +217,7 @@ var contextProjectCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		defer conn.Close(ctx)
+		defer conn.Release()
 
 		project := cpClient.Config.Project
 
@@ -228,7 +228,7 @@ var contextProjectCmd = &cobra.Command{
 			GROUP BY type ORDER BY count(*) DESC
 		`, project)
 		if err != nil {
-			return fmt.Errorf("failed to get project info: %v", err)
+			return fmt.Errorf("failed to get project info: %w", err)
 		}
 		defer rows.Close()
 
@@ -267,4 +267,5 @@ func init() {
 	contextCmd.AddCommand(contextHistoryCmd)
 	contextCmd.AddCommand(contextMorningCmd)
 	contextCmd.AddCommand(contextProjectCmd)
+	postgresOnly(contextCmd)
 }
@@ -369,7 +369,89 @@ func shortAgent(agent string) string {
 	return strings.TrimPrefix(agent, "agent-")
 }
 
+var epicPlanCmd = &cobra.Command{
+	Use:   "plan <epic-id>",
+	Short: "Critical path, parallelism and slack for an epic",
+	Long: `Schedule the epic's unfinished children over the blocking edges between them
+(critical path method) and report:
+
+  - the critical path: the longest blocking chain, which sets how soon the
+    epic can finish
+  - what can start now, and the most tasks that could run at once if each
+    started as early as possible (how many agents are worth fanning out)
+  - per-task earliest start and slack: how long a task can slip without
+    delaying the epic
+
+Durations come from each task's 'estimate' metadata: a number of hours, or a
+string such as "30m", "4h", "2d" (8h days) or "1w". Tasks without one use
+--default-estimate. In-progress tasks count their full estimate. Open blockers
+outside the epic keep a task from being ready but don't move the schedule.`,
+	Example: `  cp epic plan pf-epic-3
+  cp epic plan pf-epic-3 --default-estimate 4
+  cp epic plan pf-epic-3 -o json | jq '.ready'
+  cp shard metadata set pf-task-9 estimate 2d`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defaultEstimate, _ := cmd.Flags().GetFloat64("default-estimate")
+		if defaultEstimate < 0 {
+			return fmt.Errorf("--default-estimate must not be negative")
+		}
+
+		plan, err := cpClient.PlanEpic(context.Background(), args[0], defaultEstimate)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(plan)
+			fmt.Println(s)
+			return nil
+		}
+
+		fmt.Printf("%s (%s)\n", plan.Title, plan.EpicID)
+		if len(plan.Tasks) == 0 {
+			fmt.Printf("Nothing left to schedule (%d done).\n", plan.Done)
+			return nil
+		}
+		fmt.Printf("Remaining: %d tasks, %d done. Critical path: %s\n",
+			len(plan.Tasks), plan.Done, client.FormatHours(plan.Length))
+		fmt.Printf("Ready now: %d   Running: %d   Max parallel: %d\n",
+			len(plan.Ready), len(plan.Running), plan.MaxParallel)
+		if plan.Unestimated > 0 {
+			fmt.Printf("%d tasks have no estimate and use %s.\n", plan.Unestimated, client.FormatHours(defaultEstimate))
+		}
+
+		fmt.Println()
+		tbl := client.NewTable("ID", "STATUS", "EST", "START", "FINISH", "SLACK", "BLOCKED BY", "TITLE")
+		for _, t := range plan.Tasks {
+			id := t.ID
+			if t.Critical {
+				id = "*" + id
+			}
+			status := t.Status
+			if t.Ready {
+				status = "ready"
+			}
+			est := client.FormatHours(t.Estimate)
+			if !t.Estimated {
+				est += "?"
+			}
+			blockers := append(append([]string{}, t.BlockedBy...), t.ExternalBlockers...)
+			tbl.AddRow(id, status, est, client.FormatHours(t.EarliestStart), client.FormatHours(t.EarliestFinish),
+				client.FormatHours(t.Slack), orDash(strings.Join(blockers, ",")), client.Truncate(t.Title, 40))
+		}
+		fmt.Print(tbl.String())
+
+		fmt.Printf("\nCritical path: %s\n", strings.Join(plan.CriticalPath, " -> "))
+		fmt.Println("* critical (no slack), ? default estimate")
+		return nil
+	},
+}
+
 func init() {
+	// epic plan flags
+	epicPlanCmd.Flags().Float64("default-estimate", 1, "Hours assumed for tasks without estimate metadata")
+
 	// epic create flags
 	epicCreateCmd.Flags().String("title", "", "Epic title (required)")
 	epicCreateCmd.Flags().String("body", "", "Epic description")
@@ -386,6 +468,8 @@ func init() {
 	epicCmd.AddCommand(epicCreateCmd)
 	epicCmd.AddCommand(epicShowCmd)
 	epicCmd.AddCommand(epicListCmd)
+	epicCmd.AddCommand(epicPlanCmd)
 
 	rootCmd.AddCommand(epicCmd)
+	postgresOnly(epicCmd)
 }
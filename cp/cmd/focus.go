@@ -66,7 +66,7 @@ var focusCmd = &cobra.Command{
 				fmt.Printf("  In progress: %s %s (%s)\n", ch.ID, ch.Title, shortAgent(*ch.Owner))
 			}
 		}
-		nextShards, _ := cpClient.GetNextShards(ctx, &focus.EpicID, 1)
+		nextShards, _ := cpClient.GetNextShards(ctx, &focus.EpicID, nil, 1)
 		if len(nextShards) > 0 {
 			fmt.Printf("  Next: %s %s\n", nextShards[0].ID, nextShards[0].Title)
 		}
@@ -191,4 +191,5 @@ func init() {
 	focusCmd.AddCommand(focusClearCmd)
 
 	rootCmd.AddCommand(focusCmd)
+	postgresOnly(focusCmd)
 }
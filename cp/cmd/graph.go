@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Shard relationship graphs",
+}
+
+var graphExportCmd = &cobra.Command{
+	Use:   "export [root-id]",
+	Short: "Export shard relationships as DOT, Mermaid or JSON",
+	Long: `Export the graph of shards and edges around a root shard, an epic (--epic) or
+the whole project (--project).
+
+From a root shard, edges are followed up to --depth hops. For an epic, its
+children are included (linked to the epic as child-of) along with the edges
+between them; for the project, every shard matching --status/--type is
+included. --depth then adds neighbours outside that set (default 0).
+
+Formats:
+  dot      Graphviz ('cp graph export pf-1 | dot -Tsvg > deps.svg')
+  mermaid  flowchart to paste into Markdown specs and reviews
+  json     {"nodes": [...], "links": [...]} for other tools (also -o json)
+
+Nodes are coloured by status (open blue, in_progress amber, closed green).
+Blocking edges are drawn bold/thick; edges on a cycle, and the shards on it,
+are red and flagged in JSON.`,
+	Example: `  cp graph export pf-req-01
+  cp graph export pf-req-01 --depth 5 --edge-type blocked-by --format mermaid
+  cp graph export --epic pf-epic-3 --status open,in_progress | dot -Tpng > epic.png
+  cp graph export --project --type task --edge-type blocked-by --format json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		epicID, _ := cmd.Flags().GetString("epic")
+		project, _ := cmd.Flags().GetBool("project")
+		format, _ := cmd.Flags().GetString("format")
+		direction, _ := cmd.Flags().GetString("direction")
+		edgeTypeFlag, _ := cmd.Flags().GetString("edge-type")
+		statusFlag, _ := cmd.Flags().GetString("status")
+		typeFlag, _ := cmd.Flags().GetString("type")
+		maxNodes, _ := cmd.Flags().GetInt("max-nodes")
+		outFile, _ := cmd.Flags().GetString("file")
+
+		opts := client.GraphOptions{
+			EpicID:    epicID,
+			Project:   project,
+			Direction: direction,
+			EdgeTypes: splitList(edgeTypeFlag),
+			Status:    splitList(statusFlag),
+			Types:     splitList(typeFlag),
+			MaxNodes:  maxNodes,
+		}
+		scopes := 0
+		if len(args) == 1 {
+			opts.RootID = args[0]
+			scopes++
+		}
+		if epicID != "" {
+			scopes++
+		}
+		if project {
+			scopes++
+		}
+		if scopes != 1 {
+			return fmt.Errorf("give exactly one of a root shard ID, --epic or --project")
+		}
+
+		opts.Depth, _ = cmd.Flags().GetInt("depth")
+		if !cmd.Flags().Changed("depth") && opts.RootID == "" {
+			opts.Depth = 0
+		}
+		if opts.Depth < 0 {
+			return fmt.Errorf("--depth must not be negative")
+		}
+		switch direction {
+		case "", "both":
+			opts.Direction = ""
+		case "outgoing", "incoming":
+		default:
+			return fmt.Errorf("--direction must be outgoing, incoming or both")
+		}
+		if outputFormat == "json" {
+			format = "json"
+		}
+
+		g, err := cpClient.BuildGraph(context.Background(), opts)
+		if err != nil {
+			return err
+		}
+
+		var out string
+		switch format {
+		case "dot":
+			out = g.DOT()
+		case "mermaid":
+			out = g.Mermaid()
+		case "json":
+			s, err := client.FormatJSON(g)
+			if err != nil {
+				return err
+			}
+			out = s + "\n"
+		default:
+			return fmt.Errorf("unknown format %q: use dot, mermaid or json", format)
+		}
+
+		if outFile == "" {
+			fmt.Print(out)
+		} else if err := os.WriteFile(outFile, []byte(out), 0o644); err != nil {
+			return fmt.Errorf("cannot write %s: %v", outFile, err)
+		}
+
+		if len(g.Nodes) >= maxNodes {
+			fmt.Fprintf(os.Stderr, "Stopped at %d shards (--max-nodes); the graph is incomplete.\n", len(g.Nodes))
+		}
+		return nil
+	},
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func init() {
+	graphExportCmd.Flags().String("epic", "", "Export an epic, its children and the edges between them")
+	graphExportCmd.Flags().Bool("project", false, "Export every shard in the project")
+	graphExportCmd.Flags().String("format", "dot", "Output format: dot, mermaid or json")
+	graphExportCmd.Flags().Int("depth", 3, "Hops to follow from the starting shards (default 3 from a root, 0 for --epic/--project)")
+	graphExportCmd.Flags().String("direction", "both", "Edges to follow: outgoing, incoming or both")
+	graphExportCmd.Flags().String("edge-type", "", "Comma-separated edge types to follow (default: all)")
+	graphExportCmd.Flags().String("status", "", "Comma-separated statuses of shards to include (default: all)")
+	graphExportCmd.Flags().String("type", "", "Comma-separated shard types to include (default: all)")
+	graphExportCmd.Flags().Int("max-nodes", 500, "Stop adding shards past this many")
+	graphExportCmd.Flags().String("file", "", "Write to this file instead of stdout")
+
+	graphCmd.AddCommand(graphExportCmd)
+	rootCmd.AddCommand(graphCmd)
+}
@@ -405,4 +405,5 @@ func init() {
 	knowledgeCmd.AddCommand(kdDiffCmd)
 
 	rootCmd.AddCommand(knowledgeCmd)
+	postgresOnly(knowledgeCmd)
 }
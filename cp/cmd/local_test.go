@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// useLocalStore points the CLI at a fresh local data file for the test
+func useLocalStore(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir) // no ~/.cp/config.yaml
+	t.Setenv("CP_BACKEND", client.BackendLocal)
+	t.Setenv("CP_LOCAL_PATH", filepath.Join(dir, "local.json"))
+	t.Setenv("CP_PROJECT", "demo")
+	t.Setenv("CP_AGENT", "agent-a")
+}
+
+// runCP runs cp with args and returns what it printed on stdout
+func runCP(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(args)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	err = Execute()
+	os.Stdout = stdout
+	w.Close()
+	return <-out, err
+}
+
+// mustCP is runCP for commands that must succeed, decoding JSON output into v
+func mustCP(t *testing.T, v any, args ...string) {
+	t.Helper()
+	out, err := runCP(t, append(args, "-o", "json")...)
+	if err != nil {
+		t.Fatalf("cp %s: %v", strings.Join(args, " "), err)
+	}
+	if v != nil {
+		if err := json.Unmarshal([]byte(out), v); err != nil {
+			t.Fatalf("cp %s: bad JSON %q: %v", strings.Join(args, " "), out, err)
+		}
+	}
+}
+
+// resetFlags puts every flag back to its default; cobra keeps values between
+// Execute calls
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func TestLocalTaskLifecycle(t *testing.T) {
+	useLocalStore(t)
+
+	var created struct{ ID string }
+	mustCP(t, &created, "shard", "create", "--title", "Fix parser", "--type", "task", "--label", "backend")
+	if created.ID == "" {
+		t.Fatal("shard create returned no ID")
+	}
+
+	var taken struct {
+		Taken bool
+		Owner string
+		Shard struct{ ID string }
+	}
+	mustCP(t, &taken, "shard", "take", "--agent", "agent-w1")
+	if !taken.Taken || taken.Owner != "agent-w1" || taken.Shard.ID != created.ID {
+		t.Fatalf("shard take = %+v, want %s taken by agent-w1", taken, created.ID)
+	}
+
+	var again struct{ Taken bool }
+	mustCP(t, &again, "shard", "take", "--agent", "agent-w2")
+	if again.Taken {
+		t.Fatal("second shard take claimed the only task again")
+	}
+
+	mustCP(t, nil, "task", "progress", created.ID, "half way", "--agent", "agent-w1")
+	mustCP(t, nil, "shard", "close", created.ID, "--reason", "done")
+
+	var list struct {
+		Results []struct {
+			ID, Status string
+			Labels     []string
+		}
+	}
+	mustCP(t, &list, "shard", "list", "--status", "closed")
+	if len(list.Results) != 1 || list.Results[0].ID != created.ID {
+		t.Fatalf("closed shards = %+v, want only %s", list.Results, created.ID)
+	}
+	if got := list.Results[0].Labels; len(got) != 1 || got[0] != "backend" {
+		t.Errorf("labels = %v, want [backend]", got)
+	}
+}
+
+func TestLocalProgressOnBug(t *testing.T) {
+	useLocalStore(t)
+
+	var bug, memory struct{ ID string }
+	mustCP(t, &bug, "shard", "create", "--title", "Crash on empty input", "--type", "bug")
+	mustCP(t, &memory, "shard", "create", "--title", "Parser notes", "--type", "memory")
+
+	var taken struct{ Shard struct{ ID string } }
+	mustCP(t, &taken, "shard", "take", "--agent", "agent-w1")
+	if taken.Shard.ID != bug.ID {
+		t.Fatalf("shard take = %+v, want %s", taken, bug.ID)
+	}
+	mustCP(t, nil, "task", "progress", bug.ID, "repro found", "--agent", "agent-w1")
+
+	var shown struct {
+		Content  string
+		Metadata struct {
+			AssignedAt  string `json:"assigned_at"`
+			HeartbeatAt string `json:"heartbeat_at"`
+		}
+	}
+	mustCP(t, &shown, "shard", "show", bug.ID)
+	if !strings.Contains(shown.Content, "agent-w1:** repro found") {
+		t.Errorf("content = %q, want the progress note", shown.Content)
+	}
+	if shown.Metadata.HeartbeatAt == shown.Metadata.AssignedAt {
+		t.Error("progress from the owner did not renew the lease")
+	}
+
+	if _, err := runCP(t, "task", "progress", memory.ID, "note"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("progress on a memory: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalRequestReply(t *testing.T) {
+	useLocalStore(t)
+
+	var sent struct{ ID string }
+	mustCP(t, &sent, "message", "send", "agent-b", "Verify batch 7", "--body", "please", "--due", "4h")
+
+	var pending struct {
+		Owed []struct {
+			ID   string
+			Owed []string
+		}
+		Sent []struct{ ID string }
+	}
+	mustCP(t, &pending, "message", "pending", "--agent", "agent-b")
+	if len(pending.Owed) != 1 || pending.Owed[0].ID != sent.ID {
+		t.Fatalf("agent-b owes %+v, want %s", pending.Owed, sent.ID)
+	}
+
+	mustCP(t, nil, "message", "reply", sent.ID, "--body", "verified", "--agent", "agent-b")
+
+	pending.Owed, pending.Sent = nil, nil
+	mustCP(t, &pending, "message", "pending")
+	if len(pending.Sent) != 0 {
+		t.Fatalf("agent-a still waits on %+v after the reply", pending.Sent)
+	}
+}
+
+func TestPostgresOnlyCommandsOnLocal(t *testing.T) {
+	useLocalStore(t)
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"requirement", []string{"requirement", "create", "Login"}},
+		{"knowledge", []string{"knowledge", "list"}},
+		{"focus", []string{"focus"}},
+		{"epic", []string{"epic", "list"}},
+		{"session", []string{"session", "start"}},
+		{"memory hierarchy", []string{"memory", "tree"}},
+		{"task claim", []string{"task", "claim", "de-000000"}},
+		{"shard metadata", []string{"shard", "metadata", "get", "de-000000"}},
+		{"watch", []string{"watch"}},
+		{"admin migrate", []string{"admin", "migrate", "status"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runCP(t, tt.args...)
+			if !errors.Is(err, client.ErrUnsupported) {
+				t.Fatalf("cp %s: err = %v, want ErrUnsupported", strings.Join(tt.args, " "), err)
+			}
+			if want := "cp " + tt.args[0]; !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not name %q", err, want)
+			}
+		})
+	}
+
+	// Commands on the Store interface still work
+	if _, err := runCP(t, "memory", "add", "Timeouts need a retry"); err != nil {
+		t.Fatalf("memory add: %v", err)
+	}
+}
@@ -0,0 +1,522 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/otherjamesbrown/context-palace/cp/internal/mcp"
+	"github.com/otherjamesbrown/context-palace/cp/internal/summary"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Model Context Protocol server",
+	Long:  `Expose Context Palace to agents as Model Context Protocol (MCP) tools.`,
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve MCP tools over stdio",
+	Long: `Speak the Model Context Protocol over stdin/stdout so agents can call
+Context Palace operations as typed tools instead of parsing cp output.
+
+Tools act as the configured agent and project. Logs go to stderr; stdout
+carries only protocol messages. Register it with an MCP client, e.g.:
+
+  {"mcpServers": {"context-palace": {"command": "cp", "args": ["mcp", "serve"]}}}`,
+	Example: `  cp mcp serve
+  cp mcp serve --config ~/.cp/agent-mycroft.yaml
+  cp mcp tools -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		server := newMCPServer()
+		fmt.Fprintf(os.Stderr, "cp mcp: serving %d tools for %s on %s (stdio)\n", len(server.Tools()), cpClient.Config.Agent, cpClient.Config.Project)
+		return server.Serve(ctx, os.Stdin, os.Stdout)
+	},
+}
+
+var mcpToolsCmd = &cobra.Command{
+	Use:     "tools",
+	Short:   "List the MCP tools and their input schemas",
+	Example: "  cp mcp tools\n  cp mcp tools -o json",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tools := newMCPServer().Tools()
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(tools)
+			fmt.Println(s)
+			return nil
+		}
+
+		tbl := client.NewTable("TOOL", "ARGUMENTS", "DESCRIPTION")
+		for _, t := range tools {
+			var argNames []string
+			for name := range t.InputSchema.Properties {
+				argNames = append(argNames, name)
+			}
+			sort.Strings(argNames)
+			tbl.AddRow(t.Name, client.Truncate(strings.Join(argNames, ","), 40), client.Truncate(t.Description, 60))
+		}
+		fmt.Print(tbl.String())
+		return nil
+	},
+}
+
+// newMCPServer registers every Context Palace tool
+func newMCPServer() *mcp.Server {
+	s := mcp.NewServer("context-palace", Version)
+	for _, t := range mcpTools() {
+		s.AddTool(t)
+	}
+	return s
+}
+
+// mcpTool builds a Tool whose handler receives decoded, validated arguments
+func mcpTool[T any](name, description string, schema *mcp.Schema, fn func(ctx context.Context, args T) (any, error)) mcp.Tool {
+	return mcp.Tool{Name: name, Description: description, InputSchema: schema, Handler: mcp.Typed(schema, fn)}
+}
+
+type mcpInboxArgs struct {
+	MarkRead bool `json:"mark_read"`
+}
+
+type mcpSendArgs struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	CC      []string `json:"cc"`
+	Kind    string   `json:"kind"`
+	ReplyTo string   `json:"reply_to"`
+	Due     string   `json:"due"`
+}
+
+type mcpReplyArgs struct {
+	ID      string   `json:"id"`
+	Body    string   `json:"body"`
+	CC      []string `json:"cc"`
+	Kind    string   `json:"kind"`
+	All     bool     `json:"all"`
+	NoQuote bool     `json:"no_quote"`
+}
+
+type mcpSearchArgs struct {
+	Query         string   `json:"query"`
+	Mode          string   `json:"mode"`
+	Types         []string `json:"types"`
+	Labels        []string `json:"labels"`
+	Status        []string `json:"status"`
+	IncludeClosed bool     `json:"include_closed"`
+	Since         string   `json:"since"`
+	Limit         int      `json:"limit"`
+	MinSimilarity *float64 `json:"min_similarity"`
+}
+
+type mcpAddSubArgs struct {
+	ParentID string   `json:"parent_id"`
+	Title    string   `json:"title"`
+	Body     string   `json:"body"`
+	Labels   []string `json:"labels"`
+	Summary  string   `json:"summary"`
+}
+
+type mcpNextArgs struct {
+	EpicID       string   `json:"epic_id"`
+	Capabilities []string `json:"capabilities"`
+	Limit        int      `json:"limit"`
+}
+
+type mcpTakeArgs struct {
+	EpicID string `json:"epic_id"`
+	Kind   string `json:"kind"`
+	Agent  string `json:"agent"`
+}
+
+type mcpAssignArgs struct {
+	ID    string `json:"id"`
+	Agent string `json:"agent"`
+}
+
+type mcpHeartbeatArgs struct {
+	ID string `json:"id"`
+}
+
+type mcpCloseArgs struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+type mcpShardArgs struct {
+	ID string `json:"id"`
+}
+
+type mcpListDocsArgs struct {
+	DocType string `json:"doc_type"`
+	Limit   int    `json:"limit"`
+}
+
+type mcpGetDocArgs struct {
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+}
+
+type mcpUpdateDocArgs struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Summary string `json:"summary"`
+	Mode    string `json:"mode"`
+}
+
+func mcpTools() []mcp.Tool {
+	shardID := mcp.String("Shard ID, e.g. pf-abc123")
+
+	return []mcp.Tool{
+		mcpTool("get_inbox", "List unread messages addressed to this agent.",
+			mcp.Object(
+				mcp.Prop{Name: "mark_read", Schema: mcp.Boolean("Mark the returned messages as read")},
+			),
+			func(ctx context.Context, a mcpInboxArgs) (any, error) {
+				messages, err := cpClient.GetInbox(ctx)
+				if err != nil {
+					return nil, err
+				}
+				out := map[string]any{"messages": nonNil(messages)}
+				if a.MarkRead && len(messages) > 0 {
+					ids := make([]string, len(messages))
+					for i, m := range messages {
+						ids[i] = m.ID
+					}
+					n, err := cpClient.MarkRead(ctx, ids)
+					if err != nil {
+						return nil, err
+					}
+					out["marked_read"] = n
+				}
+				return out, nil
+			}),
+
+		mcpTool("send_message", "Send a message to one or more agents.",
+			mcp.Object(
+				mcp.Prop{Name: "to", Schema: mcp.StringArray("Recipient agent names, or groups such as @implementers and @all"), Required: true},
+				mcp.Prop{Name: "subject", Schema: mcp.String("Message subject"), Required: true},
+				mcp.Prop{Name: "body", Schema: mcp.String("Message body (markdown)")},
+				mcp.Prop{Name: "cc", Schema: mcp.StringArray("CC agent names")},
+				mcp.Prop{Name: "kind", Schema: mcp.String("Message kind, e.g. bug-report, status-update")},
+				mcp.Prop{Name: "reply_to", Schema: mcp.String("ID of the message this replies to")},
+				mcp.Prop{Name: "due", Schema: mcp.String("Reply deadline, e.g. 4h, 2d or 2026-03-01 17:00; makes the message a request that expects a reply")},
+			),
+			func(ctx context.Context, a mcpSendArgs) (any, error) {
+				if len(a.To) == 0 {
+					return nil, fmt.Errorf("at least one recipient is required")
+				}
+				var sent *client.SendResult
+				var err error
+				if a.Due != "" {
+					if a.Kind != "" && a.Kind != client.RequestKind {
+						return nil, fmt.Errorf("due only applies to kind %s", client.RequestKind)
+					}
+					due, perr := client.ParseDue(a.Due, time.Now())
+					if perr != nil {
+						return nil, perr
+					}
+					sent, err = cpClient.SendRequest(ctx, a.To, a.Subject, a.Body, a.CC, a.ReplyTo, &due)
+				} else {
+					sent, err = cpClient.SendMessage(ctx, a.To, a.Subject, a.Body, a.CC, a.Kind, a.ReplyTo)
+				}
+				if err != nil {
+					return nil, err
+				}
+				out := map[string]any{"id": sent.ID, "to": a.To}
+				if len(sent.Warnings) > 0 {
+					out["warnings"] = sent.Warnings
+				}
+				return out, nil
+			}),
+
+		mcpTool("reply_message", "Reply to a message: goes to its sender with the cc list copied, subject prefixed Re: and the original quoted.",
+			mcp.Object(
+				mcp.Prop{Name: "id", Schema: mcp.String("ID of the message to reply to"), Required: true},
+				mcp.Prop{Name: "body", Schema: mcp.String("Reply body (markdown)")},
+				mcp.Prop{Name: "cc", Schema: mcp.StringArray("Additional CC agent names")},
+				mcp.Prop{Name: "kind", Schema: mcp.String("Message kind, e.g. status-update")},
+				mcp.Prop{Name: "all", Schema: mcp.Boolean("Also copy the original's other recipients")},
+				mcp.Prop{Name: "no_quote", Schema: mcp.Boolean("Do not quote the original")},
+			),
+			func(ctx context.Context, a mcpReplyArgs) (any, error) {
+				draft, err := cpClient.PrepareReply(ctx, a.ID, a.Body, a.All, !a.NoQuote)
+				if err != nil {
+					return nil, err
+				}
+				sent, err := cpClient.Reply(ctx, draft, a.CC, a.Kind)
+				if err != nil {
+					return nil, err
+				}
+				out := map[string]any{"id": sent.ID, "to": draft.To, "cc": nonNil(draft.Cc), "subject": draft.Subject}
+				if len(sent.Warnings) > 0 {
+					out["warnings"] = sent.Warnings
+				}
+				return out, nil
+			}),
+
+		mcpTool("get_pending_requests", "List requests (kind=request messages) you sent that still await replies, and those you owe a reply to, with deadlines.",
+			mcp.Object(),
+			func(ctx context.Context, a struct{}) (any, error) {
+				sent, owed, err := cpClient.PendingRequests(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{"sent": sent, "owed": owed}, nil
+			}),
+
+		mcpTool("get_thread", "Read the whole conversation a message belongs to, in reply order, with who has read each message.",
+			mcp.Object(
+				mcp.Prop{Name: "id", Schema: mcp.String("ID of any message in the thread"), Required: true},
+			),
+			func(ctx context.Context, a mcpShardArgs) (any, error) {
+				return cpClient.GetThread(ctx, a.ID)
+			}),
+
+		mcpTool("semantic_search", "Search shards (memories, bugs, tasks, docs, messages) by meaning and exact terms, like cp recall.",
+			mcp.Object(
+				mcp.Prop{Name: "query", Schema: mcp.String("What to search for"), Required: true},
+				mcp.Prop{Name: "mode", Schema: mcp.Enum("hybrid (default), semantic or keyword", "hybrid", "semantic", "keyword")},
+				mcp.Prop{Name: "types", Schema: mcp.StringArray("Only these shard types")},
+				mcp.Prop{Name: "labels", Schema: mcp.StringArray("Only shards with any of these labels")},
+				mcp.Prop{Name: "status", Schema: mcp.StringArray("Only these statuses (default: open)")},
+				mcp.Prop{Name: "include_closed", Schema: mcp.Boolean("Search all statuses")},
+				mcp.Prop{Name: "since", Schema: mcp.String("Created after: duration (7d, 24h) or date (2026-01-01)")},
+				mcp.Prop{Name: "limit", Schema: mcp.Integer("Maximum results (default 10)", 1, 100)},
+				mcp.Prop{Name: "min_similarity", Schema: mcp.Number("Minimum semantic similarity (default 0.3)", 0, 1)},
+			),
+			func(ctx context.Context, a mcpSearchArgs) (any, error) {
+				opts := client.RecallOptions{Mode: a.Mode, Types: a.Types, Labels: a.Labels, Status: a.Status, MinSimilarity: 0.3, Limit: 10,
+					Weights: client.HybridWeights{Semantic: 1, Keyword: 1}}
+				if opts.Mode == "" {
+					opts.Mode = "hybrid"
+				}
+				if a.Limit > 0 {
+					opts.Limit = min(a.Limit, 100)
+				}
+				if a.MinSimilarity != nil {
+					opts.MinSimilarity = *a.MinSimilarity
+				}
+				if opts.Status == nil && !a.IncludeClosed {
+					opts.Status = []string{"open"}
+				}
+				if a.Since != "" {
+					cutoff, err := parseSince(a.Since)
+					if err != nil {
+						return nil, err
+					}
+					opts.Since = &cutoff
+				}
+				if cpClient.EmbedProvider == nil && opts.Mode == "hybrid" {
+					opts.Mode = "keyword"
+				}
+				results, err := cpClient.Recall(ctx, a.Query, opts)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{"mode": opts.Mode, "results": nonNil(results)}, nil
+			}),
+
+		mcpTool("get_shard", "Read a shard's full content and metadata.",
+			mcp.Object(
+				mcp.Prop{Name: "id", Schema: shardID, Required: true},
+			),
+			func(ctx context.Context, a mcpShardArgs) (any, error) {
+				return cpClient.GetShard(ctx, a.ID)
+			}),
+
+		mcpTool("add_sub_memory", "Store a new memory under a parent memory, with a trigger summary saying when to load it.",
+			mcp.Object(
+				mcp.Prop{Name: "parent_id", Schema: mcp.String("Parent memory ID"), Required: true},
+				mcp.Prop{Name: "title", Schema: mcp.String("Memory title"), Required: true},
+				mcp.Prop{Name: "body", Schema: mcp.String("Memory content (markdown)"), Required: true},
+				mcp.Prop{Name: "labels", Schema: mcp.StringArray("Labels")},
+				mcp.Prop{Name: "summary", Schema: mcp.String("Trigger summary (max 120 chars): when should an agent read this? Generated if omitted and a generation provider is configured")},
+			),
+			func(ctx context.Context, a mcpAddSubArgs) (any, error) {
+				parent, err := cpClient.GetShard(ctx, a.ParentID)
+				if err != nil {
+					return nil, fmt.Errorf("parent %s not found: %v", a.ParentID, err)
+				}
+				if parent.Type != "memory" {
+					return nil, fmt.Errorf("parent %s is type '%s', expected 'memory'", a.ParentID, parent.Type)
+				}
+
+				trigger := a.Summary
+				if trigger == "" {
+					if cpClient.Generator == nil {
+						return nil, fmt.Errorf("summary is required when no generation provider is configured")
+					}
+					trigger, err = summary.GenerateTrigger(ctx, cpClient.Generator, a.ParentID, parent.Content, a.Title, a.Body)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				return cpClient.AddSubMemory(ctx, a.ParentID, client.AddSubOpts{
+					Title:   a.Title,
+					Body:    a.Body,
+					Labels:  a.Labels,
+					Summary: trigger,
+					Vector:  cpClient.PrecomputeEmbedding(ctx, a.Title, a.Body),
+				})
+			}),
+
+		mcpTool("get_next_shards", "List the next unblocked open shards to work on, optionally within an epic.",
+			mcp.Object(
+				mcp.Prop{Name: "epic_id", Schema: mcp.String("Only shards in this epic (default: focused epic, else global)")},
+				mcp.Prop{Name: "capabilities", Schema: mcp.StringArray("Only shards whose needs: labels are all among these capabilities")},
+				mcp.Prop{Name: "limit", Schema: mcp.Integer("Maximum shards (default 5)", 1, 100)},
+			),
+			func(ctx context.Context, a mcpNextArgs) (any, error) {
+				var epicID *string
+				if a.EpicID != "" {
+					epicID = &a.EpicID
+				} else if focus, _ := cpClient.GetFocus(ctx); focus != nil {
+					epicID = &focus.EpicID
+				}
+				limit := a.Limit
+				if limit <= 0 {
+					limit = 5
+				}
+				shards, err := cpClient.GetNextShards(ctx, epicID, a.Capabilities, limit)
+				if err != nil {
+					return nil, err
+				}
+				out := map[string]any{"scope": "global", "next": nonNil(shards)}
+				if epicID != nil {
+					out["scope"] = "epic"
+					out["epic_id"] = *epicID
+				}
+				return out, nil
+			}),
+
+		mcpTool("take_shard", "Atomically claim the next unblocked open shard: pick it and assign it in one step, so parallel agents never get the same shard.",
+			mcp.Object(
+				mcp.Prop{Name: "epic_id", Schema: mcp.String("Only shards in this epic (default: focused epic, else global)")},
+				mcp.Prop{Name: "kind", Schema: mcp.String("Only shards of this kind (bug, feature, test, task)")},
+				mcp.Prop{Name: "agent", Schema: mcp.String("Agent to assign (default: this agent)")},
+			),
+			func(ctx context.Context, a mcpTakeArgs) (any, error) {
+				var epicID, kind *string
+				if a.EpicID != "" {
+					epicID = &a.EpicID
+				} else if focus, _ := cpClient.GetFocus(ctx); focus != nil {
+					epicID = &focus.EpicID
+				}
+				if a.Kind != "" {
+					kind = &a.Kind
+				}
+				agent := a.Agent
+				if agent == "" {
+					agent = cpClient.Config.Agent
+				}
+				taken, err := cpClient.TakeShard(ctx, epicID, kind, agent, 0)
+				if err != nil {
+					return nil, err
+				}
+				if taken == nil {
+					return map[string]any{"taken": false}, nil
+				}
+				return map[string]any{"taken": true, "owner": agent, "shard": taken}, nil
+			}),
+
+		mcpTool("assign_shard", "Claim a shard: set its owner and mark it in_progress.",
+			mcp.Object(
+				mcp.Prop{Name: "id", Schema: shardID, Required: true},
+				mcp.Prop{Name: "agent", Schema: mcp.String("Agent to assign (default: this agent)")},
+			),
+			func(ctx context.Context, a mcpAssignArgs) (any, error) {
+				return cpClient.AssignShard(ctx, a.ID, a.Agent, 0)
+			}),
+
+		mcpTool("heartbeat_shard", "Renew your lease on a shard you are working on. Claims lapse and return to open if not renewed; call this during long work.",
+			mcp.Object(
+				mcp.Prop{Name: "id", Schema: shardID, Required: true},
+			),
+			func(ctx context.Context, a mcpHeartbeatArgs) (any, error) {
+				return cpClient.Heartbeat(ctx, a.ID, 0)
+			}),
+
+		mcpTool("close_shard", "Close a shard and report any shards it unblocked.",
+			mcp.Object(
+				mcp.Prop{Name: "id", Schema: shardID, Required: true},
+				mcp.Prop{Name: "reason", Schema: mcp.String("Why it was closed / what was done")},
+			),
+			func(ctx context.Context, a mcpCloseArgs) (any, error) {
+				return cpClient.CloseShard(ctx, a.ID, a.Reason)
+			}),
+
+		mcpTool("list_knowledge_docs", "List versioned knowledge documents (architecture, runbooks, decisions).",
+			mcp.Object(
+				mcp.Prop{Name: "doc_type", Schema: mcp.Enum("Only this document type", client.ValidDocTypes...)},
+				mcp.Prop{Name: "limit", Schema: mcp.Integer("Maximum documents (default 20)", 1, 1000)},
+			),
+			func(ctx context.Context, a mcpListDocsArgs) (any, error) {
+				limit := a.Limit
+				if limit <= 0 {
+					limit = 20
+				}
+				docs, err := cpClient.ListKnowledgeDocs(ctx, a.DocType, limit)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{"documents": nonNil(docs)}, nil
+			}),
+
+		mcpTool("get_knowledge_doc", "Read a knowledge document, optionally at a past version.",
+			mcp.Object(
+				mcp.Prop{Name: "id", Schema: mcp.String("Document ID"), Required: true},
+				mcp.Prop{Name: "version", Schema: mcp.Integer("Version to read (default: current)", 1, 1e6)},
+			),
+			func(ctx context.Context, a mcpGetDocArgs) (any, error) {
+				if a.Version > 0 {
+					return cpClient.GetKnowledgeVersion(ctx, a.ID, a.Version)
+				}
+				return cpClient.ShowKnowledgeDoc(ctx, a.ID)
+			}),
+
+		mcpTool("update_knowledge_doc", "Replace or append to a knowledge document. The previous version is preserved.",
+			mcp.Object(
+				mcp.Prop{Name: "id", Schema: mcp.String("Document ID"), Required: true},
+				mcp.Prop{Name: "content", Schema: mcp.String("New full content (replace) or text to append"), Required: true},
+				mcp.Prop{Name: "summary", Schema: mcp.String("What changed, for the version history"), Required: true},
+				mcp.Prop{Name: "mode", Schema: mcp.Enum("replace (default) or append", "replace", "append")},
+			),
+			func(ctx context.Context, a mcpUpdateDocArgs) (any, error) {
+				if strings.TrimSpace(a.Summary) == "" {
+					return nil, fmt.Errorf("summary must describe the change")
+				}
+				if a.Mode == "append" {
+					return cpClient.AppendKnowledgeDoc(ctx, a.ID, a.Content, a.Summary)
+				}
+				return cpClient.UpdateKnowledgeDoc(ctx, a.ID, a.Content, a.Summary)
+			}),
+	}
+}
+
+// nonNil returns an empty slice for nil so JSON shows [] rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func init() {
+	mcpCmd.AddCommand(mcpServeCmd)
+	mcpCmd.AddCommand(mcpToolsCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
@@ -269,4 +269,5 @@ func init() {
 	memoryShowCmd.Flags().Int("depth", 0, "Expand children inline (0-5)")
 
 	memoryCmd.AddCommand(memoryShowCmd)
+	postgresOnly(memoryShowCmd)
 }
@@ -397,4 +397,5 @@ func init() {
 	memoryCmd.AddCommand(memoryDeleteCmd)
 	memoryCmd.AddCommand(memoryMoveCmd)
 	memoryCmd.AddCommand(memoryPromoteCmd)
+	postgresOnly(memoryAddSubCmd, memoryDeleteCmd, memoryMoveCmd, memoryPromoteCmd)
 }
@@ -338,4 +338,5 @@ func init() {
 	memoryCmd.AddCommand(memoryTreeCmd)
 	memoryCmd.AddCommand(memoryHotCmd)
 	memoryCmd.AddCommand(memorySyncCmd)
+	postgresOnly(memoryTreeCmd, memoryHotCmd, memorySyncCmd)
 }
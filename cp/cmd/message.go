@@ -3,7 +3,9 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/otherjamesbrown/context-palace/cp/internal/client"
 	"github.com/spf13/cobra"
@@ -18,10 +20,30 @@ var messageCmd = &cobra.Command{
 var messageSendCmd = &cobra.Command{
 	Use:   "send <recipient> <subject>",
 	Short: "Send a message",
-	Args:  cobra.ExactArgs(2),
+	Long: `Send a message to one or more agents (comma-separated).
+
+A recipient written @group, in the recipient list or --cc, is expanded to the
+registered agents in that group (cp agent register --group) that serve this
+project; @all reaches every one of them. Each member gets the message in their
+own inbox and has their own read receipt (cp message sent).
+
+Recipients and cc are checked against the agent registry (cp agent list): a
+name that is not registered, or that does not serve this project, gets a
+warning, or with --strict the message is not sent. Projects with no registered
+agents skip the check.
+
+--kind request marks the message as expecting a reply from each recipient,
+and --due (which implies --kind request) sets a deadline. Outstanding
+requests show in cp message pending until every recipient has replied with
+cp message reply; overdue ones are chased by cp serve (every 5 minutes by
+default) or by running cp message escalate.`,
+	Args: cobra.ExactArgs(2),
 	Example: `  cp message send agent-mycroft "Bug found in entity pipeline"
   cp message send agent-mycroft "Bug found" --body "Details here" --kind bug-report
-  cp message send agent-mycroft "Re: Bug" --body "Looking into it" --reply-to pf-abc123`,
+  cp message send agent-mycroft "Re: Bug" --body "Looking into it" --reply-to pf-abc123
+  cp message send @all "Deploy freeze until 18:00" --kind announcement
+  cp message send @implementers "Schema change in 019" --cc agent-lead
+  cp message send agent-mycroft "Verify pf-abc123 on staging" --due 4h`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 
@@ -32,23 +54,59 @@ var messageSendCmd = &cobra.Command{
 		ccStr, _ := cmd.Flags().GetString("cc")
 		kind, _ := cmd.Flags().GetString("kind")
 		replyTo, _ := cmd.Flags().GetString("reply-to")
+		strict, _ := cmd.Flags().GetBool("strict")
+		dueStr, _ := cmd.Flags().GetString("due")
 
 		var cc []string
 		if ccStr != "" {
 			cc = strings.Split(ccStr, ",")
 		}
 
-		id, err := cpClient.SendMessage(ctx, recipients, subject, body, cc, kind, replyTo)
+		var due *time.Time
+		if dueStr != "" {
+			if kind != "" && kind != client.RequestKind {
+				return fmt.Errorf("--due only applies to requests (--kind %s)", client.RequestKind)
+			}
+			t, err := client.ParseDue(dueStr, time.Now())
+			if err != nil {
+				return err
+			}
+			due, kind = &t, client.RequestKind
+		}
+
+		if strict {
+			if err := checkRecipients(ctx, append(append([]string{}, recipients...), cc...)); err != nil {
+				return err
+			}
+		}
+
+		var sent *client.SendResult
+		var err error
+		if kind == client.RequestKind {
+			sent, err = cpClient.SendRequest(ctx, recipients, subject, body, cc, replyTo, due)
+		} else {
+			sent, err = cpClient.SendMessage(ctx, recipients, subject, body, cc, kind, replyTo)
+		}
 		if err != nil {
 			return err
 		}
+		printWarnings(sent.Warnings)
+		id := sent.ID
 
 		if outputFormat == "json" {
-			fmt.Printf(`{"id": "%s"}`+"\n", id)
+			s, _ := client.FormatJSON(sent)
+			fmt.Println(s)
 			return nil
 		}
 
-		fmt.Printf("Sent message %s to %s\n", id, strings.Join(recipients, ", "))
+		switch {
+		case due != nil:
+			fmt.Printf("Sent request %s to %s (reply due %s)\n", id, strings.Join(recipients, ", "), due.Local().Format("2006-01-02 15:04"))
+		case kind == client.RequestKind:
+			fmt.Printf("Sent request %s to %s\n", id, strings.Join(recipients, ", "))
+		default:
+			fmt.Printf("Sent message %s to %s\n", id, strings.Join(recipients, ", "))
+		}
 		return nil
 	},
 }
@@ -108,6 +166,12 @@ var messageShowCmd = &cobra.Command{
 
 		fmt.Printf("ID:      %s\n", msg.ID)
 		fmt.Printf("From:    %s\n", msg.Creator)
+		if len(msg.To) > 0 {
+			fmt.Printf("To:      %s\n", strings.Join(msg.To, ", "))
+		}
+		if len(msg.Cc) > 0 {
+			fmt.Printf("Cc:      %s\n", strings.Join(msg.Cc, ", "))
+		}
 		fmt.Printf("Date:    %s\n", msg.CreatedAt.Format("2006-01-02 15:04:05"))
 		fmt.Printf("Subject: %s\n", msg.Title)
 		if msg.Content != "" {
@@ -139,15 +203,411 @@ var messageReadCmd = &cobra.Command{
 	},
 }
 
+var messageReplyCmd = &cobra.Command{
+	Use:   "reply <message-id>",
+	Short: "Reply to a message",
+	Long: `Reply to a message, linked to it with a replies-to edge so it shows up in
+cp message thread. The reply goes to the original sender with the original cc
+list copied (--all also copies the other recipients); replying to your own
+message goes to its recipients. The subject gets a "Re: " prefix and the
+original is quoted below --body unless --no-quote is given. Replying to an
+"Overdue:" escalation answers the request it chases.
+
+Recipients are checked against the agent registry as for cp message send.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  cp message reply pf-abc123 --body "Fixed in pf-def456"
+  cp message reply pf-abc123 --all --body "Taking this one" --no-quote
+  cp message reply pf-abc123 --body "Escalating" --cc agent-lead`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		body, _ := cmd.Flags().GetString("body")
+		ccStr, _ := cmd.Flags().GetString("cc")
+		kind, _ := cmd.Flags().GetString("kind")
+		all, _ := cmd.Flags().GetBool("all")
+		noQuote, _ := cmd.Flags().GetBool("no-quote")
+		strict, _ := cmd.Flags().GetBool("strict")
+
+		draft, err := cpClient.PrepareReply(ctx, args[0], body, all, !noQuote)
+		if err != nil {
+			return err
+		}
+		extraCc := splitList(ccStr)
+
+		if strict {
+			if err := checkRecipients(ctx, append(append(append([]string{}, draft.To...), draft.Cc...), extraCc...)); err != nil {
+				return err
+			}
+		}
+
+		sent, err := cpClient.Reply(ctx, draft, extraCc, kind)
+		if err != nil {
+			return err
+		}
+		printWarnings(sent.Warnings)
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(map[string]any{"id": sent.ID, "reply_to": draft.ReplyTo, "to": draft.To, "cc": nonNil(draft.Cc), "subject": draft.Subject, "warnings": nonNil(sent.Warnings)})
+			fmt.Println(s)
+			return nil
+		}
+
+		fmt.Printf("Sent reply %s to %s", sent.ID, strings.Join(draft.To, ", "))
+		if len(draft.Cc) > 0 {
+			fmt.Printf(" (cc %s)", strings.Join(draft.Cc, ", "))
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+var messageThreadCmd = &cobra.Command{
+	Use:   "thread <message-id>",
+	Short: "Show the conversation a message belongs to",
+	Long: `Show the whole replies-to tree around a message, from the message that
+started it, with each recipient's read status.
+
+--format markdown renders the thread as a Markdown document, e.g. to attach
+to a bug or archive a design discussion; --file writes it to a file.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  cp message thread pf-abc123
+  cp message thread pf-abc123 --brief
+  cp message thread pf-abc123 --format markdown --file thread.md`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		brief, _ := cmd.Flags().GetBool("brief")
+		outFile, _ := cmd.Flags().GetString("file")
+
+		t, err := cpClient.GetThread(context.Background(), args[0])
+		if err != nil {
+			return err
+		}
+		if outputFormat == "json" {
+			format = "json"
+		}
+
+		var out string
+		switch format {
+		case "text":
+			out = threadText(t, args[0], brief)
+		case "markdown", "md":
+			out = t.Markdown()
+		case "json":
+			s, err := client.FormatJSON(t)
+			if err != nil {
+				return err
+			}
+			out = s + "\n"
+		default:
+			return fmt.Errorf("unknown format %q: use text, markdown or json", format)
+		}
+
+		if outFile == "" {
+			fmt.Print(out)
+		} else if err := os.WriteFile(outFile, []byte(out), 0o644); err != nil {
+			return fmt.Errorf("cannot write %s: %v", outFile, err)
+		}
+		return nil
+	},
+}
+
+var messageSentCmd = &cobra.Command{
+	Use:   "sent",
+	Short: "Show sent messages with each recipient's read status",
+	Long: `List the messages you sent, newest first, with who has and has not read
+each one. Messages sent to a group list every member.`,
+	Example: `  cp message sent
+  cp message sent --unread --limit 50`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unread, _ := cmd.Flags().GetBool("unread")
+
+		sent, err := cpClient.ListSent(context.Background(), limitFlag, unread)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(sent)
+			fmt.Println(s)
+			return nil
+		}
+
+		if len(sent) == 0 {
+			fmt.Println("No sent messages.")
+			return nil
+		}
+
+		for i, m := range sent {
+			if i > 0 {
+				fmt.Println()
+			}
+			to := "to " + strings.Join(m.To, ", ")
+			if len(m.Groups) > 0 {
+				to = "via " + strings.Join(m.Groups, ", ")
+			}
+			fmt.Printf("%s  %s  %s  (%s; %d/%d read)\n", m.ID, m.CreatedAt.Local().Format("01-02 15:04"),
+				m.Title, to, m.Read, len(m.Receipts))
+
+			var read, pending []string
+			for _, r := range m.Receipts {
+				if r.ReadAt != nil {
+					read = append(read, fmt.Sprintf("%s (%s)", r.Agent, timeAgo(*r.ReadAt)))
+				} else {
+					pending = append(pending, r.Agent)
+				}
+			}
+			if len(read) > 0 {
+				fmt.Printf("  read:   %s\n", strings.Join(read, ", "))
+			}
+			if len(pending) > 0 {
+				fmt.Printf("  unread: %s\n", strings.Join(pending, ", "))
+			}
+		}
+		return nil
+	},
+}
+
+var messagePendingCmd = &cobra.Command{
+	Use:   "pending",
+	Short: "Show outstanding requests you sent or owe a reply to",
+	Long: `List open kind=request messages that still await a reply: those you sent,
+with the recipients who have not replied yet, and those you owe a reply to.
+A recipient has replied once they answer with cp message reply. Requests are
+ordered by deadline; overdue ones are marked.
+
+Close a request (cp shard close <id>) to withdraw it.`,
+	Example: `  cp message pending
+  cp message pending --owed
+  cp message pending --overdue -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		onlySent, _ := cmd.Flags().GetBool("sent")
+		onlyOwed, _ := cmd.Flags().GetBool("owed")
+		overdue, _ := cmd.Flags().GetBool("overdue")
+
+		sent, owed, err := cpClient.PendingRequests(context.Background())
+		if err != nil {
+			return err
+		}
+		if onlyOwed {
+			sent = []client.Request{}
+		}
+		if onlySent {
+			owed = []client.Request{}
+		}
+		if overdue {
+			sent, owed = overdueRequests(sent), overdueRequests(owed)
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(map[string]any{"sent": sent, "owed": owed})
+			fmt.Println(s)
+			return nil
+		}
+
+		if len(sent) == 0 && len(owed) == 0 {
+			fmt.Println("No pending requests.")
+			return nil
+		}
+		if len(owed) > 0 {
+			fmt.Printf("You owe a reply (%d):\n", len(owed))
+			tbl := client.NewTable("ID", "FROM", "DUE", "SUBJECT")
+			for _, r := range owed {
+				tbl.AddRow(r.ID, r.Creator, requestDue(r), client.Truncate(r.Title, 50))
+			}
+			fmt.Print(tbl.String())
+		}
+		if len(sent) > 0 {
+			if len(owed) > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("Waiting on replies (%d):\n", len(sent))
+			tbl := client.NewTable("ID", "WAITING ON", "REPLIED", "DUE", "SUBJECT")
+			for _, r := range sent {
+				tbl.AddRow(r.ID, strings.Join(r.Owed, ","), orDash(strings.Join(r.Replied, ",")),
+					requestDue(r), client.Truncate(r.Title, 40))
+			}
+			fmt.Print(tbl.String())
+		}
+		return nil
+	},
+}
+
+var messageEscalateCmd = &cobra.Command{
+	Use:   "escalate",
+	Short: "Chase overdue requests",
+	Long: `Find requests in the project that are past their --due deadline and still
+missing replies, and escalate each once: the recipients who have not replied
+get an "Overdue:" follow-up and the sender gets a "No reply yet:" notice in
+their inbox. Both are kind escalation and appear in the request's thread.
+
+'cp serve' runs the same sweep every 5 minutes (see --escalate-interval);
+without a running server nothing is chased until this command runs.`,
+	Example: `  cp message escalate
+  cp message escalate --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		escalated, err := cpClient.EscalateRequests(context.Background(), dryRun)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(map[string]any{"dry_run": dryRun, "escalated": escalated})
+			fmt.Println(s)
+			return nil
+		}
+
+		if len(escalated) == 0 {
+			fmt.Println("No overdue requests.")
+			return nil
+		}
+
+		tbl := client.NewTable("ID", "FROM", "WAITING ON", "DUE", "SUBJECT")
+		for _, e := range escalated {
+			tbl.AddRow(e.ID, e.Creator, strings.Join(e.Owed, ","), requestDue(e.Request), client.Truncate(e.Title, 40))
+		}
+		fmt.Print(tbl.String())
+
+		if dryRun {
+			fmt.Printf("\n%d requests would be escalated (dry run).\n", len(escalated))
+		} else {
+			fmt.Printf("\nEscalated %d requests.\n", len(escalated))
+		}
+		return nil
+	},
+}
+
+// checkRecipients refuses a --strict send to anyone UnknownRecipients
+// reports, or when the registry cannot be read
+func checkRecipients(ctx context.Context, recipients []string) error {
+	unknown, err := cpClient.UnknownRecipients(ctx, recipients)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("not sent: %s", client.FormatUnknownRecipients(unknown))
+	}
+	return nil
+}
+
+// printWarnings reports send warnings on stderr
+func printWarnings(warnings []string) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+}
+
+// overdueRequests keeps the requests past their deadline
+func overdueRequests(reqs []client.Request) []client.Request {
+	out := []client.Request{}
+	for _, r := range reqs {
+		if r.Overdue {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// requestDue renders a request's deadline relative to now
+func requestDue(r client.Request) string {
+	if r.DueAt == nil {
+		return "-"
+	}
+	if r.Overdue {
+		s := "OVERDUE, due " + timeAgo(*r.DueAt)
+		if r.EscalatedAt != nil {
+			s += ", escalated"
+		}
+		return s
+	}
+	return "in " + shortDuration(time.Until(*r.DueAt))
+}
+
+// threadText renders a thread as an indented tree, marking the message asked
+// about
+func threadText(t *client.Thread, selected string, brief bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Thread %s: %s (%d messages; %s)\n", t.RootID, t.Subject, len(t.Messages), strings.Join(t.Participants, ", "))
+	for _, m := range t.Messages {
+		indent := strings.Repeat("   ", m.Depth)
+		branch := ""
+		if m.Depth > 0 {
+			branch = "└─ "
+		}
+		marker := ""
+		if m.ID == selected {
+			marker = "  <"
+		}
+		kind := ""
+		if m.Kind != nil {
+			kind = " [" + strings.TrimPrefix(*m.Kind, "kind:") + "]"
+		}
+		fmt.Fprintf(&b, "\n%s%s%s  %s%s%s\n", indent, branch, m.ID, m.Title, kind, marker)
+
+		pad := indent
+		if m.Depth > 0 {
+			pad += "   "
+		}
+		addr := m.Creator
+		if len(m.To) > 0 {
+			addr += " → " + strings.Join(m.To, ", ")
+		}
+		if len(m.Cc) > 0 {
+			addr += " (cc " + strings.Join(m.Cc, ", ") + ")"
+		}
+		if len(m.Groups) > 0 {
+			addr += " via " + strings.Join(m.Groups, ", ")
+		}
+		fmt.Fprintf(&b, "%s%s · %s\n", pad, addr, m.CreatedAt.Local().Format("2006-01-02 15:04"))
+		if len(m.Receipts) > 0 {
+			fmt.Fprintf(&b, "%sread: %s\n", pad, client.FormatReceipts(m.Receipts))
+		}
+		if !brief && m.Content != "" {
+			for _, line := range strings.Split(strings.TrimRight(m.Content, "\n"), "\n") {
+				fmt.Fprintf(&b, "%s%s\n", pad, strings.TrimRight("│ "+line, " "))
+			}
+		}
+	}
+	return b.String()
+}
+
 func init() {
 	messageSendCmd.Flags().String("body", "", "Message body")
 	messageSendCmd.Flags().String("cc", "", "CC recipients (comma-separated)")
 	messageSendCmd.Flags().String("kind", "", "Message kind (e.g., bug-report, feature-request)")
 	messageSendCmd.Flags().String("reply-to", "", "Shard ID to reply to")
+	messageSendCmd.Flags().Bool("strict", false, "Refuse to send to agents missing from the registry")
+	messageSendCmd.Flags().String("due", "", "Reply deadline, e.g. 4h, 2d or \"2026-03-01 17:00\" (implies --kind request)")
+
+	messageReplyCmd.Flags().String("body", "", "Reply body")
+	messageReplyCmd.Flags().String("cc", "", "Additional CC recipients (comma-separated)")
+	messageReplyCmd.Flags().String("kind", "", "Message kind (e.g., status-update)")
+	messageReplyCmd.Flags().Bool("all", false, "Also copy the original's other recipients")
+	messageReplyCmd.Flags().Bool("no-quote", false, "Do not quote the original message")
+	messageReplyCmd.Flags().Bool("strict", false, "Refuse to send to agents missing from the registry")
+
+	messagePendingCmd.Flags().Bool("sent", false, "Only requests you sent")
+	messagePendingCmd.Flags().Bool("owed", false, "Only requests you owe a reply to")
+	messagePendingCmd.Flags().Bool("overdue", false, "Only requests past their deadline")
+	messagePendingCmd.MarkFlagsMutuallyExclusive("sent", "owed")
+
+	messageEscalateCmd.Flags().Bool("dry-run", false, "List overdue requests without sending anything")
+
+	messageSentCmd.Flags().Bool("unread", false, "Only messages someone has not read yet")
+
+	messageThreadCmd.Flags().String("format", "text", "Output format: text, markdown or json")
+	messageThreadCmd.Flags().Bool("brief", false, "Headers and read status only, no message bodies")
+	messageThreadCmd.Flags().String("file", "", "Write to this file instead of stdout")
 
 	rootCmd.AddCommand(messageCmd)
 	messageCmd.AddCommand(messageSendCmd)
 	messageCmd.AddCommand(messageInboxCmd)
 	messageCmd.AddCommand(messageShowCmd)
 	messageCmd.AddCommand(messageReadCmd)
+	messageCmd.AddCommand(messageReplyCmd)
+	messageCmd.AddCommand(messageThreadCmd)
+	messageCmd.AddCommand(messageSentCmd)
+	messageCmd.AddCommand(messagePendingCmd)
+	messageCmd.AddCommand(messageEscalateCmd)
 }
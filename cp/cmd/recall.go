@@ -9,14 +9,27 @@ import (
 
 	"github.com/otherjamesbrown/context-palace/cp/internal/client"
 	"github.com/otherjamesbrown/context-palace/cp/internal/embedding"
+	"github.com/otherjamesbrown/context-palace/cp/internal/rerank"
 	"github.com/spf13/cobra"
 )
 
 var recallCmd = &cobra.Command{
 	Use:   "recall <query>",
-	Short: "Semantic search across all shard types",
-	Long: `Search Context Palace by meaning, not just keywords.
-Uses vector embeddings to find semantically similar shards.
+	Short: "Hybrid keyword + semantic search across all shard types",
+	Long: `Search Context Palace by meaning and by exact terms.
+
+The default --mode hybrid runs vector search and keyword search (full text plus
+exact ID/title/content matches) and fuses the rankings with reciprocal rank
+fusion, so both "pipeline timeout issues" and "ERR_CONN_RESET" find what you
+mean. Use --semantic-weight/--keyword-weight to bias the fusion, or
+--mode semantic|keyword to run a single ranking. Without embedding config,
+hybrid mode falls back to keyword search; on a database without keyword_search()
+(migration 011) it warns and falls back to semantic search.
+
+--expand asks the generation model for alternative phrasings and merges their
+results; --rerank has it rescore the top --rerank-top candidates (0-10) against
+the query. Both are cached per query in ~/.cp/recall-cache-<project>.json and
+are skipped with a warning when no generator is configured.
 
 By default, only open shards are searched.
 Use --include-closed to also search closed shards.`,
@@ -27,7 +40,11 @@ Use --include-closed to also search closed shards.`,
   cp recall "timeout" --include-closed
   cp recall "vague query" --min-similarity 0.5
   cp recall "deployment" --limit 5 --since 7d
-  cp recall "entity" --show-snippet`,
+  cp recall "entity" --show-snippet
+  cp recall "ERR_CONN_RESET" --keyword-weight 2
+  cp recall "pipeline timeout" --mode semantic
+  cp recall "why did the deploy not restart" --rerank --limit 5
+  cp recall "flaky auth" --expand`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		query := args[0]
 
@@ -39,6 +56,15 @@ Use --include-closed to also search closed shards.`,
 		minSim, _ := cmd.Flags().GetFloat64("min-similarity")
 		includeClosed, _ := cmd.Flags().GetBool("include-closed")
 		showSnippet, _ := cmd.Flags().GetBool("show-snippet")
+		allowMismatch, _ := cmd.Flags().GetBool("allow-model-mismatch")
+		doRerank, _ := cmd.Flags().GetBool("rerank")
+		rerankTop, _ := cmd.Flags().GetInt("rerank-top")
+		doExpand, _ := cmd.Flags().GetBool("expand")
+		expansions, _ := cmd.Flags().GetInt("expansions")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		mode, _ := cmd.Flags().GetString("mode")
+		semWeight, _ := cmd.Flags().GetFloat64("semantic-weight")
+		kwWeight, _ := cmd.Flags().GetFloat64("keyword-weight")
 
 		// Validate mutually exclusive flags
 		if statusFlag != "" && includeClosed {
@@ -51,51 +77,123 @@ Use --include-closed to also search closed shards.`,
 			return fmt.Errorf("limit must be 1-1000")
 		}
 
+		switch mode {
+		case "hybrid", "semantic", "keyword":
+		default:
+			return fmt.Errorf("invalid --mode %q: must be hybrid, semantic or keyword", mode)
+		}
+		if semWeight < 0 || kwWeight < 0 {
+			return fmt.Errorf("weights must not be negative")
+		}
+		if rerankTop < 1 || rerankTop > 100 {
+			return fmt.Errorf("rerank-top must be 1-100")
+		}
+		if expansions < 1 || expansions > 10 {
+			return fmt.Errorf("expansions must be 1-10")
+		}
+
 		if cpClient.EmbedProvider == nil {
-			return fmt.Errorf("semantic search requires embedding config. Use `cp shard list --search` for text search")
+			if mode == "semantic" {
+				return fmt.Errorf("semantic search requires embedding config. Use `--mode keyword` for text search")
+			}
+			if mode == "hybrid" {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Note: no embedding config; using keyword search only.")
+				mode = "keyword"
+			}
 		}
 
 		ctx := context.Background()
 
-		// Embed the query
-		vec, err := cpClient.EmbedProvider.Embed(ctx, query)
-		if err != nil {
-			return fmt.Errorf("failed to embed query: %v", err)
+		opts := client.RecallOptions{
+			Mode:          mode,
+			MinSimilarity: minSim,
+			Limit:         limitFlag,
+			Weights:       client.HybridWeights{Semantic: semWeight, Keyword: kwWeight},
 		}
-
-		var types []string
 		if typeFlag != "" {
-			types = strings.Split(typeFlag, ",")
+			opts.Types = strings.Split(typeFlag, ",")
 		}
-
-		var labels []string
 		if labelFlag != "" {
-			labels = strings.Split(labelFlag, ",")
+			opts.Labels = strings.Split(labelFlag, ",")
 		}
-
-		var status []string
 		if statusFlag != "" {
-			status = strings.Split(statusFlag, ",")
+			opts.Status = strings.Split(statusFlag, ",")
 		} else if !includeClosed {
-			status = []string{"open"}
+			opts.Status = []string{"open"}
 		}
 		// If --include-closed and no --status, status stays nil (no filter)
 
 		// Parse --since into time cutoff
-		var since *time.Time
 		if sinceFlag != "" {
 			cutoff, err := parseSince(sinceFlag)
 			if err != nil {
 				return err
 			}
-			since = &cutoff
+			opts.Since = &cutoff
 		}
 
-		results, err := cpClient.SemanticSearchWithSince(ctx, vec, types, labels, status, limitFlag, minSim, since)
+		if mode != "keyword" {
+			if err := checkEmbeddingModel(ctx, cmd, cpClient.EmbedProvider.Info(), allowMismatch); err != nil {
+				return err
+			}
+		}
+
+		if (doRerank || doExpand) && cpClient.Generator == nil {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Warning: --rerank/--expand need generation config; showing results without them.")
+			doRerank, doExpand = false, false
+		}
+		var cache *rerank.Cache
+		if (doRerank || doExpand) && !noCache {
+			cache = rerank.LoadCache(defaultCheckpointPath("recall-cache", cpClient.Config.Project), rerank.DefaultCacheTTL)
+		}
+
+		// Reranking needs a deeper candidate list than the final --limit
+		if doRerank {
+			opts.Limit = max(limitFlag, rerankTop)
+		}
+
+		results, err := cpClient.Recall(ctx, query, opts)
 		if err != nil {
 			return err
 		}
 
+		if doExpand {
+			phrasings, err := expandQuery(ctx, cache, query, expansions)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: query expansion failed, using the original query only: %v\n", err)
+			} else {
+				lists := [][]client.RecallResult{results}
+				for _, q := range phrasings {
+					more, err := cpClient.Recall(ctx, q, opts)
+					if err != nil {
+						return err
+					}
+					for i := range more {
+						more[i].MatchedQuery = q
+					}
+					lists = append(lists, more)
+				}
+				results = client.MergeResults(lists, opts.Limit)
+			}
+		}
+
+		if doRerank {
+			reranked, err := rerankResults(ctx, cache, query, results, rerankTop)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: reranking failed, keeping search order: %v\n", err)
+				doRerank = false
+			} else {
+				results = reranked
+			}
+		}
+		if len(results) > limitFlag {
+			results = results[:limitFlag]
+		}
+
+		if err := cache.Save(); err != nil && debugFlag {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+		}
+
 		if outputFormat == "json" {
 			s, _ := client.FormatJSON(results)
 			fmt.Println(s)
@@ -103,19 +201,43 @@ Use --include-closed to also search closed shards.`,
 		}
 
 		if len(results) == 0 {
-			fmt.Printf("No results above %.2f similarity.\n", minSim)
+			if mode == "keyword" {
+				fmt.Println("No keyword matches.")
+			} else {
+				fmt.Printf("No results above %.2f similarity.\n", minSim)
+			}
 			return nil
 		}
 
-		tbl := client.NewTable("SIMILARITY", "TYPE", "STATUS", "ID", "TITLE")
+		var tbl *client.Table
+		switch mode {
+		case "hybrid":
+			tbl = client.NewTable("SCORE", "SEMANTIC", "KEYWORD", "TYPE", "STATUS", "ID", "TITLE")
+		case "keyword":
+			tbl = client.NewTable("KEYWORD", "TYPE", "STATUS", "ID", "TITLE")
+		default:
+			tbl = client.NewTable("SIMILARITY", "TYPE", "STATUS", "ID", "TITLE")
+		}
+		if doRerank {
+			tbl = client.NewTable(append([]string{"RERANK"}, tbl.Headers...)...)
+		}
 		for _, r := range results {
-			tbl.AddRow(
-				fmt.Sprintf("%.2f", r.Similarity),
-				r.Type,
-				r.Status,
-				r.ID,
-				client.Truncate(r.Title, 50),
-			)
+			row := []string{fmt.Sprintf("%.2f", r.Similarity)}
+			if r.Scores != nil {
+				row = []string{
+					fmt.Sprintf("%.4f", r.Scores.Fused),
+					formatRankedScore(r.Scores.Semantic, r.Scores.SemanticRank),
+					formatRankedScore(r.Scores.Keyword, r.Scores.KeywordRank),
+				}
+			}
+			if doRerank {
+				rr := "-"
+				if r.Rerank != nil {
+					rr = fmt.Sprintf("%.0f", *r.Rerank)
+				}
+				row = append([]string{rr}, row...)
+			}
+			tbl.AddRow(append(row, r.Type, r.Status, r.ID, client.Truncate(r.Title, 50))...)
 		}
 		fmt.Print(tbl.String())
 
@@ -124,16 +246,36 @@ Use --include-closed to also search closed shards.`,
 			for _, r := range results {
 				if r.Snippet != "" {
 					snippet := strings.ReplaceAll(r.Snippet, "\n", " ")
-					fmt.Printf("%s  \"%s\"\n", r.ID, client.Truncate(snippet, 100))
+					if r.ChunkHeading != nil {
+						fmt.Printf("%s  [%s] \"%s\"\n", r.ID, *r.ChunkHeading, client.Truncate(snippet, 100))
+					} else {
+						fmt.Printf("%s  \"%s\"\n", r.ID, client.Truncate(snippet, 100))
+					}
 				}
 			}
 		}
 
-		fmt.Printf("\n%d results (min similarity: %.2f)\n", len(results), minSim)
+		switch mode {
+		case "hybrid":
+			fmt.Printf("\n%d results (hybrid: semantic weight %.1f, keyword weight %.1f, min similarity %.2f)\n", len(results), semWeight, kwWeight, minSim)
+		case "keyword":
+			fmt.Printf("\n%d results (keyword)\n", len(results))
+		default:
+			fmt.Printf("\n%d results (min similarity: %.2f)\n", len(results), minSim)
+		}
 		return nil
 	},
 }
 
+// formatRankedScore renders one side of a hybrid score as "0.82 #1", or "-"
+// when that ranking didn't find the shard
+func formatRankedScore(score *float64, rank int) string {
+	if score == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f #%d", *score, rank)
+}
+
 // parseSince parses duration strings like "7d", "24h", "30m", "2w" or ISO dates "2026-01-01"
 func parseSince(s string) (time.Time, error) {
 	s = strings.TrimSpace(s)
@@ -174,6 +316,54 @@ func parseSince(s string) (time.Time, error) {
 	}
 }
 
+// checkEmbeddingModel compares the query model against the models that produced
+// the stored embeddings. Vectors from different models aren't comparable: if no
+// stored embedding matches, search is refused (unless allowMismatch); a partial
+// mismatch or untagged legacy embeddings only warn.
+func checkEmbeddingModel(ctx context.Context, cmd *cobra.Command, query embedding.ModelInfo, allowMismatch bool) error {
+	counts, err := cpClient.GetEmbeddingModelCounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	var matching, untagged, total int
+	var others []string
+	for _, mc := range counts {
+		total += mc.Count
+		switch {
+		case mc.Model == nil:
+			untagged += mc.Count
+		case *mc.Model == query:
+			matching += mc.Count
+		default:
+			others = append(others, fmt.Sprintf("%s (%d shards)", mc.Model, mc.Count))
+		}
+	}
+	other := total - matching - untagged
+
+	stderr := cmd.ErrOrStderr()
+	if other > 0 && matching == 0 && untagged == 0 {
+		msg := fmt.Sprintf("stored embeddings were produced by %s, but queries use %s.\n"+
+			"Similarities across models are meaningless. Run `cp admin reembed` to re-embed with %s,\n"+
+			"or set embedding.model back to the stored model",
+			strings.Join(others, ", "), query, query.Model)
+		if !allowMismatch {
+			return fmt.Errorf("%s (or pass --allow-model-mismatch)", msg)
+		}
+		fmt.Fprintf(stderr, "Warning: %s.\n", msg)
+		return nil
+	}
+	if other > 0 {
+		fmt.Fprintf(stderr, "Warning: %d of %d embedded shards use a different model than the query (%s): %s.\n"+
+			"They will rank unreliably until you run `cp admin reembed`.\n",
+			other, total, query, strings.Join(others, ", "))
+	}
+	if untagged > 0 {
+		fmt.Fprintf(stderr, "Warning: %d embeddings predate model tracking; run `cp admin reembed` to verify and tag them.\n", untagged)
+	}
+	return nil
+}
+
 // embedShard embeds a shard and updates its embedding in the database.
 // Used by both embed-on-write and backfill.
 func embedShard(ctx context.Context, cl *client.Client, provider embedding.Provider, shardID, shardType, title, content string) error {
@@ -187,7 +377,11 @@ func embedShard(ctx context.Context, cl *client.Client, provider embedding.Provi
 		return err
 	}
 
-	return cl.UpdateEmbedding(ctx, shardID, vec)
+	if err := cl.UpdateEmbedding(ctx, shardID, vec, provider.Info()); err != nil {
+		return err
+	}
+
+	return cl.EmbedChunks(ctx, provider, shardID, shardType, title, content)
 }
 
 func init() {
@@ -198,6 +392,15 @@ func init() {
 	recallCmd.Flags().Float64("min-similarity", 0.3, "Minimum similarity threshold (0.0-1.0)")
 	recallCmd.Flags().Bool("include-closed", false, "Include all statuses")
 	recallCmd.Flags().Bool("show-snippet", false, "Show content preview under each result")
+	recallCmd.Flags().Bool("allow-model-mismatch", false, "Search even if stored embeddings came from a different model")
+	recallCmd.Flags().String("mode", "hybrid", "Search mode: hybrid, semantic, keyword")
+	recallCmd.Flags().Float64("semantic-weight", 1.0, "Weight of the semantic ranking in hybrid mode")
+	recallCmd.Flags().Float64("keyword-weight", 1.0, "Weight of the keyword ranking in hybrid mode")
+	recallCmd.Flags().Bool("rerank", false, "Rescore the top candidates with the generation model")
+	recallCmd.Flags().Int("rerank-top", 20, "Number of candidates to rerank (1-100)")
+	recallCmd.Flags().Bool("expand", false, "Also search generated alternative phrasings of the query")
+	recallCmd.Flags().Int("expansions", 3, "Number of alternative phrasings for --expand (1-10)")
+	recallCmd.Flags().Bool("no-cache", false, "Don't reuse cached rerank scores and expansions")
 
 	rootCmd.AddCommand(recallCmd)
 }
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/otherjamesbrown/context-palace/cp/internal/rerank"
+)
+
+// expandQuery asks the generator for n alternative phrasings of query
+func expandQuery(ctx context.Context, cache *rerank.Cache, query string, n int) ([]string, error) {
+	key := rerank.ExpandKey(cpClient.Config.Project, query, n)
+	if e, ok := cache.Get(key); ok {
+		return e.Expansions, nil
+	}
+
+	response, err := cpClient.Generator.Generate(ctx, rerank.BuildExpandPrompt(query, n))
+	if err != nil {
+		return nil, err
+	}
+	phrasings, err := rerank.ParseExpandResponse(response, query, n)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Put(key, rerank.CacheEntry{Expansions: phrasings})
+	return phrasings, nil
+}
+
+// rerankResults has the generator rescore the top n results against query and
+// reorders them by that score. Ties, and candidates the model skipped, keep
+// their search order; results beyond n follow unchanged.
+func rerankResults(ctx context.Context, cache *rerank.Cache, query string, results []client.RecallResult, n int) ([]client.RecallResult, error) {
+	n = min(n, len(results))
+	if n == 0 {
+		return results, nil
+	}
+
+	candidates := make([]rerank.Candidate, n)
+	for i, r := range results[:n] {
+		content := r.Snippet
+		if _, _, full, err := cpClient.GetShardContentForEmbedding(ctx, r.ID); err == nil && full != "" {
+			content = full
+		}
+		candidates[i] = rerank.Candidate{ID: r.ID, Type: r.Type, Title: r.Title, Content: content}
+	}
+
+	key := rerank.RerankKey(cpClient.Config.Project, query, candidates)
+	scores := map[string]float64(nil)
+	if e, ok := cache.Get(key); ok {
+		scores = e.Scores
+	} else {
+		response, err := cpClient.Generator.Generate(ctx, rerank.BuildRerankPrompt(query, candidates))
+		if err != nil {
+			return nil, err
+		}
+		scores, err = rerank.ParseRerankResponse(response, candidates)
+		if err != nil {
+			return nil, err
+		}
+		cache.Put(key, rerank.CacheEntry{Scores: scores})
+	}
+
+	top := append([]client.RecallResult{}, results[:n]...)
+	for i := range top {
+		if s, ok := scores[top[i].ID]; ok {
+			top[i].Rerank = &s
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		a, b := top[i].Rerank, top[j].Rerank
+		switch {
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		default:
+			return *a > *b
+		}
+	})
+	return append(top, results[n:]...), nil
+}
@@ -524,4 +524,5 @@ func init() {
 	requirementCmd.AddCommand(reqDashboardCmd)
 
 	rootCmd.AddCommand(requirementCmd)
+	postgresOnly(requirementCmd)
 }
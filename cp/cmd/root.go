@@ -10,13 +10,13 @@ import (
 )
 
 var (
-	outputFormat   string
-	projectFlag    string
-	agentFlag      string
-	limitFlag      int
-	debugFlag      bool
-	configFlag     string
-	cpClient       *client.Client
+	outputFormat string
+	projectFlag  string
+	agentFlag    string
+	limitFlag    int
+	debugFlag    bool
+	configFlag   string
+	cpClient     *client.Client
 )
 
 var Version = "0.1.0"
@@ -64,6 +64,8 @@ CONFIGURATION:
     CP_USER       Database user
     CP_PROJECT    Project name
     CP_AGENT      Agent identity
+    CP_BACKEND    Storage backend: postgres (default) or local
+    CP_LOCAL_PATH Local backend data file (default: ~/.cp/local.json)
 
 EXAMPLES:
   cp status
@@ -93,7 +95,13 @@ EXAMPLES:
 			cfg.Agent = agentFlag
 		}
 
-		cpClient = client.NewClient(cfg)
+		cpClient, err = client.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		if err := checkBackend(cmd); err != nil {
+			return err
+		}
 
 		// Initialize embedding provider (warn on failure, don't block)
 		if cfg.Embedding != nil {
@@ -123,6 +131,32 @@ EXAMPLES:
 	},
 }
 
+// backendAnnotation names the storage backend a command needs
+const backendAnnotation = "backend"
+
+// postgresOnly marks commands (and everything under them) that work only on
+// the postgres backend: they go through client.Connect rather than the Store
+// interface. On another backend they fail before running.
+func postgresOnly(cmds ...*cobra.Command) {
+	for _, c := range cmds {
+		if c.Annotations == nil {
+			c.Annotations = map[string]string{}
+		}
+		c.Annotations[backendAnnotation] = client.BackendPostgres
+	}
+}
+
+// checkBackend fails a command marked by postgresOnly, or under one, when
+// another backend is configured
+func checkBackend(cmd *cobra.Command) error {
+	for c := cmd; c != nil; c = c.Parent() {
+		if need := c.Annotations[backendAnnotation]; need != "" && need != cpClient.Backend() {
+			return fmt.Errorf("%s %w (configured backend: %s)", cmd.CommandPath(), client.ErrUnsupported, cpClient.Backend())
+		}
+	}
+	return nil
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
@@ -132,7 +166,16 @@ var versionCmd = &cobra.Command{
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	defer func() {
+		if cpClient != nil {
+			cpClient.Close()
+		}
+	}()
+	err := rootCmd.Execute()
+	if client.IsUndefinedFunction(err) {
+		return fmt.Errorf("%w\nThe database schema may be older than this cp; run 'cp admin doctor' to check", err)
+	}
+	return err
 }
 
 func init() {
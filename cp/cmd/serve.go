@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/api"
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the Context Palace REST API",
+	Long: `Host a JSON REST API backed by this cp's storage, for sandboxed agents and
+CI runners that cannot hold Postgres client certificates.
+
+Each request authenticates with a bearer token and runs as the agent that owns
+it, in the configured project. Tokens are listed by SHA-256 hash under
+server.tokens in the config; create them with ` + "`cp serve token <agent>`" + `.
+
+The OpenAPI document is served unauthenticated at /v1/openapi.json and printed
+by ` + "`cp serve openapi`" + `. Bind to a non-loopback address only behind TLS,
+either with --tls-cert/--tls-key or a terminating proxy.
+
+With --reap-interval (or server.reap_interval), the server also returns shards
+whose claim lease has expired to open, as ` + "`cp admin reap`" + ` does.
+
+The server chases overdue requests every 5 minutes, as ` + "`cp message escalate`" + `
+does; change that with --escalate-interval (or server.escalate_interval), or
+turn it off with --escalate-interval 0. Without a running server, overdue
+requests are only chased by running cp message escalate.`,
+	Example: `  cp serve
+  cp serve --listen 0.0.0.0:8420 --tls-cert server.pem --tls-key server-key.pem
+  cp serve --reap-interval 5m --escalate-interval 15m
+  cp serve --escalate-interval 0
+  curl -H "Authorization: Bearer $CP_TOKEN" http://127.0.0.1:8420/v1/messages/inbox`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listen, _ := cmd.Flags().GetString("listen")
+		tlsCert, _ := cmd.Flags().GetString("tls-cert")
+		tlsKey, _ := cmd.Flags().GetString("tls-key")
+		reapInterval, _ := cmd.Flags().GetDuration("reap-interval")
+		escalateInterval, _ := cmd.Flags().GetDuration("escalate-interval")
+
+		if (tlsCert == "") != (tlsKey == "") {
+			return fmt.Errorf("--tls-cert and --tls-key must be given together")
+		}
+		if listen == "" && cpClient.Config.Server != nil {
+			listen = cpClient.Config.Server.Listen
+		}
+		if listen == "" {
+			listen = api.DefaultListen
+		}
+		if !cmd.Flags().Changed("reap-interval") && cpClient.Config.Server != nil {
+			reapInterval = cpClient.Config.Server.ReapInterval
+		}
+		if !cmd.Flags().Changed("escalate-interval") && cpClient.Config.Server != nil && cpClient.Config.Server.EscalateInterval != nil {
+			escalateInterval = *cpClient.Config.Server.EscalateInterval
+		}
+
+		logger := log.New(os.Stderr, "cp serve: ", log.LstdFlags)
+		server, err := api.New(cpClient, Version, logger)
+		if err != nil {
+			return err
+		}
+
+		srv := &http.Server{
+			Addr:              listen,
+			Handler:           server.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		errCh := make(chan error, 1)
+		go func() {
+			if tlsCert != "" {
+				errCh <- srv.ListenAndServeTLS(tlsCert, tlsKey)
+			} else {
+				errCh <- srv.ListenAndServe()
+			}
+		}()
+		scheme := "http"
+		if tlsCert != "" {
+			scheme = "https"
+		}
+		logger.Printf("serving %s on %s://%s (backend: %s)", cpClient.Config.Project, scheme, listen, cpClient.Backend())
+		if reapInterval > 0 {
+			go reapLoop(ctx, reapInterval, logger)
+		}
+		if escalateInterval > 0 {
+			go escalateLoop(ctx, escalateInterval, logger)
+		} else {
+			logger.Printf("request escalation is off; overdue requests are chased only by cp message escalate")
+		}
+
+		select {
+		case err := <-errCh:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("server failed: %v", err)
+			}
+			return nil
+		case <-ctx.Done():
+		}
+
+		logger.Printf("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	},
+}
+
+// reapLoop returns shards with expired leases to open every interval until
+// ctx is done
+func reapLoop(ctx context.Context, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		reaped, err := cpClient.ReapLeases(ctx, false)
+		if err != nil {
+			logger.Printf("lease sweep failed: %v", err)
+		}
+		for _, r := range reaped {
+			owner := "nobody"
+			if r.Owner != nil {
+				owner = *r.Owner
+			}
+			logger.Printf("lease expired: returned %s to open (was %s)", r.ID, owner)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+var serveTokenCmd = &cobra.Command{
+	Use:   "token <agent>",
+	Short: "Generate an API token for an agent",
+	Long: `Generate a random bearer token for an agent and print the config entry that
+authorizes it. Only the hash goes in the config; hand the token to the agent
+(e.g. as CP_TOKEN) and do not store it elsewhere. Revoke a token by deleting
+its entry and restarting cp serve.`,
+	Args:    cobra.ExactArgs(1),
+	Example: "  cp serve token ci-runner",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		agent := args[0]
+		token, hash, err := api.NewToken()
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(map[string]string{"agent": agent, "token": token, "sha256": hash})
+			fmt.Println(s)
+			return nil
+		}
+
+		fmt.Printf("Token for %s (shown once):\n\n  %s\n\n", agent, token)
+		fmt.Println("Add to the server's config:")
+		fmt.Println()
+		fmt.Println("server:")
+		fmt.Println("  tokens:")
+		fmt.Printf("    - agent: %s\n", agent)
+		fmt.Printf("      sha256: %s\n", hash)
+		return nil
+	},
+}
+
+var serveOpenAPICmd = &cobra.Command{
+	Use:     "openapi",
+	Short:   "Print the OpenAPI document for the REST API",
+	Example: "  cp serve openapi > openapi.json",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := client.FormatJSON(api.OpenAPI(Version))
+		if err != nil {
+			return err
+		}
+		fmt.Println(s)
+		return nil
+	},
+}
+
+// defaultEscalateInterval is how often cp serve chases overdue requests
+// unless told otherwise
+const defaultEscalateInterval = 5 * time.Minute
+
+// escalateLoop chases overdue requests every interval until ctx is done
+func escalateLoop(ctx context.Context, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		escalated, err := cpClient.EscalateRequests(ctx, false)
+		if err != nil {
+			logger.Printf("request escalation failed: %v", err)
+		}
+		for _, e := range escalated {
+			logger.Printf("request overdue: escalated %s from %s (waiting on %s)", e.ID, e.Creator, strings.Join(e.Owed, ", "))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func init() {
+	serveCmd.Flags().String("listen", "", "Listen address (default: server.listen, else "+api.DefaultListen+")")
+	serveCmd.Flags().String("tls-cert", "", "TLS certificate file (PEM)")
+	serveCmd.Flags().String("tls-key", "", "TLS private key file (PEM)")
+	serveCmd.Flags().Duration("reap-interval", 0, "Return shards with expired leases to open this often, e.g. 5m (default: server.reap_interval, else off)")
+	serveCmd.Flags().Duration("escalate-interval", defaultEscalateInterval, "Chase overdue requests this often; 0 turns it off (default: server.escalate_interval, else 5m)")
+
+	serveCmd.AddCommand(serveTokenCmd)
+	serveCmd.AddCommand(serveOpenAPICmd)
+	rootCmd.AddCommand(serveCmd)
+}
@@ -154,4 +154,5 @@ func init() {
 	sessionCmd.AddCommand(sessionCheckpointCmd)
 	sessionCmd.AddCommand(sessionShowCmd)
 	sessionCmd.AddCommand(sessionEndCmd)
+	postgresOnly(sessionCmd)
 }
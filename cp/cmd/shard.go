@@ -715,6 +715,7 @@ func init() {
 
 	shardCmd.AddCommand(shardMetadataCmd)
 	shardCmd.AddCommand(shardQueryCmd)
+	postgresOnly(shardMetadataCmd, shardQueryCmd)
 	shardCmd.AddCommand(shardCreateCmd)
 	shardCmd.AddCommand(shardListCmd)
 	shardCmd.AddCommand(shardShowCmd)
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// -- shard edge-type --
+
+var shardEdgeTypeCmd = &cobra.Command{
+	Use:   "edge-type",
+	Short: "Edge type registry",
+	Long: `List and declare the edge types a project accepts.
+
+Each edge type has properties that 'cp shard link' and the lifecycle commands
+honour:
+  inverse     name for the relation read from the other end (blocked-by/blocks)
+  blocks      the source cannot be assigned or picked by 'cp shard next' until
+              the target is closed
+  acyclic     edges of this type may not form a cycle (blocking types always are)
+  from/to     shard types the edge may connect
+
+Built-in types are available in every project. Redeclaring one changes its
+properties for this project; removing the declaration restores the default.`,
+}
+
+// -- shard edge-type list --
+
+var shardEdgeTypeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List edge types in effect for the project",
+	Example: `  cp shard edge-type list
+  cp shard edge-type list -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := cpClient.EdgeTypes(context.Background())
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(reg)
+			fmt.Println(s)
+			return nil
+		}
+
+		tbl := client.NewTable("TYPE", "INVERSE", "BLOCKS", "ACYCLIC", "FROM", "TO", "SOURCE", "DESCRIPTION")
+		for _, t := range reg {
+			source := "project"
+			if t.BuiltIn {
+				source = "built-in"
+			}
+			tbl.AddRow(t.Name, orDash(t.Inverse), yesNo(t.Blocks), yesNo(t.Acyclic),
+				orDash(strings.Join(t.FromTypes, ",")), orDash(strings.Join(t.ToTypes, ",")),
+				source, client.Truncate(t.Description, 40))
+		}
+		fmt.Print(tbl.String())
+		return nil
+	},
+}
+
+// -- shard edge-type define --
+
+var shardEdgeTypeDefineCmd = &cobra.Command{
+	Use:   "define <name>",
+	Short: "Declare or redeclare an edge type for the project",
+	Args:  cobra.ExactArgs(1),
+	Example: `  cp shard edge-type define waits-on --inverse unblocks --blocks
+  cp shard edge-type define verifies --from test --to requirement,task
+  cp shard edge-type define implements --from task --to requirement`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inverse, _ := cmd.Flags().GetString("inverse")
+		blocks, _ := cmd.Flags().GetBool("blocks")
+		acyclic, _ := cmd.Flags().GetBool("acyclic")
+		fromTypes, _ := cmd.Flags().GetStringSlice("from")
+		toTypes, _ := cmd.Flags().GetStringSlice("to")
+		description, _ := cmd.Flags().GetString("description")
+
+		t, err := cpClient.DefineEdgeType(context.Background(), client.EdgeType{
+			Name:        args[0],
+			Inverse:     inverse,
+			Blocks:      blocks,
+			Acyclic:     acyclic,
+			FromTypes:   fromTypes,
+			ToTypes:     toTypes,
+			Description: description,
+		})
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(t)
+			fmt.Println(s)
+			return nil
+		}
+
+		var props []string
+		if t.Inverse != "" {
+			props = append(props, "inverse "+t.Inverse)
+		}
+		if t.Blocks {
+			props = append(props, "blocks")
+		}
+		if t.Acyclic {
+			props = append(props, "acyclic")
+		}
+		if len(t.FromTypes) > 0 {
+			props = append(props, "from "+strings.Join(t.FromTypes, ","))
+		}
+		if len(t.ToTypes) > 0 {
+			props = append(props, "to "+strings.Join(t.ToTypes, ","))
+		}
+		if len(props) == 0 {
+			props = append(props, "no constraints")
+		}
+		fmt.Printf("Declared edge type %s (%s)\n", t.Name, strings.Join(props, ", "))
+		return nil
+	},
+}
+
+// -- shard edge-type remove --
+
+var shardEdgeTypeRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove the project's declaration of an edge type",
+	Long: `Remove an edge type declared for the project. For a built-in type this
+restores its default properties. A project-only type cannot be removed while
+edges still use it.`,
+	Args:    cobra.ExactArgs(1),
+	Example: "  cp shard edge-type remove waits-on",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cpClient.RemoveEdgeType(context.Background(), args[0]); err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(map[string]any{"name": args[0], "removed": true})
+			fmt.Println(s)
+			return nil
+		}
+
+		fmt.Printf("Removed edge type declaration %s\n", args[0])
+		return nil
+	},
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	shardEdgeTypeDefineCmd.Flags().String("inverse", "", "Name for the relation read from the target's side")
+	shardEdgeTypeDefineCmd.Flags().Bool("blocks", false, "Source cannot start until target is closed (implies --acyclic)")
+	shardEdgeTypeDefineCmd.Flags().Bool("acyclic", false, "Refuse edges that would form a cycle")
+	shardEdgeTypeDefineCmd.Flags().StringSlice("from", nil, "Allowed source shard types (default: any)")
+	shardEdgeTypeDefineCmd.Flags().StringSlice("to", nil, "Allowed target shard types (default: any)")
+	shardEdgeTypeDefineCmd.Flags().String("description", "", "What the relation means")
+
+	shardEdgeTypeCmd.AddCommand(shardEdgeTypeListCmd)
+	shardEdgeTypeCmd.AddCommand(shardEdgeTypeDefineCmd)
+	shardEdgeTypeCmd.AddCommand(shardEdgeTypeRemoveCmd)
+
+	shardCmd.AddCommand(shardEdgeTypeCmd)
+}
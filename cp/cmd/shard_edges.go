@@ -146,35 +146,30 @@ func printTreeNodes(nodes []*client.EdgeTreeNode, prefix string) {
 var shardLinkCmd = &cobra.Command{
 	Use:   "link <from-shard-id>",
 	Short: "Create a typed edge between shards",
-	Args:  cobra.ExactArgs(1),
+	Long: `Create an edge from a shard to another. Built-in edge types have their own
+flag; types declared for the project with 'cp shard edge-type define' are
+given with --type and --to.
+
+Linking with an inverse name (e.g. --blocks, --parent) stores the edge
+reversed under the canonical type (blocked-by, child-of). Acyclic types refuse
+edges that would close a cycle, and types restricted to certain shard types
+refuse others.`,
+	Args: cobra.ExactArgs(1),
 	Example: `  cp shard link pf-task-123 --implements pf-req-01
   cp shard link pf-bug-03 --references pf-task-456
-  cp shard link pf-req-05 --blocked-by pf-req-03`,
+  cp shard link pf-req-05 --blocked-by pf-req-03
+  cp shard link pf-req-03 --blocks pf-req-05
+  cp shard link pf-task-9 --type waits-on --to pf-task-4`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 		fromID := args[0]
 
-		// Find which edge type flag was set
-		var edgeType, toID string
-		flagCount := 0
-		for _, et := range client.ValidEdgeTypes {
-			val, _ := cmd.Flags().GetString(et)
-			if val != "" {
-				edgeType = et
-				toID = val
-				flagCount++
-			}
-		}
-
-		if flagCount == 0 {
-			return fmt.Errorf("specify an edge type flag. Valid types: %s", strings.Join(client.ValidEdgeTypes, ", "))
-		}
-		if flagCount > 1 {
-			return fmt.Errorf("exactly one edge type flag allowed")
+		edgeType, toID, err := edgeTypeFlag(ctx, cmd)
+		if err != nil {
+			return err
 		}
 
-		err := cpClient.CreateEdge(ctx, fromID, toID, edgeType, nil)
-		if err != nil {
+		if err := cpClient.CreateEdge(ctx, fromID, toID, edgeType, nil); err != nil {
 			return err
 		}
 
@@ -194,6 +189,44 @@ var shardLinkCmd = &cobra.Command{
 	},
 }
 
+// builtinEdgeFlags are the edge types with their own link/unlink flag
+var builtinEdgeFlags = client.EdgeTypeRegistry(client.BuiltinEdgeTypes).Names()
+
+// edgeTypeFlag returns the edge type and target shard given to link/unlink,
+// either as --<type> <shard> or as --type <type> --to <shard>
+func edgeTypeFlag(ctx context.Context, cmd *cobra.Command) (string, string, error) {
+	var edgeType, toID string
+	flagCount := 0
+	for _, et := range builtinEdgeFlags {
+		val, _ := cmd.Flags().GetString(et)
+		if val != "" {
+			edgeType = et
+			toID = val
+			flagCount++
+		}
+	}
+	if t, _ := cmd.Flags().GetString("type"); t != "" {
+		edgeType = t
+		toID, _ = cmd.Flags().GetString("to")
+		if toID == "" {
+			return "", "", fmt.Errorf("--type needs --to <shard-id>")
+		}
+		flagCount++
+	}
+
+	if flagCount == 0 {
+		names := builtinEdgeFlags
+		if reg, err := cpClient.EdgeTypes(ctx); err == nil {
+			names = reg.Names()
+		}
+		return "", "", fmt.Errorf("specify an edge type flag. Valid types: %s", strings.Join(names, ", "))
+	}
+	if flagCount > 1 {
+		return "", "", fmt.Errorf("exactly one edge type flag allowed")
+	}
+	return edgeType, toID, nil
+}
+
 // -- shard unlink --
 
 var shardUnlinkCmd = &cobra.Command{
@@ -201,29 +234,16 @@ var shardUnlinkCmd = &cobra.Command{
 	Short: "Remove a typed edge between shards",
 	Args:  cobra.ExactArgs(1),
 	Example: `  cp shard unlink pf-task-123 --implements pf-req-01
-  cp shard unlink pf-task-123 --implements pf-req-01 --force`,
+  cp shard unlink pf-task-123 --implements pf-req-01 --force
+  cp shard unlink pf-task-9 --type waits-on --to pf-task-4`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 		fromID := args[0]
 		force, _ := cmd.Flags().GetBool("force")
 
-		// Find which edge type flag was set
-		var edgeType, toID string
-		flagCount := 0
-		for _, et := range client.ValidEdgeTypes {
-			val, _ := cmd.Flags().GetString(et)
-			if val != "" {
-				edgeType = et
-				toID = val
-				flagCount++
-			}
-		}
-
-		if flagCount == 0 {
-			return fmt.Errorf("specify an edge type flag. Valid types: %s", strings.Join(client.ValidEdgeTypes, ", "))
-		}
-		if flagCount > 1 {
-			return fmt.Errorf("exactly one edge type flag allowed")
+		edgeType, toID, err := edgeTypeFlag(ctx, cmd)
+		if err != nil {
+			return err
 		}
 
 		if !force {
@@ -236,8 +256,7 @@ var shardUnlinkCmd = &cobra.Command{
 			}
 		}
 
-		err := cpClient.DeleteEdge(ctx, fromID, toID, edgeType)
-		if err != nil {
+		if err := cpClient.DeleteEdge(ctx, fromID, toID, edgeType); err != nil {
 			return err
 		}
 
@@ -266,10 +285,14 @@ func init() {
 	shardEdgesCmd.Flags().Int("max-depth", 2, "Max hops for --follow (1-5)")
 
 	// shard link flags — one flag per edge type
-	for _, et := range client.ValidEdgeTypes {
+	for _, et := range builtinEdgeFlags {
 		shardLinkCmd.Flags().String(et, "", fmt.Sprintf("Target shard for %s edge", et))
 		shardUnlinkCmd.Flags().String(et, "", fmt.Sprintf("Target shard for %s edge", et))
 	}
+	for _, c := range []*cobra.Command{shardLinkCmd, shardUnlinkCmd} {
+		c.Flags().String("type", "", "Edge type declared for the project (with --to)")
+		c.Flags().String("to", "", "Target shard for --type")
+	}
 	shardUnlinkCmd.Flags().Bool("force", false, "Skip confirmation prompt")
 
 	shardCmd.AddCommand(shardEdgesCmd)
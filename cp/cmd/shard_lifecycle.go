@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/otherjamesbrown/context-palace/cp/internal/client"
 	"github.com/spf13/cobra"
@@ -12,34 +13,42 @@ import (
 // -- shard assign --
 
 var shardAssignCmd = &cobra.Command{
-	Use:     "assign <shard-id>",
-	Short:   "Claim a shard (set owner + in_progress)",
+	Use:   "assign <shard-id>",
+	Short: "Claim a shard (set owner + in_progress)",
+	Long: `Claim a shard: set its owner and mark it in_progress.
+
+The claim carries a lease (--lease, else the config's lease, default 2h). The
+owner renews it with 'cp task heartbeat' or 'cp task progress'; once it runs
+out, 'cp admin reap' (or the sweep in 'cp serve') returns the shard to open.`,
 	Args:    cobra.ExactArgs(1),
-	Example: "  cp shard assign pf-abc123\n  cp shard assign pf-abc123 --agent agent-mycroft",
+	Example: "  cp shard assign pf-abc123\n  cp shard assign pf-abc123 --agent agent-mycroft\n  cp shard assign pf-abc123 --lease 6h",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 		id := args[0]
 
 		agent, _ := cmd.Flags().GetString("agent")
+		lease, _ := cmd.Flags().GetDuration("lease")
 
-		result, err := cpClient.AssignShard(ctx, id, agent)
+		result, err := cpClient.AssignShard(ctx, id, agent, lease)
 		if err != nil {
 			return err
 		}
 
 		if outputFormat == "json" {
 			out := map[string]any{
-				"id":     result.ID,
-				"title":  result.Title,
-				"owner":  result.Owner,
-				"status": "in_progress",
+				"id":               result.ID,
+				"title":            result.Title,
+				"owner":            result.Owner,
+				"status":           "in_progress",
+				"lease_expires_at": result.LeaseExpiresAt,
 			}
 			s, _ := client.FormatJSON(out)
 			fmt.Println(s)
 			return nil
 		}
 
-		fmt.Printf("Assigned %s %q to %s\n", result.ID, result.Title, result.Owner)
+		fmt.Printf("Assigned %s %q to %s (lease until %s)\n", result.ID, result.Title, result.Owner,
+			result.LeaseExpiresAt.Local().Format("15:04"))
 		return nil
 	},
 }
@@ -49,19 +58,39 @@ var shardAssignCmd = &cobra.Command{
 var shardNextCmd = &cobra.Command{
 	Use:   "next",
 	Short: "Find next unblocked shard",
+	Long: `Show the next unblocked open shards, by priority, within the focused epic,
+--epic, or everywhere (--global).
+
+Shards can name the capabilities they need with needs:<capability> labels.
+--capability offers only shards whose needs are all in the given list;
+--capable uses your capabilities from the agent registry (cp agent register).`,
 	Example: `  cp shard next                     # within focused epic (if set)
   cp shard next --epic pf-abc123    # within specific epic
-  cp shard next --global            # all open work`,
+  cp shard next --global            # all open work
+  cp shard next --capable           # work matching my registered capabilities
+  cp shard next --capability go,sql`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 
 		epicFlag, _ := cmd.Flags().GetString("epic")
 		globalFlag, _ := cmd.Flags().GetBool("global")
 		nextLimit, _ := cmd.Flags().GetInt("limit")
+		capabilities, _ := cmd.Flags().GetStringSlice("capability")
+		capable, _ := cmd.Flags().GetBool("capable")
 
 		if nextLimit <= 0 {
 			nextLimit = 1
 		}
+		if capable {
+			me, err := cpClient.GetAgent(ctx, cpClient.Config.Agent)
+			if err != nil {
+				return err
+			}
+			capabilities = append(capabilities, me.Capabilities...)
+		}
+		if (capable || cmd.Flags().Changed("capability")) && capabilities == nil {
+			capabilities = []string{}
+		}
 
 		// Determine scope
 		var epicID *string
@@ -79,7 +108,7 @@ var shardNextCmd = &cobra.Command{
 			}
 		}
 
-		shards, err := cpClient.GetNextShards(ctx, epicID, nextLimit)
+		shards, err := cpClient.GetNextShards(ctx, epicID, capabilities, nextLimit)
 		if err != nil {
 			return err
 		}
@@ -152,6 +181,88 @@ var shardNextCmd = &cobra.Command{
 	},
 }
 
+// -- shard take --
+
+var shardTakeCmd = &cobra.Command{
+	Use:   "take",
+	Short: "Claim the next unblocked shard in one step",
+	Long: `Pick the highest-priority unblocked open shard and assign it to you, as one
+atomic operation. Unlike 'cp shard next' followed by 'cp shard assign', two
+agents running take at the same time never get the same shard: each skips
+whatever another is claiming and takes the next one.
+
+Scope follows 'cp shard next': the focused epic unless --epic or --global is
+given. When nothing is available, prints a notice (or "taken": false with
+-o json) and exits successfully. The claim carries a lease as with
+'cp shard assign'.`,
+	Example: `  cp shard take                      # within focused epic (if set)
+  cp shard take --epic pf-abc123     # within specific epic
+  cp shard take --global --kind bug  # any open bug
+  cp shard take -o json --agent agent-worker-3`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		epicFlag, _ := cmd.Flags().GetString("epic")
+		globalFlag, _ := cmd.Flags().GetBool("global")
+		kindFlag, _ := cmd.Flags().GetString("kind")
+		agent, _ := cmd.Flags().GetString("agent")
+		if agent == "" {
+			agent = cpClient.Config.Agent
+		}
+		lease, _ := cmd.Flags().GetDuration("lease")
+
+		// Determine scope
+		var epicID, kind *string
+		scopeLabel := "global"
+
+		if epicFlag != "" {
+			epicID = &epicFlag
+			scopeLabel = fmt.Sprintf("epic %q", epicFlag)
+		} else if !globalFlag {
+			// Check focus
+			focus, _ := cpClient.GetFocus(ctx)
+			if focus != nil {
+				epicID = &focus.EpicID
+				scopeLabel = fmt.Sprintf("epic %q", focus.EpicTitle)
+			}
+		}
+		if kindFlag != "" {
+			kind = &kindFlag
+			scopeLabel += ", kind " + kindFlag
+		}
+
+		taken, err := cpClient.TakeShard(ctx, epicID, kind, agent, lease)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			out := map[string]any{"taken": taken != nil}
+			if taken != nil {
+				out["shard"] = taken
+				out["status"] = "in_progress"
+				out["owner"] = agent
+			}
+			s, _ := client.FormatJSON(out)
+			fmt.Println(s)
+			return nil
+		}
+
+		if taken == nil {
+			fmt.Printf("No unblocked shards available (%s).\n", scopeLabel)
+			return nil
+		}
+
+		pri := "P?"
+		if taken.Priority != nil {
+			pri = fmt.Sprintf("P%d", *taken.Priority)
+		}
+		fmt.Printf("Took %s %q (%s, %s), assigned to %s (lease until %s)\n", taken.ID, taken.Title, taken.Kind, pri, agent,
+			taken.LeaseExpiresAt.Local().Format("15:04"))
+		return nil
+	},
+}
+
 // -- shard board --
 
 var shardBoardCmd = &cobra.Command{
@@ -279,6 +390,9 @@ var shardBoardCmd = &cobra.Command{
 				if s.AssignedAt != nil {
 					ago = fmt.Sprintf(", %s", timeAgo(*s.AssignedAt))
 				}
+				if s.LeaseExpiresAt != nil {
+					ago += ", " + leaseStatus(s.HeartbeatAt, *s.LeaseExpiresAt)
+				}
 				fmt.Printf("  \u2192 %-10s %-8s %-40s (%s%s)\n", s.ID, s.Kind, client.Truncate(s.Title, 40), owner, ago)
 			}
 			fmt.Println()
@@ -300,14 +414,52 @@ var shardBoardCmd = &cobra.Command{
 	},
 }
 
+// leaseStatus describes a claim's lease for the board: when the owner last
+// checked in and how long is left, or that it has expired
+func leaseStatus(heartbeatAt *time.Time, expiresAt time.Time) string {
+	left := time.Until(expiresAt)
+	if left <= 0 {
+		return "lease expired " + timeAgo(expiresAt)
+	}
+	status := "lease " + shortDuration(left) + " left"
+	if heartbeatAt != nil {
+		status = "heartbeat " + timeAgo(*heartbeatAt) + ", " + status
+	}
+	return status
+}
+
+// shortDuration renders a duration in its largest whole unit ("45m", "3h")
+func shortDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
 func init() {
 	// shard assign flags
 	shardAssignCmd.Flags().String("agent", "", "Agent claiming the shard (default: config agent)")
+	shardAssignCmd.Flags().Duration("lease", 0, "Lease on the claim, e.g. 30m (default: config lease, else 2h)")
 
 	// shard next flags
 	shardNextCmd.Flags().String("epic", "", "Scope to specific epic")
 	shardNextCmd.Flags().Bool("global", false, "Ignore focus, search all open work")
 	shardNextCmd.Flags().Int("limit", 1, "Number of candidates to return (max 10)")
+	shardNextCmd.Flags().StringSlice("capability", nil, "Only shards whose needs: labels are all among these capabilities")
+	shardNextCmd.Flags().Bool("capable", false, "Only shards matching your registered capabilities")
+
+	// shard take flags
+	shardTakeCmd.Flags().String("epic", "", "Scope to specific epic")
+	shardTakeCmd.Flags().Bool("global", false, "Ignore focus, take from all open work")
+	shardTakeCmd.Flags().String("kind", "", "Only take shards of this kind (kind: label, default task)")
+	shardTakeCmd.Flags().String("agent", "", "Agent claiming the shard (default: config agent)")
+	shardTakeCmd.Flags().Duration("lease", 0, "Lease on the claim, e.g. 30m (default: config lease, else 2h)")
 
 	// shard board flags
 	shardBoardCmd.Flags().String("epic", "", "Scope to specific epic")
@@ -317,5 +469,6 @@ func init() {
 	// Wire into shard command tree
 	shardCmd.AddCommand(shardAssignCmd)
 	shardCmd.AddCommand(shardNextCmd)
+	shardCmd.AddCommand(shardTakeCmd)
 	shardCmd.AddCommand(shardBoardCmd)
 }
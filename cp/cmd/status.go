@@ -16,12 +16,16 @@ var statusCmd = &cobra.Command{
 
 		counts, err := cpClient.GetShardCounts(ctx)
 		if err != nil {
+			if cpClient.Backend() == client.BackendLocal {
+				return fmt.Errorf("Cannot open local store: %v", err)
+			}
 			return fmt.Errorf("Cannot connect to Context Palace at %s. Check config.",
 				cpClient.Config.Connection.Host)
 		}
 
 		if outputFormat == "json" {
 			type statusOutput struct {
+				Backend  string              `json:"backend"`
 				Host     string              `json:"host"`
 				Database string              `json:"database"`
 				Project  string              `json:"project"`
@@ -30,6 +34,7 @@ var statusCmd = &cobra.Command{
 				Shards   *client.ShardCounts `json:"shards"`
 			}
 			out := statusOutput{
+				Backend:  cpClient.Backend(),
 				Host:     cpClient.Config.Connection.Host,
 				Database: cpClient.Config.Connection.Database,
 				Project:  cpClient.Config.Project,
@@ -43,8 +48,12 @@ var statusCmd = &cobra.Command{
 		}
 
 		fmt.Println("Context Palace")
-		fmt.Printf("  Host:     %s\n", cpClient.Config.Connection.Host)
-		fmt.Printf("  Database: %s\n", cpClient.Config.Connection.Database)
+		if cpClient.Backend() == client.BackendLocal {
+			fmt.Printf("  Backend:  local (%s)\n", cpClient.Config.Storage.Path)
+		} else {
+			fmt.Printf("  Host:     %s\n", cpClient.Config.Connection.Host)
+			fmt.Printf("  Database: %s\n", cpClient.Config.Connection.Database)
+		}
 		fmt.Printf("  Project:  %s\n", cpClient.Config.Project)
 		fmt.Printf("  Agent:    %s\n", cpClient.Config.Agent)
 		fmt.Printf("  Status:   connected\n")
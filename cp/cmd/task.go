@@ -61,10 +61,11 @@ var taskClaimCmd = &cobra.Command{
 	Use:     "claim <shard-id>",
 	Short:   "Claim a task",
 	Args:    cobra.ExactArgs(1),
-	Example: "  cp task claim pf-123",
+	Example: "  cp task claim pf-123\n  cp task claim pf-123 --lease 30m",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
-		success, err := cpClient.ClaimTask(ctx, args[0])
+		lease, _ := cmd.Flags().GetDuration("lease")
+		success, err := cpClient.ClaimTask(ctx, args[0], lease)
 		if err != nil {
 			return err
 		}
@@ -87,6 +88,8 @@ var taskClaimCmd = &cobra.Command{
 var taskProgressCmd = &cobra.Command{
 	Use:     "progress <shard-id> <note>",
 	Short:   "Log progress on a task",
+	Long:    `Append a timestamped progress note to a task, or any shard you can take (a bug, a
+backlog item, ...). If you hold it, this also renews your lease on it.`,
 	Args:    cobra.ExactArgs(2),
 	Example: `  cp task progress pf-123 "Found bug in oauth.go line 45"`,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -106,6 +109,34 @@ var taskProgressCmd = &cobra.Command{
 	},
 }
 
+var taskHeartbeatCmd = &cobra.Command{
+	Use:   "heartbeat <shard-id>",
+	Short: "Renew your lease on a task",
+	Long: `Renew your lease on a task (or any shard) you hold, so it is not returned to
+open by 'cp admin reap'. Run it periodically during long work; 'cp task
+progress' renews the lease too. Fails if the lease already ran out and the
+shard was reaped: claim it again.`,
+	Args:    cobra.ExactArgs(1),
+	Example: "  cp task heartbeat pf-123\n  cp task heartbeat pf-123 --lease 4h",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		lease, _ := cmd.Flags().GetDuration("lease")
+		l, err := cpClient.Heartbeat(ctx, args[0], lease)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			s, _ := client.FormatJSON(l)
+			fmt.Println(s)
+			return nil
+		}
+
+		fmt.Printf("Renewed lease on %s until %s\n", l.ID, l.ExpiresAt.Local().Format("2006-01-02 15:04"))
+		return nil
+	},
+}
+
 var taskCloseCmd = &cobra.Command{
 	Use:     "close <shard-id> <summary>",
 	Short:   "Close a task",
@@ -129,9 +160,14 @@ var taskCloseCmd = &cobra.Command{
 }
 
 func init() {
+	taskClaimCmd.Flags().Duration("lease", 0, "Lease on the claim, e.g. 30m (default: config lease, else 2h)")
+	taskHeartbeatCmd.Flags().Duration("lease", 0, "New lease from now (default: config lease, else 2h)")
+
 	rootCmd.AddCommand(taskCmd)
 	taskCmd.AddCommand(taskGetCmd)
 	taskCmd.AddCommand(taskClaimCmd)
 	taskCmd.AddCommand(taskProgressCmd)
+	taskCmd.AddCommand(taskHeartbeatCmd)
 	taskCmd.AddCommand(taskCloseCmd)
+	postgresOnly(taskClaimCmd, taskCloseCmd)
 }
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream shard, edge and label changes as they happen",
+	Long: `Subscribe to change notifications for the project and print one JSON object
+per line (NDJSON) until interrupted. Replaces polling loops: an orchestrator can
+react to task closures and new messages as soon as they commit.
+
+Events: shard.created, shard.updated, shard.closed, shard.deleted,
+edge.created, edge.deleted, label.added, label.removed. Each carries the shard
+ID, type, status, title and labels; edge events add edge_type and to, label
+events add label. Lease renewals by a claim's owner (cp task heartbeat,
+cp task progress) are shard.heartbeat events, shown only with --heartbeats
+(needs migration 020_notify_heartbeat.sql; before it they are shard.updated).
+
+Dropped connections are re-established automatically (reported on stderr);
+changes made while disconnected are not replayed. Requires the postgres
+backend and migration 012_notify.sql.`,
+	Example: `  cp watch
+  cp watch --type task,message --label backend
+  cp watch --type task --event shard.closed
+  cp watch | jq -c 'select(.event == "shard.closed")'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		typeFlag, _ := cmd.Flags().GetString("type")
+		labelFlag, _ := cmd.Flags().GetString("label")
+		eventFlag, _ := cmd.Flags().GetString("event")
+		heartbeats, _ := cmd.Flags().GetBool("heartbeats")
+
+		var events []string
+		if eventFlag != "" {
+			events = strings.Split(eventFlag, ",")
+		}
+
+		opts := client.WatchOptions{Heartbeats: heartbeats}
+		if typeFlag != "" {
+			opts.Types = strings.Split(typeFlag, ",")
+		}
+		if labelFlag != "" {
+			opts.Labels = strings.Split(labelFlag, ",")
+		}
+
+		return runWatch(opts, func(ev client.Event) error {
+			if events != nil && !slices.Contains(events, ev.Event) {
+				return nil
+			}
+			return emitNDJSON(ev)
+		})
+	},
+}
+
+var messageWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream incoming messages as they arrive",
+	Long: `Print each message addressed to you (to: or cc:) as one JSON line (NDJSON) the
+moment it is sent, until interrupted.
+
+Unread messages already in the inbox are printed first, and again after a
+reconnect, so nothing sent while disconnected is missed; each message is
+printed once per run. Requires the postgres backend and migration
+012_notify.sql.`,
+	Example: `  cp message watch
+  cp message watch --new-only
+  cp message watch --mark-read | while read -r msg; do ...; done`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newOnly, _ := cmd.Flags().GetBool("new-only")
+		markRead, _ := cmd.Flags().GetBool("mark-read")
+
+		agent := cpClient.Config.Agent
+		seen := map[string]bool{}
+		first := true
+
+		emit := func(ctx context.Context, m messageEvent) error {
+			if seen[m.ID] {
+				return nil
+			}
+			seen[m.ID] = true
+			if err := emitNDJSON(m); err != nil {
+				return err
+			}
+			if markRead {
+				if _, err := cpClient.MarkRead(ctx, []string{m.ID}); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to mark %s read: %v\n", m.ID, err)
+				}
+			}
+			return nil
+		}
+
+		opts := client.WatchOptions{
+			Types: []string{"message"},
+			// LISTEN is active before this runs, so the inbox plus
+			// notifications cover every message
+			OnConnect: func(ctx context.Context) error {
+				inbox, err := cpClient.GetInbox(ctx)
+				if err != nil {
+					return err
+				}
+				skip := first && newOnly
+				first = false
+				for _, m := range inbox {
+					if skip {
+						seen[m.ID] = true
+						continue
+					}
+					kind := ""
+					if m.Kind != nil {
+						kind = strings.TrimPrefix(*m.Kind, "kind:")
+					}
+					ev := messageEvent{Event: "message", ID: m.ID, Title: m.Title, Creator: m.Creator, Kind: kind, CreatedAt: m.CreatedAt}
+					if err := emit(ctx, ev); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+
+		return runWatch(opts, func(ev client.Event) error {
+			if ev.Event != "label.added" || (ev.Label != "to:"+agent && ev.Label != "cc:"+agent) {
+				return nil
+			}
+			return emit(context.Background(), messageEvent{
+				Event:     "message",
+				ID:        ev.ID,
+				Title:     ev.Title,
+				Creator:   ev.Creator,
+				Kind:      client.MessageKind(ev.Labels),
+				CreatedAt: ev.At,
+			})
+		})
+	},
+}
+
+// messageEvent is one line of cp message watch output
+type messageEvent struct {
+	Event     string    `json:"event"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Creator   string    `json:"creator"`
+	Kind      string    `json:"kind,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// runWatch runs a watch until SIGINT/SIGTERM, reporting connection state on
+// stderr so stdout stays pure NDJSON
+func runWatch(opts client.WatchOptions, fn func(client.Event) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	onConnect := opts.OnConnect
+	connects := 0
+	opts.OnConnect = func(ctx context.Context) error {
+		connects++
+		if connects == 1 {
+			fmt.Fprintf(os.Stderr, "cp watch: listening for changes in %s as %s\n", cpClient.Config.Project, cpClient.Config.Agent)
+		} else {
+			fmt.Fprintf(os.Stderr, "cp watch: reconnected\n")
+		}
+		if onConnect != nil {
+			return onConnect(ctx)
+		}
+		return nil
+	}
+	opts.OnDisconnect = func(err error, retryIn time.Duration) {
+		fmt.Fprintf(os.Stderr, "cp watch: %v; reconnecting in %s\n", err, retryIn)
+	}
+	return cpClient.Watch(ctx, opts, fn)
+}
+
+// emitNDJSON writes v as a single JSON line
+func emitNDJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(b))
+	return err
+}
+
+func init() {
+	watchCmd.Flags().String("type", "", "Only shards of these types (comma-separated)")
+	watchCmd.Flags().String("label", "", "Only shards with any of these labels (comma-separated)")
+	watchCmd.Flags().String("event", "", "Only these events (comma-separated, e.g. shard.closed,edge.created)")
+	watchCmd.Flags().Bool("heartbeats", false, "Include shard.heartbeat events (lease renewals)")
+	rootCmd.AddCommand(watchCmd)
+
+	messageWatchCmd.Flags().Bool("new-only", false, "Skip messages already unread at startup")
+	messageWatchCmd.Flags().Bool("mark-read", false, "Mark each message read once printed")
+	messageCmd.AddCommand(messageWatchCmd)
+	postgresOnly(watchCmd, messageWatchCmd)
+}
@@ -0,0 +1,138 @@
+package answer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// CharsPerToken is the rough size of one token, used for budgeting.
+const CharsPerToken = 4
+
+// minSourceTokens is the smallest truncated excerpt worth including.
+const minSourceTokens = 100
+
+// Source is one retrieved shard offered to the model as context.
+type Source struct {
+	ID         string
+	Type       string
+	Title      string
+	Content    string
+	Heading    string // matched chunk heading path, if any
+	Similarity float64
+	Truncated  bool
+}
+
+// EstimateTokens approximates the token count of text.
+func EstimateTokens(text string) int {
+	return (len(text) + CharsPerToken - 1) / CharsPerToken
+}
+
+// Pack selects sources in rank order until budget tokens are used. A source
+// that doesn't fit whole is cut down to the remaining budget (starting at its
+// matched heading) as long as a useful excerpt remains.
+func Pack(sources []Source, budget int) []Source {
+	var packed []Source
+	remaining := budget
+	for _, s := range sources {
+		cost := EstimateTokens(sourceHeader(s)) + EstimateTokens(s.Content)
+		if cost <= remaining {
+			packed = append(packed, s)
+			remaining -= cost
+			continue
+		}
+		room := remaining - EstimateTokens(sourceHeader(s))
+		if room < minSourceTokens {
+			continue
+		}
+		s.Content = Excerpt(s.Content, s.Heading, room*CharsPerToken)
+		s.Truncated = true
+		packed = append(packed, s)
+		remaining -= EstimateTokens(sourceHeader(s)) + EstimateTokens(s.Content)
+	}
+	return packed
+}
+
+// Excerpt returns at most maxChars of content, starting at the line holding
+// the last element of heading when it can be found.
+func Excerpt(content, heading string, maxChars int) string {
+	if heading != "" {
+		parts := strings.Split(heading, " > ")
+		last := parts[len(parts)-1]
+		offset := 0
+		for _, line := range strings.SplitAfter(content, "\n") {
+			if strings.HasPrefix(line, "#") && strings.TrimSpace(strings.TrimLeft(line, "#")) == last {
+				content = content[offset:]
+				break
+			}
+			offset += len(line)
+		}
+	}
+	if len(content) <= maxChars {
+		return content
+	}
+	cut := maxChars
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut] + "\n[...]"
+}
+
+func sourceHeader(s Source) string {
+	return fmt.Sprintf("[%s] (%s) %s\n", s.ID, s.Type, s.Title)
+}
+
+// BuildAskPrompt creates the prompt asking the model to answer question from
+// the packed sources only, citing shard IDs inline.
+func BuildAskPrompt(question string, sources []Source) string {
+	var b strings.Builder
+	for _, s := range sources {
+		b.WriteString(sourceHeader(s))
+		b.WriteString(strings.TrimSpace(s.Content))
+		b.WriteString("\n---\n")
+	}
+
+	return fmt.Sprintf(`You are answering a question for an AI agent from its project memory
+(Context Palace shards: bugs, tasks, requirements, design docs, lessons learned).
+
+SOURCES:
+---
+%s
+QUESTION: %s
+
+Answer using only the sources above. After every claim, cite the source ID in
+square brackets, e.g. [%s]. If several sources support a claim, cite each.
+If the sources don't contain the answer, say so plainly instead of guessing.
+Be concise.`, b.String(), question, exampleID(sources))
+}
+
+func exampleID(sources []Source) string {
+	if len(sources) > 0 {
+		return sources[0].ID
+	}
+	return "pf-abc123"
+}
+
+var citationRe = regexp.MustCompile(`\[([A-Za-z0-9][A-Za-z0-9_-]*(?:\s*,\s*[A-Za-z0-9][A-Za-z0-9_-]*)*)\]`)
+
+// Citations returns the IDs of sources cited in answer, in order of first
+// citation. Bracketed text that isn't a source ID is ignored.
+func Citations(answer string, sources []Source) []string {
+	known := make(map[string]bool, len(sources))
+	for _, s := range sources {
+		known[s.ID] = true
+	}
+	seen := map[string]bool{}
+	var cited []string
+	for _, m := range citationRe.FindAllStringSubmatch(answer, -1) {
+		for _, id := range strings.Split(m[1], ",") {
+			id = strings.TrimSpace(id)
+			if known[id] && !seen[id] {
+				seen[id] = true
+				cited = append(cited, id)
+			}
+		}
+	}
+	return cited
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+	pathVar  = regexp.MustCompile(`\{([^}]+)\}`)
+)
+
+// OpenAPI returns the OpenAPI 3.0 document describing every route. Request
+// and response schemas are derived from the Go types' JSON encoding.
+func OpenAPI(version string) map[string]any {
+	g := &schemaGen{components: map[string]any{}}
+	errorRef := g.schema(reflect.TypeOf(apiErrorBody{}))
+
+	paths := map[string]map[string]any{}
+	for _, rt := range routes() {
+		op := map[string]any{
+			"tags":        []string{rt.Tag},
+			"summary":     rt.Summary,
+			"operationId": operationID(rt),
+		}
+
+		var params []map[string]any
+		documented := map[string]bool{}
+		for _, p := range rt.Params {
+			documented[p.Name] = true
+			params = append(params, map[string]any{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.Required,
+				"description": p.Description,
+				"schema":      map[string]any{"type": p.Type},
+			})
+		}
+		for _, m := range pathVar.FindAllStringSubmatch(rt.Path, -1) {
+			if !documented[m[1]] {
+				params = append(params, map[string]any{
+					"name": m[1], "in": "path", "required": true,
+					"schema": map[string]any{"type": "string"},
+				})
+			}
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if rt.Body != nil {
+			op["requestBody"] = map[string]any{
+				"required": rt.Method != "POST" || !optionalBody(rt.Body),
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(rt.Body))},
+				},
+			}
+		}
+
+		status := rt.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		errResp := func(desc string) map[string]any {
+			return map[string]any{
+				"description": desc,
+				"content":     map[string]any{"application/json": map[string]any{"schema": errorRef}},
+			}
+		}
+		op["responses"] = map[string]any{
+			strconv.Itoa(status): map[string]any{
+				"description": http.StatusText(status),
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(rt.Response))},
+				},
+			},
+			"400": errResp("Malformed request"),
+			"401": errResp("Missing or invalid token"),
+			"404": errResp("Not found"),
+			"409": errResp("Conflicts with the shard's current state"),
+			"422": errResp("Invalid request"),
+		}
+
+		if paths[rt.Path] == nil {
+			paths[rt.Path] = map[string]any{}
+		}
+		paths[rt.Path][strings.ToLower(rt.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Context Palace API",
+			"version":     version,
+			"description": "Shards, edges, labels, messages, memory, recall, requirements and knowledge documents. Every /v1 call runs as the agent that owns the bearer token.",
+		},
+		"security": []map[string]any{{"bearerAuth": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+			"schemas": g.components,
+		},
+	}
+}
+
+// optionalBody reports whether every field of a body type may be omitted, in
+// which case the handler accepts an empty body
+func optionalBody(body any) bool {
+	t := reflect.TypeOf(body)
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if !strings.Contains(tag, "omitempty") {
+			return false
+		}
+	}
+	return true
+}
+
+// operationID derives a stable camelCase ID like getShardsIdEdges
+func operationID(rt route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(rt.Method))
+	for _, part := range strings.Split(strings.TrimPrefix(rt.Path, "/v1/"), "/") {
+		part = strings.Trim(part, "{}")
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool { return r == '_' || r == '-' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// schemaGen converts Go types to JSON Schema, registering named structs as
+// shared components
+type schemaGen struct {
+	components map[string]any
+}
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	if t == rawType {
+		return map[string]any{} // any JSON value
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if _, isRef := s["$ref"]; !isRef {
+			s["nullable"] = true
+		}
+		return s
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if name == "" {
+			return g.structSchema(t)
+		}
+		if _, seen := g.components[name]; !seen {
+			g.components[name] = nil // placeholder breaks recursion
+			g.components[name] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if required != nil {
+		s["required"] = required
+	}
+	return s
+}
@@ -0,0 +1,1015 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+	"github.com/otherjamesbrown/context-palace/cp/internal/summary"
+)
+
+// route is one authenticated endpoint. Body and Response are zero values of
+// the request and response types, used only to generate the OpenAPI schema.
+type route struct {
+	Method   string
+	Path     string
+	Tag      string
+	Summary  string
+	Params   []param
+	Body     any
+	Response any
+	Status   int // success status, default 200
+	handle   handlerFunc
+}
+
+// param documents a query or path parameter
+type param struct {
+	Name        string
+	In          string // "query" or "path"
+	Type        string // "string", "integer" or "boolean"
+	Description string
+	Required    bool
+}
+
+func query(name, typ, desc string) param {
+	return param{Name: name, In: "query", Type: typ, Description: desc}
+}
+
+func pathID(desc string) param {
+	return param{Name: "id", In: "path", Type: "string", Description: desc, Required: true}
+}
+
+// Request bodies
+
+// CreateShardRequest is the body of POST /v1/shards.
+type CreateShardRequest struct {
+	Title    string          `json:"title"`
+	Type     string          `json:"type"`
+	Content  string          `json:"content,omitempty"`
+	Priority *int            `json:"priority,omitempty"`
+	Labels   []string        `json:"labels,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// UpdateShardRequest is the body of PATCH /v1/shards/{id}. Omitted fields are
+// left unchanged.
+type UpdateShardRequest struct {
+	Title   *string `json:"title,omitempty"`
+	Content *string `json:"content,omitempty"`
+	Status  *string `json:"status,omitempty"`
+}
+
+// AssignRequest is the body of POST /v1/shards/{id}/assign.
+type AssignRequest struct {
+	Agent string `json:"agent,omitempty"` // default: the calling agent
+	Lease string `json:"lease,omitempty"` // e.g. "30m"; default: the server's lease
+}
+
+// TakeRequest is the body of POST /v1/shards/take.
+type TakeRequest struct {
+	Epic  string `json:"epic,omitempty"`  // only shards in this epic
+	Kind  string `json:"kind,omitempty"`  // only shards of this kind
+	Agent string `json:"agent,omitempty"` // default: the calling agent
+	Lease string `json:"lease,omitempty"` // e.g. "30m"; default: the server's lease
+}
+
+// TakeResponse is the result of POST /v1/shards/take. Shard is absent when
+// nothing was available.
+type TakeResponse struct {
+	Taken bool              `json:"taken"`
+	Owner string            `json:"owner,omitempty"`
+	Shard *client.NextShard `json:"shard,omitempty"`
+}
+
+// HeartbeatRequest is the body of POST /v1/shards/{id}/heartbeat.
+type HeartbeatRequest struct {
+	Lease string `json:"lease,omitempty"` // e.g. "30m"; default: the server's lease
+}
+
+// CloseRequest is the body of POST /v1/shards/{id}/close.
+type CloseRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// EdgeRequest is the body of POST /v1/edges.
+type EdgeRequest struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Type     string          `json:"type"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// LabelsRequest is the body of POST /v1/shards/{id}/labels.
+type LabelsRequest struct {
+	Labels []string `json:"labels"`
+}
+
+// SendMessageRequest is the body of POST /v1/messages.
+type SendMessageRequest struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body,omitempty"`
+	CC      []string `json:"cc,omitempty"`
+	Kind    string   `json:"kind,omitempty"`
+	ReplyTo string   `json:"reply_to,omitempty"`
+	Due     string   `json:"due,omitempty"` // reply deadline, e.g. "4h"; implies kind request
+}
+
+// ReplyRequest is the body of POST /v1/messages/{id}/reply.
+type ReplyRequest struct {
+	Body    string   `json:"body,omitempty"`
+	CC      []string `json:"cc,omitempty"` // in addition to the original's cc list
+	Kind    string   `json:"kind,omitempty"`
+	All     bool     `json:"all,omitempty"`      // also copy the original's other recipients
+	NoQuote bool     `json:"no_quote,omitempty"` // do not quote the original
+}
+
+// ReplyResponse is returned by POST /v1/messages/{id}/reply.
+type ReplyResponse struct {
+	ID       string   `json:"id"`
+	To       []string `json:"to"`
+	CC       []string `json:"cc"`
+	Subject  string   `json:"subject"`
+	Warnings []string `json:"warnings,omitempty"` // recipients that are not registered agents
+}
+
+// EscalateRequest is the body of POST /v1/messages/escalate.
+type EscalateRequest struct {
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// PendingResponse is the response of GET /v1/messages/pending.
+type PendingResponse struct {
+	Sent []client.Request `json:"sent"` // sent by the caller, awaiting replies
+	Owed []client.Request `json:"owed"` // the caller owes a reply
+}
+
+// MarkReadRequest is the body of POST /v1/messages/read.
+type MarkReadRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// AddMemoryRequest is the body of POST /v1/memory/{id}/children.
+type AddMemoryRequest struct {
+	Title   string   `json:"title"`
+	Body    string   `json:"body"`
+	Labels  []string `json:"labels,omitempty"`
+	Summary string   `json:"summary,omitempty"` // generated when omitted
+}
+
+// RecallRequest is the body of POST /v1/recall.
+type RecallRequest struct {
+	Query          string   `json:"query"`
+	Mode           string   `json:"mode,omitempty"` // hybrid (default), semantic or keyword
+	Types          []string `json:"types,omitempty"`
+	Labels         []string `json:"labels,omitempty"`
+	IncludeClosed  bool     `json:"include_closed,omitempty"`
+	Since          string   `json:"since,omitempty"`
+	MinSimilarity  *float64 `json:"min_similarity,omitempty"`
+	Limit          int      `json:"limit,omitempty"`
+	SemanticWeight *float64 `json:"semantic_weight,omitempty"`
+	KeywordWeight  *float64 `json:"keyword_weight,omitempty"`
+}
+
+// CreateRequirementRequest is the body of POST /v1/requirements.
+type CreateRequirementRequest struct {
+	Title    string `json:"title"`
+	Content  string `json:"content,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// VerifyRequest is the body of POST /v1/requirements/{id}/verify.
+type VerifyRequest struct {
+	Force bool `json:"force,omitempty"`
+}
+
+// ReopenRequest is the body of POST /v1/requirements/{id}/reopen.
+type ReopenRequest struct {
+	Reason string `json:"reason"`
+}
+
+// LinkRequest is the body of POST /v1/requirements/{id}/links.
+type LinkRequest struct {
+	Kind     string `json:"kind"` // task, test or dependency
+	TargetID string `json:"target_id"`
+}
+
+// CreateKnowledgeRequest is the body of POST /v1/knowledge.
+type CreateKnowledgeRequest struct {
+	Title   string   `json:"title"`
+	DocType string   `json:"doc_type"`
+	Content string   `json:"content,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+}
+
+// KnowledgeUpdateRequest is the body of PUT /v1/knowledge/{id} and
+// POST /v1/knowledge/{id}/append.
+type KnowledgeUpdateRequest struct {
+	Content string `json:"content"`
+	Summary string `json:"summary"`
+}
+
+// Responses
+
+// ShardList is the response of GET /v1/shards.
+type ShardList struct {
+	Shards []client.ShardListResult `json:"shards"`
+	Total  int                      `json:"total"`
+}
+
+// Created reports the ID of a newly created shard.
+type Created struct {
+	ID string `json:"id"`
+}
+
+// OK is returned by operations with nothing else to report.
+type OK struct {
+	OK bool `json:"ok"`
+}
+
+// LabelsResponse lists a shard's labels after a change.
+type LabelsResponse struct {
+	Labels []string `json:"labels"`
+}
+
+// MarkReadResponse counts messages newly marked read.
+type MarkReadResponse struct {
+	Marked int `json:"marked"`
+}
+
+// RequirementDetail is the response of GET /v1/requirements/{id}.
+type RequirementDetail struct {
+	Shard      *client.Shard            `json:"shard"`
+	Edges      []client.RequirementEdge `json:"edges"`
+	TaskTotal  int                      `json:"task_total"`
+	TaskClosed int                      `json:"task_closed"`
+	TestCount  int                      `json:"test_count"`
+}
+
+// DiffResponse is the response of GET /v1/knowledge/{id}/diff.
+type DiffResponse struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
+
+// WhoAmI identifies the authenticated agent.
+type WhoAmI struct {
+	Agent   string `json:"agent"`
+	Project string `json:"project"`
+	Backend string `json:"backend"`
+}
+
+func routes() []route {
+	limit := query("limit", "integer", "Maximum results (default 20)")
+	return []route{
+		// Shards
+		{Method: "GET", Path: "/v1/shards", Tag: "shards", Summary: "List shards",
+			Params: []param{
+				query("type", "string", "Comma-separated shard types"),
+				query("status", "string", "Comma-separated statuses"),
+				query("label", "string", "Comma-separated labels (any)"),
+				query("creator", "string", "Filter by creator"),
+				query("search", "string", "Full-text search"),
+				query("since", "string", "Created after (RFC 3339 or YYYY-MM-DD)"),
+				limit,
+				query("offset", "integer", "Skip N results"),
+			},
+			Response: ShardList{}, handle: listShards},
+		{Method: "POST", Path: "/v1/shards", Tag: "shards", Summary: "Create a shard",
+			Body: CreateShardRequest{}, Response: client.Shard{}, Status: http.StatusCreated, handle: createShard},
+		{Method: "GET", Path: "/v1/shards/next", Tag: "shards", Summary: "Next unblocked open shards",
+			Params: []param{query("epic", "string", "Only shards in this epic"),
+				query("capability", "string", "Comma-separated capabilities; only shards whose needs: labels they cover"), limit},
+			Response: []client.NextShard{}, handle: nextShards},
+		{Method: "POST", Path: "/v1/shards/take", Tag: "shards", Summary: "Atomically claim the next unblocked shard",
+			Body: TakeRequest{}, Response: TakeResponse{}, handle: takeShard},
+		{Method: "GET", Path: "/v1/shards/board", Tag: "shards", Summary: "Shard board grouped by status",
+			Params:   []param{query("epic", "string", "Only shards in this epic"), query("agent", "string", "Only shards owned by this agent")},
+			Response: []client.BoardShard{}, handle: shardBoard},
+		{Method: "GET", Path: "/v1/shards/{id}", Tag: "shards", Summary: "Get a shard",
+			Params: []param{pathID("Shard ID")}, Response: client.Shard{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				return cl.GetShard(ctx, r.PathValue("id"))
+			}},
+		{Method: "PATCH", Path: "/v1/shards/{id}", Tag: "shards", Summary: "Update a shard's title, content or status",
+			Params: []param{pathID("Shard ID")}, Body: UpdateShardRequest{}, Response: client.Shard{}, handle: updateShard},
+		{Method: "POST", Path: "/v1/shards/{id}/assign", Tag: "shards", Summary: "Assign a shard",
+			Params: []param{pathID("Shard ID")}, Body: AssignRequest{}, Response: client.AssignResult{}, handle: assignShard},
+		{Method: "POST", Path: "/v1/shards/{id}/heartbeat", Tag: "shards", Summary: "Renew the caller's lease on a shard it holds",
+			Params: []param{pathID("Shard ID")}, Body: HeartbeatRequest{}, Response: client.Lease{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req HeartbeatRequest
+				if err := decodeOptionalBody(r, &req); err != nil {
+					return nil, err
+				}
+				lease, err := parseLease(req.Lease)
+				if err != nil {
+					return nil, err
+				}
+				return cl.Heartbeat(ctx, r.PathValue("id"), lease)
+			}},
+		{Method: "POST", Path: "/v1/shards/{id}/close", Tag: "shards", Summary: "Close a shard",
+			Params: []param{pathID("Shard ID")}, Body: CloseRequest{}, Response: client.CloseResult{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req CloseRequest
+				if err := decodeOptionalBody(r, &req); err != nil {
+					return nil, err
+				}
+				return cl.CloseShard(ctx, r.PathValue("id"), req.Reason)
+			}},
+
+		// Edges
+		{Method: "GET", Path: "/v1/shards/{id}/edges", Tag: "edges", Summary: "List a shard's edges",
+			Params: []param{pathID("Shard ID"),
+				query("direction", "string", "outgoing, incoming or both (default)"),
+				query("type", "string", "Comma-separated edge types")},
+			Response: []client.EdgeInfo{}, handle: shardEdges},
+		{Method: "GET", Path: "/v1/edge-types", Tag: "edges", Summary: "Edge types in effect for the project",
+			Response: []client.EdgeType{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				reg, err := cl.EdgeTypes(ctx)
+				return []client.EdgeType(reg), err
+			}},
+		{Method: "POST", Path: "/v1/edges", Tag: "edges", Summary: "Create an edge",
+			Body: EdgeRequest{}, Response: OK{}, Status: http.StatusCreated,
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req EdgeRequest
+				if err := decodeBody(r, &req); err != nil {
+					return nil, err
+				}
+				if req.From == "" || req.To == "" || req.Type == "" {
+					return nil, badRequest("from, to and type are required")
+				}
+				if err := cl.CreateEdge(ctx, req.From, req.To, req.Type, req.Metadata); err != nil {
+					return nil, err
+				}
+				return OK{true}, nil
+			}},
+		{Method: "DELETE", Path: "/v1/edges", Tag: "edges", Summary: "Delete an edge",
+			Params: []param{
+				{Name: "from", In: "query", Type: "string", Description: "Source shard ID", Required: true},
+				{Name: "to", In: "query", Type: "string", Description: "Target shard ID", Required: true},
+				{Name: "type", In: "query", Type: "string", Description: "Edge type", Required: true},
+			},
+			Response: OK{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				q := r.URL.Query()
+				if q.Get("from") == "" || q.Get("to") == "" || q.Get("type") == "" {
+					return nil, badRequest("from, to and type query parameters are required")
+				}
+				if err := cl.DeleteEdge(ctx, q.Get("from"), q.Get("to"), q.Get("type")); err != nil {
+					return nil, err
+				}
+				return OK{true}, nil
+			}},
+
+		// Labels
+		{Method: "GET", Path: "/v1/labels", Tag: "labels", Summary: "Label usage counts",
+			Response: []client.LabelCount{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				labels, err := cl.LabelSummary(ctx)
+				return nonNil(labels), err
+			}},
+		{Method: "POST", Path: "/v1/shards/{id}/labels", Tag: "labels", Summary: "Add labels to a shard",
+			Params: []param{pathID("Shard ID")}, Body: LabelsRequest{}, Response: LabelsResponse{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req LabelsRequest
+				if err := decodeBody(r, &req); err != nil {
+					return nil, err
+				}
+				if len(req.Labels) == 0 {
+					return nil, badRequest("labels is required")
+				}
+				labels, err := cl.AddShardLabels(ctx, r.PathValue("id"), req.Labels)
+				return LabelsResponse{nonNil(labels)}, err
+			}},
+		{Method: "DELETE", Path: "/v1/shards/{id}/labels/{label}", Tag: "labels", Summary: "Remove a label from a shard",
+			Params:   []param{pathID("Shard ID"), {Name: "label", In: "path", Type: "string", Description: "Label to remove", Required: true}},
+			Response: LabelsResponse{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				labels, err := cl.RemoveShardLabels(ctx, r.PathValue("id"), []string{r.PathValue("label")})
+				return LabelsResponse{nonNil(labels)}, err
+			}},
+
+		// Messages
+		{Method: "GET", Path: "/v1/messages/inbox", Tag: "messages", Summary: "Unread messages for the calling agent",
+			Response: []client.Message{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				msgs, err := cl.GetInbox(ctx)
+				return nonNil(msgs), err
+			}},
+		{Method: "GET", Path: "/v1/messages/sent", Tag: "messages", Summary: "Messages the calling agent sent, with read status per recipient",
+			Params:   []param{query("unread", "boolean", "Only messages someone has not read"), limit},
+			Response: []client.SentMessage{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				n, err := queryInt(r, "limit", 20, 1, 1000)
+				if err != nil {
+					return nil, err
+				}
+				return cl.ListSent(ctx, n, r.URL.Query().Get("unread") == "true")
+			}},
+		{Method: "GET", Path: "/v1/messages/pending", Tag: "messages", Summary: "Requests the caller sent or owes a reply to",
+			Response: PendingResponse{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				sent, owed, err := cl.PendingRequests(ctx)
+				return PendingResponse{sent, owed}, err
+			}},
+		{Method: "POST", Path: "/v1/messages/escalate", Tag: "messages", Summary: "Chase overdue requests in the project",
+			Body: EscalateRequest{}, Response: []client.Escalation{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req EscalateRequest
+				if err := decodeOptionalBody(r, &req); err != nil {
+					return nil, err
+				}
+				return cl.EscalateRequests(ctx, req.DryRun)
+			}},
+		{Method: "POST", Path: "/v1/messages", Tag: "messages", Summary: "Send a message",
+			Body: SendMessageRequest{}, Response: client.SendResult{}, Status: http.StatusCreated,
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req SendMessageRequest
+				if err := decodeBody(r, &req); err != nil {
+					return nil, err
+				}
+				if len(req.To) == 0 || req.Subject == "" {
+					return nil, badRequest("to and subject are required")
+				}
+				if req.Due == "" {
+					return cl.SendMessage(ctx, req.To, req.Subject, req.Body, req.CC, req.Kind, req.ReplyTo)
+				}
+				if req.Kind != "" && req.Kind != client.RequestKind {
+					return nil, badRequest("due only applies to kind " + client.RequestKind)
+				}
+				due, err := client.ParseDue(req.Due, time.Now())
+				if err != nil {
+					return nil, badRequest(err.Error())
+				}
+				return cl.SendRequest(ctx, req.To, req.Subject, req.Body, req.CC, req.ReplyTo, &due)
+			}},
+		{Method: "POST", Path: "/v1/messages/read", Tag: "messages", Summary: "Mark messages read",
+			Body: MarkReadRequest{}, Response: MarkReadResponse{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req MarkReadRequest
+				if err := decodeBody(r, &req); err != nil {
+					return nil, err
+				}
+				if len(req.IDs) == 0 {
+					return nil, badRequest("ids is required")
+				}
+				n, err := cl.MarkRead(ctx, req.IDs)
+				return MarkReadResponse{n}, err
+			}},
+		{Method: "GET", Path: "/v1/messages/{id}", Tag: "messages", Summary: "Get a message",
+			Params: []param{pathID("Message ID")}, Response: client.Message{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				return cl.GetMessage(ctx, r.PathValue("id"))
+			}},
+		{Method: "GET", Path: "/v1/messages/{id}/thread", Tag: "messages", Summary: "The conversation a message belongs to, with read status",
+			Params: []param{pathID("Any message in the thread")}, Response: client.Thread{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				return cl.GetThread(ctx, r.PathValue("id"))
+			}},
+		{Method: "POST", Path: "/v1/messages/{id}/reply", Tag: "messages", Summary: "Reply to the sender and cc list of a message",
+			Params: []param{pathID("Message ID")}, Body: ReplyRequest{}, Response: ReplyResponse{}, Status: http.StatusCreated,
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req ReplyRequest
+				if err := decodeOptionalBody(r, &req); err != nil {
+					return nil, err
+				}
+				draft, err := cl.PrepareReply(ctx, r.PathValue("id"), req.Body, req.All, !req.NoQuote)
+				if err != nil {
+					return nil, err
+				}
+				sent, err := cl.Reply(ctx, draft, req.CC, req.Kind)
+				if err != nil {
+					return nil, err
+				}
+				return ReplyResponse{sent.ID, draft.To, nonNil(draft.Cc), draft.Subject, sent.Warnings}, nil
+			}},
+
+		// Memory
+		{Method: "GET", Path: "/v1/memory/tree", Tag: "memory", Summary: "Memory hierarchy",
+			Params:   []param{query("root", "string", "Only the subtree under this memory")},
+			Response: []client.MemoryTreeNode{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var root *string
+				if v := r.URL.Query().Get("root"); v != "" {
+					root = &v
+				}
+				nodes, err := cl.GetMemoryTree(ctx, root)
+				return nonNil(nodes), err
+			}},
+		{Method: "GET", Path: "/v1/memory/{id}/children", Tag: "memory", Summary: "Direct children of a memory",
+			Params: []param{pathID("Memory ID")}, Response: []client.MemoryChild{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				children, err := cl.GetMemoryChildren(ctx, r.PathValue("id"))
+				return nonNil(children), err
+			}},
+		{Method: "GET", Path: "/v1/memory/{id}/path", Tag: "memory", Summary: "Path from the root to a memory",
+			Params: []param{pathID("Memory ID")}, Response: []client.MemoryPathNode{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				path, err := cl.GetMemoryPath(ctx, r.PathValue("id"))
+				return nonNil(path), err
+			}},
+		{Method: "POST", Path: "/v1/memory/{id}/children", Tag: "memory", Summary: "Add a sub-memory",
+			Params: []param{pathID("Parent memory ID")}, Body: AddMemoryRequest{}, Response: client.AddSubResult{},
+			Status: http.StatusCreated, handle: addMemory},
+
+		// Recall
+		{Method: "POST", Path: "/v1/recall", Tag: "recall", Summary: "Hybrid, semantic or keyword search",
+			Body: RecallRequest{}, Response: []client.RecallResult{}, handle: recall},
+
+		// Requirements
+		{Method: "GET", Path: "/v1/requirements", Tag: "requirements", Summary: "Requirement dashboard",
+			Params: []param{
+				query("status", "string", "Comma-separated lifecycle statuses"),
+				query("category", "string", "Filter by category"),
+				limit,
+			},
+			Response: []client.RequirementDashboardRow{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				n, err := queryInt(r, "limit", 20, 1, 1000)
+				if err != nil {
+					return nil, err
+				}
+				rows, err := cl.ListRequirements(ctx, queryList(r, "status"), r.URL.Query().Get("category"), n)
+				return nonNil(rows), err
+			}},
+		{Method: "POST", Path: "/v1/requirements", Tag: "requirements", Summary: "Create a requirement",
+			Body: CreateRequirementRequest{}, Response: Created{}, Status: http.StatusCreated,
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req CreateRequirementRequest
+				if err := decodeBody(r, &req); err != nil {
+					return nil, err
+				}
+				if req.Title == "" {
+					return nil, badRequest("title is required")
+				}
+				if req.Priority == 0 {
+					req.Priority = 2
+				}
+				id, err := cl.CreateRequirement(ctx, req.Title, req.Content, req.Priority, req.Category)
+				if err != nil {
+					return nil, err
+				}
+				return Created{id}, nil
+			}},
+		{Method: "GET", Path: "/v1/requirements/{id}", Tag: "requirements", Summary: "Requirement with linked tasks and tests",
+			Params: []param{pathID("Requirement ID")}, Response: RequirementDetail{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				shard, edges, taskTotal, taskClosed, testCount, err := cl.ShowRequirement(ctx, r.PathValue("id"))
+				if err != nil {
+					return nil, err
+				}
+				return RequirementDetail{shard, nonNil(edges), taskTotal, taskClosed, testCount}, nil
+			}},
+		{Method: "POST", Path: "/v1/requirements/{id}/approve", Tag: "requirements", Summary: "Approve a draft requirement",
+			Params: []param{pathID("Requirement ID")}, Response: OK{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				return ok(cl.ApproveRequirement(ctx, r.PathValue("id")))
+			}},
+		{Method: "POST", Path: "/v1/requirements/{id}/verify", Tag: "requirements", Summary: "Mark a requirement verified",
+			Params: []param{pathID("Requirement ID")}, Body: VerifyRequest{}, Response: OK{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req VerifyRequest
+				if err := decodeOptionalBody(r, &req); err != nil {
+					return nil, err
+				}
+				return ok(cl.VerifyRequirement(ctx, r.PathValue("id"), req.Force))
+			}},
+		{Method: "POST", Path: "/v1/requirements/{id}/reopen", Tag: "requirements", Summary: "Reopen a requirement",
+			Params: []param{pathID("Requirement ID")}, Body: ReopenRequest{}, Response: OK{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req ReopenRequest
+				if err := decodeBody(r, &req); err != nil {
+					return nil, err
+				}
+				if req.Reason == "" {
+					return nil, badRequest("reason is required")
+				}
+				return ok(cl.ReopenRequirement(ctx, r.PathValue("id"), req.Reason))
+			}},
+		{Method: "POST", Path: "/v1/requirements/{id}/links", Tag: "requirements", Summary: "Link a task, test or dependency",
+			Params: []param{pathID("Requirement ID")}, Body: LinkRequest{}, Response: OK{}, Status: http.StatusCreated,
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req LinkRequest
+				if err := decodeBody(r, &req); err != nil {
+					return nil, err
+				}
+				if req.TargetID == "" {
+					return nil, badRequest("target_id is required")
+				}
+				id := r.PathValue("id")
+				switch req.Kind {
+				case "task":
+					return ok(cl.LinkTask(ctx, id, req.TargetID))
+				case "test":
+					return ok(cl.LinkTest(ctx, id, req.TargetID))
+				case "dependency":
+					return ok(cl.LinkDependency(ctx, id, req.TargetID))
+				default:
+					return nil, badRequest("kind must be task, test or dependency")
+				}
+			}},
+
+		// Knowledge documents
+		{Method: "GET", Path: "/v1/knowledge", Tag: "knowledge", Summary: "List knowledge documents",
+			Params:   []param{query("doc_type", "string", "Filter by document type"), limit},
+			Response: []client.KnowledgeDoc{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				n, err := queryInt(r, "limit", 20, 1, 1000)
+				if err != nil {
+					return nil, err
+				}
+				docs, err := cl.ListKnowledgeDocs(ctx, r.URL.Query().Get("doc_type"), n)
+				return nonNil(docs), err
+			}},
+		{Method: "POST", Path: "/v1/knowledge", Tag: "knowledge", Summary: "Create a knowledge document",
+			Body: CreateKnowledgeRequest{}, Response: Created{}, Status: http.StatusCreated,
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				var req CreateKnowledgeRequest
+				if err := decodeBody(r, &req); err != nil {
+					return nil, err
+				}
+				if req.Title == "" {
+					return nil, badRequest("title is required")
+				}
+				if err := client.ValidateDocType(req.DocType); err != nil {
+					return nil, badRequest("%v", err)
+				}
+				id, err := cl.CreateKnowledgeDoc(ctx, req.Title, req.Content, req.DocType, req.Labels)
+				if err != nil {
+					return nil, err
+				}
+				return Created{id}, nil
+			}},
+		{Method: "GET", Path: "/v1/knowledge/{id}", Tag: "knowledge", Summary: "Get a knowledge document",
+			Params:   []param{pathID("Document ID"), query("version", "integer", "A past version (default: current)")},
+			Response: client.KnowledgeDoc{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				version, err := queryInt(r, "version", 0, 1, 1<<30)
+				if err != nil {
+					return nil, err
+				}
+				if version > 0 {
+					return cl.GetKnowledgeVersion(ctx, r.PathValue("id"), version)
+				}
+				return cl.ShowKnowledgeDoc(ctx, r.PathValue("id"))
+			}},
+		{Method: "PUT", Path: "/v1/knowledge/{id}", Tag: "knowledge", Summary: "Replace a knowledge document's content",
+			Params: []param{pathID("Document ID")}, Body: KnowledgeUpdateRequest{}, Response: client.UpdateResult{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				req, err := decodeKnowledgeUpdate(r)
+				if err != nil {
+					return nil, err
+				}
+				return cl.UpdateKnowledgeDoc(ctx, r.PathValue("id"), req.Content, req.Summary)
+			}},
+		{Method: "POST", Path: "/v1/knowledge/{id}/append", Tag: "knowledge", Summary: "Append to a knowledge document",
+			Params: []param{pathID("Document ID")}, Body: KnowledgeUpdateRequest{}, Response: client.UpdateResult{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				req, err := decodeKnowledgeUpdate(r)
+				if err != nil {
+					return nil, err
+				}
+				return cl.AppendKnowledgeDoc(ctx, r.PathValue("id"), req.Content, req.Summary)
+			}},
+		{Method: "GET", Path: "/v1/knowledge/{id}/history", Tag: "knowledge", Summary: "Version history",
+			Params: []param{pathID("Document ID")}, Response: []client.VersionEntry{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				history, err := cl.KnowledgeHistory(ctx, r.PathValue("id"))
+				return nonNil(history), err
+			}},
+		{Method: "GET", Path: "/v1/knowledge/{id}/diff", Tag: "knowledge", Summary: "Diff two versions",
+			Params: []param{pathID("Document ID"),
+				{Name: "from", In: "query", Type: "integer", Description: "Older version", Required: true},
+				{Name: "to", In: "query", Type: "integer", Description: "Newer version", Required: true}},
+			Response: DiffResponse{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				from, err1 := strconv.Atoi(r.URL.Query().Get("from"))
+				to, err2 := strconv.Atoi(r.URL.Query().Get("to"))
+				if err1 != nil || err2 != nil {
+					return nil, badRequest("from and to must be version numbers")
+				}
+				diff, err := cl.DiffVersions(ctx, r.PathValue("id"), from, to)
+				if err != nil {
+					return nil, err
+				}
+				return DiffResponse{from, to, diff}, nil
+			}},
+
+		// Agents
+		{Method: "GET", Path: "/v1/agents", Tag: "agents", Summary: "Registered agents with presence",
+			Params: []param{query("all_projects", "boolean", "Include agents that do not serve this project"),
+				query("capability", "string", "Only agents with this capability"),
+				query("group", "string", "Only members of this message group")},
+			Response: []client.Agent{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				all := r.URL.Query().Get("all_projects") == "true"
+				return cl.ListAgents(ctx, all, r.URL.Query().Get("capability"), r.URL.Query().Get("group"))
+			}},
+		{Method: "POST", Path: "/v1/agents/heartbeat", Tag: "agents", Summary: "Mark the calling agent as alive",
+			Response: client.Agent{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				return cl.AgentHeartbeat(ctx, cl.Config.Agent)
+			}},
+
+		// Meta
+		{Method: "GET", Path: "/v1/whoami", Tag: "meta", Summary: "The authenticated agent",
+			Response: WhoAmI{},
+			handle: func(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+				return WhoAmI{cl.Config.Agent, cl.Config.Project, cl.Backend()}, nil
+			}},
+	}
+}
+
+// ok turns a bare error into an OK response
+func ok(err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	return OK{true}, nil
+}
+
+// decodeOptionalBody decodes the body if one was sent
+func decodeOptionalBody(r *http.Request, v any) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	err := decodeBody(r, v)
+	if he, isHTTP := err.(*httpError); isHTTP && he.msg == "request body is required" {
+		return nil
+	}
+	return err
+}
+
+func decodeKnowledgeUpdate(r *http.Request) (KnowledgeUpdateRequest, error) {
+	var req KnowledgeUpdateRequest
+	if err := decodeBody(r, &req); err != nil {
+		return req, err
+	}
+	if req.Content == "" || req.Summary == "" {
+		return req, badRequest("content and summary are required")
+	}
+	return req, nil
+}
+
+func listShards(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	n, err := queryInt(r, "limit", 20, 1, 1000)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := queryInt(r, "offset", 0, 0, 1<<30)
+	if err != nil {
+		return nil, err
+	}
+	since, err := parseTime("since", r.URL.Query().Get("since"))
+	if err != nil {
+		return nil, err
+	}
+	opts := client.ListShardsOpts{
+		Types:   queryList(r, "type"),
+		Status:  queryList(r, "status"),
+		Labels:  queryList(r, "label"),
+		Creator: r.URL.Query().Get("creator"),
+		Search:  r.URL.Query().Get("search"),
+		Since:   since,
+		Limit:   n,
+		Offset:  offset,
+	}
+	shards, err := cl.ListShardsFiltered(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	total, err := cl.ListShardsCount(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ShardList{nonNil(shards), total}, nil
+}
+
+func createShard(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	var req CreateShardRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Title == "" || req.Type == "" {
+		return nil, badRequest("title and type are required")
+	}
+	if len(req.Metadata) > 0 && !json.Valid(req.Metadata) {
+		return nil, badRequest("metadata must be a JSON object")
+	}
+	id, err := cl.CreateShardWithMetadata(ctx, req.Title, req.Content, req.Type, req.Priority, req.Labels, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return cl.GetShard(ctx, id)
+}
+
+func updateShard(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	var req UpdateShardRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Title == nil && req.Content == nil && req.Status == nil {
+		return nil, badRequest("nothing to update: set title, content or status")
+	}
+	id := r.PathValue("id")
+	if req.Title != nil || req.Content != nil {
+		if _, err := cl.UpdateShardFields(ctx, id, req.Title, req.Content); err != nil {
+			return nil, err
+		}
+	}
+	if req.Status != nil {
+		if err := cl.UpdateShardStatus(ctx, id, *req.Status); err != nil {
+			return nil, err
+		}
+	}
+	return cl.GetShard(ctx, id)
+}
+
+func assignShard(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	var req AssignRequest
+	if err := decodeOptionalBody(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Agent == "" {
+		req.Agent = cl.Config.Agent
+	}
+	lease, err := parseLease(req.Lease)
+	if err != nil {
+		return nil, err
+	}
+	return cl.AssignShard(ctx, r.PathValue("id"), req.Agent, lease)
+}
+
+func nextShards(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	n, err := queryInt(r, "limit", 5, 1, 100)
+	if err != nil {
+		return nil, err
+	}
+	var epic *string
+	if v := r.URL.Query().Get("epic"); v != "" {
+		epic = &v
+	}
+	var capabilities []string
+	if v := r.URL.Query().Get("capability"); v != "" {
+		capabilities = strings.Split(v, ",")
+	}
+	shards, err := cl.GetNextShards(ctx, epic, capabilities, n)
+	return nonNil(shards), err
+}
+
+func takeShard(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	var req TakeRequest
+	if err := decodeOptionalBody(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Agent == "" {
+		req.Agent = cl.Config.Agent
+	}
+	var epic, kind *string
+	if req.Epic != "" {
+		epic = &req.Epic
+	}
+	if req.Kind != "" {
+		kind = &req.Kind
+	}
+	lease, err := parseLease(req.Lease)
+	if err != nil {
+		return nil, err
+	}
+	taken, err := cl.TakeShard(ctx, epic, kind, req.Agent, lease)
+	if err != nil {
+		return nil, err
+	}
+	if taken == nil {
+		return TakeResponse{}, nil
+	}
+	return TakeResponse{Taken: true, Owner: req.Agent, Shard: taken}, nil
+}
+
+func shardBoard(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	var epic, agent *string
+	if v := r.URL.Query().Get("epic"); v != "" {
+		epic = &v
+	}
+	if v := r.URL.Query().Get("agent"); v != "" {
+		agent = &v
+	}
+	board, err := cl.GetShardBoard(ctx, epic, agent)
+	return nonNil(board), err
+}
+
+func shardEdges(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	id := r.PathValue("id")
+	direction := r.URL.Query().Get("direction")
+	switch direction {
+	case "", "both", "outgoing", "incoming":
+	default:
+		return nil, badRequest("direction must be outgoing, incoming or both")
+	}
+	if direction == "both" {
+		direction = ""
+	}
+	exists, err := cl.ShardExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, notFound("shard %s not found", id)
+	}
+	edges, err := cl.GetShardEdges(ctx, id, direction, queryList(r, "type"))
+	return nonNil(edges), err
+}
+
+func addMemory(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	var req AddMemoryRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Title == "" || req.Body == "" {
+		return nil, badRequest("title and body are required")
+	}
+	parentID := r.PathValue("id")
+	parent, err := cl.GetShard(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.Type != "memory" {
+		return nil, badRequest("parent %s is type '%s', expected 'memory'", parentID, parent.Type)
+	}
+
+	trigger := req.Summary
+	if trigger == "" {
+		if cl.Generator == nil {
+			return nil, badRequest("summary is required when no generation provider is configured")
+		}
+		trigger, err = summary.GenerateTrigger(ctx, cl.Generator, parentID, parent.Content, req.Title, req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cl.AddSubMemory(ctx, parentID, client.AddSubOpts{
+		Title:   req.Title,
+		Body:    req.Body,
+		Labels:  req.Labels,
+		Summary: trigger,
+		Vector:  cl.PrecomputeEmbedding(ctx, req.Title, req.Body),
+	})
+}
+
+func recall(ctx context.Context, cl *client.Client, r *http.Request) (any, error) {
+	var req RecallRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, badRequest("query is required")
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+	if req.Limit < 1 || req.Limit > 100 {
+		return nil, badRequest("limit must be 1-100")
+	}
+	since, err := parseTime("since", req.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := client.RecallOptions{
+		Mode:          req.Mode,
+		Types:         req.Types,
+		Labels:        req.Labels,
+		Since:         since,
+		MinSimilarity: 0.3,
+		Limit:         req.Limit,
+		Weights:       client.HybridWeights{Semantic: 1, Keyword: 1},
+	}
+	if req.MinSimilarity != nil {
+		opts.MinSimilarity = *req.MinSimilarity
+	}
+	if req.SemanticWeight != nil {
+		opts.Weights.Semantic = *req.SemanticWeight
+	}
+	if req.KeywordWeight != nil {
+		opts.Weights.Keyword = *req.KeywordWeight
+	}
+	if !req.IncludeClosed {
+		opts.Status = []string{"open"}
+	}
+	// Like cp recall, hybrid degrades to keyword search without embeddings
+	if cl.EmbedProvider == nil && (opts.Mode == "" || opts.Mode == "hybrid") {
+		opts.Mode = "keyword"
+	}
+
+	results, err := cl.Recall(ctx, req.Query, opts)
+	return nonNil(results), err
+}
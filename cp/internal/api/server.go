@@ -0,0 +1,325 @@
+// Package api serves Context Palace over HTTP/JSON for agents that can't hold
+// Postgres credentials. Every request authenticates with a per-agent bearer
+// token and runs as that agent through a client.Client.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/client"
+)
+
+// DefaultListen is the default listen address.
+const DefaultListen = "127.0.0.1:8420"
+
+// maxBodyBytes bounds request bodies.
+const maxBodyBytes = 8 << 20
+
+// Server is the HTTP API. Create it with New and serve Handler().
+type Server struct {
+	base    *client.Client
+	version string
+	tokens  map[string]string // sha256 hex -> agent
+	logger  *log.Logger
+
+	mu      sync.Mutex
+	clients map[string]*client.Client // per agent
+}
+
+// New creates a Server backed by base. Tokens come from base.Config.Server;
+// at least one is required.
+func New(base *client.Client, version string, logger *log.Logger) (*Server, error) {
+	s := &Server{
+		base:    base,
+		version: version,
+		tokens:  map[string]string{},
+		logger:  logger,
+		clients: map[string]*client.Client{},
+	}
+	if base.Config.Server != nil {
+		for _, t := range base.Config.Server.Tokens {
+			hash := strings.ToLower(strings.TrimSpace(t.SHA256))
+			if t.Agent == "" || len(hash) != sha256.Size*2 {
+				return nil, fmt.Errorf("invalid server token entry for agent %q: need agent and a 64-char sha256", t.Agent)
+			}
+			s.tokens[hash] = t.Agent
+		}
+	}
+	if len(s.tokens) == 0 {
+		return nil, fmt.Errorf("no API tokens configured. Create one with `cp serve token <agent>` and add it under server.tokens")
+	}
+	return s, nil
+}
+
+// NewToken returns a random bearer token and its SHA-256 hash for the config.
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	token = "cpt_" + hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex SHA-256 of a token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Handler returns the HTTP handler serving every route.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, OpenAPI(s.version))
+	})
+	for _, rt := range routes() {
+		mux.Handle(rt.Method+" "+rt.Path, s.authed(rt))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "no route for "+r.Method+" "+r.URL.Path)
+	})
+	return s.logRequests(mux)
+}
+
+// authed authenticates the bearer token and runs the route as its agent
+func (s *Server) authed(rt route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="context-palace"`)
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		agent, ok := s.tokens[HashToken(strings.TrimSpace(token))]
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="context-palace", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		cl, err := s.clientFor(agent)
+		if err != nil {
+			s.logInternal(r, agent, err)
+			writeError(w, http.StatusInternalServerError, internalErrorMsg)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		value, err := rt.handle(r.Context(), cl, r)
+		if err != nil {
+			status, msg := errorStatus(err)
+			if status == http.StatusInternalServerError {
+				s.logInternal(r, agent, err)
+			}
+			writeError(w, status, msg)
+			return
+		}
+		status := rt.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, value)
+	})
+}
+
+// clientFor returns the cached client acting as agent
+func (s *Server) clientFor(agent string) (*client.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cl, ok := s.clients[agent]; ok {
+		return cl, nil
+	}
+	cl, err := s.base.ForAgent(agent)
+	if err != nil {
+		return nil, err
+	}
+	s.clients[agent] = cl
+	return cl, nil
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if s.logger != nil {
+			s.logger.Printf("%s %s %d %s", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+		}
+	})
+}
+
+// httpError is an error with an explicit HTTP status
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string { return e.msg }
+
+func badRequest(format string, args ...any) error {
+	return &httpError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...any) error {
+	return &httpError{http.StatusNotFound, fmt.Sprintf(format, args...)}
+}
+
+// errorStatus maps an error to an HTTP status by its client error class:
+// missing resources are 404, state conflicts 409, invalid requests 422,
+// Postgres-only operations on the local backend 501, and anything
+// unclassified (storage failures) 500. A 500 gets a generic message: storage
+// failures can carry SQL, connection details or file paths the caller
+// shouldn't see, so only the server log has them.
+func errorStatus(err error) (int, string) {
+	var he *httpError
+	if errors.As(err, &he) {
+		return he.status, he.msg
+	}
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return http.StatusRequestEntityTooLarge, "request body too large"
+	}
+	msg := err.Error()
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return http.StatusNotFound, msg
+	case errors.Is(err, client.ErrConflict):
+		return http.StatusConflict, msg
+	case errors.Is(err, client.ErrInvalid):
+		return http.StatusUnprocessableEntity, msg
+	case errors.Is(err, client.ErrUnsupported):
+		return http.StatusNotImplemented, msg
+	default:
+		return http.StatusInternalServerError, internalErrorMsg
+	}
+}
+
+// apiErrorBody is the JSON body of every error response
+type apiErrorBody struct {
+	Error string `json:"error"`
+}
+
+// internalErrorMsg is the body of every 500
+const internalErrorMsg = "internal error"
+
+// logInternal logs the error behind a 500
+func (s *Server) logInternal(r *http.Request, agent string, err error) {
+	if s.logger != nil {
+		s.logger.Printf("%s %s (agent %s): %v", r.Method, r.URL.Path, agent, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apiErrorBody{Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// decodeBody decodes the JSON request body into v, rejecting unknown fields
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return badRequest("request body is required")
+		}
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return err
+		}
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// queryList splits a comma-separated query parameter; nil when absent
+func queryList(r *http.Request, name string) []string {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// queryInt parses an integer query parameter within [min, max]
+func queryInt(r *http.Request, name string, def, min, max int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return 0, badRequest("%s must be an integer between %d and %d", name, min, max)
+	}
+	return n, nil
+}
+
+// parseTime parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseTime(name, v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return &t, nil
+	}
+	return nil, badRequest("%s must be an RFC 3339 timestamp or YYYY-MM-DD date", name)
+}
+
+// parseLease parses an optional claim lease such as "30m"; empty means the
+// configured lease
+func parseLease(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, badRequest("lease must be a positive duration such as 30m or 2h")
+	}
+	return d, nil
+}
+
+// nonNil returns an empty slice for nil so JSON shows [] rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// handlerFunc runs one route as the authenticated agent's client
+type handlerFunc func(ctx context.Context, cl *client.Client, r *http.Request) (any, error)
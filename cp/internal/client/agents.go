@@ -0,0 +1,356 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Presence windows: an agent seen within PresenceOnline is online, within
+// PresenceIdle idle, otherwise offline
+const (
+	PresenceOnline = 5 * time.Minute
+	PresenceIdle   = time.Hour
+)
+
+// CapabilityLabelPrefix marks a shard label naming a capability the work
+// needs, e.g. needs:go. Capability-filtered `shard next` only offers shards
+// whose needs: labels are all among the agent's capabilities.
+const CapabilityLabelPrefix = "needs:"
+
+var agentIDRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Agent is a registered agent identity
+type Agent struct {
+	ID           string     `json:"id"`
+	DisplayName  string     `json:"display_name,omitempty"`
+	Capabilities []string   `json:"capabilities"`
+	Groups       []string   `json:"groups"`   // message groups, addressed as @name
+	Projects     []string   `json:"projects"` // projects it serves (empty = all)
+	Description  string     `json:"description,omitempty"`
+	RegisteredAt time.Time  `json:"registered_at"`
+	LastSeen     *time.Time `json:"last_seen,omitempty"`
+	Presence     string     `json:"presence"` // online, idle, offline (from LastSeen)
+}
+
+// AgentUpdate holds the fields to set when registering an agent. Nil fields
+// keep their current value (or are empty for a new agent).
+type AgentUpdate struct {
+	DisplayName  *string
+	Capabilities []string // nil: keep
+	Groups       []string // nil: keep
+	Projects     []string // nil: keep
+	Description  *string
+}
+
+// Serves reports whether the agent works in project
+func (a *Agent) Serves(project string) bool {
+	return len(a.Projects) == 0 || containsString(a.Projects, project)
+}
+
+// InGroup reports whether the agent is a member of a message group; every
+// agent is in GroupAll
+func (a *Agent) InGroup(group string) bool {
+	return group == GroupAll || containsString(a.Groups, group)
+}
+
+// HasCapabilities reports whether the agent has every capability in need
+func (a *Agent) HasCapabilities(need []string) bool {
+	for _, c := range need {
+		if !containsString(a.Capabilities, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// setPresence derives Presence from LastSeen
+func (a *Agent) setPresence(now time.Time) {
+	switch {
+	case a.LastSeen == nil:
+		a.Presence = "offline"
+	case now.Sub(*a.LastSeen) <= PresenceOnline:
+		a.Presence = "online"
+	case now.Sub(*a.LastSeen) <= PresenceIdle:
+		a.Presence = "idle"
+	default:
+		a.Presence = "offline"
+	}
+}
+
+// ListAgents returns registered agents, by default only those serving the
+// configured project. capability and group, when set, keep agents that have
+// that capability or are in that group.
+func (c *Client) ListAgents(ctx context.Context, allProjects bool, capability, group string) ([]Agent, error) {
+	agents, err := c.store.ListAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := []Agent{}
+	for _, a := range agents {
+		if !allProjects && !a.Serves(c.Config.Project) {
+			continue
+		}
+		if capability != "" && !containsString(a.Capabilities, capability) {
+			continue
+		}
+		if group != "" && !a.InGroup(group) {
+			continue
+		}
+		a.setPresence(now)
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// GetAgent returns a registered agent
+func (c *Client) GetAgent(ctx context.Context, id string) (*Agent, error) {
+	a, err := c.store.GetAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, notFoundf("agent %s is not registered (see cp agent list)", id)
+	}
+	a.setPresence(time.Now())
+	return a, nil
+}
+
+// RegisterAgent creates or updates an agent's registry entry and marks it as
+// seen now
+func (c *Client) RegisterAgent(ctx context.Context, id string, u AgentUpdate) (*Agent, error) {
+	if !agentIDRe.MatchString(id) {
+		return nil, invalidf("invalid agent name %q", id)
+	}
+	existing, err := c.store.GetAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	a := Agent{ID: id, Capabilities: []string{}, Groups: []string{}, Projects: []string{}, RegisteredAt: now}
+	if existing != nil {
+		a = *existing
+	}
+	if u.DisplayName != nil {
+		a.DisplayName = *u.DisplayName
+	}
+	if u.Description != nil {
+		a.Description = *u.Description
+	}
+	if u.Capabilities != nil {
+		a.Capabilities = sortedUnique(u.Capabilities)
+	}
+	if u.Groups != nil {
+		for _, g := range u.Groups {
+			if g == GroupAll || !agentIDRe.MatchString(g) {
+				return nil, invalidf("invalid group name %q", g)
+			}
+		}
+		a.Groups = sortedUnique(u.Groups)
+	}
+	if u.Projects != nil {
+		a.Projects = sortedUnique(u.Projects)
+	}
+	a.LastSeen = &now
+
+	if err := c.store.SaveAgent(ctx, a); err != nil {
+		return nil, err
+	}
+	a.setPresence(now)
+	return &a, nil
+}
+
+// AgentHeartbeat records that an agent is alive
+func (c *Client) AgentHeartbeat(ctx context.Context, id string) (*Agent, error) {
+	if err := c.store.TouchAgent(ctx, id); err != nil {
+		return nil, err
+	}
+	return c.GetAgent(ctx, id)
+}
+
+// UnknownRecipients returns the names among recipients that are not
+// registered agents serving this project, with the reason for each. A
+// project with no registered agents does not use the registry, so nothing is
+// reported.
+func (c *Client) UnknownRecipients(ctx context.Context, recipients []string) (map[string]string, error) {
+	agents, err := c.store.ListAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(agents) == 0 {
+		return nil, nil
+	}
+	byID := map[string]Agent{}
+	for _, a := range agents {
+		byID[a.ID] = a
+	}
+	unknown := map[string]string{}
+	for _, r := range recipients {
+		if strings.HasPrefix(r, GroupPrefix) {
+			continue // groups only expand to registered agents
+		}
+		a, ok := byID[r]
+		switch {
+		case !ok:
+			unknown[r] = "not a registered agent"
+			if s := suggestAgent(r, agents); s != "" {
+				unknown[r] += fmt.Sprintf(" (did you mean %s?)", s)
+			}
+		case !a.Serves(c.Config.Project):
+			unknown[r] = fmt.Sprintf("does not serve project %s", c.Config.Project)
+		}
+	}
+	return unknown, nil
+}
+
+// FormatUnknownRecipients renders UnknownRecipients output as one warning line
+func FormatUnknownRecipients(unknown map[string]string) string {
+	names := make([]string, 0, len(unknown))
+	for n := range unknown {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = n + ": " + unknown[n]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// suggestAgent returns the registered agent closest to a misspelled name
+func suggestAgent(name string, agents []Agent) string {
+	best, bestDist := "", 3 // only suggest within two edits
+	for _, a := range agents {
+		if d := editDistance(name, a.ID); d < bestDist {
+			best, bestDist = a.ID, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func (pg *pgStore) ListAgents(ctx context.Context) ([]Agent, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT id, COALESCE(display_name, ''), capabilities, groups, projects,
+			COALESCE(description, ''), registered_at, last_seen
+		FROM agents ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var agents []Agent
+	for rows.Next() {
+		var a Agent
+		if err := rows.Scan(&a.ID, &a.DisplayName, &a.Capabilities, &a.Groups, &a.Projects,
+			&a.Description, &a.RegisteredAt, &a.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan agent: %w", err)
+		}
+		agents = append(agents, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("agent iteration error: %w", err)
+	}
+	return agents, nil
+}
+
+func (pg *pgStore) GetAgent(ctx context.Context, id string) (*Agent, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var a Agent
+	err = conn.QueryRow(ctx, `
+		SELECT id, COALESCE(display_name, ''), capabilities, groups, projects,
+			COALESCE(description, ''), registered_at, last_seen
+		FROM agents WHERE id = $1
+	`, id).Scan(&a.ID, &a.DisplayName, &a.Capabilities, &a.Groups, &a.Projects,
+		&a.Description, &a.RegisteredAt, &a.LastSeen)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch agent: %w", pgError(err))
+	}
+	return &a, nil
+}
+
+func (pg *pgStore) SaveAgent(ctx context.Context, a Agent) error {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	var displayName, description any
+	if a.DisplayName != "" {
+		displayName = a.DisplayName
+	}
+	if a.Description != "" {
+		description = a.Description
+	}
+	_, err = conn.Exec(ctx, `
+		INSERT INTO agents (id, display_name, capabilities, groups, projects, description, registered_at, last_seen)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE
+		SET display_name = EXCLUDED.display_name, capabilities = EXCLUDED.capabilities,
+			groups = EXCLUDED.groups, projects = EXCLUDED.projects,
+			description = EXCLUDED.description, last_seen = EXCLUDED.last_seen
+	`, a.ID, displayName, a.Capabilities, a.Groups, a.Projects, description, a.RegisteredAt, a.LastSeen)
+	if err != nil {
+		return fmt.Errorf("failed to save agent: %w", pgError(err))
+	}
+	return nil
+}
+
+func (pg *pgStore) TouchAgent(ctx context.Context, id string) error {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, `UPDATE agents SET last_seen = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", pgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return notFoundf("agent %s is not registered (run cp agent register)", id)
+	}
+	return nil
+}
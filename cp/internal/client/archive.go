@@ -0,0 +1,889 @@
+package client
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	pgvec "github.com/pgvector/pgvector-go"
+)
+
+// Archive format written by ExportArchive. Version is bumped whenever a
+// record type changes incompatibly; ImportArchive refuses newer versions.
+const (
+	ArchiveFormat  = "cp-archive"
+	ArchiveVersion = 1
+)
+
+// Archive members, in the order they are written and imported. Each is JSONL
+// (one record per line) except the manifest.
+const (
+	archiveManifest = "manifest.json"
+	archiveShards   = "shards.jsonl"
+	archiveLabels   = "labels.jsonl"
+	archiveEdges    = "edges.jsonl"
+	archiveReceipts = "read_receipts.jsonl"
+	archiveFocus    = "focus.jsonl"
+	archiveTypes    = "edge_types.jsonl"
+	archiveChunks   = "shard_chunks.jsonl"
+)
+
+// ArchiveMembers lists the JSONL members in write order; manifest counts and
+// ImportResult are keyed by these names
+var ArchiveMembers = []string{archiveShards, archiveLabels, archiveEdges, archiveReceipts, archiveFocus, archiveTypes, archiveChunks}
+
+// ArchiveManifest describes an archive and is its first member
+type ArchiveManifest struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	Project    string         `json:"project"`
+	Prefix     string         `json:"prefix"` // shard ID prefix of the source project
+	ExportedAt time.Time      `json:"exported_at"`
+	ExportedBy string         `json:"exported_by"`
+	Embeddings bool           `json:"embeddings"` // shard and chunk vectors included
+	Counts     map[string]int `json:"counts"`     // records per member
+}
+
+// ArchiveShard is one shard row. Knowledge versions are ordinary shards linked
+// by previous-version edges, and memory telemetry lives in metadata, so both
+// travel with the shards.
+type ArchiveShard struct {
+	ID                string          `json:"id"`
+	Title             string          `json:"title"`
+	Content           *string         `json:"content,omitempty"`
+	Type              *string         `json:"type,omitempty"`
+	Status            string          `json:"status"`
+	Priority          *int            `json:"priority,omitempty"`
+	Creator           string          `json:"creator"`
+	Owner             *string         `json:"owner,omitempty"`
+	ParentID          *string         `json:"parent_id,omitempty"`
+	Labels            []string        `json:"labels,omitempty"`
+	Metadata          json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	ClosedAt          *time.Time      `json:"closed_at,omitempty"`
+	ClosedReason      *string         `json:"closed_reason,omitempty"`
+	ClosedBy          *string         `json:"closed_by,omitempty"`
+	Embedding         []float32       `json:"embedding,omitempty"`
+	EmbeddingProvider *string         `json:"embedding_provider,omitempty"`
+	EmbeddingModel    *string         `json:"embedding_model,omitempty"`
+	EmbeddingDims     *int            `json:"embedding_dims,omitempty"`
+}
+
+// ArchiveLabel is one labels table row
+type ArchiveLabel struct {
+	ShardID string `json:"shard_id"`
+	Label   string `json:"label"`
+}
+
+// ArchiveEdge is one edge whose source shard is in the project. Targets may be
+// in other projects.
+type ArchiveEdge struct {
+	FromID    string          `json:"from_id"`
+	ToID      string          `json:"to_id"`
+	EdgeType  string          `json:"edge_type"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ArchiveReadReceipt is one read_receipts row
+type ArchiveReadReceipt struct {
+	ShardID string    `json:"shard_id"`
+	AgentID string    `json:"agent_id"`
+	ReadAt  time.Time `json:"read_at"`
+}
+
+// ArchiveFocus is one agent's focus row
+type ArchiveFocus struct {
+	Agent  string    `json:"agent"`
+	EpicID string    `json:"epic_id"`
+	SetAt  time.Time `json:"set_at"`
+	Note   *string   `json:"note,omitempty"`
+}
+
+// ArchiveEdgeType is one of the project's edge type declarations
+type ArchiveEdgeType struct {
+	Name        string   `json:"name"`
+	Inverse     string   `json:"inverse,omitempty"`
+	Blocks      bool     `json:"blocks"`
+	Acyclic     bool     `json:"acyclic"`
+	FromTypes   []string `json:"from_types,omitempty"`
+	ToTypes     []string `json:"to_types,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// ArchiveChunk is one embedded chunk of a long shard (embeddings only)
+type ArchiveChunk struct {
+	ShardID           string    `json:"shard_id"`
+	ChunkIndex        int       `json:"chunk_index"`
+	Heading           *string   `json:"heading,omitempty"`
+	Content           string    `json:"content"`
+	Embedding         []float32 `json:"embedding,omitempty"`
+	EmbeddingProvider *string   `json:"embedding_provider,omitempty"`
+	EmbeddingModel    *string   `json:"embedding_model,omitempty"`
+	EmbeddingDims     *int      `json:"embedding_dims,omitempty"`
+}
+
+// ExportOptions controls ExportArchive
+type ExportOptions struct {
+	Embeddings bool // include shard and chunk vectors (large; otherwise re-run embed-backfill after import)
+}
+
+// ExportArchive writes every shard of the configured project, with its labels,
+// outgoing edges, read receipts, focus rows, edge type declarations and
+// (optionally) embeddings, to w
+// as a gzipped tar of JSONL files. Requires the postgres backend.
+func (c *Client) ExportArchive(ctx context.Context, w io.Writer, opts ExportOptions) (*ArchiveManifest, error) {
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	m := &ArchiveManifest{
+		Format:     ArchiveFormat,
+		Version:    ArchiveVersion,
+		Project:    c.Config.Project,
+		ExportedAt: time.Now().UTC(),
+		ExportedBy: c.Config.Agent,
+		Embeddings: opts.Embeddings,
+		Counts:     map[string]int{},
+	}
+	err = conn.QueryRow(ctx, `SELECT prefix FROM projects WHERE name = $1`, c.Config.Project).Scan(&m.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("project %q not found: %w", c.Config.Project, err)
+	}
+
+	// Export from one snapshot so edges and labels match the shards
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`); err != nil {
+		return nil, fmt.Errorf("failed to start snapshot: %w", err)
+	}
+
+	members := map[string]*bytes.Buffer{}
+	writer := func(name string) *json.Encoder {
+		buf := &bytes.Buffer{}
+		members[name] = buf
+		return json.NewEncoder(buf)
+	}
+
+	// Shards
+	enc := writer(archiveShards)
+	embCol := "NULL::text"
+	if opts.Embeddings {
+		embCol = "embedding::text"
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT id, title, content, type, status, priority, creator, owner, parent_id,
+			COALESCE(labels, '{}'), COALESCE(metadata, '{}'), created_at, updated_at,
+			closed_at, closed_reason, closed_by,
+			`+embCol+`, embedding_provider, embedding_model, embedding_dims
+		FROM shards
+		WHERE project = $1
+		ORDER BY created_at, id
+	`, c.Config.Project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export shards: %w", err)
+	}
+	for rows.Next() {
+		var s ArchiveShard
+		var emb *string
+		if err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Type, &s.Status, &s.Priority, &s.Creator,
+			&s.Owner, &s.ParentID, &s.Labels, &s.Metadata, &s.CreatedAt, &s.UpdatedAt,
+			&s.ClosedAt, &s.ClosedReason, &s.ClosedBy,
+			&emb, &s.EmbeddingProvider, &s.EmbeddingModel, &s.EmbeddingDims); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read shard: %w", err)
+		}
+		if s.Embedding, err = parseVector(emb); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("shard %s: %w", s.ID, err)
+		}
+		if !opts.Embeddings {
+			s.EmbeddingProvider, s.EmbeddingModel, s.EmbeddingDims = nil, nil, nil
+		}
+		if err := enc.Encode(s); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Counts[archiveShards]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export shards: %w", err)
+	}
+
+	// Labels
+	enc = writer(archiveLabels)
+	rows, err = tx.Query(ctx, `
+		SELECT l.shard_id, l.label
+		FROM labels l JOIN shards s ON s.id = l.shard_id
+		WHERE s.project = $1
+		ORDER BY l.shard_id, l.label
+	`, c.Config.Project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export labels: %w", err)
+	}
+	for rows.Next() {
+		var l ArchiveLabel
+		if err := rows.Scan(&l.ShardID, &l.Label); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read label: %w", err)
+		}
+		if err := enc.Encode(l); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Counts[archiveLabels]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export labels: %w", err)
+	}
+
+	// Edges
+	enc = writer(archiveEdges)
+	rows, err = tx.Query(ctx, `
+		SELECT e.from_id, e.to_id, e.edge_type, e.metadata, e.created_at
+		FROM edges e JOIN shards s ON s.id = e.from_id
+		WHERE s.project = $1
+		ORDER BY e.created_at, e.from_id, e.to_id, e.edge_type
+	`, c.Config.Project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export edges: %w", err)
+	}
+	for rows.Next() {
+		var e ArchiveEdge
+		if err := rows.Scan(&e.FromID, &e.ToID, &e.EdgeType, &e.Metadata, &e.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read edge: %w", err)
+		}
+		if err := enc.Encode(e); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Counts[archiveEdges]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export edges: %w", err)
+	}
+
+	// Read receipts
+	enc = writer(archiveReceipts)
+	rows, err = tx.Query(ctx, `
+		SELECT r.shard_id, r.agent_id, r.read_at
+		FROM read_receipts r JOIN shards s ON s.id = r.shard_id
+		WHERE s.project = $1
+		ORDER BY r.read_at, r.shard_id, r.agent_id
+	`, c.Config.Project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export read receipts: %w", err)
+	}
+	for rows.Next() {
+		var r ArchiveReadReceipt
+		if err := rows.Scan(&r.ShardID, &r.AgentID, &r.ReadAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read read receipt: %w", err)
+		}
+		if err := enc.Encode(r); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Counts[archiveReceipts]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export read receipts: %w", err)
+	}
+
+	// Focus
+	enc = writer(archiveFocus)
+	rows, err = tx.Query(ctx, `
+		SELECT agent, epic_id, set_at, note FROM focus WHERE project = $1 ORDER BY agent
+	`, c.Config.Project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export focus: %w", err)
+	}
+	for rows.Next() {
+		var f ArchiveFocus
+		if err := rows.Scan(&f.Agent, &f.EpicID, &f.SetAt, &f.Note); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read focus: %w", err)
+		}
+		if err := enc.Encode(f); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Counts[archiveFocus]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export focus: %w", err)
+	}
+
+	// Edge type declarations
+	enc = writer(archiveTypes)
+	rows, err = tx.Query(ctx, `
+		SELECT name, COALESCE(inverse, ''), blocks, acyclic, from_types, to_types, COALESCE(description, '')
+		FROM edge_types WHERE project = $1 ORDER BY name
+	`, c.Config.Project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export edge types: %w", err)
+	}
+	for rows.Next() {
+		var t ArchiveEdgeType
+		if err := rows.Scan(&t.Name, &t.Inverse, &t.Blocks, &t.Acyclic, &t.FromTypes, &t.ToTypes, &t.Description); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read edge type: %w", err)
+		}
+		if err := enc.Encode(t); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Counts[archiveTypes]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export edge types: %w", err)
+	}
+
+	// Chunks
+	if opts.Embeddings {
+		enc = writer(archiveChunks)
+		rows, err = tx.Query(ctx, `
+			SELECT c.shard_id, c.chunk_index, c.heading, c.content, c.embedding::text,
+				c.embedding_provider, c.embedding_model, c.embedding_dims
+			FROM shard_chunks c JOIN shards s ON s.id = c.shard_id
+			WHERE s.project = $1
+			ORDER BY c.shard_id, c.chunk_index
+		`, c.Config.Project)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export chunks: %w", err)
+		}
+		for rows.Next() {
+			var ch ArchiveChunk
+			var emb *string
+			if err := rows.Scan(&ch.ShardID, &ch.ChunkIndex, &ch.Heading, &ch.Content, &emb,
+				&ch.EmbeddingProvider, &ch.EmbeddingModel, &ch.EmbeddingDims); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read chunk: %w", err)
+			}
+			if ch.Embedding, err = parseVector(emb); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("chunk %s/%d: %w", ch.ShardID, ch.ChunkIndex, err)
+			}
+			if err := enc.Encode(ch); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			m.Counts[archiveChunks]++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to export chunks: %w", err)
+		}
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: m.ExportedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		return nil
+	}
+	if err := add(archiveManifest, append(manifest, '\n')); err != nil {
+		return nil, err
+	}
+	for _, name := range ArchiveMembers {
+		if buf, ok := members[name]; ok {
+			if err := add(name, buf.Bytes()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return m, nil
+}
+
+// parseVector decodes a pgvector text value ("[1,2,3]"); nil stays nil
+func parseVector(s *string) ([]float32, error) {
+	if s == nil {
+		return nil, nil
+	}
+	var v pgvec.Vector
+	if err := v.Parse(*s); err != nil {
+		return nil, fmt.Errorf("invalid embedding: %w", err)
+	}
+	return v.Slice(), nil
+}
+
+// Archive is a decoded archive, as read by ReadArchive
+type Archive struct {
+	Manifest ArchiveManifest
+	Shards   []ArchiveShard
+	Labels   []ArchiveLabel
+	Edges    []ArchiveEdge
+	Receipts []ArchiveReadReceipt
+	Focus    []ArchiveFocus
+	Types    []ArchiveEdgeType
+	Chunks   []ArchiveChunk
+}
+
+// ReadArchive decodes an archive written by ExportArchive. Gzipped and plain
+// tar are both accepted.
+func ReadArchive(r io.Reader) (*Archive, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	a := &Archive{}
+	tr := tar.NewReader(src)
+	sawManifest := false
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+
+		if hdr.Name == archiveManifest {
+			if err := json.NewDecoder(tr).Decode(&a.Manifest); err != nil {
+				return nil, fmt.Errorf("invalid archive manifest: %w", err)
+			}
+			if a.Manifest.Format != ArchiveFormat {
+				return nil, fmt.Errorf("not a cp archive (format %q)", a.Manifest.Format)
+			}
+			if a.Manifest.Version > ArchiveVersion {
+				return nil, fmt.Errorf("archive version %d is newer than this cp supports (%d); upgrade cp", a.Manifest.Version, ArchiveVersion)
+			}
+			sawManifest = true
+			continue
+		}
+		if !sawManifest {
+			return nil, fmt.Errorf("invalid archive: %s must be the first member", archiveManifest)
+		}
+
+		switch hdr.Name {
+		case archiveShards:
+			err = decodeJSONL(tr, &a.Shards)
+		case archiveLabels:
+			err = decodeJSONL(tr, &a.Labels)
+		case archiveEdges:
+			err = decodeJSONL(tr, &a.Edges)
+		case archiveReceipts:
+			err = decodeJSONL(tr, &a.Receipts)
+		case archiveFocus:
+			err = decodeJSONL(tr, &a.Focus)
+		case archiveTypes:
+			err = decodeJSONL(tr, &a.Types)
+		case archiveChunks:
+			err = decodeJSONL(tr, &a.Chunks)
+		default:
+			// Unknown members come from newer minor additions; skip them
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive member %s: %w", hdr.Name, err)
+		}
+	}
+	if !sawManifest {
+		return nil, fmt.Errorf("invalid archive: no %s", archiveManifest)
+	}
+	return a, nil
+}
+
+// decodeJSONL appends one record per line of r to out
+func decodeJSONL[T any](r io.Reader, out *[]T) error {
+	dec := json.NewDecoder(r)
+	for {
+		var v T
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		*out = append(*out, v)
+	}
+}
+
+// ImportOptions controls ImportArchive
+type ImportOptions struct {
+	// Prefix is the shard ID prefix for the target project. Required only when
+	// the project doesn't exist yet; defaults to the archive's prefix.
+	Prefix string
+	// SkipExisting leaves shards whose (remapped) ID already exists untouched
+	// instead of failing the import
+	SkipExisting bool
+	// DryRun validates and counts without writing
+	DryRun bool
+}
+
+// ImportResult reports what ImportArchive did
+type ImportResult struct {
+	SourceProject  string         `json:"source_project"`
+	Project        string         `json:"project"`
+	FromPrefix     string         `json:"from_prefix"`
+	ToPrefix       string         `json:"to_prefix"`
+	Remapped       bool           `json:"remapped"`        // IDs were rewritten to the target prefix
+	CreatedProject bool           `json:"created_project"` // the target project row was created
+	Imported       map[string]int `json:"imported"`        // rows written per member
+	Skipped        map[string]int `json:"skipped"`         // rows left out (existing, or edge endpoint missing)
+	DryRun         bool           `json:"dry_run"`
+}
+
+// ImportArchive loads an archive into the configured project in a single
+// transaction. When the target project's prefix differs from the archive's,
+// every archived shard ID (and references to it in parent_id, edges, labels,
+// receipts, focus and metadata) is rewritten to the target prefix. Shard
+// content is not rewritten. Edges to shards that exist in neither the archive
+// nor the target database are dropped. Requires the postgres backend.
+func (c *Client) ImportArchive(ctx context.Context, a *Archive, opts ImportOptions) (*ImportResult, error) {
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	res := &ImportResult{
+		SourceProject: a.Manifest.Project,
+		Project:       c.Config.Project,
+		FromPrefix:    a.Manifest.Prefix,
+		Imported:      map[string]int{},
+		Skipped:       map[string]int{},
+		DryRun:        opts.DryRun,
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Resolve (or create) the target project
+	err = tx.QueryRow(ctx, `SELECT prefix FROM projects WHERE name = $1`, c.Config.Project).Scan(&res.ToPrefix)
+	switch {
+	case err == nil:
+		if opts.Prefix != "" && opts.Prefix != res.ToPrefix {
+			return nil, fmt.Errorf("project %q already uses prefix %q, not %q", c.Config.Project, res.ToPrefix, opts.Prefix)
+		}
+	case err == pgx.ErrNoRows:
+		res.ToPrefix = opts.Prefix
+		if res.ToPrefix == "" {
+			res.ToPrefix = a.Manifest.Prefix
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO projects (name, prefix) VALUES ($1, $2)`, c.Config.Project, res.ToPrefix); err != nil {
+			return nil, fmt.Errorf("failed to create project %q: %w", c.Config.Project, pgError(err))
+		}
+		res.CreatedProject = true
+	default:
+		return nil, fmt.Errorf("failed to look up project %q: %w", c.Config.Project, err)
+	}
+
+	// Build the ID map for archived shards
+	ids := make(map[string]string, len(a.Shards))
+	res.Remapped = a.Manifest.Prefix != res.ToPrefix
+	for _, s := range a.Shards {
+		ids[s.ID] = s.ID
+		if rest, ok := strings.CutPrefix(s.ID, a.Manifest.Prefix+"-"); ok && res.Remapped {
+			ids[s.ID] = res.ToPrefix + "-" + rest
+		}
+	}
+	remap := func(id string) string {
+		if n, ok := ids[id]; ok {
+			return n
+		}
+		return id
+	}
+
+	// Find shards that already exist
+	newIDs := make([]string, 0, len(ids))
+	for _, n := range ids {
+		newIDs = append(newIDs, n)
+	}
+	existing := map[string]bool{}
+	rows, err := tx.Query(ctx, `SELECT id FROM shards WHERE id = ANY($1)`, newIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing shards: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[id] = true
+	}
+	rows.Close()
+	if len(existing) > 0 && !opts.SkipExisting {
+		sample := make([]string, 0, len(existing))
+		for id := range existing {
+			sample = append(sample, id)
+		}
+		sort.Strings(sample)
+		if len(sample) > 5 {
+			sample = append(sample[:5], "...")
+		}
+		return nil, fmt.Errorf("%d shards already exist (%s); use --skip-existing or import into another project",
+			len(existing), strings.Join(sample, ", "))
+	}
+
+	// Shards, parents first so parent_id references resolve
+	for _, s := range orderByParent(a.Shards) {
+		id := remap(s.ID)
+		if existing[id] {
+			res.Skipped[archiveShards]++
+			continue
+		}
+		var parent *string
+		if s.ParentID != nil {
+			p := remap(*s.ParentID)
+			parent = &p
+		}
+		labels := s.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		var emb any
+		if s.Embedding != nil {
+			emb = pgvec.NewVector(s.Embedding)
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO shards (id, project, title, content, type, status, priority, creator, owner, parent_id,
+				labels, metadata, created_at, updated_at, closed_at, closed_reason, closed_by,
+				embedding, embedding_provider, embedding_model, embedding_dims)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		`, id, c.Config.Project, s.Title, s.Content, s.Type, s.Status, s.Priority, s.Creator, s.Owner, parent,
+			labels, remapMetadata(s.Metadata, ids), s.CreatedAt, s.UpdatedAt, s.ClosedAt, s.ClosedReason, s.ClosedBy,
+			emb, s.EmbeddingProvider, s.EmbeddingModel, s.EmbeddingDims)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import shard %s: %w", s.ID, pgError(err))
+		}
+		res.Imported[archiveShards]++
+	}
+
+	// Labels may already have been written alongside the shard's labels column
+	for _, l := range a.Labels {
+		id := remap(l.ShardID)
+		if existing[id] {
+			res.Skipped[archiveLabels]++
+			continue
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO labels (shard_id, label) VALUES ($1, $2) ON CONFLICT DO NOTHING
+		`, id, l.Label); err != nil {
+			return nil, fmt.Errorf("failed to import label %s on %s: %w", l.Label, l.ShardID, pgError(err))
+		}
+		res.Imported[archiveLabels]++
+	}
+
+	for _, e := range a.Edges {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO edges (from_id, to_id, edge_type, metadata, created_at)
+			SELECT $1, $2, $3, $4, $5
+			WHERE EXISTS (SELECT 1 FROM shards WHERE id = $1)
+			  AND EXISTS (SELECT 1 FROM shards WHERE id = $2)
+			ON CONFLICT DO NOTHING
+		`, remap(e.FromID), remap(e.ToID), e.EdgeType, remapMetadata(e.Metadata, ids), e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import edge %s -[%s]-> %s: %w", e.FromID, e.EdgeType, e.ToID, pgError(err))
+		}
+		if tag.RowsAffected() == 0 {
+			res.Skipped[archiveEdges]++
+			continue
+		}
+		res.Imported[archiveEdges]++
+	}
+
+	for _, r := range a.Receipts {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO read_receipts (shard_id, agent_id, read_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING
+		`, remap(r.ShardID), r.AgentID, r.ReadAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import read receipt on %s: %w", r.ShardID, pgError(err))
+		}
+		if tag.RowsAffected() == 0 {
+			res.Skipped[archiveReceipts]++
+			continue
+		}
+		res.Imported[archiveReceipts]++
+	}
+
+	// An agent's existing focus in the target project wins
+	for _, f := range a.Focus {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO focus (project, agent, epic_id, set_at, note) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (project, agent) DO NOTHING
+		`, c.Config.Project, f.Agent, remap(f.EpicID), f.SetAt, f.Note)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import focus for %s: %w", f.Agent, pgError(err))
+		}
+		if tag.RowsAffected() == 0 {
+			res.Skipped[archiveFocus]++
+			continue
+		}
+		res.Imported[archiveFocus]++
+	}
+
+	// The target project's own declarations win
+	for _, t := range a.Types {
+		var inverse, description any
+		if t.Inverse != "" {
+			inverse = t.Inverse
+		}
+		if t.Description != "" {
+			description = t.Description
+		}
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO edge_types (project, name, inverse, blocks, acyclic, from_types, to_types, description)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT DO NOTHING
+		`, c.Config.Project, t.Name, inverse, t.Blocks, t.Acyclic, sortedUnique(t.FromTypes), sortedUnique(t.ToTypes), description)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import edge type %s: %w", t.Name, pgError(err))
+		}
+		if tag.RowsAffected() == 0 {
+			res.Skipped[archiveTypes]++
+			continue
+		}
+		res.Imported[archiveTypes]++
+	}
+
+	for _, ch := range a.Chunks {
+		id := remap(ch.ShardID)
+		if existing[id] {
+			res.Skipped[archiveChunks]++
+			continue
+		}
+		var emb any
+		if ch.Embedding != nil {
+			emb = pgvec.NewVector(ch.Embedding)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO shard_chunks
+				(shard_id, chunk_index, heading, content, embedding, embedding_provider, embedding_model, embedding_dims)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT DO NOTHING
+		`, id, ch.ChunkIndex, ch.Heading, ch.Content, emb, ch.EmbeddingProvider, ch.EmbeddingModel, ch.EmbeddingDims); err != nil {
+			return nil, fmt.Errorf("failed to import chunk %s/%d: %w", ch.ShardID, ch.ChunkIndex, pgError(err))
+		}
+		res.Imported[archiveChunks]++
+	}
+
+	if opts.DryRun {
+		return res, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return res, nil
+}
+
+// orderByParent returns shards with every parent ahead of its children.
+// Parents outside the archive are assumed to exist already.
+func orderByParent(shards []ArchiveShard) []ArchiveShard {
+	byID := make(map[string]int, len(shards))
+	for i, s := range shards {
+		byID[s.ID] = i
+	}
+	out := make([]ArchiveShard, 0, len(shards))
+	state := make([]int, len(shards)) // 0 = pending, 1 = visiting, 2 = done
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != 0 {
+			return // done, or a parent cycle (left for the FK to report)
+		}
+		state[i] = 1
+		if p := shards[i].ParentID; p != nil {
+			if j, ok := byID[*p]; ok {
+				visit(j)
+			}
+		}
+		state[i] = 2
+		out = append(out, shards[i])
+	}
+	for i := range shards {
+		visit(i)
+	}
+	return out
+}
+
+// remapMetadata rewrites every JSON string in raw that is a remapped shard ID
+// (e.g. previous_version_id, parent references in memory metadata)
+func remapMetadata(raw json.RawMessage, ids map[string]string) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	changed := false
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch t := v.(type) {
+		case string:
+			if n, ok := ids[t]; ok && n != t {
+				changed = true
+				return n
+			}
+		case []any:
+			for i := range t {
+				t[i] = walk(t[i])
+			}
+		case map[string]any:
+			for k := range t {
+				t[k] = walk(t[k])
+			}
+		}
+		return v
+	}
+	v = walk(v)
+	if !changed {
+		return raw
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return out
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	pgxvec "github.com/pgvector/pgvector-go/pgx"
 	"gopkg.in/yaml.v3"
 
@@ -16,11 +18,14 @@ import (
 
 // Config holds the cp CLI configuration
 type Config struct {
-	Connection ConnectionConfig              `yaml:"connection"`
-	Agent      string                        `yaml:"agent"`
-	Project    string                        `yaml:"project"`
-	Embedding  *embedding.EmbeddingConfig    `yaml:"embedding,omitempty"`
-	Generation *generation.GenerationConfig  `yaml:"generation,omitempty"`
+	Connection ConnectionConfig             `yaml:"connection"`
+	Storage    StorageConfig                `yaml:"storage,omitempty"`
+	Agent      string                       `yaml:"agent"`
+	Project    string                       `yaml:"project"`
+	Lease      time.Duration                `yaml:"lease,omitempty"` // claim lease without a heartbeat, e.g. "30m" (default 2h)
+	Embedding  *embedding.EmbeddingConfig   `yaml:"embedding,omitempty"`
+	Generation *generation.GenerationConfig `yaml:"generation,omitempty"`
+	Server     *ServerConfig                `yaml:"server,omitempty"`
 }
 
 // ConnectionConfig holds database connection settings
@@ -29,6 +34,33 @@ type ConnectionConfig struct {
 	Database string `yaml:"database"`
 	User     string `yaml:"user"`
 	SSLMode  string `yaml:"sslmode"`
+
+	// Pool settings (zero = pgxpool defaults)
+	MaxConns        int32         `yaml:"max_conns,omitempty"`
+	MinConns        int32         `yaml:"min_conns,omitempty"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time,omitempty"` // e.g. "5m"
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime,omitempty"`  // e.g. "1h"
+
+	// Statement cache settings. QueryExecMode is one of cache_statement
+	// (default), cache_describe, describe_exec, exec or simple_protocol; use
+	// exec or simple_protocol behind a transaction-mode pgbouncer.
+	StatementCacheCapacity int    `yaml:"statement_cache_capacity,omitempty"`
+	QueryExecMode          string `yaml:"query_exec_mode,omitempty"`
+}
+
+// ServerConfig configures the HTTP API served by `cp serve`
+type ServerConfig struct {
+	Listen           string         `yaml:"listen,omitempty"` // host:port (default 127.0.0.1:8420)
+	Tokens           []AgentToken   `yaml:"tokens,omitempty"`
+	ReapInterval     time.Duration  `yaml:"reap_interval,omitempty"`     // return expired claims to open this often (0 = off)
+	EscalateInterval *time.Duration `yaml:"escalate_interval,omitempty"` // chase overdue requests this often (unset = 5m, 0s = off)
+}
+
+// AgentToken maps an API bearer token to the agent it authenticates as.
+// Only the token's SHA-256 hash is stored.
+type AgentToken struct {
+	Agent  string `yaml:"agent"`
+	SHA256 string `yaml:"sha256"`
 }
 
 // Client provides database operations for Context Palace
@@ -36,27 +68,168 @@ type Client struct {
 	Config        *Config
 	EmbedProvider embedding.Provider
 	Generator     generation.Generator
-}
 
-// NewClient creates a new client with the given config
-func NewClient(cfg *Config) *Client {
-	return &Client{Config: cfg}
+	store Store
 }
 
-// Connect opens a database connection
-func (c *Client) Connect(ctx context.Context) (*pgx.Conn, error) {
-	conn, err := pgx.Connect(ctx, c.ConnectionString())
+// NewClient creates a new client with the given config, opening the
+// configured storage backend
+func NewClient(cfg *Config) (*Client, error) {
+	store, err := NewStore(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("cannot connect to Context Palace at %s: %v", c.Config.Connection.Host, err)
+		return nil, err
+	}
+	return &Client{Config: cfg, store: store}, nil
+}
+
+// NewClientWithStore creates a client backed by an existing Store
+// (e.g. a fake in tests)
+func NewClientWithStore(cfg *Config, store Store) *Client {
+	return &Client{Config: cfg, store: store}
+}
+
+// ForAgent returns a client acting as agent that shares this client's storage
+// (connection pool or local file) and providers. Closing it is a no-op; close
+// the original client instead.
+func (c *Client) ForAgent(agent string) (*Client, error) {
+	cfg := *c.Config
+	cfg.Agent = agent
+
+	var store Store
+	switch s := c.store.(type) {
+	case *pgStore:
+		store = &pgStore{cfg: &cfg, pool: s.pool, shared: true}
+	case *localStore:
+		store = s.withConfig(&cfg)
+	default:
+		return nil, fmt.Errorf("the %s backend does not support per-agent clients", c.store.Backend())
+	}
+	return &Client{Config: &cfg, EmbedProvider: c.EmbedProvider, Generator: c.Generator, store: store}, nil
+}
+
+// Backend returns the name of the storage backend in use
+func (c *Client) Backend() string {
+	return c.store.Backend()
+}
+
+// Close releases the storage backend
+func (c *Client) Close() {
+	if c.store != nil {
+		c.store.Close()
 	}
-	// Best-effort pgvector type registration (silent failure if extension not installed)
-	_ = pgxvec.RegisterTypes(ctx, conn)
-	return conn, nil
+}
+
+// Connect acquires a connection from the pool; release it with conn.Release().
+// Only the postgres backend has one; Postgres-only operations fail here when
+// another backend is configured.
+func (c *Client) Connect(ctx context.Context) (*pgxpool.Conn, error) {
+	pg, ok := c.store.(*pgStore)
+	if !ok {
+		return nil, fmt.Errorf("this operation %w (configured backend: %s)", ErrUnsupported, c.store.Backend())
+	}
+	return pg.connect(ctx)
 }
 
 // ConnectionString returns the PostgreSQL connection string
 func (c *Client) ConnectionString() string {
-	cfg := c.Config.Connection
+	return connectionString(c.Config.Connection)
+}
+
+// pgStore is the PostgreSQL Store backend. It relies on the SQL functions
+// installed by cp/migrations. The pool is created once per process and dials
+// lazily, so commands that never touch the database pay nothing.
+type pgStore struct {
+	cfg    *Config
+	pool   *pgxpool.Pool
+	shared bool // pool belongs to another pgStore (ForAgent)
+}
+
+func newPgStore(cfg *Config) (*pgStore, error) {
+	poolCfg, err := poolConfig(cfg.Connection)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create connection pool for %s: %w", cfg.Connection.Host, err)
+	}
+	return &pgStore{cfg: cfg, pool: pool}, nil
+}
+
+func (pg *pgStore) Backend() string { return BackendPostgres }
+
+func (pg *pgStore) Close() {
+	if !pg.shared {
+		pg.pool.Close()
+	}
+}
+
+// connect acquires a pooled database connection
+func (pg *pgStore) connect(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := pg.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to Context Palace at %s: %w", pg.cfg.Connection.Host, err)
+	}
+	return conn, nil
+}
+
+// poolConfig builds the pgxpool config from ConnectionConfig
+func poolConfig(cfg ConnectionConfig) (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(connectionString(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection settings: %w", err)
+	}
+
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.StatementCacheCapacity > 0 {
+		poolCfg.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	}
+	if cfg.QueryExecMode != "" {
+		mode, err := parseQueryExecMode(cfg.QueryExecMode)
+		if err != nil {
+			return nil, err
+		}
+		poolCfg.ConnConfig.DefaultQueryExecMode = mode
+	}
+
+	// Best-effort pgvector type registration (silent failure if extension not installed)
+	poolCfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_ = pgxvec.RegisterTypes(ctx, conn)
+		return nil
+	}
+	return poolCfg, nil
+}
+
+// parseQueryExecMode maps a query_exec_mode config value to pgx
+func parseQueryExecMode(mode string) (pgx.QueryExecMode, error) {
+	switch mode {
+	case "cache_statement":
+		return pgx.QueryExecModeCacheStatement, nil
+	case "cache_describe":
+		return pgx.QueryExecModeCacheDescribe, nil
+	case "describe_exec":
+		return pgx.QueryExecModeDescribeExec, nil
+	case "exec":
+		return pgx.QueryExecModeExec, nil
+	case "simple_protocol":
+		return pgx.QueryExecModeSimpleProtocol, nil
+	}
+	return 0, fmt.Errorf("invalid query_exec_mode: %q (valid: cache_statement, cache_describe, describe_exec, exec, simple_protocol)", mode)
+}
+
+// connectionString returns the PostgreSQL connection string
+func connectionString(cfg ConnectionConfig) string {
 	sslmode := cfg.SSLMode
 	if sslmode == "" {
 		sslmode = "verify-full"
@@ -111,9 +284,23 @@ func LoadConfig(configOverride string) (*Config, error) {
 	if v := os.Getenv("CP_AGENT"); v != "" {
 		cfg.Agent = v
 	}
+	if v := os.Getenv("CP_LEASE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid CP_LEASE %q: use a duration such as 30m or 2h", v)
+		}
+		cfg.Lease = d
+	}
+	if v := os.Getenv("CP_BACKEND"); v != "" {
+		cfg.Storage.Backend = v
+	}
+	if v := os.Getenv("CP_LOCAL_PATH"); v != "" {
+		cfg.Storage.Path = v
+	}
 
 	// Validate
-	if cfg.Connection.User == "" {
+	usesPostgres := cfg.Storage.Backend == "" || cfg.Storage.Backend == BackendPostgres
+	if usesPostgres && cfg.Connection.User == "" {
 		return nil, fmt.Errorf("database user is required (set via CP_USER, .cp.yaml, or ~/.cp/config.yaml)")
 	}
 	if cfg.Agent == "" {
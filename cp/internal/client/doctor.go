@@ -0,0 +1,499 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Doctor check outcomes
+const (
+	DoctorOK   = "ok"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+	DoctorSkip = "skip"
+)
+
+// sqlFunction is a SQL function the client calls, with the argument types it
+// passes and the migration that installs it
+type sqlFunction struct {
+	Name      string
+	Args      string
+	Migration int
+}
+
+// expectedFunctions lists every SQL function the client calls. Keep in step
+// with cp/migrations when a call site or signature changes.
+var expectedFunctions = []sqlFunction{
+	{"gen_shard_id", "text", 1},
+	{"unread_for", "text, text", 1},
+	{"send_message", "text, text, text[], text, text, text[], text, text", 1},
+	{"mark_read", "text[], text", 1},
+	{"link", "text, text, text", 1},
+	{"claim_task", "text, text, interval", 15},
+	{"close_task", "text, text", 1},
+	{"add_artifact", "text, text, text, text", 1},
+	{"get_artifacts", "text", 1},
+	{"create_shard", "text, text, text, text, text, text[], text, integer, jsonb", 2},
+	{"update_metadata", "text, jsonb", 2},
+	{"set_metadata_path", "text, text[], jsonb", 2},
+	{"delete_metadata_key", "text, text", 2},
+	{"requirement_dashboard", "text", 3},
+	{"update_knowledge_doc", "text, text, text, text, text", 5},
+	{"append_knowledge_doc", "text, text, text, text, text", 5},
+	{"knowledge_history", "text, text", 5},
+	{"knowledge_version", "text, integer, text", 5},
+	{"shard_detail", "text", 6},
+	{"shard_edges", "text, text, text[]", 6},
+	{"create_edge", "text, text, text, jsonb", 6},
+	{"delete_edge", "text, text, text", 6},
+	{"add_shard_labels", "text, text[]", 6},
+	{"remove_shard_labels", "text, text[]", 6},
+	{"label_summary", "text", 6},
+	{"update_shard", "text, text, text, text", 6},
+	{"memory_recall", "text, vector, text[], integer, double precision", 6},
+	{"epic_progress", "text, text", 7},
+	{"epic_children", "text, text", 7},
+	{"focus_set", "text, text, text, text", 7},
+	{"focus_get", "text, text", 7},
+	{"focus_clear", "text, text", 7},
+	{"shard_assign", "text, text, text, interval", 15},
+	{"shard_close", "text, text, text, text", 7},
+	{"shard_next", "text, text, integer, text[]", 16},
+	{"message_thread", "text, text", 17},
+	{"sent_by", "text, text, integer", 18},
+	{"open_requests", "text, text", 19},
+	{"shard_board", "text, text, text", 7},
+	{"list_shards", "text, text[], text[], text[], text, text, timestamptz, integer, integer, boolean", 8},
+	{"list_shards_count", "text, text[], text[], text[], text, text, timestamptz, boolean", 8},
+	{"memory_tree", "text, text", 8},
+	{"memory_children", "text, text", 8},
+	{"memory_path", "text", 8},
+	{"memory_hot", "text, integer, integer", 8},
+	{"memory_touch", "text, text, integer", 8},
+	{"semantic_search", "text, vector, text[], text[], text[], integer, double precision, timestamptz", 10},
+	{"shards_needing_embedding", "text, integer", 10},
+	{"keyword_search", "text, text, text[], text[], text[], integer, timestamptz", 11},
+	{"has_circular_dependency", "text, text, text[]", 13},
+	{"shard_take", "text, text, interval, text, text", 15},
+	{"shard_heartbeat", "text, text, text, interval", 15},
+	{"shard_reap", "text, text, boolean", 15},
+	{"embedding_dimensions", "", 21},
+	{"resize_embeddings", "integer", 21},
+}
+
+// expectedVectorIndexes are the approximate-nearest-neighbour indexes recall relies on
+var expectedVectorIndexes = []struct{ Table, Index string }{
+	{"shards", "idx_shards_embedding"},
+	{"shard_chunks", "idx_shard_chunks_embedding"},
+}
+
+// DoctorCheck is the outcome of one doctor check
+type DoctorCheck struct {
+	Name     string   `json:"name"`
+	Status   string   `json:"status"` // ok, warn, fail, skip
+	Summary  string   `json:"summary"`
+	Problems []string `json:"problems,omitempty"`
+	Fixed    int      `json:"fixed,omitempty"` // problems repaired by --fix
+	Hint     string   `json:"hint,omitempty"`  // what to run when --fix can't repair it
+}
+
+// DoctorReport is the result of Doctor
+type DoctorReport struct {
+	Project string        `json:"project"`
+	Checks  []DoctorCheck `json:"checks"`
+}
+
+// Healthy reports whether no check failed
+func (r *DoctorReport) Healthy() bool {
+	for _, c := range r.Checks {
+		if c.Status == DoctorFail {
+			return false
+		}
+	}
+	return true
+}
+
+// doctorProblemLimit caps the problems listed per check
+const doctorProblemLimit = 20
+
+// Doctor checks the database schema against what this client expects, then
+// the project's data for integrity problems. With fix, the problems that are
+// safe to repair automatically (dangling edges, pointer blocks) are repaired.
+// Requires the postgres backend.
+func (c *Client) Doctor(ctx context.Context, fix bool) (*DoctorReport, error) {
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	report := &DoctorReport{Project: c.Config.Project}
+	add := func(check DoctorCheck) {
+		if len(check.Problems) > doctorProblemLimit {
+			more := len(check.Problems) - doctorProblemLimit
+			check.Problems = append(check.Problems[:doctorProblemLimit], fmt.Sprintf("... and %d more", more))
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	add(c.doctorMigrations(ctx, conn))
+	add(doctorFunctions(ctx, conn))
+	vec := doctorVector(ctx, conn)
+	add(vec)
+	add(c.doctorEdges(ctx, conn, fix))
+	add(c.doctorParentCycles(ctx, conn))
+	add(c.doctorLeases(ctx, fix))
+	add(c.doctorMemoryPointers(ctx, fix))
+	if vec.Status == DoctorFail {
+		add(DoctorCheck{Name: "embeddings", Status: DoctorSkip, Summary: "pgvector not available"})
+	} else {
+		add(c.doctorEmbeddings(ctx, conn))
+	}
+	return report, nil
+}
+
+func (c *Client) doctorMigrations(ctx context.Context, conn *pgxpool.Conn) DoctorCheck {
+	check := DoctorCheck{Name: "migrations"}
+	all, err := LoadMigrations()
+	if err != nil {
+		check.Status, check.Summary = DoctorFail, err.Error()
+		return check
+	}
+
+	var exists bool
+	if err := conn.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		check.Status, check.Summary = DoctorFail, fmt.Sprintf("failed to query: %v", err)
+		return check
+	}
+	if !exists {
+		check.Status = DoctorWarn
+		check.Summary = "no schema_migrations table; migration state unknown"
+		check.Hint = "cp admin migrate baseline <version> (hand-built schema) or cp admin migrate up (fresh database)"
+		return check
+	}
+
+	status, err := migrationStatus(ctx, conn, all)
+	if err != nil {
+		check.Status, check.Summary = DoctorFail, err.Error()
+		return check
+	}
+	pending := 0
+	for _, s := range status {
+		switch {
+		case !s.Applied:
+			pending++
+			check.Problems = append(check.Problems, fmt.Sprintf("%03d_%s not applied", s.Version, s.Name))
+		case s.Modified:
+			check.Problems = append(check.Problems, fmt.Sprintf("%03d_%s applied from different SQL than this cp embeds", s.Version, s.Name))
+		}
+	}
+	switch {
+	case pending > 0:
+		check.Status = DoctorFail
+		check.Summary = fmt.Sprintf("%d of %d migrations pending", pending, len(status))
+		check.Hint = "cp admin migrate up"
+	case len(check.Problems) > 0:
+		check.Status = DoctorWarn
+		check.Summary = fmt.Sprintf("all %d applied, %d modified since", len(status), len(check.Problems))
+	default:
+		check.Status = DoctorOK
+		check.Summary = fmt.Sprintf("all %d applied", len(status))
+	}
+	return check
+}
+
+func doctorFunctions(ctx context.Context, conn *pgxpool.Conn) DoctorCheck {
+	check := DoctorCheck{Name: "functions", Status: DoctorOK}
+	oldest := 0
+	for _, f := range expectedFunctions {
+		var ok bool
+		// to_regprocedure errors (rather than returning NULL) when a type
+		// such as vector doesn't exist, so treat errors as missing
+		err := conn.QueryRow(ctx, `SELECT to_regprocedure($1) IS NOT NULL`, f.Name+"("+f.Args+")").Scan(&ok)
+		if err == nil && ok {
+			continue
+		}
+
+		var found []string
+		rows, qerr := conn.Query(ctx, `
+			SELECT pg_get_function_identity_arguments(p.oid)
+			FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE p.proname = $1 AND n.nspname = current_schema()
+		`, f.Name)
+		if qerr == nil {
+			for rows.Next() {
+				var args string
+				if rows.Scan(&args) == nil {
+					found = append(found, f.Name+"("+args+")")
+				}
+			}
+			rows.Close()
+		}
+
+		if len(found) == 0 {
+			check.Problems = append(check.Problems, fmt.Sprintf("%s(%s) missing (migration %03d)", f.Name, f.Args, f.Migration))
+		} else {
+			check.Problems = append(check.Problems, fmt.Sprintf("%s(%s) missing; database has %s (migration %03d)",
+				f.Name, f.Args, strings.Join(found, ", "), f.Migration))
+		}
+		if oldest == 0 || f.Migration < oldest {
+			oldest = f.Migration
+		}
+	}
+	if len(check.Problems) > 0 {
+		check.Status = DoctorFail
+		check.Summary = fmt.Sprintf("%d of %d functions missing or with a different signature", len(check.Problems), len(expectedFunctions))
+		check.Hint = fmt.Sprintf("the database schema is older than this cp; apply migrations from %03d (cp admin migrate up)", oldest)
+		return check
+	}
+	check.Summary = fmt.Sprintf("all %d functions present", len(expectedFunctions))
+	return check
+}
+
+func doctorVector(ctx context.Context, conn *pgxpool.Conn) DoctorCheck {
+	check := DoctorCheck{Name: "pgvector"}
+
+	var version *string
+	_ = conn.QueryRow(ctx, `SELECT extversion FROM pg_extension WHERE extname = 'vector'`).Scan(&version)
+	if version == nil {
+		check.Status = DoctorFail
+		check.Summary = "vector extension not installed"
+		check.Hint = "install pgvector on the server, then cp admin migrate up"
+		return check
+	}
+
+	for _, idx := range expectedVectorIndexes {
+		var exists bool
+		err := conn.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE schemaname = current_schema() AND tablename = $1 AND indexname = $2)
+		`, idx.Table, idx.Index).Scan(&exists)
+		if err != nil || !exists {
+			check.Problems = append(check.Problems, fmt.Sprintf("index %s on %s missing", idx.Index, idx.Table))
+		}
+	}
+	if len(check.Problems) > 0 {
+		check.Status = DoctorWarn
+		check.Summary = fmt.Sprintf("vector %s; %d vector indexes missing (search falls back to sequential scans)", *version, len(check.Problems))
+		check.Hint = "cp admin migrate up"
+		return check
+	}
+	check.Status = DoctorOK
+	check.Summary = fmt.Sprintf("vector %s, %d indexes present", *version, len(expectedVectorIndexes))
+	return check
+}
+
+// doctorEdges finds edges touching the project whose other end is gone. The
+// foreign keys prevent these, but hand-built schemas may lack them.
+func (c *Client) doctorEdges(ctx context.Context, conn *pgxpool.Conn, fix bool) DoctorCheck {
+	check := DoctorCheck{Name: "edges"}
+	const dangling = `
+		FROM edges e
+		LEFT JOIN shards f ON f.id = e.from_id
+		LEFT JOIN shards t ON t.id = e.to_id
+		WHERE (f.id IS NULL OR t.id IS NULL)
+		  AND (f.project = $1 OR t.project = $1)
+	`
+	rows, err := conn.Query(ctx, `SELECT e.from_id, e.to_id, e.edge_type, f.id IS NULL `+dangling+` ORDER BY e.from_id`, c.Config.Project)
+	if err != nil {
+		check.Status, check.Summary = DoctorFail, fmt.Sprintf("failed to query: %v", err)
+		return check
+	}
+	for rows.Next() {
+		var from, to, edgeType string
+		var fromMissing bool
+		if rows.Scan(&from, &to, &edgeType, &fromMissing) != nil {
+			continue
+		}
+		missing := to
+		if fromMissing {
+			missing = from
+		}
+		check.Problems = append(check.Problems, fmt.Sprintf("%s -[%s]-> %s: %s does not exist", from, edgeType, to, missing))
+	}
+	rows.Close()
+
+	if len(check.Problems) == 0 {
+		check.Status, check.Summary = DoctorOK, "no dangling edges"
+		return check
+	}
+	check.Status = DoctorWarn
+	check.Summary = fmt.Sprintf("%d dangling edges", len(check.Problems))
+	if !fix {
+		check.Hint = "cp admin doctor --fix deletes them"
+		return check
+	}
+
+	tag, err := conn.Exec(ctx, `
+		DELETE FROM edges WHERE (from_id, to_id, edge_type) IN (SELECT e.from_id, e.to_id, e.edge_type `+dangling+`)
+	`, c.Config.Project)
+	if err != nil {
+		check.Hint = fmt.Sprintf("fix failed: %v", err)
+		return check
+	}
+	check.Fixed = int(tag.RowsAffected())
+	check.Status = DoctorOK
+	check.Summary = fmt.Sprintf("%d dangling edges deleted", check.Fixed)
+	return check
+}
+
+// doctorLeases finds claims past their lease; --fix returns them to open.
+func (c *Client) doctorLeases(ctx context.Context, fix bool) DoctorCheck {
+	check := DoctorCheck{Name: "leases"}
+	expired, err := c.ReapLeases(ctx, !fix)
+	if err != nil {
+		check.Status, check.Summary = DoctorFail, err.Error()
+		return check
+	}
+	if len(expired) == 0 {
+		check.Status, check.Summary = DoctorOK, "no expired claims"
+		return check
+	}
+	for _, r := range expired {
+		owner := "nobody"
+		if r.Owner != nil {
+			owner = *r.Owner
+		}
+		check.Problems = append(check.Problems, fmt.Sprintf("%s held by %s, lease expired %s",
+			r.ID, owner, r.ExpiredAt.Local().Format("2006-01-02 15:04")))
+	}
+	if fix {
+		check.Fixed = len(expired)
+		check.Status = DoctorOK
+		check.Summary = fmt.Sprintf("returned %d shards with expired leases to open", len(expired))
+		return check
+	}
+	check.Status = DoctorWarn
+	check.Summary = fmt.Sprintf("%d in_progress shards with expired leases", len(expired))
+	check.Hint = "cp admin reap or cp admin doctor --fix returns them to open"
+	return check
+}
+
+// doctorParentCycles finds shards whose parent_id chain loops back on itself.
+// Which link to break is a judgement call, so --fix leaves them alone.
+func (c *Client) doctorParentCycles(ctx context.Context, conn *pgxpool.Conn) DoctorCheck {
+	check := DoctorCheck{Name: "parent cycles"}
+	rows, err := conn.Query(ctx, `
+		WITH RECURSIVE walk AS (
+			SELECT s.id AS cur, s.parent_id AS next, ARRAY[s.id] AS path
+			FROM shards s
+			WHERE s.project = $1 AND s.parent_id IS NOT NULL
+			UNION ALL
+			SELECT p.id, p.parent_id, w.path || p.id
+			FROM walk w
+			JOIN shards p ON p.id = w.next
+			WHERE p.parent_id IS NOT NULL AND NOT (p.id = ANY(w.path))
+		)
+		SELECT array_to_string(path[array_position(path, next):] || next, ' -> ')
+		FROM walk
+		WHERE next = ANY(path)
+	`, c.Config.Project)
+	if err != nil {
+		check.Status, check.Summary = DoctorFail, fmt.Sprintf("failed to query: %v", err)
+		return check
+	}
+	seen := map[string]bool{}
+	for rows.Next() {
+		var cycle string
+		if rows.Scan(&cycle) != nil {
+			continue
+		}
+		key := canonicalCycle(cycle)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		check.Problems = append(check.Problems, cycle)
+	}
+	rows.Close()
+
+	if len(check.Problems) == 0 {
+		check.Status, check.Summary = DoctorOK, "no parent_id cycles"
+		return check
+	}
+	check.Status = DoctorFail
+	check.Summary = fmt.Sprintf("%d parent_id cycles", len(check.Problems))
+	check.Hint = "break each cycle by re-parenting one shard (for memories: cp memory move <id> --root)"
+	return check
+}
+
+// canonicalCycle identifies a cycle "a -> b -> c -> a" regardless of where it starts
+func canonicalCycle(cycle string) string {
+	ids := strings.Split(cycle, " -> ")
+	if len(ids) > 1 {
+		ids = ids[:len(ids)-1]
+	}
+	sortStrings(ids)
+	return strings.Join(ids, ",")
+}
+
+// doctorMemoryPointers runs the cp memory sync check across the project
+func (c *Client) doctorMemoryPointers(ctx context.Context, fix bool) DoctorCheck {
+	check := DoctorCheck{Name: "memory pointers"}
+	result, err := c.SyncMemoryPointers(ctx, nil, !fix)
+	if err != nil {
+		check.Status, check.Summary = DoctorFail, err.Error()
+		return check
+	}
+	for _, d := range result.Discrepancies {
+		switch d.Type {
+		case "missing_pointer":
+			check.Problems = append(check.Problems, fmt.Sprintf("%s: child %s not in pointer block", d.ParentID, d.ChildID))
+		case "stale_pointer":
+			check.Problems = append(check.Problems, fmt.Sprintf("%s: stale pointer to %s (shard no longer exists)", d.ParentID, d.ChildID))
+		default:
+			check.Problems = append(check.Problems, fmt.Sprintf("%s: %s %s", d.ParentID, d.Type, d.ChildID))
+		}
+	}
+
+	switch {
+	case len(result.Discrepancies) == 0:
+		check.Status = DoctorOK
+		check.Summary = fmt.Sprintf("%d parents in sync", result.ParentsChecked)
+	case result.Fixed:
+		check.Status = DoctorOK
+		check.Fixed = len(result.Discrepancies)
+		check.Summary = fmt.Sprintf("%d discrepancies fixed across %d parents", check.Fixed, result.ParentsChecked)
+	default:
+		check.Status = DoctorWarn
+		check.Summary = fmt.Sprintf("%d discrepancies across %d parents", len(result.Discrepancies), result.ParentsChecked)
+		check.Hint = "cp admin doctor --fix (or cp memory sync)"
+	}
+	return check
+}
+
+// doctorEmbeddings checks the embedding columns fit the configured provider,
+// then counts shards recall can't find by vector
+func (c *Client) doctorEmbeddings(ctx context.Context, conn *pgxpool.Conn) DoctorCheck {
+	check := DoctorCheck{Name: "embeddings"}
+	if c.EmbedProvider != nil {
+		var dims *int
+		// Before migration 021 the columns are always vector(768)
+		if err := conn.QueryRow(ctx, `SELECT embedding_dimensions()`).Scan(&dims); err == nil && dims != nil && *dims != c.EmbedProvider.Dimensions() {
+			check.Status = DoctorFail
+			check.Summary = fmt.Sprintf("embedding columns are vector(%d) but the provider returns %d dimensions", *dims, c.EmbedProvider.Dimensions())
+			check.Hint = fmt.Sprintf("cp admin embed-reset %d, then cp admin embed-backfill", c.EmbedProvider.Dimensions())
+			return check
+		}
+	}
+	var missing, total int
+	err := conn.QueryRow(ctx, `
+		SELECT (SELECT count(*) FROM shards_needing_embedding($1, 2147483647)),
+		       (SELECT count(*) FROM shards WHERE project = $1)
+	`, c.Config.Project).Scan(&missing, &total)
+	if err != nil {
+		check.Status, check.Summary = DoctorFail, fmt.Sprintf("failed to query: %s", extractPgMessage(err.Error()))
+		return check
+	}
+	if missing == 0 {
+		check.Status = DoctorOK
+		check.Summary = fmt.Sprintf("all %d shards embedded", total)
+		return check
+	}
+	check.Status = DoctorWarn
+	check.Summary = fmt.Sprintf("%d of %d shards missing embeddings or chunks", missing, total)
+	check.Hint = "cp admin embed-backfill"
+	return check
+}
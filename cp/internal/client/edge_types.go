@@ -0,0 +1,309 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EdgeType describes an edge type and the rules CreateEdge enforces for it
+type EdgeType struct {
+	Name        string   `json:"name"`
+	Inverse     string   `json:"inverse,omitempty"`    // name read from the other end; linking with it stores the edge reversed
+	Blocks      bool     `json:"blocks"`               // from cannot start until to is closed
+	Acyclic     bool     `json:"acyclic"`              // edges may not form a cycle
+	FromTypes   []string `json:"from_types,omitempty"` // allowed source shard types (empty = any)
+	ToTypes     []string `json:"to_types,omitempty"`   // allowed target shard types (empty = any)
+	Description string   `json:"description,omitempty"`
+	BuiltIn     bool     `json:"built_in"` // shipped default the project has not redeclared
+}
+
+// BuiltinEdgeTypes are available in every project. A project can redeclare one
+// to change its properties, and declare new types alongside them.
+var BuiltinEdgeTypes = []EdgeType{
+	{Name: "blocked-by", Inverse: "blocks", Blocks: true, Acyclic: true, Description: "A cannot start until B is closed"},
+	{Name: "child-of", Inverse: "parent", Acyclic: true, Description: "A is a sub-item of B"},
+	{Name: "discovered-from", Description: "A was found while working on B"},
+	{Name: "extends", Description: "A builds on B"},
+	{Name: "has-artifact", Description: "B is an artifact of A"},
+	{Name: "implements", Description: "A is work toward B"},
+	{Name: "previous-version", Acyclic: true, Description: "B is A's prior version"},
+	{Name: "references", Description: "A mentions B"},
+	{Name: "relates-to", Description: "Loose association"},
+	{Name: "replies-to", Acyclic: true, Description: "A is a response to B"},
+	{Name: "triggered-by", Description: "B caused A to be created"},
+}
+
+// legacyEdgeAliases maps the inverse names that were stored as edge types of
+// their own before the registry existed to the type such edges are read as.
+// They keep their stored direction: a stored blocks edge makes from wait on
+// to, exactly like blocked-by.
+var legacyEdgeAliases = map[string]string{"blocks": "blocked-by", "parent": "child-of"}
+
+var edgeTypeNameRe = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
+// EdgeTypeRegistry is the set of edge types in effect for a project
+type EdgeTypeRegistry []EdgeType
+
+// mergeEdgeTypes overlays project declarations on the built-in types
+func mergeEdgeTypes(declared []EdgeType) EdgeTypeRegistry {
+	byName := map[string]EdgeType{}
+	for _, t := range BuiltinEdgeTypes {
+		t.BuiltIn = true
+		byName[t.Name] = t
+	}
+	for _, t := range declared {
+		t.BuiltIn = false
+		byName[t.Name] = t
+	}
+	reg := make(EdgeTypeRegistry, 0, len(byName))
+	for _, t := range byName {
+		reg = append(reg, t)
+	}
+	sort.Slice(reg, func(i, j int) bool { return reg[i].Name < reg[j].Name })
+	return reg
+}
+
+// Lookup finds an edge type by its name or inverse name. inverse is true when
+// name is an inverse, in which case the edge is stored reversed under t.Name.
+func (r EdgeTypeRegistry) Lookup(name string) (t EdgeType, inverse bool, ok bool) {
+	for _, t := range r {
+		if t.Name == name {
+			return t, false, true
+		}
+	}
+	for _, t := range r {
+		if t.Inverse != "" && t.Inverse == name {
+			return t, true, true
+		}
+	}
+	return EdgeType{}, false, false
+}
+
+// Names returns every accepted edge type name, inverses included, sorted
+func (r EdgeTypeRegistry) Names() []string {
+	var names []string
+	for _, t := range r {
+		names = append(names, t.Name)
+		if t.Inverse != "" {
+			names = append(names, t.Inverse)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Blocking returns the stored edge type names that block, legacy aliases
+// included
+func (r EdgeTypeRegistry) Blocking() []string {
+	var names []string
+	for _, t := range r {
+		if t.Blocks {
+			names = append(names, t.Name)
+		}
+	}
+	return withLegacyAliases(names)
+}
+
+// IsBlocking reports whether stored edges of the named type block their
+// source
+func (r EdgeTypeRegistry) IsBlocking(name string) bool {
+	if alias, ok := legacyEdgeAliases[name]; ok {
+		name = alias
+	}
+	t, inverse, ok := r.Lookup(name)
+	return ok && !inverse && t.Blocks
+}
+
+// cycleTypes returns the edge types a new edge of type t must not close a
+// cycle over. All blocking types share one dependency graph.
+func (r EdgeTypeRegistry) cycleTypes(t EdgeType) []string {
+	if t.Blocks {
+		return r.Blocking()
+	}
+	return withLegacyAliases([]string{t.Name})
+}
+
+// withLegacyAliases adds the legacy aliases of the named types, sorted
+func withLegacyAliases(names []string) []string {
+	for alias, name := range legacyEdgeAliases {
+		if containsString(names, name) && !containsString(names, alias) {
+			names = append(names, alias)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkShardTypes verifies the edge may connect shards of these types
+func (t EdgeType) checkShardTypes(fromType, toType string) error {
+	if len(t.FromTypes) > 0 && !containsString(t.FromTypes, fromType) {
+		return invalidf("%s edges must start at a %s shard, not %s", t.Name, strings.Join(t.FromTypes, " or "), fromType)
+	}
+	if len(t.ToTypes) > 0 && !containsString(t.ToTypes, toType) {
+		return invalidf("%s edges must point to a %s shard, not %s", t.Name, strings.Join(t.ToTypes, " or "), toType)
+	}
+	return nil
+}
+
+// EdgeTypes returns the edge types in effect for the project: the built-in
+// types overlaid with the project's own declarations
+func (c *Client) EdgeTypes(ctx context.Context) (EdgeTypeRegistry, error) {
+	declared, err := c.store.ListEdgeTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeEdgeTypes(declared), nil
+}
+
+// DefineEdgeType declares (or redeclares) an edge type for the project.
+// Blocking types are always acyclic, since a blocking cycle can never clear.
+func (c *Client) DefineEdgeType(ctx context.Context, t EdgeType) (*EdgeType, error) {
+	if !edgeTypeNameRe.MatchString(t.Name) {
+		return nil, invalidf("invalid edge type name %q: use lowercase words joined by hyphens", t.Name)
+	}
+	if t.Inverse != "" {
+		if !edgeTypeNameRe.MatchString(t.Inverse) {
+			return nil, invalidf("invalid inverse name %q: use lowercase words joined by hyphens", t.Inverse)
+		}
+		if t.Inverse == t.Name {
+			return nil, invalidf("an edge type cannot be its own inverse")
+		}
+	}
+	if t.Blocks {
+		t.Acyclic = true
+	}
+	// sortedUnique also turns nil into an empty list for the NOT NULL columns
+	t.FromTypes = sortedUnique(t.FromTypes)
+	t.ToTypes = sortedUnique(t.ToTypes)
+	t.BuiltIn = false
+
+	reg, err := c.EdgeTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range reg {
+		if other.Name == t.Name {
+			continue
+		}
+		if other.Name == t.Inverse || other.Inverse == t.Name || (t.Inverse != "" && other.Inverse == t.Inverse) {
+			clash := t.Inverse
+			if other.Inverse == t.Name {
+				clash = t.Name
+			}
+			return nil, conflictf("%s is already used by edge type %s", clash, other.Name)
+		}
+	}
+
+	if err := c.store.SaveEdgeType(ctx, t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RemoveEdgeType drops the project's declaration of an edge type. For a
+// built-in type this restores its default properties; a project-only type
+// can only be removed once no edges use it.
+func (c *Client) RemoveEdgeType(ctx context.Context, name string) error {
+	return c.store.DeleteEdgeType(ctx, name, isBuiltinEdgeType(name))
+}
+
+func isBuiltinEdgeType(name string) bool {
+	for _, t := range BuiltinEdgeTypes {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (pg *pgStore) ListEdgeTypes(ctx context.Context) ([]EdgeType, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT name, COALESCE(inverse, ''), blocks, acyclic, from_types, to_types, COALESCE(description, '')
+		FROM edge_types WHERE project = $1 ORDER BY name
+	`, pg.cfg.Project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list edge types: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var types []EdgeType
+	for rows.Next() {
+		var t EdgeType
+		if err := rows.Scan(&t.Name, &t.Inverse, &t.Blocks, &t.Acyclic, &t.FromTypes, &t.ToTypes, &t.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan edge type: %w", err)
+		}
+		types = append(types, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("edge type iteration error: %w", err)
+	}
+	return types, nil
+}
+
+func (pg *pgStore) SaveEdgeType(ctx context.Context, t EdgeType) error {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	var inverse, description any
+	if t.Inverse != "" {
+		inverse = t.Inverse
+	}
+	if t.Description != "" {
+		description = t.Description
+	}
+	_, err = conn.Exec(ctx, `
+		INSERT INTO edge_types (project, name, inverse, blocks, acyclic, from_types, to_types, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (project, name) DO UPDATE
+		SET inverse = EXCLUDED.inverse, blocks = EXCLUDED.blocks, acyclic = EXCLUDED.acyclic,
+			from_types = EXCLUDED.from_types, to_types = EXCLUDED.to_types,
+			description = EXCLUDED.description
+	`, pg.cfg.Project, t.Name, inverse, t.Blocks, t.Acyclic, t.FromTypes, t.ToTypes, description)
+	if err != nil {
+		return fmt.Errorf("failed to save edge type: %w", pgError(err))
+	}
+	return nil
+}
+
+func (pg *pgStore) DeleteEdgeType(ctx context.Context, name string, builtIn bool) error {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if !builtIn {
+		var used int
+		err = conn.QueryRow(ctx, `
+			SELECT count(*) FROM edges e JOIN shards s ON s.id = e.from_id
+			WHERE s.project = $1 AND e.edge_type = $2
+		`, pg.cfg.Project, name).Scan(&used)
+		if err != nil {
+			return fmt.Errorf("failed to count edges: %w", err)
+		}
+		if used > 0 {
+			return conflictf("%d edges use type %s; unlink them first", used, name)
+		}
+	}
+
+	tag, err := conn.Exec(ctx, `DELETE FROM edge_types WHERE project = $1 AND name = $2`, pg.cfg.Project, name)
+	if err != nil {
+		return fmt.Errorf("failed to remove edge type: %w", pgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return notFoundf("edge type %s is not declared in project %s", name, pg.cfg.Project)
+	}
+	return nil
+}
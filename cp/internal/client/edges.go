@@ -3,37 +3,21 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 )
 
-// ValidEdgeTypes is the canonical registry of valid edge types
-var ValidEdgeTypes = []string{
-	"blocked-by", "blocks", "child-of", "discovered-from", "extends",
-	"has-artifact", "implements", "parent", "previous-version",
-	"references", "relates-to", "replies-to", "triggered-by",
-}
-
-// IsValidEdgeType checks if an edge type is in the registry
-func IsValidEdgeType(edgeType string) bool {
-	for _, t := range ValidEdgeTypes {
-		if edgeType == t {
-			return true
-		}
-	}
-	return false
-}
-
 // EdgeInfo represents an edge with linked shard details
 type EdgeInfo struct {
-	Direction      string          `json:"direction"`
-	EdgeType       string          `json:"edge_type"`
-	ShardID        string          `json:"shard_id"`
-	Title          string          `json:"title"`
-	Type           string          `json:"type"`
-	Status         string          `json:"status"`
-	EdgeMetadata   json.RawMessage `json:"edge_metadata,omitempty"`
+	Direction    string          `json:"direction"`
+	EdgeType     string          `json:"edge_type"`
+	ShardID      string          `json:"shard_id"`
+	Title        string          `json:"title"`
+	Type         string          `json:"type"`
+	Status       string          `json:"status"`
+	EdgeMetadata json.RawMessage `json:"edge_metadata,omitempty"`
 }
 
 // EdgeTreeNode represents a node in the edge follow tree
@@ -48,29 +32,60 @@ type EdgeTreeNode struct {
 	IsCycle   bool            `json:"is_cycle,omitempty"`
 }
 
-// CreateEdge creates a typed edge between two shards
+// CreateEdge creates a typed edge between two shards. The edge type must be
+// in the project's registry; an inverse name (e.g. blocks) stores the edge
+// reversed under its canonical type (blocked-by). Shard type restrictions and
+// acyclic types are enforced before the edge is written.
 func (c *Client) CreateEdge(ctx context.Context, fromID, toID, edgeType string, metadata json.RawMessage) error {
-	if !IsValidEdgeType(edgeType) {
-		return fmt.Errorf("unknown edge type: %s. Valid types: %s",
-			edgeType, strings.Join(ValidEdgeTypes, ", "))
+	reg, err := c.EdgeTypes(ctx)
+	if err != nil {
+		return err
+	}
+	t, inverse, ok := reg.Lookup(edgeType)
+	if !ok {
+		return invalidf("unknown edge type: %s. Valid types: %s (declare new ones with 'cp shard edge-type define')",
+			edgeType, strings.Join(reg.Names(), ", "))
+	}
+	if inverse {
+		fromID, toID = toID, fromID
 	}
 
-	// For blocked-by edges, check circular dependencies
-	if edgeType == "blocked-by" {
-		circular, err := c.hasCircularDependency(ctx, fromID, toID)
+	if len(t.FromTypes) > 0 || len(t.ToTypes) > 0 {
+		from, err := c.store.GetShard(ctx, fromID)
+		if err != nil {
+			return err
+		}
+		to, err := c.store.GetShard(ctx, toID)
+		if err != nil {
+			return err
+		}
+		if err := t.checkShardTypes(from.Type, to.Type); err != nil {
+			return err
+		}
+	}
+
+	if t.Acyclic {
+		circular, err := c.store.HasCircularDependency(ctx, fromID, toID, reg.cycleTypes(t))
 		if err != nil {
 			return fmt.Errorf("check circular dependency: %w", err)
 		}
 		if circular {
-			return fmt.Errorf("circular dependency detected")
+			if t.Blocks {
+				return invalidf("circular dependency detected")
+			}
+			return invalidf("%s edges cannot form a cycle: %s already reaches %s", t.Name, toID, fromID)
 		}
 	}
 
-	conn, err := c.Connect(ctx)
+	return c.store.CreateEdge(ctx, fromID, toID, t.Name, metadata)
+}
+
+func (pg *pgStore) CreateEdge(ctx context.Context, fromID, toID, edgeType string, metadata json.RawMessage) error {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var metaArg interface{}
 	if metadata != nil {
@@ -80,34 +95,82 @@ func (c *Client) CreateEdge(ctx context.Context, fromID, toID, edgeType string,
 	_, err = conn.Exec(ctx, `SELECT create_edge($1, $2, $3, $4)`,
 		fromID, toID, edgeType, metaArg)
 	if err != nil {
-		return fmt.Errorf("%s", extractPgMessage(err.Error()))
+		return pgError(err)
 	}
 	return nil
 }
 
-// DeleteEdge removes an edge between two shards
+// DeleteEdge removes an edge between two shards. An inverse name removes the
+// reversed canonical edge, mirroring CreateEdge. Edges stored under a legacy
+// name are removed either by that name as stored or by the canonical name
+// they are listed under.
 func (c *Client) DeleteEdge(ctx context.Context, fromID, toID, edgeType string) error {
-	conn, err := c.Connect(ctx)
+	reg, err := c.EdgeTypes(ctx)
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	if _, ok := legacyEdgeAliases[edgeType]; ok {
+		err := c.store.DeleteEdge(ctx, fromID, toID, edgeType)
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	if t, inverse, ok := reg.Lookup(edgeType); ok && inverse {
+		fromID, toID, edgeType = toID, fromID, t.Name
+	}
+	err = c.store.DeleteEdge(ctx, fromID, toID, edgeType)
+	if errors.Is(err, ErrNotFound) {
+		for alias, name := range legacyEdgeAliases {
+			if name != edgeType {
+				continue
+			}
+			if legacyErr := c.store.DeleteEdge(ctx, fromID, toID, alias); !errors.Is(legacyErr, ErrNotFound) {
+				return legacyErr
+			}
+		}
+	}
+	return err
+}
+
+func (pg *pgStore) DeleteEdge(ctx context.Context, fromID, toID, edgeType string) error {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
 
 	_, err = conn.Exec(ctx, `SELECT delete_edge($1, $2, $3)`,
 		fromID, toID, edgeType)
 	if err != nil {
-		return fmt.Errorf("%s", extractPgMessage(err.Error()))
+		return pgError(err)
 	}
 	return nil
 }
 
-// GetShardEdges returns all edges for a shard with optional filters
+// GetShardEdges returns all edges for a shard with optional filters. Edges
+// stored under a legacy name are returned under the type they are read as.
 func (c *Client) GetShardEdges(ctx context.Context, shardID string, direction string, edgeTypes []string) ([]EdgeInfo, error) {
-	conn, err := c.Connect(ctx)
+	if len(edgeTypes) > 0 {
+		edgeTypes = withLegacyAliases(append([]string{}, edgeTypes...))
+	}
+	edges, err := c.store.GetShardEdges(ctx, shardID, direction, edgeTypes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range edges {
+		if name, ok := legacyEdgeAliases[edges[i].EdgeType]; ok {
+			edges[i].EdgeType = name
+		}
+	}
+	return edges, nil
+}
+
+func (pg *pgStore) GetShardEdges(ctx context.Context, shardID string, direction string, edgeTypes []string) ([]EdgeInfo, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var dirArg, edgeTypesArg interface{}
 	if direction != "" {
@@ -123,7 +186,7 @@ func (c *Client) GetShardEdges(ctx context.Context, shardID string, direction st
 		FROM shard_edges($1, $2, $3)
 	`, shardID, dirArg, edgeTypesArg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get shard edges: %v", err)
+		return nil, fmt.Errorf("failed to get shard edges: %w", err)
 	}
 	defer rows.Close()
 
@@ -132,12 +195,12 @@ func (c *Client) GetShardEdges(ctx context.Context, shardID string, direction st
 		var e EdgeInfo
 		if err := rows.Scan(&e.Direction, &e.EdgeType, &e.ShardID,
 			&e.Title, &e.Type, &e.Status, &e.EdgeMetadata); err != nil {
-			return nil, fmt.Errorf("failed to scan edge: %v", err)
+			return nil, fmt.Errorf("failed to scan edge: %w", err)
 		}
 		edges = append(edges, e)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("edge iteration error: %v", err)
+		return nil, fmt.Errorf("edge iteration error: %w", err)
 	}
 	return edges, nil
 }
@@ -187,35 +250,39 @@ func (c *Client) followEdges(ctx context.Context, shardID string, direction stri
 	return nodes, nil
 }
 
-// hasCircularDependency checks if adding fromID -> toID as blocked-by would create a cycle
-func (c *Client) hasCircularDependency(ctx context.Context, fromID, toID string) (bool, error) {
-	conn, err := c.Connect(ctx)
+// HasCircularDependency checks if adding fromID -> toID would close a cycle
+// over edges of the given types
+func (pg *pgStore) HasCircularDependency(ctx context.Context, fromID, toID string, edgeTypes []string) (bool, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return false, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var circular bool
-	err = conn.QueryRow(ctx, `SELECT has_circular_dependency($1, $2)`, fromID, toID).Scan(&circular)
+	err = conn.QueryRow(ctx, `SELECT has_circular_dependency($1, $2, $3)`, fromID, toID, edgeTypes).Scan(&circular)
 	if err != nil {
-		// If function doesn't exist, skip the check
-		return false, nil
+		return false, pgError(err)
 	}
 	return circular, nil
 }
 
 // ShardExists checks if a shard exists
 func (c *Client) ShardExists(ctx context.Context, id string) (bool, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.ShardExists(ctx, id)
+}
+
+func (pg *pgStore) ShardExists(ctx context.Context, id string) (bool, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return false, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var exists bool
 	err = conn.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM shards WHERE id = $1)`, id).Scan(&exists)
 	if err != nil {
-		return false, fmt.Errorf("failed to check shard existence: %v", err)
+		return false, fmt.Errorf("failed to check shard existence: %w", err)
 	}
 	return exists, nil
 }
@@ -244,11 +311,15 @@ type ShardDetailResult struct {
 
 // GetShardDetail fetches a shard with full detail (including edge counts)
 func (c *Client) GetShardDetail(ctx context.Context, id string) (*ShardDetailResult, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.GetShardDetail(ctx, id)
+}
+
+func (pg *pgStore) GetShardDetail(ctx context.Context, id string) (*ShardDetailResult, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var d ShardDetailResult
 	err = conn.QueryRow(ctx, `
@@ -260,7 +331,7 @@ func (c *Client) GetShardDetail(ctx context.Context, id string) (*ShardDetailRes
 		&d.Labels, &d.Metadata, &d.CreatedAt, &d.UpdatedAt,
 		&d.OutgoingEdgeCount, &d.IncomingEdgeCount)
 	if err != nil {
-		return nil, fmt.Errorf("Shard %s not found", id)
+		return nil, notFoundf("Shard %s not found", id)
 	}
 
 	return &d, nil
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// newLocalClient returns a client on a fresh local data file
+func newLocalClient(t *testing.T) *Client {
+	t.Helper()
+	c, err := NewClient(&Config{
+		Storage: StorageConfig{Backend: BackendLocal, Path: filepath.Join(t.TempDir(), "local.json")},
+		Project: "demo",
+		Agent:   "agent-a",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestLegacyEdges(t *testing.T) {
+	ctx := context.Background()
+	c := newLocalClient(t)
+
+	var a, b string
+	for _, id := range []*string{&a, &b} {
+		var err error
+		if *id, err = c.CreateShard(ctx, "task", "", "task", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A stored blocks edge from a to b makes a wait on b, like blocked-by
+	legacy := func() {
+		t.Helper()
+		if err := c.store.CreateEdge(ctx, a, b, "blocks", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy()
+
+	edges, err := c.GetShardEdges(ctx, a, "outgoing", []string{"blocked-by"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edges) != 1 || edges[0].EdgeType != "blocked-by" || edges[0].ShardID != b {
+		t.Fatalf("edges of %s = %+v, want blocked-by %s", a, edges, b)
+	}
+	edges, err = c.GetShardEdges(ctx, b, "incoming", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edges) != 1 || edges[0].EdgeType != "blocked-by" || edges[0].ShardID != a {
+		t.Fatalf("edges of %s = %+v, want blocked-by from %s", b, edges, a)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		edgeType string
+	}{
+		{name: "stored name", from: a, to: b, edgeType: "blocks"},
+		{name: "listed name", from: a, to: b, edgeType: "blocked-by"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.DeleteEdge(ctx, tt.from, tt.to, tt.edgeType); err != nil {
+				t.Fatal(err)
+			}
+			edges, err := c.GetShardEdges(ctx, a, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(edges) != 0 {
+				t.Errorf("edges left: %+v", edges)
+			}
+			if err := c.DeleteEdge(ctx, tt.from, tt.to, tt.edgeType); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second delete: err = %v, want ErrNotFound", err)
+			}
+		})
+		legacy()
+	}
+
+	// blocks as an inverse name still removes the canonical edge it created
+	if err := c.DeleteEdge(ctx, a, b, "blocked-by"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateEdge(ctx, b, a, "blocks", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteEdge(ctx, b, a, "blocks"); err != nil {
+		t.Fatal(err)
+	}
+	if edges, err := c.GetShardEdges(ctx, a, "", nil); err != nil || len(edges) != 0 {
+		t.Errorf("edges left: %+v, %v", edges, err)
+	}
+}
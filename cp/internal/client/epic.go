@@ -36,7 +36,7 @@ func (c *Client) GetEpicProgress(ctx context.Context, epicID string) (*EpicProgr
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var p EpicProgress
 	err = conn.QueryRow(ctx, `
@@ -44,7 +44,7 @@ func (c *Client) GetEpicProgress(ctx context.Context, epicID string) (*EpicProgr
 		FROM epic_progress($1, $2)
 	`, c.Config.Project, epicID).Scan(&p.Total, &p.Completed, &p.InProgress, &p.Open, &p.Blocked)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get epic progress: %v", err)
+		return nil, fmt.Errorf("failed to get epic progress: %w", err)
 	}
 	return &p, nil
 }
@@ -55,7 +55,7 @@ func (c *Client) GetEpicChildren(ctx context.Context, epicID string) ([]EpicChil
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `
 		SELECT id, title, status, kind, owner, priority,
@@ -63,7 +63,7 @@ func (c *Client) GetEpicChildren(ctx context.Context, epicID string) ([]EpicChil
 		FROM epic_children($1, $2)
 	`, c.Config.Project, epicID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get epic children: %v", err)
+		return nil, fmt.Errorf("failed to get epic children: %w", err)
 	}
 	defer rows.Close()
 
@@ -73,7 +73,7 @@ func (c *Client) GetEpicChildren(ctx context.Context, epicID string) ([]EpicChil
 		if err := rows.Scan(&ch.ID, &ch.Title, &ch.Status, &ch.Kind,
 			&ch.Owner, &ch.Priority, &ch.AssignedAt, &ch.ClosedAt,
 			&ch.ClosedBy, &ch.ClosedReason, &ch.BlockedBy); err != nil {
-			return nil, fmt.Errorf("failed to scan epic child: %v", err)
+			return nil, fmt.Errorf("failed to scan epic child: %w", err)
 		}
 		if ch.BlockedBy == nil {
 			ch.BlockedBy = []string{}
@@ -81,7 +81,7 @@ func (c *Client) GetEpicChildren(ctx context.Context, epicID string) ([]EpicChil
 		children = append(children, ch)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("epic children iteration error: %v", err)
+		return nil, fmt.Errorf("epic children iteration error: %w", err)
 	}
 	return children, nil
 }
@@ -93,11 +93,11 @@ func (c *Client) CreateEpic(ctx context.Context, title, body string, priority *i
 	if err != nil {
 		return "", err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	tx, err := conn.Begin(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to begin transaction: %v", err)
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
@@ -111,7 +111,7 @@ func (c *Client) CreateEpic(ctx context.Context, title, body string, priority *i
 	`, c.Config.Project, c.Config.Agent, title, body, "epic",
 		allLabels, nil, priority, "{}").Scan(&epicID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create epic shard: %v", err)
+		return "", fmt.Errorf("failed to create epic shard: %w", err)
 	}
 
 	// Adopt children: set parent_id
@@ -122,20 +122,20 @@ func (c *Client) CreateEpic(ctx context.Context, title, body string, priority *i
 			SELECT project, parent_id FROM shards WHERE id = $1
 		`, childID).Scan(&childProject, &existingParent)
 		if err != nil {
-			return "", fmt.Errorf("Shard %s not found", childID)
+			return "", notFoundf("Shard %s not found", childID)
 		}
 		if childProject != c.Config.Project {
-			return "", fmt.Errorf("Shard %s belongs to a different project", childID)
+			return "", invalidf("Shard %s belongs to a different project", childID)
 		}
 		if existingParent != nil && *existingParent != "" {
-			return "", fmt.Errorf("Shard %s already belongs to epic %s", childID, *existingParent)
+			return "", conflictf("Shard %s already belongs to epic %s", childID, *existingParent)
 		}
 
 		_, err = tx.Exec(ctx, `
 			UPDATE shards SET parent_id = $1, updated_at = NOW() WHERE id = $2
 		`, epicID, childID)
 		if err != nil {
-			return "", fmt.Errorf("failed to adopt shard %s: %v", childID, err)
+			return "", fmt.Errorf("failed to adopt shard %s: %w", childID, err)
 		}
 	}
 
@@ -144,13 +144,13 @@ func (c *Client) CreateEpic(ctx context.Context, title, body string, priority *i
 		_, err = tx.Exec(ctx, `SELECT create_edge($1, $2, $3, $4)`,
 			edge.From, edge.BlockedBy, "blocked-by", nil)
 		if err != nil {
-			return "", fmt.Errorf("failed to create edge %s blocked-by %s: %s",
-				edge.From, edge.BlockedBy, extractPgMessage(err.Error()))
+			return "", fmt.Errorf("failed to create edge %s blocked-by %s: %w",
+				edge.From, edge.BlockedBy, pgError(err))
 		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return "", fmt.Errorf("failed to commit epic creation: %v", err)
+		return "", fmt.Errorf("failed to commit epic creation: %w", err)
 	}
 
 	// Embed-on-write
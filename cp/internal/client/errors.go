@@ -0,0 +1,95 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Error classes for callers that must react to the kind of failure rather
+// than its wording, such as the REST API choosing a status code. Client
+// errors wrap one of these; test with errors.Is. Errors wrapping none of them
+// are storage or infrastructure failures.
+var (
+	ErrNotFound    = errors.New("not found")                     // the shard, edge, agent, ... does not exist
+	ErrConflict    = errors.New("conflict")                      // the current state forbids it: already claimed, closed, blocked, in use
+	ErrInvalid     = errors.New("invalid")                       // the request itself is malformed or breaks a rule
+	ErrUnsupported = errors.New("requires the postgres backend") // the configured backend cannot do it
+)
+
+// classError is an error of one class with its own message. cause, if set,
+// is the error it was made from (such as a *pgconn.PgError), kept for
+// errors.As; class may be nil for unclassified storage failures.
+type classError struct {
+	msg   string
+	class error
+	cause error
+}
+
+func (e *classError) Error() string { return e.msg }
+
+func (e *classError) Unwrap() []error {
+	var errs []error
+	for _, err := range []error{e.class, e.cause} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func notFoundf(format string, args ...any) error {
+	return &classError{msg: fmt.Sprintf(format, args...), class: ErrNotFound}
+}
+
+func conflictf(format string, args ...any) error {
+	return &classError{msg: fmt.Sprintf(format, args...), class: ErrConflict}
+}
+
+func invalidf(format string, args ...any) error {
+	return &classError{msg: fmt.Sprintf(format, args...), class: ErrInvalid}
+}
+
+// pgError turns an error from a Postgres call into a client error with the
+// server's message. The SQL functions in migrations/ raise every rule
+// violation as raise_exception (P0001) with fixed wording, so that wording is
+// what separates missing rows from state conflicts; other P0001s are invalid
+// requests. Constraint violations map by SQLSTATE. Anything else is a storage
+// failure.
+func pgError(err error) error {
+	msg := extractPgMessage(err.Error())
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return &classError{msg: msg, cause: err}
+	}
+	switch pgErr.Code {
+	case "P0001":
+		switch {
+		case strings.Contains(msg, "not found"), strings.HasPrefix(msg, "No edge of type"):
+			return &classError{msg, ErrNotFound, err}
+		case strings.Contains(msg, " already "), strings.Contains(msg, "unresolved blockers"),
+			strings.Contains(msg, " is owned by "), strings.Contains(msg, "claim it again"),
+			strings.Contains(msg, " is closed"):
+			return &classError{msg, ErrConflict, err}
+		default:
+			return &classError{msg, ErrInvalid, err}
+		}
+	case "23505": // unique_violation
+		return &classError{msg, ErrConflict, err}
+	case "23503": // foreign_key_violation
+		return &classError{msg, ErrNotFound, err}
+	case "22P02", "23514", "22023": // invalid_text_representation, check_violation, invalid_parameter_value
+		return &classError{msg, ErrInvalid, err}
+	}
+	return &classError{msg: msg, cause: err}
+}
+
+// IsUndefinedFunction reports whether err is, or wraps, Postgres saying a
+// function does not exist (SQLSTATE 42883), as when the schema predates the
+// migration that adds it
+func IsUndefinedFunction(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42883"
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestPgError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		class     error // nil: a storage failure
+		msg       string
+		undefined bool
+	}{
+		{
+			name:  "rule violation",
+			err:   &pgconn.PgError{Severity: "ERROR", Code: "P0001", Message: "Shard pf-1 not found"},
+			class: ErrNotFound,
+			msg:   "Shard pf-1 not found",
+		},
+		{
+			name:  "unique violation",
+			err:   &pgconn.PgError{Severity: "ERROR", Code: "23505", Message: "duplicate key value"},
+			class: ErrConflict,
+			msg:   "duplicate key value",
+		},
+		{
+			name:      "missing function",
+			err:       &pgconn.PgError{Severity: "ERROR", Code: "42883", Message: "function keyword_search(text) does not exist"},
+			msg:       "function keyword_search(text) does not exist",
+			undefined: true,
+		},
+		{
+			name: "not from Postgres",
+			err:  context.Canceled,
+			msg:  "context canceled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Callers wrap the classified error with their own context
+			err := fmt.Errorf("failed to do it: %w", pgError(tt.err))
+			if want := "failed to do it: " + tt.msg; err.Error() != want {
+				t.Errorf("message = %q, want %q", err, want)
+			}
+			for _, class := range []error{ErrNotFound, ErrConflict, ErrInvalid} {
+				if errors.Is(err, class) != (class == tt.class) {
+					t.Errorf("errors.Is(%v) = %v", class, !(class == tt.class))
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				t.Error("the original error is not wrapped")
+			}
+			if IsUndefinedFunction(err) != tt.undefined {
+				t.Errorf("IsUndefinedFunction = %v, want %v", !tt.undefined, tt.undefined)
+			}
+		})
+	}
+}
@@ -20,7 +20,7 @@ func (c *Client) SetFocus(ctx context.Context, epicID string, note *string) erro
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var noteArg interface{}
 	if note != nil {
@@ -30,7 +30,7 @@ func (c *Client) SetFocus(ctx context.Context, epicID string, note *string) erro
 	_, err = conn.Exec(ctx, `SELECT focus_set($1, $2, $3, $4)`,
 		c.Config.Project, c.Config.Agent, epicID, noteArg)
 	if err != nil {
-		return fmt.Errorf("%s", extractPgMessage(err.Error()))
+		return pgError(err)
 	}
 	return nil
 }
@@ -41,7 +41,7 @@ func (c *Client) GetFocus(ctx context.Context) (*Focus, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var f Focus
 	var note *string
@@ -65,13 +65,13 @@ func (c *Client) ClearFocus(ctx context.Context) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var cleared bool
 	err = conn.QueryRow(ctx, `SELECT focus_clear($1, $2)`,
 		c.Config.Project, c.Config.Agent).Scan(&cleared)
 	if err != nil {
-		return false, fmt.Errorf("failed to clear focus: %v", err)
+		return false, fmt.Errorf("failed to clear focus: %w", err)
 	}
 	return cleared, nil
 }
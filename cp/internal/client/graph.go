@@ -0,0 +1,345 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GraphNode is a shard in an exported relationship graph
+type GraphNode struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Depth   int    `json:"depth"`              // hops from the nearest seed shard
+	Seed    bool   `json:"seed,omitempty"`     // root, epic child or project shard the export started from
+	InCycle bool   `json:"in_cycle,omitempty"` // on a cycle of links in this graph
+}
+
+// GraphLink is an edge between two nodes of the graph
+type GraphLink struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	EdgeType string `json:"edge_type"`
+	Blocks   bool   `json:"blocks,omitempty"` // edge type blocks its source
+	Cycle    bool   `json:"cycle,omitempty"`  // part of a cycle
+}
+
+// Graph is a node/link view of shard relationships
+type Graph struct {
+	Scope string      `json:"scope"` // "shard", "epic" or "project"
+	Root  string      `json:"root,omitempty"`
+	Nodes []GraphNode `json:"nodes"`
+	Links []GraphLink `json:"links"`
+}
+
+// GraphOptions selects what BuildGraph collects. Exactly one of RootID,
+// EpicID and Project is used, in that order of preference.
+type GraphOptions struct {
+	RootID    string   // start from one shard
+	EpicID    string   // start from an epic and its children
+	Project   bool     // start from every shard in the project
+	Depth     int      // hops to follow beyond the starting shards
+	Direction string   // "outgoing", "incoming" or "" for both
+	EdgeTypes []string // only follow these edge types (empty = all)
+	Status    []string // only include shards in these statuses (starting root always included)
+	Types     []string // only include shards of these types (starting root always included)
+	MaxNodes  int      // stop adding shards past this many (0 = 500)
+}
+
+// BuildGraph collects shards and the edges between them, starting from a
+// shard, an epic or the whole project and following edges up to Depth hops.
+// Links whose endpoints lie on a common cycle are marked.
+func (c *Client) BuildGraph(ctx context.Context, opts GraphOptions) (*Graph, error) {
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = 500
+	}
+	reg, err := c.EdgeTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph{Nodes: []GraphNode{}, Links: []GraphLink{}}
+	index := map[string]int{}
+	addNode := func(n GraphNode) bool {
+		if _, ok := index[n.ID]; ok {
+			return true
+		}
+		if len(g.Nodes) >= opts.MaxNodes {
+			return false
+		}
+		index[n.ID] = len(g.Nodes)
+		g.Nodes = append(g.Nodes, n)
+		return true
+	}
+	include := func(shardType, status string) bool {
+		return (len(opts.Status) == 0 || containsString(opts.Status, status)) &&
+			(len(opts.Types) == 0 || containsString(opts.Types, shardType))
+	}
+
+	// Seed shards
+	switch {
+	case opts.RootID != "":
+		g.Scope, g.Root = "shard", opts.RootID
+		sh, err := c.store.GetShard(ctx, opts.RootID)
+		if err != nil {
+			return nil, err
+		}
+		addNode(GraphNode{ID: sh.ID, Title: sh.Title, Type: sh.Type, Status: sh.Status, Seed: true})
+	case opts.EpicID != "":
+		g.Scope, g.Root = "epic", opts.EpicID
+		epic, err := c.store.GetShard(ctx, opts.EpicID)
+		if err != nil {
+			return nil, err
+		}
+		if epic.Type != "epic" {
+			return nil, invalidf("%s is a %s, not an epic", epic.ID, epic.Type)
+		}
+		addNode(GraphNode{ID: epic.ID, Title: epic.Title, Type: epic.Type, Status: epic.Status, Seed: true})
+		children, err := c.store.GetShardBoard(ctx, &opts.EpicID, nil)
+		if err != nil {
+			return nil, err
+		}
+		// Epic membership is parent_id, not an edge; show it as child-of
+		withParent := len(opts.EdgeTypes) == 0 || containsString(opts.EdgeTypes, "child-of")
+		for _, ch := range children {
+			sh, err := c.store.GetShard(ctx, ch.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !include(sh.Type, sh.Status) || !addNode(GraphNode{ID: sh.ID, Title: sh.Title, Type: sh.Type, Status: sh.Status, Seed: true}) {
+				continue
+			}
+			if withParent {
+				g.Links = append(g.Links, GraphLink{Source: sh.ID, Target: epic.ID, EdgeType: "child-of"})
+			}
+		}
+	case opts.Project:
+		g.Scope = "project"
+		shards, err := c.store.ListShardsFiltered(ctx, ListShardsOpts{
+			Types: opts.Types, Status: opts.Status, Limit: opts.MaxNodes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, sh := range shards {
+			addNode(GraphNode{ID: sh.ID, Title: sh.Title, Type: sh.Type, Status: sh.Status, Seed: true})
+		}
+	default:
+		return nil, invalidf("specify a root shard, an epic or the project")
+	}
+
+	// Breadth-first over edges. Shards at the depth limit still contribute
+	// links to shards already in the graph, just no new shards.
+	seen := map[string]bool{}
+	for _, l := range g.Links {
+		seen[l.Source+"\x00"+l.Target+"\x00"+l.EdgeType] = true
+	}
+	for i := 0; i < len(g.Nodes); i++ {
+		n := g.Nodes[i]
+		edges, err := c.GetShardEdges(ctx, n.ID, opts.Direction, opts.EdgeTypes)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range edges {
+			if _, ok := index[e.ShardID]; !ok {
+				if n.Depth >= opts.Depth || !include(e.Type, e.Status) {
+					continue
+				}
+				if !addNode(GraphNode{ID: e.ShardID, Title: e.Title, Type: e.Type, Status: e.Status, Depth: n.Depth + 1}) {
+					continue
+				}
+			}
+
+			l := GraphLink{Source: n.ID, Target: e.ShardID, EdgeType: e.EdgeType}
+			if e.Direction == "incoming" {
+				l.Source, l.Target = e.ShardID, n.ID
+			}
+			key := l.Source + "\x00" + l.Target + "\x00" + l.EdgeType
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			l.Blocks = reg.IsBlocking(l.EdgeType)
+			g.Links = append(g.Links, l)
+		}
+	}
+
+	g.markCycles()
+	sort.SliceStable(g.Links, func(i, j int) bool {
+		if g.Links[i].Source != g.Links[j].Source {
+			return index[g.Links[i].Source] < index[g.Links[j].Source]
+		}
+		return g.Links[i].Target < g.Links[j].Target
+	})
+	return g, nil
+}
+
+// markCycles flags links and nodes that lie on a directed cycle, using
+// Tarjan's strongly connected components: a link is on a cycle exactly when
+// both ends are in the same component of two or more nodes.
+func (g *Graph) markCycles() {
+	adj := map[string][]string{}
+	for _, l := range g.Links {
+		adj[l.Source] = append(adj[l.Source], l.Target)
+	}
+
+	comp := map[string]int{}
+	size := map[int]int{}
+	order := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	counter, ncomp := 0, 0
+
+	var visit func(v string)
+	visit = func(v string) {
+		order[v], low[v] = counter, counter
+		counter++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range adj[v] {
+			if _, ok := order[w]; !ok {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], order[w])
+			}
+		}
+		if low[v] == order[v] {
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp[w] = ncomp
+				size[ncomp]++
+				if w == v {
+					break
+				}
+			}
+			ncomp++
+		}
+	}
+	for _, n := range g.Nodes {
+		if _, ok := order[n.ID]; !ok {
+			visit(n.ID)
+		}
+	}
+
+	inCycle := map[string]bool{}
+	for i, l := range g.Links {
+		if comp[l.Source] == comp[l.Target] && size[comp[l.Source]] > 1 {
+			g.Links[i].Cycle = true
+			inCycle[l.Source], inCycle[l.Target] = true, true
+		}
+	}
+	for i := range g.Nodes {
+		g.Nodes[i].InCycle = inCycle[g.Nodes[i].ID]
+	}
+}
+
+// graphStatusColors are node fill colours by shard status
+var graphStatusColors = map[string]string{
+	"open":        "#dbeafe",
+	"in_progress": "#fef3c7",
+	"closed":      "#dcfce7",
+}
+
+const (
+	graphDefaultColor = "#e5e7eb"
+	graphCycleColor   = "#dc2626"
+)
+
+func graphStatusColor(status string) string {
+	if c, ok := graphStatusColors[status]; ok {
+		return c
+	}
+	return graphDefaultColor
+}
+
+// DOT renders the graph as Graphviz DOT. Nodes are filled by status; blocking
+// links are bold and links on a cycle are red.
+func (g *Graph) DOT() string {
+	esc := func(s string) string {
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s)
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph shards {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+	for _, n := range g.Nodes {
+		attrs := fmt.Sprintf(`label="%s\n%s\n(%s, %s)", fillcolor="%s"`,
+			esc(n.ID), esc(Truncate(n.Title, 40)), esc(n.Type), esc(n.Status), graphStatusColor(n.Status))
+		if n.InCycle {
+			attrs += fmt.Sprintf(`, color="%s", penwidth=2`, graphCycleColor)
+		}
+		if n.Seed && g.Scope != "project" {
+			attrs += ", peripheries=2"
+		}
+		fmt.Fprintf(&b, "  \"%s\" [%s];\n", esc(n.ID), attrs)
+	}
+	for _, l := range g.Links {
+		attrs := fmt.Sprintf(`label="%s"`, esc(l.EdgeType))
+		if l.Blocks {
+			attrs += ", style=bold"
+		}
+		if l.Cycle {
+			attrs += fmt.Sprintf(`, color="%s", fontcolor="%s"`, graphCycleColor, graphCycleColor)
+		}
+		fmt.Fprintf(&b, "  \"%s\" -> \"%s\" [%s];\n", esc(l.Source), esc(l.Target), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Nodes get a class per
+// status; blocking links are thick, and links and shards on a cycle are red.
+func (g *Graph) Mermaid() string {
+	esc := func(s string) string {
+		return strings.NewReplacer(`"`, "#quot;", "\n", " ", "<", "#lt;", ">", "#gt;").Replace(s)
+	}
+	// Shard IDs contain hyphens, which Mermaid can read as arrows
+	ids := map[string]string{}
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		class := strings.ReplaceAll(n.Status, "_", "")
+		if _, ok := graphStatusColors[n.Status]; !ok {
+			class = "other"
+		}
+		fmt.Fprintf(&b, "  %s[\"%s<br/>%s<br/><small>%s, %s</small>\"]:::%s\n",
+			ids[n.ID], esc(n.ID), esc(Truncate(n.Title, 40)), esc(n.Type), esc(n.Status), class)
+	}
+	var cycleLinks []string
+	for i, l := range g.Links {
+		arrow := "-->"
+		if l.Blocks {
+			arrow = "==>"
+		}
+		fmt.Fprintf(&b, "  %s %s|%s| %s\n", ids[l.Source], arrow, esc(l.EdgeType), ids[l.Target])
+		if l.Cycle {
+			cycleLinks = append(cycleLinks, fmt.Sprint(i))
+		}
+	}
+	for _, status := range []string{"open", "in_progress", "closed"} {
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:#6b7280\n", strings.ReplaceAll(status, "_", ""), graphStatusColors[status])
+	}
+	fmt.Fprintf(&b, "  classDef other fill:%s,stroke:#6b7280\n", graphDefaultColor)
+	if len(cycleLinks) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:%s,stroke-width:2px\n", strings.Join(cycleLinks, ","), graphCycleColor)
+	}
+	for _, n := range g.Nodes {
+		if n.InCycle {
+			fmt.Fprintf(&b, "  style %s stroke:%s,stroke-width:2px\n", ids[n.ID], graphCycleColor)
+		}
+	}
+	return b.String()
+}
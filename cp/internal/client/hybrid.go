@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// DefaultRRFK is the reciprocal rank fusion constant. Larger values flatten the
+// difference between top and lower ranks.
+const DefaultRRFK = 60
+
+// ScoreBreakdown explains a hybrid recall score. Semantic/Keyword are nil when
+// the shard wasn't found by that ranking; ranks are 1-based.
+type ScoreBreakdown struct {
+	Fused        float64  `json:"fused"`
+	Semantic     *float64 `json:"semantic,omitempty"`
+	SemanticRank int      `json:"semantic_rank,omitempty"`
+	Keyword      *float64 `json:"keyword,omitempty"`
+	KeywordRank  int      `json:"keyword_rank,omitempty"`
+}
+
+// HybridWeights weights each ranking in reciprocal rank fusion
+type HybridWeights struct {
+	Semantic float64
+	Keyword  float64
+	K        int // RRF constant; DefaultRRFK if 0
+}
+
+// KeywordSearch performs full-text search with exact-match boosts using the
+// keyword_search() SQL function. Similarity holds the keyword score.
+func (c *Client) KeywordSearch(ctx context.Context, query string, types []string, labels []string, status []string, limit int, since *time.Time) ([]RecallResult, error) {
+	return c.store.KeywordSearch(ctx, query, types, labels, status, limit, since)
+}
+
+func (pg *pgStore) KeywordSearch(ctx context.Context, query string, types []string, labels []string, status []string, limit int, since *time.Time) ([]RecallResult, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var typesArg, labelsArg, statusArg, sinceArg any
+	if types != nil {
+		typesArg = types
+	}
+	if labels != nil {
+		labelsArg = labels
+	}
+	if status != nil {
+		statusArg = status
+	}
+	if since != nil {
+		sinceArg = *since
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT id, title, type, status, score, snippet, labels, created_at
+		FROM keyword_search($1, $2, $3, $4, $5, $6, $7)
+	`, pg.cfg.Project, query, typesArg, labelsArg, statusArg, limit, sinceArg)
+	if err != nil {
+		if IsUndefinedFunction(err) {
+			return nil, fmt.Errorf("keyword search requires migration 011 (cp admin migrate up): %w", err)
+		}
+		return nil, fmt.Errorf("keyword search failed: %w", err)
+	}
+	defer rows.Close()
+
+	var results []RecallResult
+	for rows.Next() {
+		var r RecallResult
+		if err := rows.Scan(&r.ID, &r.Title, &r.Type, &r.Status, &r.Similarity, &r.Snippet, &r.Labels, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan result: %w", err)
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("result iteration error: %w", err)
+	}
+
+	return results, nil
+}
+
+// FuseRankings merges semantic and keyword results with weighted reciprocal
+// rank fusion: score = w_sem/(k+rank_sem) + w_kw/(k+rank_kw). Each result's
+// Scores holds the breakdown; Similarity stays the semantic similarity (0 for
+// keyword-only hits). Snippets prefer the keyword hit, which is centred on the
+// exact match, unless the semantic hit came from a chunk.
+func FuseRankings(semantic, keyword []RecallResult, w HybridWeights, limit int) []RecallResult {
+	k := w.K
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+
+	byID := make(map[string]*RecallResult)
+	var order []string
+	get := func(r RecallResult) *RecallResult {
+		if f, ok := byID[r.ID]; ok {
+			return f
+		}
+		f := r
+		f.Similarity = 0
+		f.Scores = &ScoreBreakdown{}
+		byID[r.ID] = &f
+		order = append(order, r.ID)
+		return &f
+	}
+
+	for i, r := range semantic {
+		f := get(r)
+		sim := r.Similarity
+		f.Similarity = sim
+		f.Scores.Semantic = &sim
+		f.Scores.SemanticRank = i + 1
+		f.Scores.Fused += w.Semantic / float64(k+i+1)
+	}
+	for i, r := range keyword {
+		f := get(r)
+		score := r.Similarity
+		f.Scores.Keyword = &score
+		f.Scores.KeywordRank = i + 1
+		f.Scores.Fused += w.Keyword / float64(k+i+1)
+		if f.ChunkIndex == nil {
+			f.Snippet = r.Snippet
+		}
+	}
+
+	fused := make([]RecallResult, 0, len(order))
+	for _, id := range order {
+		fused = append(fused, *byID[id])
+	}
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].Scores.Fused > fused[j].Scores.Fused })
+	if len(fused) > limit {
+		fused = fused[:limit]
+	}
+	return fused
+}
+
+// RankScore is the score a result was ranked by: the fused score for hybrid
+// results, otherwise Similarity.
+func (r RecallResult) RankScore() float64 {
+	if r.Scores != nil {
+		return r.Scores.Fused
+	}
+	return r.Similarity
+}
+
+// MergeResults merges result lists for several phrasings of one query, keeping
+// each shard's best-scoring hit. Lists must come from the same search mode so
+// their scores are comparable.
+func MergeResults(lists [][]RecallResult, limit int) []RecallResult {
+	best := make(map[string]int)
+	var merged []RecallResult
+	for _, list := range lists {
+		for _, r := range list {
+			if i, ok := best[r.ID]; ok {
+				if r.RankScore() > merged[i].RankScore() {
+					merged[i] = r
+				}
+				continue
+			}
+			best[r.ID] = len(merged)
+			merged = append(merged, r)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].RankScore() > merged[j].RankScore() })
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
+
+// RecallOptions configures Recall
+type RecallOptions struct {
+	Mode          string // hybrid (default), semantic or keyword
+	Types         []string
+	Labels        []string
+	Status        []string // nil: any status
+	Since         *time.Time
+	MinSimilarity float64
+	Limit         int
+	Weights       HybridWeights
+}
+
+// Recall searches shards for query in the given mode. Semantic and hybrid
+// modes embed the query with the client's EmbedProvider; hybrid fetches deeper
+// candidate lists so shards ranked well by only one side can still make the cut,
+// and falls back to semantic search with a warning when the database predates
+// keyword_search().
+func (c *Client) Recall(ctx context.Context, query string, opts RecallOptions) ([]RecallResult, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = "hybrid"
+	}
+	if mode != "hybrid" && mode != "semantic" && mode != "keyword" {
+		return nil, invalidf("invalid recall mode %q: must be hybrid, semantic or keyword", mode)
+	}
+	if mode != "keyword" && c.EmbedProvider == nil {
+		return nil, fmt.Errorf("%s search requires embedding config", mode)
+	}
+
+	fetch := opts.Limit
+	if mode == "hybrid" {
+		fetch = min(opts.Limit*3, 1000)
+	}
+
+	var semantic, keyword []RecallResult
+	if mode != "keyword" {
+		vec, err := c.EmbedProvider.Embed(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+		semantic, err = c.SemanticSearchWithSince(ctx, vec, opts.Types, opts.Labels, opts.Status, fetch, opts.MinSimilarity, opts.Since)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if mode != "semantic" {
+		var err error
+		keyword, err = c.KeywordSearch(ctx, query, opts.Types, opts.Labels, opts.Status, fetch, opts.Since)
+		if err != nil {
+			// Databases without migration 011 have no keyword_search(); hybrid
+			// recall degrades to the semantic ranking rather than failing
+			if mode != "hybrid" || !IsUndefinedFunction(err) {
+				return nil, err
+			}
+			log.Printf("warning: keyword_search() missing (apply migration 011 with cp admin migrate up); using semantic search only")
+			return semantic[:min(len(semantic), opts.Limit)], nil
+		}
+	}
+
+	switch mode {
+	case "semantic":
+		return semantic, nil
+	case "keyword":
+		return keyword, nil
+	default:
+		return FuseRankings(semantic, keyword, opts.Weights, opts.Limit), nil
+	}
+}
@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/otherjamesbrown/context-palace/cp/internal/embedding"
+)
+
+// hits builds a ranked result list; each hit's Similarity and Snippet name
+// the list it came from
+func hits(from string, sim float64, ids ...string) []RecallResult {
+	out := make([]RecallResult, len(ids))
+	for i, id := range ids {
+		out[i] = RecallResult{ID: id, Similarity: sim, Snippet: from + ":" + id}
+	}
+	return out
+}
+
+func TestFuseRankings(t *testing.T) {
+	even := HybridWeights{Semantic: 1, Keyword: 1}
+
+	tests := []struct {
+		name     string
+		semantic []RecallResult
+		keyword  []RecallResult
+		weights  HybridWeights
+		limit    int
+		want     []string
+	}{
+		{
+			name:  "both empty",
+			limit: 10,
+			want:  []string{},
+		},
+		{
+			name:     "semantic only",
+			semantic: hits("sem", 0.9, "a", "b"),
+			weights:  even,
+			limit:    10,
+			want:     []string{"a", "b"},
+		},
+		{
+			name:    "keyword only",
+			keyword: hits("kw", 3, "c", "d"),
+			weights: even,
+			limit:   10,
+			want:    []string{"c", "d"},
+		},
+		{
+			// Equal ranks tie; the stable sort keeps semantic hits first
+			name:     "disjoint lists interleave by rank",
+			semantic: hits("sem", 0.9, "a", "b"),
+			keyword:  hits("kw", 3, "c", "d"),
+			weights:  even,
+			limit:    10,
+			want:     []string{"a", "c", "b", "d"},
+		},
+		{
+			name:     "found by both beats found by one",
+			semantic: hits("sem", 0.9, "a", "b"),
+			keyword:  hits("kw", 3, "c", "b"),
+			weights:  even,
+			limit:    10,
+			want:     []string{"b", "a", "c"},
+		},
+		{
+			name:     "mirrored ranks tie in first-seen order",
+			semantic: hits("sem", 0.9, "a", "b"),
+			keyword:  hits("kw", 3, "b", "a"),
+			weights:  even,
+			limit:    10,
+			want:     []string{"a", "b"},
+		},
+		{
+			name:     "weights break the tie",
+			semantic: hits("sem", 0.9, "a", "b"),
+			keyword:  hits("kw", 3, "b", "a"),
+			weights:  HybridWeights{Semantic: 1, Keyword: 2},
+			limit:    10,
+			want:     []string{"b", "a"},
+		},
+		{
+			name:     "limit",
+			semantic: hits("sem", 0.9, "a", "b", "c"),
+			keyword:  hits("kw", 3, "d"),
+			weights:  even,
+			limit:    2,
+			want:     []string{"a", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FuseRankings(tt.semantic, tt.keyword, tt.weights, tt.limit)
+			ids := make([]string, len(got))
+			for i, r := range got {
+				ids[i] = r.ID
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("order = %v, want %v", ids, tt.want)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].Scores.Fused > got[i-1].Scores.Fused {
+					t.Errorf("%s (%.5f) ranked below %s (%.5f)", got[i].ID, got[i].Scores.Fused, got[i-1].ID, got[i-1].Scores.Fused)
+				}
+			}
+		})
+	}
+}
+
+func TestFuseRankingsBreakdown(t *testing.T) {
+	chunk := 2
+	semantic := hits("sem", 0.8, "a", "b")
+	semantic[1].ChunkIndex = &chunk
+	keyword := hits("kw", 5, "b", "c")
+
+	got := FuseRankings(semantic, keyword, HybridWeights{Semantic: 0.7, Keyword: 0.3, K: 10}, 10)
+	byID := map[string]RecallResult{}
+	for _, r := range got {
+		byID[r.ID] = r
+	}
+
+	tests := []struct {
+		id          string
+		fused       float64
+		semRank     int
+		kwRank      int
+		similarity  float64
+		snippet     string
+		hasSemantic bool
+		hasKeyword  bool
+	}{
+		{"a", 0.7 / 11, 1, 0, 0.8, "sem:a", true, false},
+		{"b", 0.7/12 + 0.3/11, 2, 1, 0.8, "sem:b", true, true}, // chunk hit keeps its snippet
+		{"c", 0.3 / 12, 0, 2, 0, "kw:c", false, true},
+	}
+	for _, tt := range tests {
+		r, ok := byID[tt.id]
+		if !ok {
+			t.Fatalf("%s missing from %v", tt.id, got)
+		}
+		s := r.Scores
+		if math.Abs(s.Fused-tt.fused) > 1e-12 {
+			t.Errorf("%s fused = %v, want %v", tt.id, s.Fused, tt.fused)
+		}
+		if s.SemanticRank != tt.semRank || s.KeywordRank != tt.kwRank {
+			t.Errorf("%s ranks = %d/%d, want %d/%d", tt.id, s.SemanticRank, s.KeywordRank, tt.semRank, tt.kwRank)
+		}
+		if (s.Semantic != nil) != tt.hasSemantic || (s.Keyword != nil) != tt.hasKeyword {
+			t.Errorf("%s breakdown = %+v", tt.id, s)
+		}
+		if r.Similarity != tt.similarity {
+			t.Errorf("%s similarity = %v, want %v", tt.id, r.Similarity, tt.similarity)
+		}
+		if r.Snippet != tt.snippet {
+			t.Errorf("%s snippet = %q, want %q", tt.id, r.Snippet, tt.snippet)
+		}
+	}
+}
+
+func TestFuseRankingsDefaultK(t *testing.T) {
+	got := FuseRankings(hits("sem", 1, "a"), nil, HybridWeights{Semantic: 1}, 10)
+	if want := 1.0 / (DefaultRRFK + 1); got[0].Scores.Fused != want {
+		t.Errorf("fused = %v, want %v with K defaulting to %d", got[0].Scores.Fused, want, DefaultRRFK)
+	}
+}
+
+// noKeywordStore is a database from before migration 011: keyword_search()
+// does not exist
+type noKeywordStore struct {
+	Store
+	semantic []RecallResult
+}
+
+func (s noKeywordStore) SemanticSearch(ctx context.Context, queryEmbedding []float32, types []string, labels []string, status []string, limit int, minSimilarity float64, since *time.Time) ([]RecallResult, error) {
+	return s.semantic[:min(limit, len(s.semantic))], nil
+}
+
+func (s noKeywordStore) KeywordSearch(ctx context.Context, query string, types []string, labels []string, status []string, limit int, since *time.Time) ([]RecallResult, error) {
+	return nil, fmt.Errorf("keyword search failed: %w", &pgconn.PgError{Code: "42883", Message: "function keyword_search does not exist"})
+}
+
+func TestRecallWithoutKeywordSearch(t *testing.T) {
+	c := NewClientWithStore(&Config{}, noKeywordStore{semantic: hits("sem", 0.9, "a", "b", "c")})
+	c.EmbedProvider = embedding.NewHashProvider(0)
+	ctx := context.Background()
+
+	got, err := c.Recall(ctx, "query", RecallOptions{Limit: 2, Weights: HybridWeights{Semantic: 1, Keyword: 1}})
+	if err != nil {
+		t.Fatalf("hybrid: %v", err)
+	}
+	ids := []string{}
+	for _, r := range got {
+		ids = append(ids, r.ID)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(ids, want) {
+		t.Errorf("hybrid fell back to %v, want the semantic ranking %v", ids, want)
+	}
+
+	if _, err := c.Recall(ctx, "query", RecallOptions{Mode: "keyword", Limit: 2}); err == nil {
+		t.Error("keyword mode: want an error")
+	}
+}
@@ -51,7 +51,7 @@ func ValidateDocType(docType string) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("invalid doc_type '%s'. Valid types: %s", docType, strings.Join(ValidDocTypes, ", "))
+	return invalidf("invalid doc_type '%s'. Valid types: %s", docType, strings.Join(ValidDocTypes, ", "))
 }
 
 // CreateKnowledgeDoc creates a new knowledge document
@@ -64,7 +64,7 @@ func (c *Client) CreateKnowledgeDoc(ctx context.Context, title, content, docType
 	}
 	metaJSON, err := json.Marshal(meta)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal metadata: %v", err)
+		return "", fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
 	return c.CreateShardWithMetadata(ctx, title, content, "knowledge", nil, labels, json.RawMessage(metaJSON))
@@ -76,7 +76,7 @@ func (c *Client) ListKnowledgeDocs(ctx context.Context, docTypeFilter string, li
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	query := `
 		SELECT s.id, s.title, COALESCE(s.metadata->>'doc_type', ''),
@@ -100,7 +100,7 @@ func (c *Client) ListKnowledgeDocs(ctx context.Context, docTypeFilter string, li
 
 	rows, err := conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list knowledge docs: %v", err)
+		return nil, fmt.Errorf("failed to list knowledge docs: %w", err)
 	}
 	defer rows.Close()
 
@@ -123,7 +123,7 @@ func (c *Client) ShowKnowledgeDoc(ctx context.Context, id string) (*KnowledgeDoc
 		return nil, err
 	}
 	if shard.Type != "knowledge" {
-		return nil, fmt.Errorf("shard %s is type '%s', expected 'knowledge'", id, shard.Type)
+		return nil, invalidf("shard %s is type '%s', expected 'knowledge'", id, shard.Type)
 	}
 
 	doc := &KnowledgeDoc{
@@ -158,7 +158,7 @@ func (c *Client) GetKnowledgeVersion(ctx context.Context, id string, version int
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var doc KnowledgeDoc
 	var metadata json.RawMessage
@@ -169,7 +169,7 @@ func (c *Client) GetKnowledgeVersion(ctx context.Context, id string, version int
 	if err != nil {
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "not found") || strings.Contains(errMsg, "Version") {
-			return nil, fmt.Errorf("%s", extractPgMessage(errMsg))
+			return nil, pgError(err)
 		}
 		return nil, fmt.Errorf("get knowledge version: %w", err)
 	}
@@ -205,7 +205,7 @@ func (c *Client) UpdateKnowledgeDoc(ctx context.Context, id, content, summary st
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var result UpdateResult
 	err = conn.QueryRow(ctx,
@@ -215,13 +215,13 @@ func (c *Client) UpdateKnowledgeDoc(ctx context.Context, id, content, summary st
 	if err != nil {
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "not found") {
-			return nil, fmt.Errorf("knowledge document %s not found", id)
+			return nil, notFoundf("knowledge document %s not found", id)
 		}
 		if strings.Contains(errMsg, "identical") {
-			return nil, fmt.Errorf("content is identical to current version")
+			return nil, invalidf("content is identical to current version")
 		}
 		if strings.Contains(errMsg, "closed") {
-			return nil, fmt.Errorf("%s", extractPgMessage(errMsg))
+			return nil, pgError(err)
 		}
 		return nil, fmt.Errorf("update knowledge doc: %w", err)
 	}
@@ -241,7 +241,7 @@ func (c *Client) AppendKnowledgeDoc(ctx context.Context, id, content, summary st
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var result UpdateResult
 	err = conn.QueryRow(ctx,
@@ -251,10 +251,10 @@ func (c *Client) AppendKnowledgeDoc(ctx context.Context, id, content, summary st
 	if err != nil {
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "not found") {
-			return nil, fmt.Errorf("knowledge document %s not found", id)
+			return nil, notFoundf("knowledge document %s not found", id)
 		}
 		if strings.Contains(errMsg, "closed") {
-			return nil, fmt.Errorf("%s", extractPgMessage(errMsg))
+			return nil, pgError(err)
 		}
 		return nil, fmt.Errorf("append knowledge doc: %w", err)
 	}
@@ -279,14 +279,14 @@ func (c *Client) KnowledgeHistory(ctx context.Context, id string) ([]VersionEntr
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx,
 		`SELECT version, changed_at, changed_by, change_summary, shard_id FROM knowledge_history($1, $2)`,
 		id, c.Config.Project,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get knowledge history: %v", err)
+		return nil, fmt.Errorf("failed to get knowledge history: %w", err)
 	}
 	defer rows.Close()
 
@@ -294,12 +294,12 @@ func (c *Client) KnowledgeHistory(ctx context.Context, id string) ([]VersionEntr
 	for rows.Next() {
 		var e VersionEntry
 		if err := rows.Scan(&e.Version, &e.ChangedAt, &e.ChangedBy, &e.ChangeSummary, &e.ShardID); err != nil {
-			return nil, fmt.Errorf("failed to scan history entry: %v", err)
+			return nil, fmt.Errorf("failed to scan history entry: %w", err)
 		}
 		entries = append(entries, e)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("history iteration error: %v", err)
+		return nil, fmt.Errorf("history iteration error: %w", err)
 	}
 
 	return entries, nil
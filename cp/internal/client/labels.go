@@ -13,47 +13,59 @@ type LabelCount struct {
 
 // AddShardLabels adds labels to a shard atomically, returns updated labels
 func (c *Client) AddShardLabels(ctx context.Context, shardID string, labels []string) ([]string, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.AddShardLabels(ctx, shardID, labels)
+}
+
+func (pg *pgStore) AddShardLabels(ctx context.Context, shardID string, labels []string) ([]string, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var result []string
 	err = conn.QueryRow(ctx, `SELECT add_shard_labels($1, $2)`, shardID, labels).Scan(&result)
 	if err != nil {
-		return nil, fmt.Errorf("%s", extractPgMessage(err.Error()))
+		return nil, pgError(err)
 	}
 	return result, nil
 }
 
 // RemoveShardLabels removes labels from a shard atomically, returns updated labels
 func (c *Client) RemoveShardLabels(ctx context.Context, shardID string, labels []string) ([]string, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.RemoveShardLabels(ctx, shardID, labels)
+}
+
+func (pg *pgStore) RemoveShardLabels(ctx context.Context, shardID string, labels []string) ([]string, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var result []string
 	err = conn.QueryRow(ctx, `SELECT remove_shard_labels($1, $2)`, shardID, labels).Scan(&result)
 	if err != nil {
-		return nil, fmt.Errorf("%s", extractPgMessage(err.Error()))
+		return nil, pgError(err)
 	}
 	return result, nil
 }
 
 // LabelSummary returns all labels in use with their counts
 func (c *Client) LabelSummary(ctx context.Context) ([]LabelCount, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.LabelSummary(ctx)
+}
+
+func (pg *pgStore) LabelSummary(ctx context.Context) ([]LabelCount, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
-	rows, err := conn.Query(ctx, `SELECT label, shard_count FROM label_summary($1)`, c.Config.Project)
+	rows, err := conn.Query(ctx, `SELECT label, shard_count FROM label_summary($1)`, pg.cfg.Project)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get label summary: %v", err)
+		return nil, fmt.Errorf("failed to get label summary: %w", err)
 	}
 	defer rows.Close()
 
@@ -61,12 +73,12 @@ func (c *Client) LabelSummary(ctx context.Context) ([]LabelCount, error) {
 	for rows.Next() {
 		var l LabelCount
 		if err := rows.Scan(&l.Label, &l.Count); err != nil {
-			return nil, fmt.Errorf("failed to scan label: %v", err)
+			return nil, fmt.Errorf("failed to scan label: %w", err)
 		}
 		labels = append(labels, l)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("label iteration error: %v", err)
+		return nil, fmt.Errorf("label iteration error: %w", err)
 	}
 	return labels, nil
 }
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultLease is how long a claim lasts without a heartbeat when the config
+// does not set one
+const DefaultLease = 2 * time.Hour
+
+// LeaseDuration returns the configured claim lease, or DefaultLease
+func (cfg *Config) LeaseDuration() time.Duration {
+	if cfg.Lease > 0 {
+		return cfg.Lease
+	}
+	return DefaultLease
+}
+
+// lease resolves a caller's lease request: zero means the configured default
+func (c *Client) lease(d time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return c.Config.LeaseDuration()
+}
+
+// Lease is an agent's renewed claim on an in_progress shard
+type Lease struct {
+	ID          string    `json:"id"`
+	Owner       string    `json:"owner"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	ExpiresAt   time.Time `json:"lease_expires_at"`
+}
+
+// ReapedShard is an in_progress shard whose lease ran out
+type ReapedShard struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Owner     *string   `json:"owner,omitempty"`
+	ExpiredAt time.Time `json:"lease_expired_at"`
+}
+
+// Heartbeat renews the configured agent's lease on an in_progress shard it
+// owns. lease of zero uses the configured lease.
+func (c *Client) Heartbeat(ctx context.Context, shardID string, lease time.Duration) (*Lease, error) {
+	return c.store.RenewLease(ctx, shardID, c.lease(lease))
+}
+
+// ReapLeases returns in_progress shards whose lease has expired to open,
+// appending a note to each. With dryRun it only lists them.
+func (c *Client) ReapLeases(ctx context.Context, dryRun bool) ([]ReapedShard, error) {
+	return c.store.ReapLeases(ctx, dryRun)
+}
+
+func (pg *pgStore) RenewLease(ctx context.Context, shardID string, lease time.Duration) (*Lease, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	l := &Lease{ID: shardID, Owner: pg.cfg.Agent, HeartbeatAt: time.Now()}
+	err = conn.QueryRow(ctx, `SELECT shard_heartbeat($1, $2, $3, $4)`,
+		pg.cfg.Project, shardID, pg.cfg.Agent, lease).Scan(&l.ExpiresAt)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return l, nil
+}
+
+func (pg *pgStore) ReapLeases(ctx context.Context, dryRun bool) ([]ReapedShard, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT id, title, owner, lease_expires_at
+		FROM shard_reap($1, $2, $3)
+	`, pg.cfg.Project, pg.cfg.Agent, dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reap leases: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	reaped := []ReapedShard{}
+	for rows.Next() {
+		var r ReapedShard
+		if err := rows.Scan(&r.ID, &r.Title, &r.Owner, &r.ExpiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reaped shard: %w", err)
+		}
+		reaped = append(reaped, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reap iteration error: %w", err)
+	}
+	return reaped, nil
+}
@@ -4,50 +4,53 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // AssignResult holds the result of assigning a shard
 type AssignResult struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
-	Owner string `json:"owner"`
+	ID             string    `json:"id"`
+	Title          string    `json:"title"`
+	Owner          string    `json:"owner"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`
+}
+
+// AssignShard atomically claims a shard for an agent. The claim lapses after
+// lease (zero: the configured lease) unless renewed with Heartbeat.
+func (c *Client) AssignShard(ctx context.Context, shardID string, agent string, lease time.Duration) (*AssignResult, error) {
+	return c.store.AssignShard(ctx, shardID, agent, c.lease(lease))
 }
 
-// AssignShard atomically claims a shard for an agent
-func (c *Client) AssignShard(ctx context.Context, shardID string, agent string) (*AssignResult, error) {
+func (pg *pgStore) AssignShard(ctx context.Context, shardID string, agent string, lease time.Duration) (*AssignResult, error) {
 	if agent == "" {
-		agent = c.Config.Agent
+		agent = pg.cfg.Agent
 	}
 
-	conn, err := c.Connect(ctx)
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
-	var title string
-	err = conn.QueryRow(ctx, `SELECT shard_assign($1, $2, $3)`,
-		c.Config.Project, shardID, agent).Scan(&title)
+	result := &AssignResult{ID: shardID, Owner: agent}
+	err = conn.QueryRow(ctx, `SELECT title, lease_expires_at FROM shard_assign($1, $2, $3, $4)`,
+		pg.cfg.Project, shardID, agent, lease).Scan(&result.Title, &result.LeaseExpiresAt)
 	if err != nil {
-		return nil, fmt.Errorf("%s", extractPgMessage(err.Error()))
+		return nil, pgError(err)
 	}
-
-	return &AssignResult{
-		ID:    shardID,
-		Title: title,
-		Owner: agent,
-	}, nil
+	return result, nil
 }
 
 // CloseResult holds the result of closing a shard
 type CloseResult struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Status    string    `json:"status"`
-	ClosedAt  time.Time `json:"closed_at"`
-	Reason    string    `json:"reason,omitempty"`
-	Unblocked []UnblockedShard `json:"unblocked,omitempty"`
-	WasAlreadyClosed bool `json:"was_already_closed,omitempty"`
+	ID               string           `json:"id"`
+	Title            string           `json:"title"`
+	Status           string           `json:"status"`
+	ClosedAt         time.Time        `json:"closed_at"`
+	Reason           string           `json:"reason,omitempty"`
+	Unblocked        []UnblockedShard `json:"unblocked,omitempty"`
+	WasAlreadyClosed bool             `json:"was_already_closed,omitempty"`
 }
 
 // UnblockedShard represents a shard that was unblocked by a close operation
@@ -58,11 +61,15 @@ type UnblockedShard struct {
 
 // CloseShard closes a shard with an optional reason, returning unblocked info
 func (c *Client) CloseShard(ctx context.Context, shardID string, reason string) (*CloseResult, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.CloseShard(ctx, shardID, reason)
+}
+
+func (pg *pgStore) CloseShard(ctx context.Context, shardID string, reason string) (*CloseResult, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var reasonArg interface{}
 	if reason != "" {
@@ -72,9 +79,9 @@ func (c *Client) CloseShard(ctx context.Context, shardID string, reason string)
 	rows, err := conn.Query(ctx, `
 		SELECT closed_title, unblocked_id, unblocked_title
 		FROM shard_close($1, $2, $3, $4)
-	`, c.Config.Project, shardID, c.Config.Agent, reasonArg)
+	`, pg.cfg.Project, shardID, pg.cfg.Agent, reasonArg)
 	if err != nil {
-		return nil, fmt.Errorf("%s", extractPgMessage(err.Error()))
+		return nil, pgError(err)
 	}
 	defer rows.Close()
 
@@ -89,7 +96,7 @@ func (c *Client) CloseShard(ctx context.Context, shardID string, reason string)
 	for rows.Next() {
 		var closedTitle, unblockedID, unblockedTitle *string
 		if err := rows.Scan(&closedTitle, &unblockedID, &unblockedTitle); err != nil {
-			return nil, fmt.Errorf("failed to scan close result: %v", err)
+			return nil, fmt.Errorf("failed to scan close result: %w", err)
 		}
 		if first && closedTitle != nil {
 			result.Title = *closedTitle
@@ -103,7 +110,7 @@ func (c *Client) CloseShard(ctx context.Context, shardID string, reason string)
 		}
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("close result iteration error: %v", err)
+		return nil, fmt.Errorf("close result iteration error: %w", err)
 	}
 
 	return result, nil
@@ -117,15 +124,22 @@ type NextShard struct {
 	Priority  *int    `json:"priority,omitempty"`
 	EpicID    *string `json:"epic_id,omitempty"`
 	EpicTitle *string `json:"epic_title,omitempty"`
+
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"` // set by TakeShard
+}
+
+// GetNextShards returns the next unblocked open shards. With capabilities,
+// only shards whose needs: labels are all among them are returned.
+func (c *Client) GetNextShards(ctx context.Context, epicID *string, capabilities []string, limit int) ([]NextShard, error) {
+	return c.store.GetNextShards(ctx, epicID, capabilities, limit)
 }
 
-// GetNextShards returns the next unblocked open shards
-func (c *Client) GetNextShards(ctx context.Context, epicID *string, limit int) ([]NextShard, error) {
-	conn, err := c.Connect(ctx)
+func (pg *pgStore) GetNextShards(ctx context.Context, epicID *string, capabilities []string, limit int) ([]NextShard, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	if limit <= 0 {
 		limit = 1
@@ -136,10 +150,10 @@ func (c *Client) GetNextShards(ctx context.Context, epicID *string, limit int) (
 
 	rows, err := conn.Query(ctx, `
 		SELECT id, title, kind, priority, epic_id, epic_title
-		FROM shard_next($1, $2, $3)
-	`, c.Config.Project, epicID, limit)
+		FROM shard_next($1, $2, $3, $4)
+	`, pg.cfg.Project, epicID, limit, capabilities)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get next shards: %v", err)
+		return nil, fmt.Errorf("failed to get next shards: %w", err)
 	}
 	defer rows.Close()
 
@@ -148,16 +162,50 @@ func (c *Client) GetNextShards(ctx context.Context, epicID *string, limit int) (
 		var s NextShard
 		if err := rows.Scan(&s.ID, &s.Title, &s.Kind, &s.Priority,
 			&s.EpicID, &s.EpicTitle); err != nil {
-			return nil, fmt.Errorf("failed to scan next shard: %v", err)
+			return nil, fmt.Errorf("failed to scan next shard: %w", err)
 		}
 		shards = append(shards, s)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("next shard iteration error: %v", err)
+		return nil, fmt.Errorf("next shard iteration error: %w", err)
 	}
 	return shards, nil
 }
 
+// TakeShard picks the highest-priority unblocked open shard (optionally within
+// an epic and of one kind) and assigns it to agent in one step, so parallel
+// agents never claim the same shard. Returns nil when nothing is available.
+// The claim carries a lease like AssignShard's.
+func (c *Client) TakeShard(ctx context.Context, epicID *string, kind *string, agent string, lease time.Duration) (*NextShard, error) {
+	return c.store.TakeShard(ctx, epicID, kind, agent, c.lease(lease))
+}
+
+func (pg *pgStore) TakeShard(ctx context.Context, epicID *string, kind *string, agent string, lease time.Duration) (*NextShard, error) {
+	if agent == "" {
+		agent = pg.cfg.Agent
+	}
+
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var s NextShard
+	err = conn.QueryRow(ctx, `
+		SELECT id, title, kind, priority, epic_id, epic_title, lease_expires_at
+		FROM shard_take($1, $2, $3, $4, $5)
+	`, pg.cfg.Project, agent, lease, epicID, kind).Scan(&s.ID, &s.Title, &s.Kind, &s.Priority,
+		&s.EpicID, &s.EpicTitle, &s.LeaseExpiresAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take shard: %w", pgError(err))
+	}
+	return &s, nil
+}
+
 // BoardShard holds a shard in the board view
 type BoardShard struct {
 	ID         string     `json:"id"`
@@ -171,23 +219,31 @@ type BoardShard struct {
 	AssignedAt *time.Time `json:"assigned_at,omitempty"`
 	ClosedAt   *time.Time `json:"closed_at,omitempty"`
 	BlockedBy  []string   `json:"blocked_by"`
+
+	HeartbeatAt    *time.Time `json:"heartbeat_at,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
 }
 
 // GetShardBoard returns shards for the board view
 func (c *Client) GetShardBoard(ctx context.Context, epicID *string, agent *string) ([]BoardShard, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.GetShardBoard(ctx, epicID, agent)
+}
+
+func (pg *pgStore) GetShardBoard(ctx context.Context, epicID *string, agent *string) ([]BoardShard, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `
 		SELECT id, title, status, kind, owner, priority,
-			epic_id, epic_title, assigned_at, closed_at, blocked_by
+			epic_id, epic_title, assigned_at, closed_at, blocked_by,
+			heartbeat_at, lease_expires_at
 		FROM shard_board($1, $2, $3)
-	`, c.Config.Project, epicID, agent)
+	`, pg.cfg.Project, epicID, agent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get shard board: %v", err)
+		return nil, fmt.Errorf("failed to get shard board: %w", err)
 	}
 	defer rows.Close()
 
@@ -196,8 +252,9 @@ func (c *Client) GetShardBoard(ctx context.Context, epicID *string, agent *strin
 		var s BoardShard
 		if err := rows.Scan(&s.ID, &s.Title, &s.Status, &s.Kind,
 			&s.Owner, &s.Priority, &s.EpicID, &s.EpicTitle,
-			&s.AssignedAt, &s.ClosedAt, &s.BlockedBy); err != nil {
-			return nil, fmt.Errorf("failed to scan board shard: %v", err)
+			&s.AssignedAt, &s.ClosedAt, &s.BlockedBy,
+			&s.HeartbeatAt, &s.LeaseExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan board shard: %w", err)
 		}
 		if s.BlockedBy == nil {
 			s.BlockedBy = []string{}
@@ -205,7 +262,7 @@ func (c *Client) GetShardBoard(ctx context.Context, epicID *string, agent *strin
 		shards = append(shards, s)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("board iteration error: %v", err)
+		return nil, fmt.Errorf("board iteration error: %w", err)
 	}
 	return shards, nil
 }
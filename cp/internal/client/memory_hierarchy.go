@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	pgvec "github.com/pgvector/pgvector-go"
 
 	"github.com/otherjamesbrown/context-palace/cp/internal/embedding"
@@ -63,7 +64,7 @@ func (c *Client) GetMemoryTree(ctx context.Context, rootID *string) ([]MemoryTre
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var rootArg any
 	if rootID != nil {
@@ -76,7 +77,7 @@ func (c *Client) GetMemoryTree(ctx context.Context, rootID *string) ([]MemoryTre
 		FROM memory_tree($1, $2)
 	`, c.Config.Project, rootArg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get memory tree: %v", err)
+		return nil, fmt.Errorf("failed to get memory tree: %w", err)
 	}
 	defer rows.Close()
 
@@ -85,12 +86,12 @@ func (c *Client) GetMemoryTree(ctx context.Context, rootID *string) ([]MemoryTre
 		var n MemoryTreeNode
 		if err := rows.Scan(&n.ID, &n.Title, &n.ParentID, &n.Depth, &n.Status,
 			&n.Labels, &n.AccessCount, &n.LastAccessed, &n.ChildCount, &n.Summary); err != nil {
-			return nil, fmt.Errorf("failed to scan tree node: %v", err)
+			return nil, fmt.Errorf("failed to scan tree node: %w", err)
 		}
 		nodes = append(nodes, n)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("tree iteration error: %v", err)
+		return nil, fmt.Errorf("tree iteration error: %w", err)
 	}
 	return nodes, nil
 }
@@ -101,14 +102,14 @@ func (c *Client) GetMemoryChildren(ctx context.Context, parentID string) ([]Memo
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `
 		SELECT id, title, status, labels, access_count, last_accessed, child_count, content
 		FROM memory_children($1, $2)
 	`, c.Config.Project, parentID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get memory children: %v", err)
+		return nil, fmt.Errorf("failed to get memory children: %w", err)
 	}
 	defer rows.Close()
 
@@ -117,12 +118,12 @@ func (c *Client) GetMemoryChildren(ctx context.Context, parentID string) ([]Memo
 		var ch MemoryChild
 		if err := rows.Scan(&ch.ID, &ch.Title, &ch.Status, &ch.Labels,
 			&ch.AccessCount, &ch.LastAccessed, &ch.ChildCount, &ch.Content); err != nil {
-			return nil, fmt.Errorf("failed to scan memory child: %v", err)
+			return nil, fmt.Errorf("failed to scan memory child: %w", err)
 		}
 		children = append(children, ch)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("children iteration error: %v", err)
+		return nil, fmt.Errorf("children iteration error: %w", err)
 	}
 	return children, nil
 }
@@ -133,11 +134,11 @@ func (c *Client) GetMemoryPath(ctx context.Context, memoryID string) ([]MemoryPa
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `SELECT id, title, depth FROM memory_path($1)`, memoryID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get memory path: %v", err)
+		return nil, fmt.Errorf("failed to get memory path: %w", err)
 	}
 	defer rows.Close()
 
@@ -145,12 +146,12 @@ func (c *Client) GetMemoryPath(ctx context.Context, memoryID string) ([]MemoryPa
 	for rows.Next() {
 		var n MemoryPathNode
 		if err := rows.Scan(&n.ID, &n.Title, &n.Depth); err != nil {
-			return nil, fmt.Errorf("failed to scan path node: %v", err)
+			return nil, fmt.Errorf("failed to scan path node: %w", err)
 		}
 		path = append(path, n)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("path iteration error: %v", err)
+		return nil, fmt.Errorf("path iteration error: %w", err)
 	}
 	return path, nil
 }
@@ -161,14 +162,14 @@ func (c *Client) GetMemoryHot(ctx context.Context, minDepth, limit int) ([]Memor
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `
 		SELECT id, title, depth, access_count, parent_id, parent_title, parent_access_count
 		FROM memory_hot($1, $2, $3)
 	`, c.Config.Project, minDepth, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get memory hot: %v", err)
+		return nil, fmt.Errorf("failed to get memory hot: %w", err)
 	}
 	defer rows.Close()
 
@@ -177,12 +178,12 @@ func (c *Client) GetMemoryHot(ctx context.Context, minDepth, limit int) ([]Memor
 		var r MemoryHotResult
 		if err := rows.Scan(&r.ID, &r.Title, &r.Depth, &r.AccessCount,
 			&r.ParentID, &r.ParentTitle, &r.ParentAccessCount); err != nil {
-			return nil, fmt.Errorf("failed to scan hot result: %v", err)
+			return nil, fmt.Errorf("failed to scan hot result: %w", err)
 		}
 		results = append(results, r)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("hot iteration error: %v", err)
+		return nil, fmt.Errorf("hot iteration error: %w", err)
 	}
 	return results, nil
 }
@@ -211,11 +212,11 @@ func (c *Client) AddSubMemory(ctx context.Context, parentID string, opts AddSubO
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	tx, err := conn.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
@@ -225,7 +226,7 @@ func (c *Client) AddSubMemory(ctx context.Context, parentID string, opts AddSubO
 		SELECT COALESCE(content, '') FROM shards WHERE id = $1 FOR UPDATE
 	`, parentID).Scan(&parentContent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to lock parent %s: %v", parentID, err)
+		return nil, fmt.Errorf("failed to lock parent %s: %w", parentID, err)
 	}
 
 	// Create child shard
@@ -239,15 +240,24 @@ func (c *Client) AddSubMemory(ctx context.Context, parentID string, opts AddSubO
 		SELECT create_shard($1, $2, $3, $4, 'memory', $5, $6, NULL, '{}')
 	`, c.Config.Project, c.Config.Agent, opts.Title, opts.Body, labels, parentID).Scan(&childID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create child shard: %v", err)
+		return nil, fmt.Errorf("failed to create child shard: %w", err)
 	}
 
 	// Store pre-computed embedding
 	if opts.Vector != nil {
 		vec := pgvec.NewVector(opts.Vector)
-		_, err = tx.Exec(ctx, `UPDATE shards SET embedding = $1 WHERE id = $2`, vec, childID)
+		var model embedding.ModelInfo
+		if c.EmbedProvider != nil {
+			model = c.EmbedProvider.Info()
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE shards
+			SET embedding = $1,
+			    embedding_provider = NULLIF($3, ''), embedding_model = NULLIF($4, ''), embedding_dims = NULLIF($5, 0)
+			WHERE id = $2
+		`, vec, childID, model.Provider, model.Model, model.Dimensions)
 		if err != nil {
-			return nil, fmt.Errorf("failed to store embedding: %v", err)
+			return nil, fmt.Errorf("failed to store embedding: %w", err)
 		}
 	}
 
@@ -258,7 +268,7 @@ func (c *Client) AddSubMemory(ctx context.Context, parentID string, opts AddSubO
 		VALUES ($1, $2, 'child-of', $3::jsonb)
 	`, childID, parentID, edgeMeta)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create child-of edge: %v", err)
+		return nil, fmt.Errorf("failed to create child-of edge: %w", err)
 	}
 
 	// Update parent content with pointer block entry
@@ -268,18 +278,18 @@ func (c *Client) AddSubMemory(ctx context.Context, parentID string, opts AddSubO
 		Summary: opts.Summary,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update pointer block: %v", err)
+		return nil, fmt.Errorf("failed to update pointer block: %w", err)
 	}
 
 	_, err = tx.Exec(ctx, `
 		UPDATE shards SET content = $1, updated_at = now() WHERE id = $2
 	`, newParentContent, parentID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update parent content: %v", err)
+		return nil, fmt.Errorf("failed to update parent content: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit: %v", err)
+		return nil, fmt.Errorf("failed to commit: %w", err)
 	}
 
 	return &AddSubResult{
@@ -302,7 +312,7 @@ func (c *Client) DeleteMemory(ctx context.Context, memoryID string, recursive bo
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	// Fetch the shard to check parent and children
 	var parentID *string
@@ -311,10 +321,10 @@ func (c *Client) DeleteMemory(ctx context.Context, memoryID string, recursive bo
 		SELECT title, parent_id FROM shards WHERE id = $1
 	`, memoryID).Scan(&title, &parentID)
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("shard %s not found", memoryID)
+		return nil, notFoundf("shard %s not found", memoryID)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch shard: %v", err)
+		return nil, fmt.Errorf("failed to fetch shard: %w", err)
 	}
 
 	// Check for children
@@ -323,16 +333,16 @@ func (c *Client) DeleteMemory(ctx context.Context, memoryID string, recursive bo
 		SELECT count(*) FROM shards WHERE parent_id = $1 AND type = 'memory'
 	`, memoryID).Scan(&childCount)
 	if err != nil {
-		return nil, fmt.Errorf("failed to count children: %v", err)
+		return nil, fmt.Errorf("failed to count children: %w", err)
 	}
 
 	if childCount > 0 && !recursive {
-		return nil, fmt.Errorf("memory has %d children. Use --recursive to delete subtree, or move children first", childCount)
+		return nil, conflictf("memory has %d children. Use --recursive to delete subtree, or move children first", childCount)
 	}
 
 	tx, err := conn.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
@@ -342,13 +352,13 @@ func (c *Client) DeleteMemory(ctx context.Context, memoryID string, recursive bo
 	if recursive && childCount > 0 {
 		descendants, err := c.collectDescendants(ctx, conn, memoryID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to collect descendants: %v", err)
+			return nil, fmt.Errorf("failed to collect descendants: %w", err)
 		}
 		// Delete in reverse order (deepest first)
 		for i := len(descendants) - 1; i >= 0; i-- {
 			_, err = tx.Exec(ctx, `DELETE FROM shards WHERE id = $1`, descendants[i])
 			if err != nil {
-				return nil, fmt.Errorf("failed to delete %s: %v", descendants[i], err)
+				return nil, fmt.Errorf("failed to delete %s: %w", descendants[i], err)
 			}
 			deleted = append(deleted, descendants[i])
 		}
@@ -361,31 +371,31 @@ func (c *Client) DeleteMemory(ctx context.Context, memoryID string, recursive bo
 			SELECT COALESCE(content, '') FROM shards WHERE id = $1 FOR UPDATE
 		`, *parentID).Scan(&parentContent)
 		if err != nil {
-			return nil, fmt.Errorf("failed to lock parent: %v", err)
+			return nil, fmt.Errorf("failed to lock parent: %w", err)
 		}
 
 		newContent, err := pointer.RemoveSubMemory(parentContent, memoryID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update parent pointer block: %v", err)
+			return nil, fmt.Errorf("failed to update parent pointer block: %w", err)
 		}
 
 		_, err = tx.Exec(ctx, `
 			UPDATE shards SET content = $1, updated_at = now() WHERE id = $2
 		`, newContent, *parentID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update parent content: %v", err)
+			return nil, fmt.Errorf("failed to update parent content: %w", err)
 		}
 	}
 
 	// Delete the shard itself (CASCADE removes edges)
 	_, err = tx.Exec(ctx, `DELETE FROM shards WHERE id = $1`, memoryID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to delete %s: %v", memoryID, err)
+		return nil, fmt.Errorf("failed to delete %s: %w", memoryID, err)
 	}
 	deleted = append(deleted, memoryID)
 
 	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit: %v", err)
+		return nil, fmt.Errorf("failed to commit: %w", err)
 	}
 
 	result := &DeleteResult{Deleted: deleted}
@@ -396,7 +406,7 @@ func (c *Client) DeleteMemory(ctx context.Context, memoryID string, recursive bo
 }
 
 // collectDescendants returns all descendant IDs of a memory (breadth-first order).
-func (c *Client) collectDescendants(ctx context.Context, conn *pgx.Conn, parentID string) ([]string, error) {
+func (c *Client) collectDescendants(ctx context.Context, conn *pgxpool.Conn, parentID string) ([]string, error) {
 	var all []string
 	queue := []string{parentID}
 
@@ -433,14 +443,14 @@ type MoveResult struct {
 // MoveMemory re-parents a memory shard. If toRoot is true, the memory becomes a root.
 func (c *Client) MoveMemory(ctx context.Context, memoryID string, newParentID string, toRoot bool) (*MoveResult, error) {
 	if !toRoot && memoryID == newParentID {
-		return nil, fmt.Errorf("cannot move memory to itself")
+		return nil, invalidf("cannot move memory to itself")
 	}
 
 	conn, err := c.Connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	// Fetch current state
 	var oldParentID *string
@@ -449,7 +459,7 @@ func (c *Client) MoveMemory(ctx context.Context, memoryID string, newParentID st
 		SELECT title, parent_id FROM shards WHERE id = $1 AND type = 'memory'
 	`, memoryID).Scan(&memTitle, &oldParentID)
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("memory %s not found", memoryID)
+		return nil, notFoundf("memory %s not found", memoryID)
 	}
 	if err != nil {
 		return nil, err
@@ -461,19 +471,19 @@ func (c *Client) MoveMemory(ctx context.Context, memoryID string, newParentID st
 		var newParentType string
 		err = conn.QueryRow(ctx, `SELECT type FROM shards WHERE id = $1`, newParentID).Scan(&newParentType)
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("target %s not found", newParentID)
+			return nil, notFoundf("target %s not found", newParentID)
 		}
 		if newParentType != "memory" {
-			return nil, fmt.Errorf("target %s is type '%s', expected 'memory'", newParentID, newParentType)
+			return nil, invalidf("target %s is type '%s', expected 'memory'", newParentID, newParentType)
 		}
 
 		path, err := c.GetMemoryPath(ctx, newParentID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to check for cycles: %v", err)
+			return nil, fmt.Errorf("failed to check for cycles: %w", err)
 		}
 		for _, node := range path {
 			if node.ID == memoryID {
-				return nil, fmt.Errorf("cannot move to own descendant (would create cycle)")
+				return nil, invalidf("cannot move to own descendant (would create cycle)")
 			}
 		}
 	}
@@ -494,7 +504,7 @@ func (c *Client) MoveMemory(ctx context.Context, memoryID string, newParentID st
 
 	tx, err := conn.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
@@ -519,11 +529,11 @@ func (c *Client) MoveMemory(ctx context.Context, memoryID string, newParentID st
 		_ = tx.QueryRow(ctx, `SELECT COALESCE(content, '') FROM shards WHERE id = $1`, *oldParentID).Scan(&oldContent)
 		newContent, err := pointer.RemoveSubMemory(oldContent, memoryID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to remove old pointer: %v", err)
+			return nil, fmt.Errorf("failed to remove old pointer: %w", err)
 		}
 		_, err = tx.Exec(ctx, `UPDATE shards SET content = $1, updated_at = now() WHERE id = $2`, newContent, *oldParentID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update old parent: %v", err)
+			return nil, fmt.Errorf("failed to update old parent: %w", err)
 		}
 
 		// Delete old child-of edge
@@ -537,7 +547,7 @@ func (c *Client) MoveMemory(ctx context.Context, memoryID string, newParentID st
 		// Set new parent_id
 		_, err = tx.Exec(ctx, `UPDATE shards SET parent_id = $1, updated_at = now() WHERE id = $2`, newParentID, memoryID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update parent_id: %v", err)
+			return nil, fmt.Errorf("failed to update parent_id: %w", err)
 		}
 
 		// Add to new parent's pointer block
@@ -553,11 +563,11 @@ func (c *Client) MoveMemory(ctx context.Context, memoryID string, newParentID st
 			Summary: summary,
 		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to add pointer to new parent: %v", err)
+			return nil, fmt.Errorf("failed to add pointer to new parent: %w", err)
 		}
 		_, err = tx.Exec(ctx, `UPDATE shards SET content = $1, updated_at = now() WHERE id = $2`, newContent, newParentID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update new parent: %v", err)
+			return nil, fmt.Errorf("failed to update new parent: %w", err)
 		}
 
 		// Create new child-of edge with same summary
@@ -567,15 +577,15 @@ func (c *Client) MoveMemory(ctx context.Context, memoryID string, newParentID st
 			VALUES ($1, $2, 'child-of', $3::jsonb)
 		`, memoryID, newParentID, edgeMeta)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create new child-of edge: %v", err)
+			return nil, fmt.Errorf("failed to create new child-of edge: %w", err)
 		}
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to update parent_id: %v", err)
+		return nil, fmt.Errorf("failed to update parent_id: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit: %v", err)
+		return nil, fmt.Errorf("failed to commit: %w", err)
 	}
 
 	result := &MoveResult{ID: memoryID, OldParent: oldParentID}
@@ -602,7 +612,7 @@ func (c *Client) PromoteMemory(ctx context.Context, memoryID string) (*PromoteRe
 	}
 
 	if len(path) == 0 {
-		return nil, fmt.Errorf("memory %s not found", memoryID)
+		return nil, notFoundf("memory %s not found", memoryID)
 	}
 
 	// Find the memory's position in the path
@@ -615,7 +625,7 @@ func (c *Client) PromoteMemory(ctx context.Context, memoryID string) (*PromoteRe
 	}
 
 	if myDepth == 0 {
-		return nil, fmt.Errorf("memory is already at root level")
+		return nil, invalidf("memory is already at root level")
 	}
 
 	// Find grandparent (parent of parent)
@@ -675,7 +685,7 @@ func (c *Client) SyncMemoryPointers(ctx context.Context, parentID *string, dryRu
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	// Find all parents to check
 	var query string
@@ -698,7 +708,7 @@ func (c *Client) SyncMemoryPointers(ctx context.Context, parentID *string, dryRu
 
 	rows, err := conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query parents: %v", err)
+		return nil, fmt.Errorf("failed to query parents: %w", err)
 	}
 	defer rows.Close()
 
@@ -784,7 +794,7 @@ func (c *Client) SyncMemoryPointers(ctx context.Context, parentID *string, dryRu
 		if err != nil {
 			return nil, err
 		}
-		defer fixConn.Close(ctx)
+		defer fixConn.Release()
 
 		// Group discrepancies by parent
 		parentDiscs := map[string][]SyncDiscrepancy{}
@@ -11,11 +11,11 @@ func (c *Client) MemoryTouch(ctx context.Context, memoryID string, agent string,
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	_, err = conn.Exec(ctx, `SELECT memory_touch($1, $2, $3)`, memoryID, agent, depth)
 	if err != nil {
-		return fmt.Errorf("failed to touch memory %s: %v", memoryID, err)
+		return fmt.Errorf("failed to touch memory %s: %w", memoryID, err)
 	}
 	return nil
 }
@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -14,15 +15,160 @@ type Message struct {
 	Kind      *string   `json:"kind,omitempty" yaml:"kind,omitempty"`
 	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
 	Content   string    `json:"content,omitempty" yaml:"content,omitempty"`
+	To        []string  `json:"to,omitempty" yaml:"to,omitempty"`
+	Cc        []string  `json:"cc,omitempty" yaml:"cc,omitempty"`
+	Groups    []string  `json:"groups,omitempty" yaml:"groups,omitempty"` // groups it was addressed to
 }
 
-// SendMessage sends a message to recipients
-func (c *Client) SendMessage(ctx context.Context, recipients []string, subject, body string, cc []string, kind string, replyTo string) (string, error) {
-	conn, err := c.Connect(ctx)
+// GroupPrefix marks a recipient as a message group, e.g. @implementers.
+// Groups are expanded to their registered members when a message is sent.
+const GroupPrefix = "@"
+
+// GroupAll is the group of every registered agent serving the project
+const GroupAll = "all"
+
+// setAddressing fills To, Cc, Groups and Kind from a message's to:, cc:,
+// group: and kind: labels
+func (m *Message) setAddressing(labels []string) {
+	for _, l := range labels {
+		switch {
+		case strings.HasPrefix(l, "to:"):
+			m.To = append(m.To, strings.TrimPrefix(l, "to:"))
+		case strings.HasPrefix(l, "cc:"):
+			m.Cc = append(m.Cc, strings.TrimPrefix(l, "cc:"))
+		case strings.HasPrefix(l, "group:"):
+			m.Groups = append(m.Groups, GroupPrefix+strings.TrimPrefix(l, "group:"))
+		case strings.HasPrefix(l, "kind:") && m.Kind == nil:
+			kind := l
+			m.Kind = &kind
+		}
+	}
+}
+
+// SendResult is a sent message and any warnings about its recipients
+type SendResult struct {
+	ID       string   `json:"id"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// SendMessage sends a message to recipients. Group recipients (@name) in
+// recipients or cc are expanded to their members, and the message is
+// labelled group:<name> for each group. Recipients that are not registered
+// agents serving the project are reported in the result's warnings; the
+// message is sent regardless.
+//
+// A message of kind request expects a reply from each recipient; see
+// SendRequest.
+func (c *Client) SendMessage(ctx context.Context, recipients []string, subject, body string, cc []string, kind string, replyTo string) (*SendResult, error) {
+	return c.sendMessage(ctx, recipients, subject, body, cc, kind, replyTo, nil)
+}
+
+func (c *Client) sendMessage(ctx context.Context, recipients []string, subject, body string, cc []string, kind string, replyTo string, due *time.Time) (*SendResult, error) {
+	warnings := c.recipientWarnings(ctx, append(append([]string{}, recipients...), cc...))
+	recipients, cc, groups, err := c.ExpandGroups(ctx, recipients, cc)
+	if err != nil {
+		return nil, err
+	}
+	var labels []string
+	for _, g := range groups {
+		labels = append(labels, "group:"+strings.TrimPrefix(g, GroupPrefix))
+	}
+	if kind != RequestKind {
+		due = nil
+	}
+	id, err := c.store.SendMessage(ctx, recipients, subject, body, cc, kind, replyTo, labels, due)
+	if err != nil {
+		return nil, err
+	}
+	return &SendResult{ID: id, Warnings: warnings}, nil
+}
+
+// recipientWarnings describes the recipients UnknownRecipients reports. A
+// registry lookup failure is only a warning: it must not stop the message.
+func (c *Client) recipientWarnings(ctx context.Context, recipients []string) []string {
+	unknown, err := c.UnknownRecipients(ctx, recipients)
+	if err != nil {
+		return []string{fmt.Sprintf("recipients not checked: %v", err)}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	return []string{"unknown recipients: " + FormatUnknownRecipients(unknown)}
+}
+
+// ExpandGroups replaces @group recipients with the registered agents in the
+// group that serve this project, leaving out the sender. Agents already in
+// to are dropped from cc. groups lists the groups that were expanded.
+func (c *Client) ExpandGroups(ctx context.Context, to, cc []string) (outTo, outCc, groups []string, err error) {
+	hasGroup := false
+	for _, r := range append(append([]string{}, to...), cc...) {
+		if strings.HasPrefix(r, GroupPrefix) {
+			hasGroup = true
+			break
+		}
+	}
+	if !hasGroup {
+		return to, cc, nil, nil
+	}
+
+	agents, err := c.ListAgents(ctx, false, "", "")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	expand := func(names []string, skip []string) ([]string, error) {
+		var out []string
+		add := func(n string) {
+			if !containsString(out, n) && !containsString(skip, n) {
+				out = append(out, n)
+			}
+		}
+		for _, n := range names {
+			if !strings.HasPrefix(n, GroupPrefix) {
+				add(n)
+				continue
+			}
+			group := strings.TrimPrefix(n, GroupPrefix)
+			found := false
+			for _, a := range agents {
+				if a.InGroup(group) && a.ID != c.Config.Agent {
+					add(a.ID)
+					found = true
+				}
+			}
+			if !found {
+				return nil, invalidf("group %s has no registered members serving project %s besides you (see cp agent list --group %s)", n, c.Config.Project, group)
+			}
+			if !containsString(groups, n) {
+				groups = append(groups, n)
+			}
+		}
+		return out, nil
+	}
+
+	if outTo, err = expand(to, nil); err != nil {
+		return nil, nil, nil, err
+	}
+	if outCc, err = expand(cc, outTo); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(outTo) == 0 {
+		return nil, nil, nil, invalidf("no recipients left after expanding groups")
+	}
+	return outTo, outCc, groups, nil
+}
+
+func (pg *pgStore) SendMessage(ctx context.Context, recipients []string, subject, body string, cc []string, kind string, replyTo string, labels []string, due *time.Time) (string, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return "", err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
 	var ccArg interface{}
 	if len(cc) > 0 {
@@ -40,31 +186,146 @@ func (c *Client) SendMessage(ctx context.Context, recipients []string, subject,
 	}
 
 	var newID string
-	err = conn.QueryRow(ctx,
+	err = tx.QueryRow(ctx,
 		`SELECT send_message($1, $2, $3, $4, $5, $6, $7, $8)`,
-		c.Config.Project, c.Config.Agent, recipients, subject, body,
+		pg.cfg.Project, pg.cfg.Agent, recipients, subject, body,
 		ccArg, kindArg, replyToArg,
 	).Scan(&newID)
 	if err != nil {
-		return "", fmt.Errorf("failed to send message: %v", err)
+		return "", fmt.Errorf("failed to send message: %w", err)
+	}
+
+	if len(labels) > 0 {
+		if _, err := tx.Exec(ctx, `SELECT add_shard_labels($1, $2)`, newID, labels); err != nil {
+			return "", fmt.Errorf("failed to label message: %w", pgError(err))
+		}
+	}
+
+	if kind == RequestKind {
+		var dueArg any
+		if due != nil {
+			dueArg = due.UTC().Format(time.RFC3339)
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE shards
+			SET metadata = COALESCE(metadata, '{}'::jsonb)
+				|| jsonb_build_object('expects_reply', true)
+				|| CASE WHEN $2::text IS NULL THEN '{}'::jsonb ELSE jsonb_build_object('due_at', $2::text) END
+			WHERE id = $1
+		`, newID, dueArg)
+		if err != nil {
+			return "", fmt.Errorf("failed to mark request: %w", pgError(err))
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit message: %w", err)
 	}
 	return newID, nil
 }
 
+// SentMessage is a message the agent sent, with each recipient's read status
+type SentMessage struct {
+	Message
+	Receipts []Receipt `json:"receipts"`
+	Read     int       `json:"read"` // recipients who have read it
+}
+
+// Unread reports whether any recipient has not read the message
+func (m *SentMessage) Unread() bool {
+	return m.Read < len(m.Receipts)
+}
+
+// ListSent returns the configured agent's most recent sent messages, newest
+// first, with read receipts for every recipient. unreadOnly keeps messages
+// that someone has yet to read.
+func (c *Client) ListSent(ctx context.Context, limit int, unreadOnly bool) ([]SentMessage, error) {
+	sent, err := c.store.ListSent(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := []SentMessage{}
+	for _, m := range sent {
+		m.Receipts = addressedReceipts(m.Message, m.Receipts)
+		m.Read = 0
+		for _, r := range m.Receipts {
+			if r.ReadAt != nil {
+				m.Read++
+			}
+		}
+		if unreadOnly && !m.Unread() {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func (pg *pgStore) ListSent(ctx context.Context, limit int) ([]SentMessage, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT id, title, created_at, labels FROM sent_by($1, $2, $3)`,
+		pg.cfg.Project, pg.cfg.Agent, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sent messages: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var sent []SentMessage
+	for rows.Next() {
+		m := SentMessage{Message: Message{Creator: pg.cfg.Agent}}
+		var labels []string
+		if err := rows.Scan(&m.ID, &m.Title, &m.CreatedAt, &labels); err != nil {
+			return nil, fmt.Errorf("failed to scan sent message: %w", err)
+		}
+		m.setAddressing(labels)
+		sent = append(sent, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sent message iteration error: %w", err)
+	}
+	if len(sent) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(sent))
+	for i, m := range sent {
+		ids[i] = m.ID
+	}
+	receipts, err := readReceipts(ctx, conn, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sent {
+		sent[i].Receipts = receipts[sent[i].ID]
+	}
+	return sent, nil
+}
+
 // GetInbox returns unread messages for the configured agent
 func (c *Client) GetInbox(ctx context.Context) ([]Message, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.GetInbox(ctx)
+}
+
+func (pg *pgStore) GetInbox(ctx context.Context) ([]Message, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx,
 		`SELECT id, title, creator, kind, created_at FROM unread_for($1, $2)`,
-		c.Config.Project, c.Config.Agent,
+		pg.cfg.Project, pg.cfg.Agent,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get inbox: %v", err)
+		return nil, fmt.Errorf("failed to get inbox: %w", err)
 	}
 	defer rows.Close()
 
@@ -85,30 +346,36 @@ func (c *Client) GetMessage(ctx context.Context, id string) (*Message, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Message{
+	m := &Message{
 		ID:        shard.ID,
 		Title:     shard.Title,
 		Creator:   shard.Creator,
 		Content:   shard.Content,
 		CreatedAt: shard.CreatedAt,
-	}, nil
+	}
+	m.setAddressing(shard.Labels)
+	return m, nil
 }
 
 // MarkRead marks messages as read for the configured agent
 func (c *Client) MarkRead(ctx context.Context, shardIDs []string) (int, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.MarkRead(ctx, shardIDs)
+}
+
+func (pg *pgStore) MarkRead(ctx context.Context, shardIDs []string) (int, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return 0, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var count int
 	err = conn.QueryRow(ctx,
 		`SELECT mark_read($1, $2)`,
-		shardIDs, c.Config.Agent,
+		shardIDs, pg.cfg.Agent,
 	).Scan(&count)
 	if err != nil {
-		return 0, fmt.Errorf("failed to mark as read: %v", err)
+		return 0, fmt.Errorf("failed to mark as read: %w", err)
 	}
 	return count, nil
 }
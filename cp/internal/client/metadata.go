@@ -16,7 +16,7 @@ func (c *Client) GetMetadata(ctx context.Context, id string) (json.RawMessage, e
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var meta json.RawMessage
 	err = conn.QueryRow(ctx, `
@@ -24,10 +24,10 @@ func (c *Client) GetMetadata(ctx context.Context, id string) (json.RawMessage, e
 	`, id).Scan(&meta)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("shard not found: %s", id)
+		return nil, notFoundf("shard not found: %s", id)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get metadata: %v", err)
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
 	}
 	return meta, nil
 }
@@ -39,10 +39,10 @@ func (c *Client) GetMetadataField(ctx context.Context, id string, path []string)
 	if err != nil {
 		return "", err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	if len(path) == 0 {
-		return "", fmt.Errorf("empty field path")
+		return "", invalidf("empty field path")
 	}
 
 	var value *string
@@ -51,13 +51,13 @@ func (c *Client) GetMetadataField(ctx context.Context, id string, path []string)
 	`, id, path).Scan(&value)
 
 	if err == pgx.ErrNoRows {
-		return "", fmt.Errorf("shard not found: %s", id)
+		return "", notFoundf("shard not found: %s", id)
 	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get metadata field: %v", err)
+		return "", fmt.Errorf("failed to get metadata field: %w", err)
 	}
 	if value == nil {
-		return "", fmt.Errorf("field not found: %s", strings.Join(path, "."))
+		return "", notFoundf("field not found: %s", strings.Join(path, "."))
 	}
 	return *value, nil
 }
@@ -68,15 +68,15 @@ func (c *Client) UpdateMetadata(ctx context.Context, id string, patch json.RawMe
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var result json.RawMessage
 	err = conn.QueryRow(ctx, `SELECT update_metadata($1, $2)`, id, patch).Scan(&result)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
-			return nil, fmt.Errorf("shard not found: %s", id)
+			return nil, notFoundf("shard not found: %s", id)
 		}
-		return nil, fmt.Errorf("failed to update metadata: %v", err)
+		return nil, fmt.Errorf("failed to update metadata: %w", err)
 	}
 	return result, nil
 }
@@ -87,15 +87,15 @@ func (c *Client) SetMetadataPath(ctx context.Context, id string, path []string,
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var result json.RawMessage
 	err = conn.QueryRow(ctx, `SELECT set_metadata_path($1, $2, $3)`, id, path, value).Scan(&result)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
-			return nil, fmt.Errorf("shard not found: %s", id)
+			return nil, notFoundf("shard not found: %s", id)
 		}
-		return nil, fmt.Errorf("failed to set metadata path: %v", err)
+		return nil, fmt.Errorf("failed to set metadata path: %w", err)
 	}
 	return result, nil
 }
@@ -106,15 +106,15 @@ func (c *Client) DeleteMetadataKey(ctx context.Context, id, key string) (json.Ra
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var result json.RawMessage
 	err = conn.QueryRow(ctx, `SELECT delete_metadata_key($1, $2)`, id, key).Scan(&result)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
-			return nil, fmt.Errorf("shard not found: %s", id)
+			return nil, notFoundf("shard not found: %s", id)
 		}
-		return nil, fmt.Errorf("failed to delete metadata key: %v", err)
+		return nil, fmt.Errorf("failed to delete metadata key: %w", err)
 	}
 	return result, nil
 }
@@ -125,7 +125,7 @@ func (c *Client) QueryByMetadata(ctx context.Context, shardType string, metaFilt
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	query := `
 		SELECT id, project, title, COALESCE(LEFT(content, 200), ''), type, status,
@@ -145,7 +145,7 @@ func (c *Client) QueryByMetadata(ctx context.Context, shardType string, metaFilt
 	if len(metaFilters) > 0 {
 		filterJSON, err := json.Marshal(metaFilters)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal metadata filter: %v", err)
+			return nil, fmt.Errorf("failed to marshal metadata filter: %w", err)
 		}
 		query += fmt.Sprintf(` AND metadata @> $%d`, paramIdx)
 		args = append(args, string(filterJSON))
@@ -157,7 +157,7 @@ func (c *Client) QueryByMetadata(ctx context.Context, shardType string, metaFilt
 
 	rows, err := conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query shards by metadata: %v", err)
+		return nil, fmt.Errorf("failed to query shards by metadata: %w", err)
 	}
 	defer rows.Close()
 
@@ -189,7 +189,7 @@ func parseMetaFlag(s string) (map[string]interface{}, error) {
 	if strings.HasPrefix(s, "{") {
 		var result map[string]interface{}
 		if err := json.Unmarshal([]byte(s), &result); err != nil {
-			return nil, fmt.Errorf("invalid JSON: %v", err)
+			return nil, invalidf("invalid JSON: %v", err)
 		}
 		return result, nil
 	}
@@ -202,7 +202,7 @@ func parseMetaFlag(s string) (map[string]interface{}, error) {
 		}, nil
 	}
 
-	return nil, fmt.Errorf("invalid metadata format. Use key=value or JSON")
+	return nil, invalidf("invalid metadata format. Use key=value or JSON")
 }
 
 // inferJSONValue detects the type of a CLI string value.
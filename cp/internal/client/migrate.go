@@ -0,0 +1,317 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otherjamesbrown/context-palace/cp/migrations"
+)
+
+// migrationLockID is the advisory lock key held while migrating, so two
+// `cp admin migrate up` runs against the same database can't interleave
+const migrationLockID = 0x63705f6d6967 // "cp_mig"
+
+// Migration is one embedded schema migration
+type Migration struct {
+	Version  int    `json:"version"`
+	Name     string `json:"name"`
+	Up       string `json:"-"`
+	Down     string `json:"-"` // empty = irreversible
+	Checksum string `json:"checksum"`
+}
+
+// Reversible reports whether the migration has a down script
+func (m Migration) Reversible() bool { return m.Down != "" }
+
+// MigrationStatus is a migration and whether it has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"` // applied from different SQL than is embedded now
+}
+
+// LoadMigrations returns the embedded migrations ordered by version
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		down := strings.HasSuffix(name, ".down.sql")
+		base := strings.TrimSuffix(strings.TrimSuffix(name, ".sql"), ".down")
+		num, label, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		sql, err := fs.ReadFile(migrations.FS, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version}
+			byVersion[version] = m
+		}
+		if m.Name != "" && m.Name != label {
+			return nil, fmt.Errorf("migration %03d has two names: %s and %s", version, m.Name, label)
+		}
+		m.Name = label
+		if down {
+			m.Down = string(sql)
+		} else {
+			m.Up = string(sql)
+			sum := sha256.Sum256(sql)
+			m.Checksum = hex.EncodeToString(sum[:])
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has a down script but no up script", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// ensureMigrationsTable creates schema_migrations if needed
+func ensureMigrationsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			checksum   TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// migrationStatus reads applied versions and merges them with the embedded set
+func migrationStatus(ctx context.Context, conn *pgxpool.Conn, all []Migration) ([]MigrationStatus, error) {
+	type applied struct {
+		checksum string
+		at       time.Time
+	}
+	done := map[int]applied{}
+	rows, err := conn.Query(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	for rows.Next() {
+		var v int
+		var a applied
+		if err := rows.Scan(&v, &a.checksum, &a.at); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		done[v] = a
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]MigrationStatus, len(all))
+	for i, m := range all {
+		out[i] = MigrationStatus{Migration: m}
+		if a, ok := done[m.Version]; ok {
+			at := a.at
+			out[i].Applied = true
+			out[i].AppliedAt = &at
+			out[i].Modified = a.checksum != m.Checksum
+		}
+	}
+	return out, nil
+}
+
+// GetMigrationStatus lists every embedded migration and whether it is applied
+func (c *Client) GetMigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	all, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	return migrationStatus(ctx, conn, all)
+}
+
+// withMigrationLock runs fn holding the migration advisory lock
+func (c *Client) withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn, status []MigrationStatus) error) error {
+	all, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	status, err := migrationStatus(ctx, conn, all)
+	if err != nil {
+		return err
+	}
+	return fn(conn, status)
+}
+
+// MigrateUp applies pending migrations up to and including target (0 = all),
+// each in its own transaction, calling onApply after each. It stops at the
+// first failure; migrations applied before it stay applied. With dryRun the
+// pending migrations are returned without running them.
+func (c *Client) MigrateUp(ctx context.Context, target int, dryRun bool, onApply func(Migration, time.Duration)) ([]Migration, error) {
+	var applied []Migration
+	err := c.withMigrationLock(ctx, func(conn *pgxpool.Conn, status []MigrationStatus) error {
+		for _, s := range status {
+			if s.Applied {
+				continue
+			}
+			if target > 0 && s.Version > target {
+				break
+			}
+			if dryRun {
+				applied = append(applied, s.Migration)
+				continue
+			}
+
+			start := time.Now()
+			tx, err := conn.Begin(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			if _, err := tx.Exec(ctx, s.Up); err != nil {
+				tx.Rollback(ctx)
+				return fmt.Errorf("migration %03d_%s failed: %w", s.Version, s.Name, pgError(err))
+			}
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)
+			`, s.Version, s.Name, s.Checksum); err != nil {
+				tx.Rollback(ctx)
+				return fmt.Errorf("failed to record migration %03d: %w", s.Version, err)
+			}
+			if err := tx.Commit(ctx); err != nil {
+				return fmt.Errorf("failed to commit migration %03d: %w", s.Version, err)
+			}
+			applied = append(applied, s.Migration)
+			if onApply != nil {
+				onApply(s.Migration, time.Since(start))
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the latest steps applied migrations, newest first. It
+// refuses to start if any of them has no down script.
+func (c *Client) MigrateDown(ctx context.Context, steps int, dryRun bool, onRevert func(Migration, time.Duration)) ([]Migration, error) {
+	var reverted []Migration
+	err := c.withMigrationLock(ctx, func(conn *pgxpool.Conn, status []MigrationStatus) error {
+		var todo []Migration
+		for i := len(status) - 1; i >= 0 && len(todo) < steps; i-- {
+			if status[i].Applied {
+				todo = append(todo, status[i].Migration)
+			}
+		}
+		for _, m := range todo {
+			if !m.Reversible() {
+				return fmt.Errorf("migration %03d_%s is irreversible (no %03d_%s.down.sql)", m.Version, m.Name, m.Version, m.Name)
+			}
+		}
+		if dryRun {
+			reverted = todo
+			return nil
+		}
+
+		for _, m := range todo {
+			start := time.Now()
+			tx, err := conn.Begin(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			if _, err := tx.Exec(ctx, m.Down); err != nil {
+				tx.Rollback(ctx)
+				return fmt.Errorf("reverting %03d_%s failed: %w", m.Version, m.Name, pgError(err))
+			}
+			if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+				tx.Rollback(ctx)
+				return fmt.Errorf("failed to unrecord migration %03d: %w", m.Version, err)
+			}
+			if err := tx.Commit(ctx); err != nil {
+				return fmt.Errorf("failed to commit revert of %03d: %w", m.Version, err)
+			}
+			reverted = append(reverted, m)
+			if onRevert != nil {
+				onRevert(m, time.Since(start))
+			}
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// MigrateBaseline records every migration up to version as applied without
+// running it, for databases whose schema was installed by hand. Returns the
+// migrations newly recorded.
+func (c *Client) MigrateBaseline(ctx context.Context, version int) ([]Migration, error) {
+	var recorded []Migration
+	err := c.withMigrationLock(ctx, func(conn *pgxpool.Conn, status []MigrationStatus) error {
+		known := false
+		for _, s := range status {
+			if s.Version == version {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("no migration %03d", version)
+		}
+
+		for _, s := range status {
+			if s.Version > version || s.Applied {
+				continue
+			}
+			if _, err := conn.Exec(ctx, `
+				INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)
+			`, s.Version, s.Name, s.Checksum); err != nil {
+				return fmt.Errorf("failed to record migration %03d: %w", s.Version, err)
+			}
+			recorded = append(recorded, s.Migration)
+		}
+		return nil
+	})
+	return recorded, err
+}
@@ -0,0 +1,326 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hoursPerDay is the length of a "d" in estimate metadata: a working day
+const hoursPerDay = 8
+
+// PlanTask is one unfinished epic child in a schedule. Times are in estimate
+// hours from now.
+type PlanTask struct {
+	ID               string   `json:"id"`
+	Title            string   `json:"title"`
+	Status           string   `json:"status"`
+	Owner            *string  `json:"owner,omitempty"`
+	Estimate         float64  `json:"estimate"`
+	Estimated        bool     `json:"estimated"`                   // estimate came from metadata, not the default
+	BlockedBy        []string `json:"blocked_by"`                  // unfinished blockers inside the epic
+	ExternalBlockers []string `json:"external_blockers,omitempty"` // open blockers outside the epic
+	EarliestStart    float64  `json:"earliest_start"`
+	EarliestFinish   float64  `json:"earliest_finish"`
+	LatestStart      float64  `json:"latest_start"`
+	LatestFinish     float64  `json:"latest_finish"`
+	Slack            float64  `json:"slack"`
+	Critical         bool     `json:"critical"`
+	Ready            bool     `json:"ready"` // open with no open blockers: can be claimed now
+}
+
+// EpicPlan is a critical-path schedule of an epic's unfinished children
+type EpicPlan struct {
+	EpicID       string     `json:"epic_id"`
+	Title        string     `json:"title"`
+	Tasks        []PlanTask `json:"tasks"`         // ordered by earliest start, then slack
+	CriticalPath []string   `json:"critical_path"` // longest blocking chain, first to last
+	Length       float64    `json:"length"`        // estimate hours until the epic can finish
+	Ready        []string   `json:"ready"`         // can start now
+	Running      []string   `json:"running"`       // in progress
+	MaxParallel  int        `json:"max_parallel"`  // most tasks running at once if each starts as early as possible
+	Done         int        `json:"done"`
+	Unestimated  int        `json:"unestimated"` // tasks using the default estimate
+}
+
+// ParseEstimate reads an estimate metadata value as hours. Numbers are hours;
+// strings may be a number or carry an m, h, d (8h working day) or w (5d) suffix.
+// Negative, NaN and infinite estimates are invalid.
+func ParseEstimate(v any) (float64, error) {
+	switch x := v.(type) {
+	case float64:
+		if x < 0 || math.IsNaN(x) || math.IsInf(x, 0) {
+			return 0, invalidf("invalid estimate %v", x)
+		}
+		return x, nil
+	case string:
+		s := strings.TrimSpace(strings.ToLower(x))
+		mult := 1.0
+		switch {
+		case strings.HasSuffix(s, "m"):
+			mult, s = 1.0/60, strings.TrimSuffix(s, "m")
+		case strings.HasSuffix(s, "h"):
+			s = strings.TrimSuffix(s, "h")
+		case strings.HasSuffix(s, "d"):
+			mult, s = hoursPerDay, strings.TrimSuffix(s, "d")
+		case strings.HasSuffix(s, "w"):
+			mult, s = 5*hoursPerDay, strings.TrimSuffix(s, "w")
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+			return 0, invalidf("invalid estimate %q", x)
+		}
+		return n * mult, nil
+	}
+	return 0, invalidf("invalid estimate %v", v)
+}
+
+// PlanEpic schedules an epic's unfinished children over the blocking edges
+// between them (critical path method). Each task takes its `estimate`
+// metadata, or defaultEstimate hours when it has none. Requires the postgres
+// backend.
+func (c *Client) PlanEpic(ctx context.Context, epicID string, defaultEstimate float64) (*EpicPlan, error) {
+	epic, err := c.GetShard(ctx, epicID)
+	if err != nil {
+		return nil, err
+	}
+	if epic.Type != "epic" {
+		return nil, invalidf("Shard %s is type '%s', expected 'epic'", epicID, epic.Type)
+	}
+	children, err := c.GetEpicChildren(ctx, epicID)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := c.EdgeTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &EpicPlan{EpicID: epicID, Title: epic.Title, Tasks: []PlanTask{},
+		CriticalPath: []string{}, Ready: []string{}, Running: []string{}}
+	index := map[string]int{}
+	for _, ch := range children {
+		if ch.Status == "closed" {
+			plan.Done++
+			continue
+		}
+		t := PlanTask{ID: ch.ID, Title: ch.Title, Status: ch.Status, Owner: ch.Owner,
+			Estimate: defaultEstimate, BlockedBy: []string{}}
+
+		sh, err := c.GetShard(ctx, ch.ID)
+		if err != nil {
+			return nil, err
+		}
+		var meta map[string]any
+		_ = json.Unmarshal(sh.Metadata, &meta)
+		if t.Estimate, t.Estimated, err = taskEstimate(meta, defaultEstimate); err != nil {
+			return nil, fmt.Errorf("%s: %w", ch.ID, err)
+		}
+		if !t.Estimated {
+			plan.Unestimated++
+		}
+		index[t.ID] = len(plan.Tasks)
+		plan.Tasks = append(plan.Tasks, t)
+	}
+
+	// Blocking edges: inside the epic they order the schedule, outside they
+	// only keep a task from being ready
+	blocking := reg.Blocking()
+	for i := range plan.Tasks {
+		t := &plan.Tasks[i]
+		edges, err := c.GetShardEdges(ctx, t.ID, "outgoing", blocking)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range edges {
+			if e.Status == "closed" || containsString(t.BlockedBy, e.ShardID) || containsString(t.ExternalBlockers, e.ShardID) {
+				continue
+			}
+			if _, ok := index[e.ShardID]; ok {
+				t.BlockedBy = append(t.BlockedBy, e.ShardID)
+			} else {
+				t.ExternalBlockers = append(t.ExternalBlockers, e.ShardID)
+			}
+		}
+		t.Ready = t.Status == "open" && len(t.BlockedBy) == 0 && len(t.ExternalBlockers) == 0
+	}
+
+	if err := schedulePlan(plan, index); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// taskEstimate returns the hours in a task's estimate metadata, or
+// defaultEstimate and false when it has none
+func taskEstimate(meta map[string]any, defaultEstimate float64) (float64, bool, error) {
+	v, ok := meta["estimate"]
+	if !ok {
+		return defaultEstimate, false, nil
+	}
+	est, err := ParseEstimate(v)
+	if err != nil {
+		return 0, false, err
+	}
+	return est, true, nil
+}
+
+// schedulePlan fills in the schedule of plan's tasks, whose estimates and
+// BlockedBy are set (index maps task IDs to positions in plan.Tasks): earliest
+// and latest times, slack, the critical path, and the summary lists. Tasks
+// end up ordered by earliest start, then slack.
+func schedulePlan(plan *EpicPlan, index map[string]int) error {
+	order, err := planOrder(plan.Tasks, index)
+	if err != nil {
+		return err
+	}
+
+	// Forward pass: earliest start is when the last blocker finishes
+	for _, i := range order {
+		t := &plan.Tasks[i]
+		for _, b := range t.BlockedBy {
+			t.EarliestStart = max(t.EarliestStart, plan.Tasks[index[b]].EarliestFinish)
+		}
+		t.EarliestFinish = t.EarliestStart + t.Estimate
+		plan.Length = max(plan.Length, t.EarliestFinish)
+	}
+
+	// Backward pass: latest finish is when the first dependent must start
+	for i := range plan.Tasks {
+		plan.Tasks[i].LatestFinish = plan.Length
+	}
+	for k := len(order) - 1; k >= 0; k-- {
+		t := &plan.Tasks[order[k]]
+		t.LatestStart = t.LatestFinish - t.Estimate
+		for _, b := range t.BlockedBy {
+			bt := &plan.Tasks[index[b]]
+			bt.LatestFinish = min(bt.LatestFinish, t.LatestStart)
+		}
+	}
+	const eps = 1e-9
+	for i := range plan.Tasks {
+		t := &plan.Tasks[i]
+		t.Slack = t.LatestStart - t.EarliestStart
+		if t.Slack < eps {
+			t.Slack = 0
+		}
+		t.Critical = t.Slack == 0
+	}
+
+	// Critical path: walk back from the task that finishes last through the
+	// blocker that finishes exactly when it can start
+	var last *PlanTask
+	for i := range plan.Tasks {
+		if t := &plan.Tasks[i]; last == nil || t.EarliestFinish > last.EarliestFinish {
+			last = t
+		}
+	}
+	for t := last; t != nil; {
+		plan.CriticalPath = append([]string{t.ID}, plan.CriticalPath...)
+		var prev *PlanTask
+		for _, b := range t.BlockedBy {
+			bt := &plan.Tasks[index[b]]
+			if bt.EarliestFinish >= t.EarliestStart-eps && (prev == nil || bt.Estimate > prev.Estimate) {
+				prev = bt
+			}
+		}
+		t = prev
+	}
+
+	plan.MaxParallel = maxOverlap(plan.Tasks)
+	for _, t := range plan.Tasks {
+		if t.Ready {
+			plan.Ready = append(plan.Ready, t.ID)
+		}
+		if t.Status == "in_progress" {
+			plan.Running = append(plan.Running, t.ID)
+		}
+	}
+
+	sort.SliceStable(plan.Tasks, func(i, j int) bool {
+		a, b := plan.Tasks[i], plan.Tasks[j]
+		if a.EarliestStart != b.EarliestStart {
+			return a.EarliestStart < b.EarliestStart
+		}
+		return a.Slack < b.Slack
+	})
+	return nil
+}
+
+// planOrder returns task indexes in topological order (blockers first)
+func planOrder(tasks []PlanTask, index map[string]int) ([]int, error) {
+	pending := make([]int, len(tasks))
+	dependents := make([][]int, len(tasks))
+	for i, t := range tasks {
+		pending[i] = len(t.BlockedBy)
+		for _, b := range t.BlockedBy {
+			dependents[index[b]] = append(dependents[index[b]], i)
+		}
+	}
+	var queue, order []int
+	for i := range tasks {
+		if pending[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		order = append(order, i)
+		for _, d := range dependents[i] {
+			if pending[d]--; pending[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+	if len(order) < len(tasks) {
+		var stuck []string
+		for i, n := range pending {
+			if n > 0 {
+				stuck = append(stuck, tasks[i].ID)
+			}
+		}
+		return nil, conflictf("blocking cycle among %s; see 'cp graph export' to find it", strings.Join(stuck, ", "))
+	}
+	return order, nil
+}
+
+// maxOverlap returns the most tasks whose [earliest start, earliest finish)
+// intervals overlap. Zero-length tasks don't occupy an agent.
+func maxOverlap(tasks []PlanTask) int {
+	type point struct {
+		at    float64
+		delta int
+	}
+	var points []point
+	for _, t := range tasks {
+		if t.Estimate > 0 {
+			points = append(points, point{t.EarliestStart, 1}, point{t.EarliestFinish, -1})
+		}
+	}
+	// Finishes sort before starts at the same instant
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].at != points[j].at {
+			return points[i].at < points[j].at
+		}
+		return points[i].delta < points[j].delta
+	})
+	cur, peak := 0, 0
+	for _, p := range points {
+		cur += p.delta
+		peak = max(peak, cur)
+	}
+	return peak
+}
+
+// FormatHours renders an estimate in hours compactly ("30m", "4h", "2.5h")
+func FormatHours(h float64) string {
+	if h > 0 && h < 1 {
+		return fmt.Sprintf("%.0fm", h*60)
+	}
+	return strconv.FormatFloat(math.Round(h*100)/100, 'f', -1, 64) + "h"
+}
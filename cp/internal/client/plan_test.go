@@ -0,0 +1,201 @@
+package client
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseEstimate(t *testing.T) {
+	tests := []struct {
+		in      any
+		want    float64
+		wantErr bool
+	}{
+		{in: 2.5, want: 2.5},
+		{in: "3", want: 3},
+		{in: " 4h ", want: 4},
+		{in: "1.5H", want: 1.5},
+		{in: "90m", want: 1.5},
+		{in: "2d", want: 2 * hoursPerDay},
+		{in: "1w", want: 5 * hoursPerDay},
+		{in: "0", want: 0},
+		{in: "-3h", wantErr: true},
+		{in: "-2", wantErr: true},
+		{in: "nan", wantErr: true},
+		{in: "inf", wantErr: true},
+		{in: "+Inf", wantErr: true},
+		{in: -1.0, wantErr: true},
+		{in: math.NaN(), wantErr: true},
+		{in: math.Inf(1), wantErr: true},
+		{in: "", wantErr: true},
+		{in: "h", wantErr: true},
+		{in: "3x", wantErr: true},
+		{in: "2 days", wantErr: true},
+		{in: "4hours", wantErr: true},
+		{in: "h4", wantErr: true},
+		{in: nil, wantErr: true},
+		{in: true, wantErr: true},
+		{in: 3, wantErr: true}, // metadata numbers decode as float64
+	}
+	for _, tt := range tests {
+		got, err := ParseEstimate(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("ParseEstimate(%#v) = %v, %v; want ErrInvalid", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseEstimate(%#v) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestTaskEstimate(t *testing.T) {
+	tests := []struct {
+		name          string
+		meta          map[string]any
+		wantHours     float64
+		wantEstimated bool
+		wantErr       bool
+	}{
+		{name: "no metadata", meta: nil, wantHours: 4},
+		{name: "no estimate key", meta: map[string]any{"assigned_at": "x"}, wantHours: 4},
+		{name: "estimate", meta: map[string]any{"estimate": "1d"}, wantHours: hoursPerDay, wantEstimated: true},
+		{name: "zero estimate is not missing", meta: map[string]any{"estimate": 0.0}, wantHours: 0, wantEstimated: true},
+		{name: "bad unit", meta: map[string]any{"estimate": "2y"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours, estimated, err := taskEstimate(tt.meta, 4)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, %v; want an error", hours, estimated)
+				}
+				return
+			}
+			if err != nil || hours != tt.wantHours || estimated != tt.wantEstimated {
+				t.Errorf("got %v, %v, %v; want %v, %v", hours, estimated, err, tt.wantHours, tt.wantEstimated)
+			}
+		})
+	}
+}
+
+// planTasks builds tasks from "id:blocker,blocker" specs with index; every
+// task takes 1 hour unless hours names it
+func planTasks(hours map[string]float64, specs ...string) ([]PlanTask, map[string]int) {
+	tasks := make([]PlanTask, len(specs))
+	index := map[string]int{}
+	for i, spec := range specs {
+		id, blockers, _ := strings.Cut(spec, ":")
+		t := PlanTask{ID: id, Status: "open", Estimate: 1, BlockedBy: []string{}}
+		if h, ok := hours[id]; ok {
+			t.Estimate, t.Estimated = h, true
+		}
+		if blockers != "" {
+			t.BlockedBy = strings.Split(blockers, ",")
+		}
+		tasks[i], index[id] = t, i
+	}
+	return tasks, index
+}
+
+func TestPlanOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []string
+		want  []string // nil: a cycle
+		stuck []string // tasks the cycle error must name
+	}{
+		{name: "empty", specs: nil, want: []string{}},
+		{name: "independent", specs: []string{"a", "b", "c"}, want: []string{"a", "b", "c"}},
+		{name: "chain listed backwards", specs: []string{"c:b", "b:a", "a"}, want: []string{"a", "b", "c"}},
+		{name: "diamond", specs: []string{"d:b,c", "b:a", "c:a", "a"}, want: []string{"a", "b", "c", "d"}},
+		{name: "two-task cycle", specs: []string{"a:b", "b:a", "c"}, stuck: []string{"a", "b"}},
+		{name: "self block", specs: []string{"a:a"}, stuck: []string{"a"}},
+		{name: "cycle blocks its dependents", specs: []string{"x", "a:b", "b:a", "d:a"}, stuck: []string{"a", "b", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, index := planTasks(nil, tt.specs...)
+			order, err := planOrder(tasks, index)
+			if tt.want == nil {
+				if !errors.Is(err, ErrConflict) {
+					t.Fatalf("order = %v, err = %v; want a cycle conflict", order, err)
+				}
+				for _, id := range tt.stuck {
+					if !strings.Contains(err.Error(), id) {
+						t.Errorf("error %q does not name %s", err, id)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, i := range order {
+				ids = append(ids, tasks[i].ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("order = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulePlan(t *testing.T) {
+	// a (2h) blocks b (3h) and c (unestimated: 1h default); both block d (1h)
+	tasks, index := planTasks(map[string]float64{"a": 2, "b": 3, "d": 1}, "d:b,c", "b:a", "c:a", "a")
+	plan := &EpicPlan{Tasks: tasks, CriticalPath: []string{}, Ready: []string{}, Running: []string{}}
+	plan.Tasks[index["a"]].Status = "in_progress"
+	if err := schedulePlan(plan, index); err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Length != 6 {
+		t.Errorf("length = %v, want 6", plan.Length)
+	}
+	if want := []string{"a", "b", "d"}; !slices.Equal(plan.CriticalPath, want) {
+		t.Errorf("critical path = %v, want %v", plan.CriticalPath, want)
+	}
+	if plan.MaxParallel != 2 {
+		t.Errorf("max parallel = %d, want 2", plan.MaxParallel)
+	}
+	if want := []string{"a"}; !slices.Equal(plan.Running, want) {
+		t.Errorf("running = %v, want %v", plan.Running, want)
+	}
+
+	want := map[string]struct{ es, ef, slack float64 }{
+		"a": {0, 2, 0},
+		"b": {2, 5, 0},
+		"c": {2, 3, 2},
+		"d": {5, 6, 0},
+	}
+	var order []string
+	for _, task := range plan.Tasks {
+		order = append(order, task.ID)
+		w := want[task.ID]
+		if task.EarliestStart != w.es || task.EarliestFinish != w.ef || task.Slack != w.slack {
+			t.Errorf("%s: start %v finish %v slack %v, want %v %v %v",
+				task.ID, task.EarliestStart, task.EarliestFinish, task.Slack, w.es, w.ef, w.slack)
+		}
+		if task.Critical != (w.slack == 0) {
+			t.Errorf("%s: critical = %v", task.ID, task.Critical)
+		}
+	}
+	if want := []string{"a", "b", "c", "d"}; !slices.Equal(order, want) {
+		t.Errorf("tasks ordered %v, want %v", order, want)
+	}
+}
+
+func TestSchedulePlanCycle(t *testing.T) {
+	tasks, index := planTasks(nil, "a:b", "b:a")
+	plan := &EpicPlan{Tasks: tasks}
+	if err := schedulePlan(plan, index); !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want a cycle conflict", err)
+	}
+}
@@ -0,0 +1,270 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message kinds with behaviour attached: a request expects a reply from each
+// recipient, and escalations chase overdue requests
+const (
+	RequestKind    = "request"
+	EscalationKind = "escalation"
+)
+
+// Request is an open kind=request message with who has and has not replied
+type Request struct {
+	Message
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	EscalatedAt *time.Time `json:"escalated_at,omitempty"`
+	Replied     []string   `json:"replied"`
+	Owed        []string   `json:"owed"` // recipients who have not replied
+	Overdue     bool       `json:"overdue"`
+}
+
+// Escalation is an overdue request that was chased: a follow-up to the
+// recipients who owe a reply and a notice to the sender
+type Escalation struct {
+	Request
+	FollowUpID string `json:"follow_up_id,omitempty"`
+	NoticeID   string `json:"notice_id,omitempty"`
+}
+
+// ParseDue parses a reply deadline: a duration from now ("90m", "4h", "2d")
+// or a time ("2026-03-01 17:00", RFC 3339, or a date meaning its end). A time
+// that is not after now is rejected: the request would be overdue on arrival.
+func ParseDue(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		var days int
+		if _, err := fmt.Sscanf(n, "%d", &days); err == nil && days > 0 && fmt.Sprint(days) == n {
+			return now.AddDate(0, 0, days), nil
+		}
+	}
+	t, ok := parseDueTime(s)
+	if !ok {
+		return time.Time{}, invalidf("invalid deadline %q: use a duration (4h, 2d) or a time (2026-03-01 17:00)", s)
+	}
+	if !t.After(now) {
+		return time.Time{}, invalidf("deadline %s is in the past", t.Format("2006-01-02 15:04"))
+	}
+	return t, nil
+}
+
+// parseDueTime parses the absolute forms accepted by ParseDue
+func parseDueTime(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), true
+	}
+	return time.Time{}, false
+}
+
+// SendRequest sends a kind=request message that expects a reply from each
+// recipient, optionally by due
+func (c *Client) SendRequest(ctx context.Context, recipients []string, subject, body string, cc []string, replyTo string, due *time.Time) (*SendResult, error) {
+	return c.sendMessage(ctx, recipients, subject, body, cc, RequestKind, replyTo, due)
+}
+
+// PendingRequests returns outstanding requests involving the configured
+// agent: those it sent that still await a reply, and those it owes a reply
+// to. Both are ordered by deadline.
+func (c *Client) PendingRequests(ctx context.Context) (sent, owed []Request, err error) {
+	reqs, err := c.openRequests(ctx, c.Config.Agent)
+	if err != nil {
+		return nil, nil, err
+	}
+	sent, owed = []Request{}, []Request{}
+	for _, r := range reqs {
+		if r.Creator == c.Config.Agent {
+			sent = append(sent, r)
+		}
+		if containsString(r.Owed, c.Config.Agent) {
+			owed = append(owed, r)
+		}
+	}
+	return sent, owed, nil
+}
+
+// openRequests lists open requests still owed a reply by someone, for one
+// agent or (agent "") the whole project
+func (c *Client) openRequests(ctx context.Context, agent string) ([]Request, error) {
+	reqs, err := c.store.OpenRequests(ctx, agent)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var out []Request
+	for _, r := range reqs {
+		r.Owed = []string{}
+		for _, a := range r.To {
+			if a != r.Creator && !containsString(r.Replied, a) {
+				r.Owed = append(r.Owed, a)
+			}
+		}
+		if len(r.Owed) == 0 {
+			continue
+		}
+		if r.Replied == nil {
+			r.Replied = []string{}
+		}
+		r.Overdue = r.DueAt != nil && r.DueAt.Before(now)
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// EscalateRequests chases every overdue request in the project that has not
+// been escalated yet: the recipients who owe a reply get a follow-up and the
+// sender a notice, both kind escalation and threaded under the request. Each
+// request is escalated once: it is claimed by setting escalated_at before
+// anything is sent, so concurrent runs (cp serve on two hosts, a manual cp
+// message escalate) skip requests another run got first. If the follow-up
+// cannot be sent the claim is released for the next run. With dryRun nothing is sent.
+func (c *Client) EscalateRequests(ctx context.Context, dryRun bool) ([]Escalation, error) {
+	reqs, err := c.openRequests(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	out := []Escalation{}
+	for _, r := range reqs {
+		if !r.Overdue || r.EscalatedAt != nil {
+			continue
+		}
+		e := Escalation{Request: r}
+		if dryRun {
+			out = append(out, e)
+			continue
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		claimed, err := c.store.ClaimEscalation(ctx, r.ID, now)
+		if err != nil {
+			return out, err
+		}
+		if !claimed {
+			continue
+		}
+		e.EscalatedAt = &now
+		if err := c.sendEscalation(ctx, &e); err != nil {
+			if e.FollowUpID != "" {
+				// The recipients were chased; only the sender's notice is missing
+				return out, err
+			}
+			if rerr := c.store.ReleaseEscalation(context.WithoutCancel(ctx), r.ID, now); rerr != nil {
+				err = fmt.Errorf("%v (and releasing it failed: %v)", err, rerr)
+			}
+			return out, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// sendEscalation sends a claimed request's follow-up and notice
+func (c *Client) sendEscalation(ctx context.Context, e *Escalation) error {
+	r := e.Request
+	due := r.DueAt.UTC().Format("2006-01-02 15:04 UTC")
+	followUp := fmt.Sprintf("%s asked for a reply to %s (%q) by %s and is still waiting.\n\nReply with: cp message reply %s",
+		r.Creator, r.ID, r.Title, due, r.ID)
+	sent, err := c.SendMessage(ctx, r.Owed, "Overdue: "+r.Title, followUp, nil, EscalationKind, r.ID)
+	if err != nil {
+		return fmt.Errorf("failed to escalate %s: %w", r.ID, err)
+	}
+	e.FollowUpID = sent.ID
+
+	notice := fmt.Sprintf("Your request %s (%q) was due %s. No reply yet from: %s.\nThey have been sent a follow-up (%s).",
+		r.ID, r.Title, due, strings.Join(r.Owed, ", "), e.FollowUpID)
+	if len(r.Replied) > 0 {
+		notice += fmt.Sprintf("\nAlready replied: %s.", strings.Join(r.Replied, ", "))
+	}
+	sent, err = c.SendMessage(ctx, []string{r.Creator}, "No reply yet: "+r.Title, notice, nil, EscalationKind, r.ID)
+	if err != nil {
+		return fmt.Errorf("failed to notify %s about %s: %w", r.Creator, r.ID, err)
+	}
+	e.NoticeID = sent.ID
+	return nil
+}
+
+func (pg *pgStore) ClaimEscalation(ctx context.Context, id string, at time.Time) (bool, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, `
+		UPDATE shards
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('escalated_at', $2::text)
+		WHERE id = $1 AND (metadata->>'escalated_at') IS NULL
+	`, id, at.UTC().Format(time.RFC3339))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim %s for escalation: %w", id, pgError(err))
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (pg *pgStore) ReleaseEscalation(ctx context.Context, id string, at time.Time) error {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `
+		UPDATE shards
+		SET metadata = metadata - 'escalated_at'
+		WHERE id = $1 AND metadata->>'escalated_at' = $2
+	`, id, at.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to release %s: %w", id, pgError(err))
+	}
+	return nil
+}
+
+func (pg *pgStore) OpenRequests(ctx context.Context, agent string) ([]Request, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var agentArg any
+	if agent != "" {
+		agentArg = agent
+	}
+	rows, err := conn.Query(ctx, `
+		SELECT id, title, creator, created_at, labels, due_at, escalated_at, replied
+		FROM open_requests($1, $2)
+	`, pg.cfg.Project, agentArg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list requests: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var reqs []Request
+	for rows.Next() {
+		var r Request
+		var labels []string
+		if err := rows.Scan(&r.ID, &r.Title, &r.Creator, &r.CreatedAt, &labels,
+			&r.DueAt, &r.EscalatedAt, &r.Replied); err != nil {
+			return nil, fmt.Errorf("failed to scan request: %w", err)
+		}
+		r.setAddressing(labels)
+		reqs = append(reqs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("request iteration error: %w", err)
+	}
+	return reqs, nil
+}
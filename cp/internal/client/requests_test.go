@@ -0,0 +1,61 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		// Relative
+		{in: "90m", want: now.Add(90 * time.Minute)},
+		{in: "4h", want: now.Add(4 * time.Hour)},
+		{in: " 1h30m ", want: now.Add(90 * time.Minute)},
+		{in: "2d", want: now.AddDate(0, 0, 2)},
+		{in: "0h", wantErr: true},
+		{in: "-1h", wantErr: true},
+		{in: "0d", wantErr: true},
+		{in: "-2d", wantErr: true},
+		{in: "1.5d", wantErr: true},
+		{in: "02d", wantErr: true},
+
+		// Absolute
+		{in: "2026-03-01 17:00", want: time.Date(2026, 3, 1, 17, 0, 0, 0, time.Local)},
+		{in: "2026-03-02", want: time.Date(2026, 3, 2, 23, 59, 59, 0, time.Local)},
+		{in: "2026-03-01", want: time.Date(2026, 3, 1, 23, 59, 59, 0, time.Local)}, // today means end of today
+		{in: "2026-03-05T09:00:00Z", want: time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{in: "2026-03-01T12:00:00+14:00", wantErr: true}, // an RFC 3339 time hours ago
+
+		// Past
+		{in: "2026-03-01 12:00", wantErr: true}, // exactly now
+		{in: "2026-03-01 11:59", wantErr: true},
+		{in: "2026-02-28", wantErr: true},
+		{in: "2020-01-01T00:00:00Z", wantErr: true},
+
+		// Malformed
+		{in: "", wantErr: true},
+		{in: "tomorrow", wantErr: true},
+		{in: "4 hours", wantErr: true},
+		{in: "2026-13-01", wantErr: true},
+		{in: "2026-03-01 25:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDue(tt.in, now)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("ParseDue(%q) = %v, %v; want ErrInvalid", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseDue(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // RequirementDashboardRow represents a row from the requirement_dashboard() function
@@ -46,31 +47,31 @@ var validTransitions = map[string][]string{
 func validateTransition(from, to string) error {
 	allowed, ok := validTransitions[from]
 	if !ok {
-		return fmt.Errorf("unknown lifecycle status: %s", from)
+		return invalidf("unknown lifecycle status: %s", from)
 	}
 	for _, a := range allowed {
 		if a == to {
 			return nil
 		}
 	}
-	return fmt.Errorf("cannot transition from '%s' to '%s'", from, to)
+	return conflictf("cannot transition from '%s' to '%s'", from, to)
 }
 
 // getLifecycleStatus fetches the current lifecycle_status from a requirement's metadata
-func (c *Client) getLifecycleStatus(ctx context.Context, conn *pgx.Conn, id string) (string, error) {
+func (c *Client) getLifecycleStatus(ctx context.Context, conn *pgxpool.Conn, id string) (string, error) {
 	var shardType string
 	var meta json.RawMessage
 	err := conn.QueryRow(ctx, `
 		SELECT type, COALESCE(metadata, '{}') FROM shards WHERE id = $1
 	`, id).Scan(&shardType, &meta)
 	if err == pgx.ErrNoRows {
-		return "", fmt.Errorf("shard not found: %s", id)
+		return "", notFoundf("shard not found: %s", id)
 	}
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch shard: %v", err)
+		return "", fmt.Errorf("failed to fetch shard: %w", err)
 	}
 	if shardType != "requirement" {
-		return "", fmt.Errorf("shard %s is type '%s', expected 'requirement'", id, shardType)
+		return "", invalidf("shard %s is type '%s', expected 'requirement'", id, shardType)
 	}
 
 	var m map[string]interface{}
@@ -95,7 +96,7 @@ func (c *Client) CreateRequirement(ctx context.Context, title, content string, p
 
 	metaJSON, err := json.Marshal(meta)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal metadata: %v", err)
+		return "", fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
 	pri := priority
@@ -108,7 +109,7 @@ func (c *Client) ListRequirements(ctx context.Context, statusFilter []string, ca
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	query := `
 		SELECT
@@ -150,7 +151,7 @@ func (c *Client) ListRequirements(ctx context.Context, statusFilter []string, ca
 
 	rows, err := conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list requirements: %v", err)
+		return nil, fmt.Errorf("failed to list requirements: %w", err)
 	}
 	defer rows.Close()
 
@@ -174,7 +175,7 @@ func (c *Client) ShowRequirement(ctx context.Context, id string) (*Shard, []Requ
 		return nil, nil, 0, 0, 0, err
 	}
 	if shard.Type != "requirement" {
-		return nil, nil, 0, 0, 0, fmt.Errorf("shard %s is type '%s', expected 'requirement'", id, shard.Type)
+		return nil, nil, 0, 0, 0, invalidf("shard %s is type '%s', expected 'requirement'", id, shard.Type)
 	}
 
 	edges, err := c.GetRequirementEdges(ctx, id)
@@ -188,7 +189,7 @@ func (c *Client) ShowRequirement(ctx context.Context, id string) (*Shard, []Requ
 	if err != nil {
 		return nil, nil, 0, 0, 0, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	conn.QueryRow(ctx, `
 		SELECT
@@ -209,14 +210,14 @@ func (c *Client) ApproveRequirement(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	status, err := c.getLifecycleStatus(ctx, conn, id)
 	if err != nil {
 		return err
 	}
 	if status != "draft" {
-		return fmt.Errorf("cannot approve: status is '%s', expected 'draft'", status)
+		return conflictf("cannot approve: status is '%s', expected 'draft'", status)
 	}
 
 	_, err = c.SetMetadataPath(ctx, id, []string{"lifecycle_status"}, json.RawMessage(`"approved"`))
@@ -229,7 +230,7 @@ func (c *Client) VerifyRequirement(ctx context.Context, id string, force bool) e
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	status, err := c.getLifecycleStatus(ctx, conn, id)
 	if err != nil {
@@ -245,9 +246,9 @@ func (c *Client) VerifyRequirement(ctx context.Context, id string, force bool) e
 			WHERE e.to_id = $1 AND e.edge_type = 'implements' AND t.status != 'closed'
 		`, id).Scan(&openTasks)
 		if openTasks > 0 {
-			return fmt.Errorf("cannot verify: status is '%s', expected 'implemented'. %d tasks still open", status, openTasks)
+			return conflictf("cannot verify: status is '%s', expected 'implemented'. %d tasks still open", status, openTasks)
 		}
-		return fmt.Errorf("cannot verify: status is '%s', expected 'implemented'", status)
+		return conflictf("cannot verify: status is '%s', expected 'implemented'", status)
 	}
 
 	if !force {
@@ -259,7 +260,7 @@ func (c *Client) VerifyRequirement(ctx context.Context, id string, force bool) e
 			AND EXISTS (SELECT 1 FROM shards a WHERE a.id = e.from_id AND a.type = 'test')
 		`, id).Scan(&testCount)
 		if testCount == 0 {
-			return fmt.Errorf("no test coverage. Use --force to verify without tests")
+			return conflictf("no test coverage. Use --force to verify without tests")
 		}
 	}
 
@@ -273,18 +274,18 @@ func (c *Client) ReopenRequirement(ctx context.Context, id, reason string) error
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	status, err := c.getLifecycleStatus(ctx, conn, id)
 	if err != nil {
 		return err
 	}
 	if status == "draft" {
-		return fmt.Errorf("cannot reopen: requirement is already in 'draft' status")
+		return conflictf("cannot reopen: requirement is already in 'draft' status")
 	}
 
 	if err := validateTransition(status, "approved"); err != nil {
-		return fmt.Errorf("cannot reopen: %v", err)
+		return fmt.Errorf("cannot reopen: %w", err)
 	}
 
 	// Set lifecycle_status and reopen_reason
@@ -303,19 +304,19 @@ func (c *Client) LinkTask(ctx context.Context, reqID, taskID string) error {
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	// Validate task shard exists and is type 'task'
 	var shardType string
 	err = conn.QueryRow(ctx, `SELECT type FROM shards WHERE id = $1`, taskID).Scan(&shardType)
 	if err == pgx.ErrNoRows {
-		return fmt.Errorf("shard not found: %s", taskID)
+		return notFoundf("shard not found: %s", taskID)
 	}
 	if err != nil {
-		return fmt.Errorf("failed to fetch shard: %v", err)
+		return fmt.Errorf("failed to fetch shard: %w", err)
 	}
 	if shardType != "task" {
-		return fmt.Errorf("shard %s is type '%s', expected 'task'", taskID, shardType)
+		return invalidf("shard %s is type '%s', expected 'task'", taskID, shardType)
 	}
 
 	// Validate requirement exists
@@ -327,14 +328,14 @@ func (c *Client) LinkTask(ctx context.Context, reqID, taskID string) error {
 	// Create edge: task --implements--> requirement
 	_, err = conn.Exec(ctx, `SELECT link($1, $2, $3)`, taskID, reqID, "implements")
 	if err != nil {
-		return fmt.Errorf("failed to link task: %v", err)
+		return fmt.Errorf("failed to link task: %w", err)
 	}
 
 	// Auto-transition: approved → in_progress
 	if status == "approved" {
 		_, err = c.SetMetadataPath(ctx, reqID, []string{"lifecycle_status"}, json.RawMessage(`"in_progress"`))
 		if err != nil {
-			return fmt.Errorf("linked task but failed to update lifecycle: %v", err)
+			return fmt.Errorf("linked task but failed to update lifecycle: %w", err)
 		}
 	}
 
@@ -347,16 +348,16 @@ func (c *Client) LinkTest(ctx context.Context, reqID, testID string) error {
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	// Validate test shard exists
 	var shardType string
 	err = conn.QueryRow(ctx, `SELECT type FROM shards WHERE id = $1`, testID).Scan(&shardType)
 	if err == pgx.ErrNoRows {
-		return fmt.Errorf("shard not found: %s", testID)
+		return notFoundf("shard not found: %s", testID)
 	}
 	if err != nil {
-		return fmt.Errorf("failed to fetch shard: %v", err)
+		return fmt.Errorf("failed to fetch shard: %w", err)
 	}
 
 	// Validate requirement exists
@@ -368,7 +369,7 @@ func (c *Client) LinkTest(ctx context.Context, reqID, testID string) error {
 	// Create edge: test --has-artifact--> requirement
 	_, err = conn.Exec(ctx, `SELECT link($1, $2, $3)`, testID, reqID, "has-artifact")
 	if err != nil {
-		return fmt.Errorf("failed to link test: %v", err)
+		return fmt.Errorf("failed to link test: %w", err)
 	}
 
 	return nil
@@ -380,7 +381,7 @@ func (c *Client) LinkDependency(ctx context.Context, reqID, dependsOnID string)
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	// Validate both are requirements
 	_, err = c.getLifecycleStatus(ctx, conn, reqID)
@@ -396,16 +397,16 @@ func (c *Client) LinkDependency(ctx context.Context, reqID, dependsOnID string)
 	var hasCycle bool
 	err = conn.QueryRow(ctx, `SELECT has_circular_dependency($1, $2)`, reqID, dependsOnID).Scan(&hasCycle)
 	if err != nil {
-		return fmt.Errorf("failed to check circular dependency: %v", err)
+		return fmt.Errorf("failed to check circular dependency: %w", err)
 	}
 	if hasCycle {
-		return fmt.Errorf("circular dependency detected: adding this edge would create a cycle")
+		return invalidf("circular dependency detected: adding this edge would create a cycle")
 	}
 
 	// Create edge: reqID --blocked-by--> dependsOnID
 	_, err = conn.Exec(ctx, `SELECT link($1, $2, $3)`, reqID, dependsOnID, "blocked-by")
 	if err != nil {
-		return fmt.Errorf("failed to link dependency: %v", err)
+		return fmt.Errorf("failed to link dependency: %w", err)
 	}
 
 	return nil
@@ -417,7 +418,7 @@ func (c *Client) UnlinkEdge(ctx context.Context, reqID, targetID, edgeType strin
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var fromID, toID string
 	switch edgeType {
@@ -431,17 +432,17 @@ func (c *Client) UnlinkEdge(ctx context.Context, reqID, targetID, edgeType strin
 		// requirement --blocked-by--> dependency
 		fromID, toID = reqID, targetID
 	default:
-		return fmt.Errorf("unknown edge type: %s", edgeType)
+		return invalidf("unknown edge type: %s", edgeType)
 	}
 
 	result, err := conn.Exec(ctx, `
 		DELETE FROM edges WHERE from_id = $1 AND to_id = $2 AND edge_type = $3
 	`, fromID, toID, edgeType)
 	if err != nil {
-		return fmt.Errorf("failed to unlink: %v", err)
+		return fmt.Errorf("failed to unlink: %w", err)
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("edge not found: %s --%s--> %s", fromID, edgeType, toID)
+		return notFoundf("edge not found: %s --%s--> %s", fromID, edgeType, toID)
 	}
 
 	return nil
@@ -453,11 +454,11 @@ func (c *Client) RequirementDashboard(ctx context.Context) ([]RequirementDashboa
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `SELECT * FROM requirement_dashboard($1)`, c.Config.Project)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get requirement dashboard: %v", err)
+		return nil, fmt.Errorf("failed to get requirement dashboard: %w", err)
 	}
 	defer rows.Close()
 
@@ -467,7 +468,7 @@ func (c *Client) RequirementDashboard(ctx context.Context) ([]RequirementDashboa
 		if err := rows.Scan(&r.ID, &r.Title, &r.LifecycleStatus, &r.Priority,
 			&r.Category, &r.TaskCountTotal, &r.TaskCountClosed, &r.TestCount,
 			&r.BlockedByIDs, &r.CreatedAt, &r.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan dashboard row: %v", err)
+			return nil, fmt.Errorf("failed to scan dashboard row: %w", err)
 		}
 		results = append(results, r)
 	}
@@ -480,7 +481,7 @@ func (c *Client) GetRequirementEdges(ctx context.Context, id string) ([]Requirem
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `
 		SELECT e.from_id, e.to_id, e.edge_type, s.title,
@@ -491,7 +492,7 @@ func (c *Client) GetRequirementEdges(ctx context.Context, id string) ([]Requirem
 		ORDER BY e.edge_type, s.title
 	`, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get requirement edges: %v", err)
+		return nil, fmt.Errorf("failed to get requirement edges: %w", err)
 	}
 	defer rows.Close()
 
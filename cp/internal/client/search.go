@@ -6,6 +6,8 @@ import (
 	"time"
 
 	pgvec "github.com/pgvector/pgvector-go"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/embedding"
 )
 
 // RecallResult represents a semantic search result
@@ -18,6 +20,18 @@ type RecallResult struct {
 	Snippet    string    `json:"snippet"`
 	Labels     []string  `json:"labels,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
+
+	// Set when the best match was a chunk of a long shard; Snippet is then the chunk text
+	ChunkIndex   *int    `json:"chunk_index,omitempty"`
+	ChunkHeading *string `json:"chunk_heading,omitempty"`
+
+	// Set by hybrid recall
+	Scores *ScoreBreakdown `json:"scores,omitempty"`
+
+	// Set by recall --rerank (0-10) and --expand (the alternative phrasing
+	// that found this shard, when not the original query)
+	Rerank       *float64 `json:"rerank,omitempty"`
+	MatchedQuery string   `json:"matched_query,omitempty"`
 }
 
 // SemanticSearch performs vector similarity search using the semantic_search() SQL function.
@@ -27,11 +41,15 @@ func (c *Client) SemanticSearch(ctx context.Context, queryEmbedding []float32, t
 
 // SemanticSearchWithSince performs semantic search with an optional time cutoff.
 func (c *Client) SemanticSearchWithSince(ctx context.Context, queryEmbedding []float32, types []string, labels []string, status []string, limit int, minSimilarity float64, since *time.Time) ([]RecallResult, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.SemanticSearch(ctx, queryEmbedding, types, labels, status, limit, minSimilarity, since)
+}
+
+func (pg *pgStore) SemanticSearch(ctx context.Context, queryEmbedding []float32, types []string, labels []string, status []string, limit int, minSimilarity float64, since *time.Time) ([]RecallResult, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	vec := pgvec.NewVector(queryEmbedding)
 
@@ -51,24 +69,24 @@ func (c *Client) SemanticSearchWithSince(ctx context.Context, queryEmbedding []f
 	}
 
 	rows, err := conn.Query(ctx, `
-		SELECT id, title, type, status, similarity, snippet, labels, created_at
+		SELECT id, title, type, status, similarity, snippet, labels, created_at, chunk_index, chunk_heading
 		FROM semantic_search($1, $2, $3, $4, $5, $6, $7, $8)
-	`, c.Config.Project, vec, typesArg, labelsArg, statusArg, limit, minSimilarity, sinceArg)
+	`, pg.cfg.Project, vec, typesArg, labelsArg, statusArg, limit, minSimilarity, sinceArg)
 	if err != nil {
-		return nil, fmt.Errorf("semantic search failed: %v", err)
+		return nil, fmt.Errorf("semantic search failed: %w", err)
 	}
 	defer rows.Close()
 
 	var results []RecallResult
 	for rows.Next() {
 		var r RecallResult
-		if err := rows.Scan(&r.ID, &r.Title, &r.Type, &r.Status, &r.Similarity, &r.Snippet, &r.Labels, &r.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan result: %v", err)
+		if err := rows.Scan(&r.ID, &r.Title, &r.Type, &r.Status, &r.Similarity, &r.Snippet, &r.Labels, &r.CreatedAt, &r.ChunkIndex, &r.ChunkHeading); err != nil {
+			return nil, fmt.Errorf("failed to scan result: %w", err)
 		}
 		results = append(results, r)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("result iteration error: %v", err)
+		return nil, fmt.Errorf("result iteration error: %w", err)
 	}
 
 	return results, nil
@@ -90,7 +108,7 @@ func (c *Client) MemoryRecall(ctx context.Context, queryEmbedding []float32, lab
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	vec := pgvec.NewVector(queryEmbedding)
 
@@ -104,7 +122,7 @@ func (c *Client) MemoryRecall(ctx context.Context, queryEmbedding []float32, lab
 		FROM memory_recall($1, $2, $3, $4, $5)
 	`, c.Config.Project, vec, labelsArg, limit, minSimilarity)
 	if err != nil {
-		return nil, fmt.Errorf("memory recall failed: %v", err)
+		return nil, fmt.Errorf("memory recall failed: %w", err)
 	}
 	defer rows.Close()
 
@@ -112,39 +130,212 @@ func (c *Client) MemoryRecall(ctx context.Context, queryEmbedding []float32, lab
 	for rows.Next() {
 		var r MemoryRecallResult
 		if err := rows.Scan(&r.ID, &r.Title, &r.Content, &r.Similarity, &r.Labels, &r.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan result: %v", err)
+			return nil, fmt.Errorf("failed to scan result: %w", err)
 		}
 		results = append(results, r)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("result iteration error: %v", err)
+		return nil, fmt.Errorf("result iteration error: %w", err)
 	}
 
 	return results, nil
 }
 
-// UpdateEmbedding stores an embedding vector for a shard.
-func (c *Client) UpdateEmbedding(ctx context.Context, shardID string, emb []float32) error {
-	conn, err := c.Connect(ctx)
+// UpdateEmbedding stores an embedding vector for a shard, tagged with the
+// model that produced it.
+func (c *Client) UpdateEmbedding(ctx context.Context, shardID string, emb []float32, model embedding.ModelInfo) error {
+	return c.store.UpdateEmbedding(ctx, shardID, emb, model)
+}
+
+func (pg *pgStore) UpdateEmbedding(ctx context.Context, shardID string, emb []float32, model embedding.ModelInfo) error {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	vec := pgvec.NewVector(emb)
 
 	result, err := conn.Exec(ctx, `
-		UPDATE shards SET embedding = $1 WHERE id = $2
-	`, vec, shardID)
+		UPDATE shards
+		SET embedding = $1, embedding_provider = $3, embedding_model = $4, embedding_dims = $5
+		WHERE id = $2
+	`, vec, shardID, model.Provider, model.Model, model.Dimensions)
 	if err != nil {
-		return fmt.Errorf("failed to update embedding: %v", err)
+		return fmt.Errorf("failed to update embedding: %w", err)
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("shard not found: %s", shardID)
+		return notFoundf("shard not found: %s", shardID)
 	}
 	return nil
 }
 
+// ChunkEmbedding is one embedded chunk of a long shard
+type ChunkEmbedding struct {
+	Index     int
+	Heading   string
+	Content   string
+	Embedding []float32
+}
+
+// EmbedChunks splits a long shard into heading-aware chunks, embeds them and
+// replaces the shard's stored chunks. Shards below the chunking threshold have
+// any stale chunks removed.
+func (c *Client) EmbedChunks(ctx context.Context, provider embedding.Provider, id, shardType, title, content string) error {
+	if !embedding.NeedsChunking(content) {
+		return c.store.ReplaceChunks(ctx, id, nil, provider.Info())
+	}
+
+	chunks := embedding.ChunkText(content, embedding.ChunkSize)
+	texts := make([]string, len(chunks))
+	for i, ch := range chunks {
+		texts[i] = embedding.BuildChunkText(shardType, title, ch)
+	}
+
+	vecs, err := embedding.EmbedBatch(ctx, provider, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed chunks: %w", err)
+	}
+
+	embedded := make([]ChunkEmbedding, len(chunks))
+	for i, ch := range chunks {
+		embedded[i] = ChunkEmbedding{Index: ch.Index, Heading: ch.Heading, Content: ch.Text, Embedding: vecs[i]}
+	}
+	return c.store.ReplaceChunks(ctx, id, embedded, provider.Info())
+}
+
+func (pg *pgStore) ReplaceChunks(ctx context.Context, shardID string, chunks []ChunkEmbedding, model embedding.ModelInfo) error {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM shard_chunks WHERE shard_id = $1`, shardID); err != nil {
+		return fmt.Errorf("failed to clear chunks: %w", err)
+	}
+
+	for _, ch := range chunks {
+		var heading any
+		if ch.Heading != "" {
+			heading = ch.Heading
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO shard_chunks
+				(shard_id, chunk_index, heading, content, embedding, embedding_provider, embedding_model, embedding_dims)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, shardID, ch.Index, heading, ch.Content, pgvec.NewVector(ch.Embedding), model.Provider, model.Model, model.Dimensions)
+		if err != nil {
+			return fmt.Errorf("failed to store chunk %d: %w", ch.Index, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit chunks: %w", err)
+	}
+	return nil
+}
+
+// EmbeddingModelCount is the number of stored embeddings per model.
+// Model is nil for embeddings stored before model tracking.
+type EmbeddingModelCount struct {
+	Model *embedding.ModelInfo `json:"model"`
+	Count int                  `json:"count"`
+}
+
+// GetEmbeddingModelCounts returns how many of the project's embeddings each model produced.
+func (c *Client) GetEmbeddingModelCounts(ctx context.Context) ([]EmbeddingModelCount, error) {
+	return c.store.EmbeddingModelCounts(ctx)
+}
+
+func (pg *pgStore) EmbeddingModelCounts(ctx context.Context) ([]EmbeddingModelCount, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT embedding_provider, embedding_model, embedding_dims, count(*)
+		FROM shards
+		WHERE project = $1 AND embedding IS NOT NULL
+		GROUP BY 1, 2, 3
+		ORDER BY 4 DESC
+	`, pg.cfg.Project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count embedding models: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []EmbeddingModelCount
+	for rows.Next() {
+		var provider, model *string
+		var dims *int
+		var mc EmbeddingModelCount
+		if err := rows.Scan(&provider, &model, &dims, &mc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding model count: %w", err)
+		}
+		if provider != nil && model != nil && dims != nil {
+			mc.Model = &embedding.ModelInfo{Provider: *provider, Model: *model, Dimensions: *dims}
+		}
+		counts = append(counts, mc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("result iteration error: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetShardsWithStaleEmbedding returns shards whose stored embedding was produced
+// by a model other than current (including untagged embeddings).
+func (c *Client) GetShardsWithStaleEmbedding(ctx context.Context, current embedding.ModelInfo, limit int) ([]ShardForEmbedding, error) {
+	return c.store.GetShardsWithStaleEmbedding(ctx, current, limit)
+}
+
+func (pg *pgStore) GetShardsWithStaleEmbedding(ctx context.Context, current embedding.ModelInfo, limit int) ([]ShardForEmbedding, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT id, title, type FROM shards
+		WHERE project = $1
+		  AND embedding IS NOT NULL
+		  AND (embedding_provider IS DISTINCT FROM $2
+		       OR embedding_model IS DISTINCT FROM $3
+		       OR embedding_dims IS DISTINCT FROM $4)
+		ORDER BY created_at DESC
+		LIMIT $5
+	`, pg.cfg.Project, current.Provider, current.Model, current.Dimensions, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var shards []ShardForEmbedding
+	for rows.Next() {
+		var s ShardForEmbedding
+		if err := rows.Scan(&s.ID, &s.Title, &s.Type); err != nil {
+			return nil, fmt.Errorf("failed to scan shard: %w", err)
+		}
+		shards = append(shards, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("result iteration error: %w", err)
+	}
+
+	return shards, nil
+}
+
 // ShardForEmbedding represents a shard that needs embedding
 type ShardForEmbedding struct {
 	ID    string `json:"id"`
@@ -154,17 +345,21 @@ type ShardForEmbedding struct {
 
 // GetShardsNeedingEmbedding returns shards without embeddings.
 func (c *Client) GetShardsNeedingEmbedding(ctx context.Context, limit int) ([]ShardForEmbedding, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.GetShardsNeedingEmbedding(ctx, limit)
+}
+
+func (pg *pgStore) GetShardsNeedingEmbedding(ctx context.Context, limit int) ([]ShardForEmbedding, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `
 		SELECT id, title, type FROM shards_needing_embedding($1, $2)
-	`, c.Config.Project, limit)
+	`, pg.cfg.Project, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query shards needing embedding: %v", err)
+		return nil, fmt.Errorf("failed to query shards needing embedding: %w", err)
 	}
 	defer rows.Close()
 
@@ -172,12 +367,12 @@ func (c *Client) GetShardsNeedingEmbedding(ctx context.Context, limit int) ([]Sh
 	for rows.Next() {
 		var s ShardForEmbedding
 		if err := rows.Scan(&s.ID, &s.Title, &s.Type); err != nil {
-			return nil, fmt.Errorf("failed to scan shard: %v", err)
+			return nil, fmt.Errorf("failed to scan shard: %w", err)
 		}
 		shards = append(shards, s)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("result iteration error: %v", err)
+		return nil, fmt.Errorf("result iteration error: %w", err)
 	}
 
 	return shards, nil
@@ -185,17 +380,59 @@ func (c *Client) GetShardsNeedingEmbedding(ctx context.Context, limit int) ([]Sh
 
 // GetShardContentForEmbedding fetches the type, title, and content of a shard for embedding.
 func (c *Client) GetShardContentForEmbedding(ctx context.Context, id string) (shardType, title, content string, err error) {
-	conn, err := c.Connect(ctx)
+	return c.store.GetShardContentForEmbedding(ctx, id)
+}
+
+func (pg *pgStore) GetShardContentForEmbedding(ctx context.Context, id string) (shardType, title, content string, err error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return "", "", "", err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	err = conn.QueryRow(ctx, `
 		SELECT type, title, COALESCE(content, '') FROM shards WHERE id = $1
 	`, id).Scan(&shardType, &title, &content)
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to fetch shard for embedding: %v", err)
+		return "", "", "", fmt.Errorf("failed to fetch shard for embedding: %w", err)
 	}
 	return shardType, title, content, nil
 }
+
+// EmbeddingDimensions returns the size of the embedding columns (0 when they
+// are unsized). Requires the postgres backend and migration 021.
+func (c *Client) EmbeddingDimensions(ctx context.Context) (int, error) {
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var dims *int
+	if err := conn.QueryRow(ctx, `SELECT embedding_dimensions()`).Scan(&dims); err != nil {
+		return 0, pgError(err)
+	}
+	if dims == nil {
+		return 0, nil
+	}
+	return *dims, nil
+}
+
+// ResizeEmbeddings re-types the embedding columns to hold vectors of dims
+// values. Stored vectors cannot be converted, so every embedding in the
+// database (all projects) is cleared; it returns how many shards lost theirs.
+// Resizing to the current size clears nothing. Requires the postgres backend
+// and migration 021.
+func (c *Client) ResizeEmbeddings(ctx context.Context, dims int) (int, error) {
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var cleared int
+	if err := conn.QueryRow(ctx, `SELECT resize_embeddings($1)`, dims).Scan(&cleared); err != nil {
+		return 0, pgError(err)
+	}
+	return cleared, nil
+}
@@ -33,7 +33,7 @@ func (c *Client) Checkpoint(ctx context.Context, sessionID, note string) error {
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	timestamp := time.Now().Format("15:04:05")
 	checkpoint := fmt.Sprintf("\n\n### [%s] Checkpoint\n%s", timestamp, note)
@@ -43,10 +43,10 @@ func (c *Client) Checkpoint(ctx context.Context, sessionID, note string) error {
 		WHERE id = $2 AND type = 'session'
 	`, checkpoint, sessionID)
 	if err != nil {
-		return fmt.Errorf("failed to add checkpoint: %v", err)
+		return fmt.Errorf("failed to add checkpoint: %w", err)
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("session not found: %s", sessionID)
+		return notFoundf("session not found: %s", sessionID)
 	}
 	return nil
 }
@@ -58,7 +58,7 @@ func (c *Client) ShowSession(ctx context.Context, sessionID string) (*Session, e
 		return nil, err
 	}
 	if shard.Type != "session" {
-		return nil, fmt.Errorf("%s is not a session (type: %s)", sessionID, shard.Type)
+		return nil, invalidf("%s is not a session (type: %s)", sessionID, shard.Type)
 	}
 	return &Session{
 		ID:        shard.ID,
@@ -76,7 +76,7 @@ func (c *Client) EndSession(ctx context.Context, sessionID string) error {
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	timestamp := time.Now().Format("15:04:05")
 	ending := fmt.Sprintf("\n\n### [%s] Session ended", timestamp)
@@ -87,7 +87,7 @@ func (c *Client) EndSession(ctx context.Context, sessionID string) error {
 		WHERE id = $2 AND type = 'session'
 	`, ending, sessionID)
 	if err != nil {
-		return fmt.Errorf("failed to end session: %v", err)
+		return fmt.Errorf("failed to end session: %w", err)
 	}
 	return nil
 }
@@ -98,7 +98,7 @@ func (c *Client) GetCurrentSession(ctx context.Context) (*Session, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var s Session
 	err = conn.QueryRow(ctx, `
@@ -109,7 +109,7 @@ func (c *Client) GetCurrentSession(ctx context.Context) (*Session, error) {
 	`, c.Config.Project, c.Config.Agent).Scan(
 		&s.ID, &s.Title, &s.Content, &s.Status, &s.CreatedAt, &s.UpdatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("no open session found")
+		return nil, notFoundf("no open session found")
 	}
 	return &s, nil
 }
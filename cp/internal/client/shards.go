@@ -13,20 +13,20 @@ import (
 
 // Shard represents a Context Palace shard
 type Shard struct {
-	ID        string     `json:"id" yaml:"id"`
-	Project   string     `json:"project" yaml:"project"`
-	Title     string     `json:"title" yaml:"title"`
-	Content   string     `json:"content,omitempty" yaml:"content,omitempty"`
-	Type      string     `json:"type" yaml:"type"`
-	Status    string     `json:"status" yaml:"status"`
-	Priority  *int       `json:"priority,omitempty" yaml:"priority,omitempty"`
-	Creator   string     `json:"creator" yaml:"creator"`
-	Owner     *string          `json:"owner,omitempty" yaml:"owner,omitempty"`
-	CreatedAt time.Time        `json:"created_at" yaml:"created_at"`
-	UpdatedAt time.Time        `json:"updated_at" yaml:"updated_at"`
-	Metadata  json.RawMessage  `json:"metadata,omitempty" yaml:"metadata,omitempty"`
-	Labels    []string         `json:"labels,omitempty" yaml:"labels,omitempty"`
-	Artifacts []Artifact `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
+	ID        string          `json:"id" yaml:"id"`
+	Project   string          `json:"project" yaml:"project"`
+	Title     string          `json:"title" yaml:"title"`
+	Content   string          `json:"content,omitempty" yaml:"content,omitempty"`
+	Type      string          `json:"type" yaml:"type"`
+	Status    string          `json:"status" yaml:"status"`
+	Priority  *int            `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Creator   string          `json:"creator" yaml:"creator"`
+	Owner     *string         `json:"owner,omitempty" yaml:"owner,omitempty"`
+	CreatedAt time.Time       `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at" yaml:"updated_at"`
+	Metadata  json.RawMessage `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Labels    []string        `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Artifacts []Artifact      `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
 }
 
 // Artifact represents a task artifact
@@ -46,11 +46,15 @@ type ShardCounts struct {
 
 // GetShardCounts returns shard count statistics for a project
 func (c *Client) GetShardCounts(ctx context.Context) (*ShardCounts, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.GetShardCounts(ctx)
+}
+
+func (pg *pgStore) GetShardCounts(ctx context.Context) (*ShardCounts, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	counts := &ShardCounts{}
 	err = conn.QueryRow(ctx, `
@@ -60,20 +64,24 @@ func (c *Client) GetShardCounts(ctx context.Context) (*ShardCounts, error) {
 			count(*) FILTER (WHERE status = 'closed'),
 			count(*) FILTER (WHERE status NOT IN ('open', 'in_progress', 'closed'))
 		FROM shards WHERE project = $1
-	`, c.Config.Project).Scan(&counts.Total, &counts.Open, &counts.Closed, &counts.Other)
+	`, pg.cfg.Project).Scan(&counts.Total, &counts.Open, &counts.Closed, &counts.Other)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query shard counts: %v", err)
+		return nil, fmt.Errorf("failed to query shard counts: %w", err)
 	}
 	return counts, nil
 }
 
 // GetShard fetches a shard by ID
 func (c *Client) GetShard(ctx context.Context, id string) (*Shard, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.GetShard(ctx, id)
+}
+
+func (pg *pgStore) GetShard(ctx context.Context, id string) (*Shard, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var s Shard
 	err = conn.QueryRow(ctx, `
@@ -86,10 +94,10 @@ func (c *Client) GetShard(ctx context.Context, id string) (*Shard, error) {
 		&s.Metadata)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("shard not found: %s", id)
+		return nil, notFoundf("shard not found: %s", id)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch shard: %v", err)
+		return nil, fmt.Errorf("failed to fetch shard: %w", err)
 	}
 
 	// Fetch labels
@@ -109,11 +117,15 @@ func (c *Client) GetShard(ctx context.Context, id string) (*Shard, error) {
 
 // GetTask fetches a task by ID with its artifacts
 func (c *Client) GetTask(ctx context.Context, id string) (*Shard, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.GetTask(ctx, id)
+}
+
+func (pg *pgStore) GetTask(ctx context.Context, id string) (*Shard, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var s Shard
 	err = conn.QueryRow(ctx, `
@@ -124,10 +136,10 @@ func (c *Client) GetTask(ctx context.Context, id string) (*Shard, error) {
 		&s.Priority, &s.Creator, &s.Owner, &s.CreatedAt, &s.UpdatedAt)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("task not found: %s", id)
+		return nil, notFoundf("task not found: %s", id)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch task: %v", err)
+		return nil, fmt.Errorf("failed to fetch task: %w", err)
 	}
 
 	// Fetch artifacts
@@ -146,58 +158,75 @@ func (c *Client) GetTask(ctx context.Context, id string) (*Shard, error) {
 	return &s, nil
 }
 
-// ClaimTask claims a task for the configured agent
-func (c *Client) ClaimTask(ctx context.Context, id string) (bool, error) {
+// ClaimTask claims a task for the configured agent, with a lease like
+// AssignShard's (zero: the configured lease)
+func (c *Client) ClaimTask(ctx context.Context, id string, lease time.Duration) (bool, error) {
 	conn, err := c.Connect(ctx)
 	if err != nil {
 		return false, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var success bool
-	err = conn.QueryRow(ctx, `SELECT claim_task($1, $2)`, id, c.Config.Agent).Scan(&success)
+	err = conn.QueryRow(ctx, `SELECT claim_task($1, $2, $3)`, id, c.Config.Agent, c.lease(lease)).Scan(&success)
 	if err != nil {
-		return false, fmt.Errorf("failed to claim task: %v", err)
+		return false, fmt.Errorf("failed to claim task: %w", err)
 	}
 	return success, nil
 }
 
-// AddProgress adds a progress note to a task
+// AddProgress adds a progress note to a task, or any other shard type that
+// can be taken (everything but epics, memories and messages). A note from the
+// agent holding the shard also renews its lease (never shortening a longer one).
 func (c *Client) AddProgress(ctx context.Context, id, note string) error {
-	conn, err := c.Connect(ctx)
+	return c.store.AddProgress(ctx, id, note)
+}
+
+func (pg *pgStore) AddProgress(ctx context.Context, id, note string) error {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
-
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	progressNote := fmt.Sprintf("\n\n---\n**[%s] %s:** %s", timestamp, c.Config.Agent, note)
+	defer conn.Release()
 
 	result, err := conn.Exec(ctx, `
-		UPDATE shards SET content = content || $1, updated_at = NOW()
-		WHERE id = $2 AND type IN ('task', 'backlog')
-	`, progressNote, id)
+		UPDATE shards SET content = content || $1, updated_at = NOW(),
+			metadata = CASE WHEN status = 'in_progress' AND owner = $3
+				THEN COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+					'heartbeat_at', NOW()::text,
+					'lease_expires_at', GREATEST(
+						COALESCE((metadata->>'lease_expires_at')::timestamptz, NOW()),
+						NOW() + $4::interval)::text)
+				ELSE metadata END
+		WHERE id = $2 AND type NOT IN ('epic', 'memory', 'message')
+	`, progressNote(pg.cfg.Agent, note), id, pg.cfg.Agent, pg.cfg.LeaseDuration())
 
 	if err != nil {
-		return fmt.Errorf("failed to add progress note: %v", err)
+		return fmt.Errorf("failed to add progress note: %w", err)
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("task not found: %s", id)
+		return notFoundf("task not found: %s", id)
 	}
 	return nil
 }
 
+// progressNote formats a timestamped progress note for appending to content
+func progressNote(agent, note string) string {
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	return fmt.Sprintf("\n\n---\n**[%s] %s:** %s", timestamp, agent, note)
+}
+
 // CloseTask closes a task with a summary
 func (c *Client) CloseTask(ctx context.Context, id, summary string) error {
 	conn, err := c.Connect(ctx)
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	_, err = conn.Exec(ctx, `SELECT close_task($1, $2)`, id, summary)
 	if err != nil {
-		return fmt.Errorf("failed to close task: %v", err)
+		return fmt.Errorf("failed to close task: %w", err)
 	}
 	return nil
 }
@@ -208,11 +237,11 @@ func (c *Client) AddArtifact(ctx context.Context, id, artifactType, reference, d
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	_, err = conn.Exec(ctx, `SELECT add_artifact($1, $2, $3, $4)`, id, artifactType, reference, description)
 	if err != nil {
-		return fmt.Errorf("failed to add artifact: %v", err)
+		return fmt.Errorf("failed to add artifact: %w", err)
 	}
 	return nil
 }
@@ -224,11 +253,23 @@ func (c *Client) CreateShard(ctx context.Context, title, content, shardType stri
 
 // CreateShardWithMetadata creates a new shard with metadata and returns its ID
 func (c *Client) CreateShardWithMetadata(ctx context.Context, title, content, shardType string, priority *int, labels []string, metadata json.RawMessage) (string, error) {
-	conn, err := c.Connect(ctx)
+	newID, err := c.store.CreateShard(ctx, title, content, shardType, priority, labels, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	// Embed-on-write: synchronous, non-fatal
+	c.tryEmbed(ctx, newID, shardType, title, content)
+
+	return newID, nil
+}
+
+func (pg *pgStore) CreateShard(ctx context.Context, title, content, shardType string, priority *int, labels []string, metadata json.RawMessage) (string, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return "", err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	if labels == nil {
 		labels = []string{}
@@ -240,15 +281,11 @@ func (c *Client) CreateShardWithMetadata(ctx context.Context, title, content, sh
 	var newID string
 	err = conn.QueryRow(ctx, `
 		SELECT create_shard($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`, c.Config.Project, c.Config.Agent, title, content, shardType,
+	`, pg.cfg.Project, pg.cfg.Agent, title, content, shardType,
 		labels, nil, priority, metadata).Scan(&newID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create shard: %v", err)
+		return "", fmt.Errorf("failed to create shard: %w", err)
 	}
-
-	// Embed-on-write: synchronous, non-fatal
-	c.tryEmbed(ctx, newID, shardType, title, content)
-
 	return newID, nil
 }
 
@@ -258,7 +295,7 @@ func (c *Client) UpdateShardContent(ctx context.Context, id, content string) err
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	// Fetch type and title for embedding text
 	var shardType, title string
@@ -268,10 +305,10 @@ func (c *Client) UpdateShardContent(ctx context.Context, id, content string) err
 		UPDATE shards SET content = $1, updated_at = NOW() WHERE id = $2
 	`, content, id)
 	if err != nil {
-		return fmt.Errorf("failed to update shard: %v", err)
+		return fmt.Errorf("failed to update shard: %w", err)
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("shard not found: %s", id)
+		return notFoundf("shard not found: %s", id)
 	}
 
 	// Embed-on-write: synchronous, non-fatal
@@ -282,31 +319,39 @@ func (c *Client) UpdateShardContent(ctx context.Context, id, content string) err
 
 // UpdateShardStatus updates a shard's status
 func (c *Client) UpdateShardStatus(ctx context.Context, id, status string) error {
-	conn, err := c.Connect(ctx)
+	return c.store.UpdateShardStatus(ctx, id, status)
+}
+
+func (pg *pgStore) UpdateShardStatus(ctx context.Context, id, status string) error {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	result, err := conn.Exec(ctx, `
 		UPDATE shards SET status = $1, updated_at = NOW() WHERE id = $2
 	`, status, id)
 	if err != nil {
-		return fmt.Errorf("failed to update shard status: %v", err)
+		return fmt.Errorf("failed to update shard status: %w", err)
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("shard not found: %s", id)
+		return notFoundf("shard not found: %s", id)
 	}
 	return nil
 }
 
 // ListShardsByType lists shards of a given type for the configured project
 func (c *Client) ListShardsByType(ctx context.Context, shardType string, statusFilter string, limit int) ([]Shard, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.ListShardsByType(ctx, shardType, statusFilter, limit)
+}
+
+func (pg *pgStore) ListShardsByType(ctx context.Context, shardType string, statusFilter string, limit int) ([]Shard, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	query := `
 		SELECT id, project, title, COALESCE(LEFT(content, 200), ''), type, status,
@@ -314,7 +359,7 @@ func (c *Client) ListShardsByType(ctx context.Context, shardType string, statusF
 			COALESCE(metadata, '{}')
 		FROM shards WHERE project = $1 AND type = $2
 	`
-	args := []interface{}{c.Config.Project, shardType}
+	args := []interface{}{pg.cfg.Project, shardType}
 
 	if statusFilter != "" {
 		query += ` AND status = $3`
@@ -328,7 +373,7 @@ func (c *Client) ListShardsByType(ctx context.Context, shardType string, statusF
 
 	rows, err := conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list shards: %v", err)
+		return nil, fmt.Errorf("failed to list shards: %w", err)
 	}
 	defer rows.Close()
 
@@ -347,11 +392,15 @@ func (c *Client) ListShardsByType(ctx context.Context, shardType string, statusF
 
 // SearchShards does full-text search across shards
 func (c *Client) SearchShards(ctx context.Context, query string, shardType string, limit int) ([]Shard, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.SearchShards(ctx, query, shardType, limit)
+}
+
+func (pg *pgStore) SearchShards(ctx context.Context, query string, shardType string, limit int) ([]Shard, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	sqlQuery := `
 		SELECT id, project, title, COALESCE(LEFT(content, 200), ''), type, status,
@@ -361,7 +410,7 @@ func (c *Client) SearchShards(ctx context.Context, query string, shardType strin
 		FROM shards
 		WHERE project = $1 AND search_vector @@ plainto_tsquery($2)
 	`
-	args := []interface{}{c.Config.Project, query}
+	args := []interface{}{pg.cfg.Project, query}
 
 	if shardType != "" {
 		sqlQuery += ` AND type = $3 ORDER BY rank DESC LIMIT $4`
@@ -373,7 +422,7 @@ func (c *Client) SearchShards(ctx context.Context, query string, shardType strin
 
 	rows, err := conn.Query(ctx, sqlQuery, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search shards: %v", err)
+		return nil, fmt.Errorf("failed to search shards: %w", err)
 	}
 	defer rows.Close()
 
@@ -419,11 +468,15 @@ type ShardListResult struct {
 
 // ListShardsFiltered lists shards using the list_shards() SQL function with all filters
 func (c *Client) ListShardsFiltered(ctx context.Context, opts ListShardsOpts) ([]ShardListResult, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.ListShardsFiltered(ctx, opts)
+}
+
+func (pg *pgStore) ListShardsFiltered(ctx context.Context, opts ListShardsOpts) ([]ShardListResult, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var typesArg, statusArg, labelsArg, creatorArg, searchArg, sinceArg any
 	if opts.Types != nil {
@@ -453,9 +506,9 @@ func (c *Client) ListShardsFiltered(ctx context.Context, opts ListShardsOpts) ([
 	rows, err := conn.Query(ctx, `
 		SELECT id, title, type, status, creator, labels, created_at, updated_at, snippet
 		FROM list_shards($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`, c.Config.Project, typesArg, statusArg, labelsArg, creatorArg, searchArg, sinceArg, limit, opts.Offset, opts.RootsOnly)
+	`, pg.cfg.Project, typesArg, statusArg, labelsArg, creatorArg, searchArg, sinceArg, limit, opts.Offset, opts.RootsOnly)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list shards: %v", err)
+		return nil, fmt.Errorf("failed to list shards: %w", err)
 	}
 	defer rows.Close()
 
@@ -464,23 +517,27 @@ func (c *Client) ListShardsFiltered(ctx context.Context, opts ListShardsOpts) ([
 		var r ShardListResult
 		if err := rows.Scan(&r.ID, &r.Title, &r.Type, &r.Status, &r.Creator,
 			&r.Labels, &r.CreatedAt, &r.UpdatedAt, &r.Snippet); err != nil {
-			return nil, fmt.Errorf("failed to scan shard: %v", err)
+			return nil, fmt.Errorf("failed to scan shard: %w", err)
 		}
 		results = append(results, r)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("shard list iteration error: %v", err)
+		return nil, fmt.Errorf("shard list iteration error: %w", err)
 	}
 	return results, nil
 }
 
 // ListShardsCount returns the total count of shards matching the given filters
 func (c *Client) ListShardsCount(ctx context.Context, opts ListShardsOpts) (int, error) {
-	conn, err := c.Connect(ctx)
+	return c.store.ListShardsCount(ctx, opts)
+}
+
+func (pg *pgStore) ListShardsCount(ctx context.Context, opts ListShardsOpts) (int, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return 0, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var typesArg, statusArg, labelsArg, creatorArg, searchArg, sinceArg any
 	if opts.Types != nil {
@@ -504,9 +561,9 @@ func (c *Client) ListShardsCount(ctx context.Context, opts ListShardsOpts) (int,
 
 	var count int
 	err = conn.QueryRow(ctx, `SELECT list_shards_count($1, $2, $3, $4, $5, $6, $7, $8)`,
-		c.Config.Project, typesArg, statusArg, labelsArg, creatorArg, searchArg, sinceArg, opts.RootsOnly).Scan(&count)
+		pg.cfg.Project, typesArg, statusArg, labelsArg, creatorArg, searchArg, sinceArg, opts.RootsOnly).Scan(&count)
 	if err != nil {
-		return 0, fmt.Errorf("failed to count shards: %v", err)
+		return 0, fmt.Errorf("failed to count shards: %w", err)
 	}
 	return count, nil
 }
@@ -522,11 +579,29 @@ type UpdateShardResult struct {
 
 // UpdateShardFields updates a shard's title and/or content using update_shard()
 func (c *Client) UpdateShardFields(ctx context.Context, id string, title *string, content *string) (*UpdateShardResult, error) {
-	conn, err := c.Connect(ctx)
+	r, err := c.store.UpdateShardFields(ctx, id, title, content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-embed if content changed
+	if content != nil {
+		var shardTitle string
+		if title != nil {
+			shardTitle = *title
+		}
+		c.tryEmbed(ctx, id, r.ShardType, shardTitle, *content)
+	}
+
+	return r, nil
+}
+
+func (pg *pgStore) UpdateShardFields(ctx context.Context, id string, title *string, content *string) (*UpdateShardResult, error) {
+	conn, err := pg.connect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	defer conn.Release()
 
 	var titleArg, contentArg any
 	if title != nil {
@@ -540,21 +615,11 @@ func (c *Client) UpdateShardFields(ctx context.Context, id string, title *string
 	err = conn.QueryRow(ctx, `
 		SELECT id, updated_at, title_changed, content_changed, shard_type
 		FROM update_shard($1, $2, $3, $4)
-	`, id, c.Config.Project, titleArg, contentArg).Scan(
+	`, id, pg.cfg.Project, titleArg, contentArg).Scan(
 		&r.ID, &r.UpdatedAt, &r.TitleChanged, &r.ContentChanged, &r.ShardType)
 	if err != nil {
-		return nil, fmt.Errorf("%s", extractPgMessage(err.Error()))
+		return nil, pgError(err)
 	}
-
-	// Re-embed if content changed
-	if content != nil {
-		var shardTitle string
-		if title != nil {
-			shardTitle = *title
-		}
-		c.tryEmbed(ctx, id, r.ShardType, shardTitle, *content)
-	}
-
 	return &r, nil
 }
 
@@ -578,5 +643,12 @@ func (c *Client) tryEmbed(ctx context.Context, id, shardType, title, content str
 		return // Silent failure — shard was already created/updated
 	}
 
-	_ = c.UpdateEmbedding(ctx, id, vec)
+	if err := c.UpdateEmbedding(ctx, id, vec, c.EmbedProvider.Info()); err != nil {
+		return
+	}
+
+	// Long shards also get per-section chunk vectors
+	chunkCtx, cancelChunks := context.WithTimeout(ctx, 60*time.Second)
+	defer cancelChunks()
+	_ = c.EmbedChunks(chunkCtx, c.EmbedProvider, id, shardType, title, content)
 }
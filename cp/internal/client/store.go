@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/embedding"
+)
+
+// StorageConfig selects the storage backend behind Client
+type StorageConfig struct {
+	Backend string `yaml:"backend"` // "postgres" (default) or "local"
+	Path    string `yaml:"path"`    // local backend data file (default: ~/.cp/local.json)
+	Prefix  string `yaml:"prefix"`  // local backend shard ID prefix (default: first 2 chars of project)
+}
+
+// Storage backend names
+const (
+	BackendPostgres = "postgres"
+	BackendLocal    = "local"
+)
+
+// Store is the storage backend behind Client. It covers the core shard, edge,
+// label, message, lifecycle and embedding operations. Everything else (memory
+// hierarchy, requirements, knowledge docs, focus, ...) is Postgres-only and
+// goes through Client.Connect, which fails with ErrUnsupported on other
+// backends; the CLI commands built on it are marked postgresOnly in cmd.
+type Store interface {
+	// Backend returns the backend name ("postgres" or "local")
+	Backend() string
+	// Close releases any resources held by the backend
+	Close()
+
+	// Shards
+	GetShardCounts(ctx context.Context) (*ShardCounts, error)
+	GetShard(ctx context.Context, id string) (*Shard, error)
+	GetTask(ctx context.Context, id string) (*Shard, error)
+	GetShardDetail(ctx context.Context, id string) (*ShardDetailResult, error)
+	ShardExists(ctx context.Context, id string) (bool, error)
+	CreateShard(ctx context.Context, title, content, shardType string, priority *int, labels []string, metadata json.RawMessage) (string, error)
+	UpdateShardFields(ctx context.Context, id string, title *string, content *string) (*UpdateShardResult, error)
+	UpdateShardStatus(ctx context.Context, id, status string) error
+	AddProgress(ctx context.Context, id, note string) error
+	ListShardsByType(ctx context.Context, shardType string, statusFilter string, limit int) ([]Shard, error)
+	ListShardsFiltered(ctx context.Context, opts ListShardsOpts) ([]ShardListResult, error)
+	ListShardsCount(ctx context.Context, opts ListShardsOpts) (int, error)
+	SearchShards(ctx context.Context, query string, shardType string, limit int) ([]Shard, error)
+
+	// Edges and labels
+	CreateEdge(ctx context.Context, fromID, toID, edgeType string, metadata json.RawMessage) error
+	DeleteEdge(ctx context.Context, fromID, toID, edgeType string) error
+	GetShardEdges(ctx context.Context, shardID string, direction string, edgeTypes []string) ([]EdgeInfo, error)
+	HasCircularDependency(ctx context.Context, fromID, toID string, edgeTypes []string) (bool, error)
+	ListEdgeTypes(ctx context.Context) ([]EdgeType, error)
+	SaveEdgeType(ctx context.Context, t EdgeType) error
+	DeleteEdgeType(ctx context.Context, name string, builtIn bool) error
+	AddShardLabels(ctx context.Context, shardID string, labels []string) ([]string, error)
+	RemoveShardLabels(ctx context.Context, shardID string, labels []string) ([]string, error)
+	LabelSummary(ctx context.Context) ([]LabelCount, error)
+
+	// Messages
+	// SendMessage stores the message, its extra labels and, for kind request,
+	// the expects-reply marker with due in one step
+	SendMessage(ctx context.Context, recipients []string, subject, body string, cc []string, kind string, replyTo string, labels []string, due *time.Time) (string, error)
+	GetInbox(ctx context.Context) ([]Message, error)
+	MarkRead(ctx context.Context, shardIDs []string) (int, error)
+	GetThread(ctx context.Context, id string) ([]ThreadMessage, error)          // whole conversation containing id
+	ListSent(ctx context.Context, limit int) ([]SentMessage, error)             // newest first
+	ClaimEscalation(ctx context.Context, id string, at time.Time) (bool, error) // false if already escalated
+	ReleaseEscalation(ctx context.Context, id string, at time.Time) error       // undo a claim made at at
+	OpenRequests(ctx context.Context, agent string) ([]Request, error)          // agent "": whole project; Owed left to the caller
+
+	// Agent registry (shared by all projects)
+	ListAgents(ctx context.Context) ([]Agent, error)
+	GetAgent(ctx context.Context, id string) (*Agent, error) // nil when not registered
+	SaveAgent(ctx context.Context, a Agent) error
+	TouchAgent(ctx context.Context, id string) error
+
+	// Lifecycle
+	AssignShard(ctx context.Context, shardID string, agent string, lease time.Duration) (*AssignResult, error)
+	CloseShard(ctx context.Context, shardID string, reason string) (*CloseResult, error)
+	GetNextShards(ctx context.Context, epicID *string, capabilities []string, limit int) ([]NextShard, error)
+	TakeShard(ctx context.Context, epicID *string, kind *string, agent string, lease time.Duration) (*NextShard, error)
+	RenewLease(ctx context.Context, shardID string, lease time.Duration) (*Lease, error)
+	ReapLeases(ctx context.Context, dryRun bool) ([]ReapedShard, error)
+	GetShardBoard(ctx context.Context, epicID *string, agent *string) ([]BoardShard, error)
+
+	// Embeddings
+	SemanticSearch(ctx context.Context, queryEmbedding []float32, types []string, labels []string, status []string, limit int, minSimilarity float64, since *time.Time) ([]RecallResult, error)
+	KeywordSearch(ctx context.Context, query string, types []string, labels []string, status []string, limit int, since *time.Time) ([]RecallResult, error)
+	UpdateEmbedding(ctx context.Context, shardID string, emb []float32, model embedding.ModelInfo) error
+	ReplaceChunks(ctx context.Context, shardID string, chunks []ChunkEmbedding, model embedding.ModelInfo) error
+	EmbeddingModelCounts(ctx context.Context) ([]EmbeddingModelCount, error)
+	GetShardsWithStaleEmbedding(ctx context.Context, current embedding.ModelInfo, limit int) ([]ShardForEmbedding, error)
+	GetShardsNeedingEmbedding(ctx context.Context, limit int) ([]ShardForEmbedding, error)
+	GetShardContentForEmbedding(ctx context.Context, id string) (shardType, title, content string, err error)
+}
+
+// NewStore creates the Store selected by cfg.Storage.Backend
+func NewStore(cfg *Config) (Store, error) {
+	switch cfg.Storage.Backend {
+	case "", BackendPostgres:
+		return newPgStore(cfg)
+	case BackendLocal:
+		return newLocalStore(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %q (supported: %s, %s)",
+			cfg.Storage.Backend, BackendPostgres, BackendLocal)
+	}
+}
@@ -0,0 +1,2073 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/embedding"
+)
+
+// localStore is a pure-Go Store backend that keeps shards, edges and read
+// receipts in a single JSON file. It needs no database server, so a solo agent
+// on a laptop or a CI job can use cp offline. Every operation holds an
+// exclusive lock on the file and rereads it if another process has written it
+// since, so several cp processes can share one file.
+type localStore struct {
+	cfg *Config
+	*localFile
+}
+
+// localFile is the data file and its in-memory copy, shared by per-agent
+// views of the same file
+type localFile struct {
+	path string
+	mu   sync.Mutex // serialises goroutines; the file lock serialises processes
+	held *os.File   // lock file, while mu is held
+	read os.FileInfo
+	data *localData
+}
+
+// localData is the on-disk layout of the local backend
+type localData struct {
+	Version  int                    `json:"version"`
+	Shards   map[string]*localShard `json:"shards"`
+	Edges    []localEdge            `json:"edges"`
+	Receipts []localReceipt         `json:"read_receipts"`
+	Types    []localEdgeType        `json:"edge_types,omitempty"`
+	Agents   []Agent                `json:"agents,omitempty"`
+}
+
+type localShard struct {
+	ID           string               `json:"id"`
+	Project      string               `json:"project"`
+	Title        string               `json:"title"`
+	Content      string               `json:"content"`
+	Type         string               `json:"type"`
+	Status       string               `json:"status"`
+	Priority     *int                 `json:"priority,omitempty"`
+	Creator      string               `json:"creator"`
+	Owner        *string              `json:"owner,omitempty"`
+	ParentID     *string              `json:"parent_id,omitempty"`
+	Labels       []string             `json:"labels,omitempty"`
+	Metadata     json.RawMessage      `json:"metadata,omitempty"`
+	Embedding    []float32            `json:"embedding,omitempty"`
+	EmbeddingBy  *embedding.ModelInfo `json:"embedding_model,omitempty"`
+	Chunks       []localChunk         `json:"chunks,omitempty"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+	ClosedAt     *time.Time           `json:"closed_at,omitempty"`
+	ClosedBy     *string              `json:"closed_by,omitempty"`
+	ClosedReason *string              `json:"closed_reason,omitempty"`
+}
+
+type localChunk struct {
+	Index     int       `json:"index"`
+	Heading   string    `json:"heading,omitempty"`
+	Content   string    `json:"content"`
+	Embedding []float32 `json:"embedding"`
+}
+
+type localEdge struct {
+	FromID    string          `json:"from_id"`
+	ToID      string          `json:"to_id"`
+	EdgeType  string          `json:"edge_type"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// localEdgeType is a project's edge type declaration
+type localEdgeType struct {
+	Project string `json:"project"`
+	EdgeType
+}
+
+type localReceipt struct {
+	ShardID string    `json:"shard_id"`
+	AgentID string    `json:"agent_id"`
+	ReadAt  time.Time `json:"read_at"`
+}
+
+const localDataVersion = 1
+
+func newLocalStore(cfg *Config) (*localStore, error) {
+	path := cfg.Storage.Path
+	if path == "" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot determine local data path: %v", err)
+		}
+		if path == "" {
+			path = filepath.Join(home, ".cp", "local.json")
+		} else {
+			path = filepath.Join(home, path[2:])
+		}
+		cfg.Storage.Path = path
+	}
+
+	s := &localStore{cfg: cfg, localFile: &localFile{path: path}}
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	s.unlock()
+	return s, nil
+}
+
+func (s *localStore) Backend() string { return BackendLocal }
+
+func (s *localStore) Close() {}
+
+// withConfig returns a view of the same data acting under cfg (e.g. another agent)
+func (s *localStore) withConfig(cfg *Config) *localStore {
+	return &localStore{cfg: cfg, localFile: s.localFile}
+}
+
+// lock takes the data file for this goroutine, holding an exclusive lock on
+// path.lock against other processes, and rereads the file if it changed since
+// it was last read. Every lock must be paired with unlock.
+func (f *localFile) lock() error {
+	f.mu.Lock()
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		f.mu.Unlock()
+		return fmt.Errorf("cannot create %s: %v", filepath.Dir(f.path), err)
+	}
+	held, err := lockFile(f.path + ".lock")
+	if err != nil {
+		f.mu.Unlock()
+		return fmt.Errorf("cannot lock local data %s: %v", f.path, err)
+	}
+	f.held = held
+	if err := f.load(); err != nil {
+		f.unlock()
+		return err
+	}
+	return nil
+}
+
+// unlock releases the data file
+func (f *localFile) unlock() {
+	unlockFile(f.held)
+	f.held = nil
+	f.mu.Unlock()
+}
+
+// load reads the data file unless the copy in memory is current, starting
+// empty if the file doesn't exist yet
+func (f *localFile) load() error {
+	info, err := os.Stat(f.path)
+	if os.IsNotExist(err) {
+		if f.data == nil {
+			f.data = &localData{Version: localDataVersion, Shards: map[string]*localShard{}}
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read local data %s: %v", f.path, err)
+	}
+	// save replaces the file, so an unchanged file is the very one we read
+	if f.data != nil && f.read != nil && os.SameFile(info, f.read) &&
+		info.ModTime().Equal(f.read.ModTime()) && info.Size() == f.read.Size() {
+		return nil
+	}
+
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("cannot read local data %s: %v", f.path, err)
+	}
+	data := &localData{}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return fmt.Errorf("invalid local data %s: %v", f.path, err)
+	}
+	if data.Shards == nil {
+		data.Shards = map[string]*localShard{}
+	}
+	f.data, f.read = data, info
+	return nil
+}
+
+// save writes the data file atomically: a temp file in the same directory,
+// renamed over the old one. The caller holds the lock.
+func (f *localFile) save() error {
+	raw, err := json.MarshalIndent(f.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode local data: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write local data: %v", err)
+	}
+	_, err = tmp.Write(raw)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write local data: %v", err)
+	}
+	if info, err := os.Stat(f.path); err == nil {
+		f.read = info
+	}
+	return nil
+}
+
+// newID generates a shard ID: {prefix}-{6 hex chars}
+func (s *localStore) newID() string {
+	prefix := s.cfg.Storage.Prefix
+	if prefix == "" {
+		prefix = s.cfg.Project
+		if len(prefix) > 2 {
+			prefix = prefix[:2]
+		}
+		if prefix == "" {
+			prefix = "cp"
+		}
+	}
+	for {
+		b := make([]byte, 3)
+		_, _ = rand.Read(b)
+		id := prefix + "-" + hex.EncodeToString(b)
+		if _, exists := s.data.Shards[id]; !exists {
+			return id
+		}
+	}
+}
+
+// projectShards returns the configured project's shards (unordered)
+func (s *localStore) projectShards() []*localShard {
+	var out []*localShard
+	for _, sh := range s.data.Shards {
+		if sh.Project == s.cfg.Project {
+			out = append(out, sh)
+		}
+	}
+	return out
+}
+
+// shardKind mirrors the SQL kind lookup: first kind:* label, default "task"
+func (sh *localShard) kind() string {
+	for _, l := range sh.Labels {
+		if strings.HasPrefix(l, "kind:") {
+			return strings.TrimPrefix(l, "kind:")
+		}
+	}
+	return "task"
+}
+
+// capableOf reports whether every needs: label is among capabilities
+func (sh *localShard) capableOf(capabilities []string) bool {
+	for _, l := range sh.Labels {
+		if strings.HasPrefix(l, CapabilityLabelPrefix) && !containsString(capabilities, strings.TrimPrefix(l, CapabilityLabelPrefix)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (sh *localShard) hasLabel(label string) bool {
+	for _, l := range sh.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func (sh *localShard) toShard() *Shard {
+	out := &Shard{
+		ID:        sh.ID,
+		Project:   sh.Project,
+		Title:     sh.Title,
+		Content:   sh.Content,
+		Type:      sh.Type,
+		Status:    sh.Status,
+		Priority:  sh.Priority,
+		Creator:   sh.Creator,
+		Owner:     sh.Owner,
+		CreatedAt: sh.CreatedAt,
+		UpdatedAt: sh.UpdatedAt,
+		Metadata:  sh.metadata(),
+	}
+	out.Labels = append(out.Labels, sh.Labels...)
+	return out
+}
+
+func (sh *localShard) metadata() json.RawMessage {
+	if len(sh.Metadata) == 0 {
+		return json.RawMessage("{}")
+	}
+	return sh.Metadata
+}
+
+// setMetadataKey sets a top-level metadata key
+func (sh *localShard) setMetadataKey(key string, value any) {
+	meta := map[string]any{}
+	_ = json.Unmarshal(sh.metadata(), &meta)
+	meta[key] = value
+	sh.Metadata, _ = json.Marshal(meta)
+}
+
+// metadataString returns a top-level string metadata value, or ""
+func (sh *localShard) metadataString(key string) string {
+	meta := map[string]any{}
+	_ = json.Unmarshal(sh.metadata(), &meta)
+	v, _ := meta[key].(string)
+	return v
+}
+
+// deleteMetadataKeys removes top-level metadata keys
+func (sh *localShard) deleteMetadataKeys(keys ...string) {
+	meta := map[string]any{}
+	_ = json.Unmarshal(sh.metadata(), &meta)
+	for _, k := range keys {
+		delete(meta, k)
+	}
+	sh.Metadata, _ = json.Marshal(meta)
+}
+
+// renewLease records a heartbeat and pushes the lease expiry out from now
+func (sh *localShard) renewLease(now time.Time, lease time.Duration) time.Time {
+	expires := now.Add(lease)
+	sh.setMetadataKey("heartbeat_at", now.Format(time.RFC3339Nano))
+	sh.setMetadataKey("lease_expires_at", expires.Format(time.RFC3339Nano))
+	return expires
+}
+
+// registry returns the edge types in effect for the project
+func (s *localStore) registry() EdgeTypeRegistry {
+	var declared []EdgeType
+	for _, t := range s.data.Types {
+		if t.Project == s.cfg.Project {
+			declared = append(declared, t.EdgeType)
+		}
+	}
+	return mergeEdgeTypes(declared)
+}
+
+// openBlockers returns IDs of unclosed shards that sh waits on through a
+// blocking edge
+func (s *localStore) openBlockers(id string) []string {
+	reg := s.registry()
+	var blockers []string
+	for _, e := range s.data.Edges {
+		if e.FromID != id || !reg.IsBlocking(e.EdgeType) {
+			continue
+		}
+		if b, ok := s.data.Shards[e.ToID]; ok && b.Status != "closed" {
+			blockers = append(blockers, e.ToID)
+		}
+	}
+	return blockers
+}
+
+func (s *localStore) epicTitle(parentID *string) *string {
+	if parentID == nil {
+		return nil
+	}
+	if p, ok := s.data.Shards[*parentID]; ok && p.Type == "epic" {
+		title := p.Title
+		return &title
+	}
+	return nil
+}
+
+func snippet(content string) string {
+	if len(content) > 200 {
+		return content[:200]
+	}
+	return content
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedUnique(labels []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, l := range labels {
+		if !seen[l] {
+			seen[l] = true
+			out = append(out, l)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// priorityLess orders by priority (NULLS LAST), then created_at
+func priorityLess(a, b *localShard) bool {
+	switch {
+	case a.Priority != nil && b.Priority == nil:
+		return true
+	case a.Priority == nil && b.Priority != nil:
+		return false
+	case a.Priority != nil && b.Priority != nil && *a.Priority != *b.Priority:
+		return *a.Priority < *b.Priority
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// textRank counts query term occurrences in title+content; 0 means no match.
+// All terms must appear (like plainto_tsquery's AND semantics).
+func textRank(sh *localShard, query string) int {
+	haystack := strings.ToLower(sh.Title + "\n" + sh.Content)
+	rank := 0
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		n := strings.Count(haystack, term)
+		if n == 0 {
+			return 0
+		}
+		rank += n
+	}
+	return rank
+}
+
+// -- Shards --
+
+func (s *localStore) GetShardCounts(ctx context.Context) (*ShardCounts, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	counts := &ShardCounts{}
+	for _, sh := range s.projectShards() {
+		counts.Total++
+		switch sh.Status {
+		case "open", "in_progress":
+			counts.Open++
+		case "closed":
+			counts.Closed++
+		default:
+			counts.Other++
+		}
+	}
+	return counts, nil
+}
+
+func (s *localStore) GetShard(ctx context.Context, id string) (*Shard, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[id]
+	if !ok {
+		return nil, notFoundf("shard not found: %s", id)
+	}
+	return sh.toShard(), nil
+}
+
+func (s *localStore) GetTask(ctx context.Context, id string) (*Shard, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[id]
+	if !ok || (sh.Type != "task" && sh.Type != "backlog") {
+		return nil, notFoundf("task not found: %s", id)
+	}
+	task := sh.toShard()
+	task.Labels = nil
+	task.Metadata = nil
+	return task, nil
+}
+
+func (s *localStore) GetShardDetail(ctx context.Context, id string) (*ShardDetailResult, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[id]
+	if !ok {
+		return nil, notFoundf("Shard %s not found", id)
+	}
+	d := &ShardDetailResult{
+		ID:        sh.ID,
+		Title:     sh.Title,
+		Content:   sh.Content,
+		Type:      sh.Type,
+		Status:    sh.Status,
+		Creator:   sh.Creator,
+		Labels:    append([]string{}, sh.Labels...),
+		Metadata:  sh.metadata(),
+		CreatedAt: sh.CreatedAt,
+		UpdatedAt: sh.UpdatedAt,
+	}
+	for _, e := range s.data.Edges {
+		if e.FromID == id {
+			d.OutgoingEdgeCount++
+		}
+		if e.ToID == id {
+			d.IncomingEdgeCount++
+		}
+	}
+	return d, nil
+}
+
+func (s *localStore) ShardExists(ctx context.Context, id string) (bool, error) {
+	if err := s.lock(); err != nil {
+		return false, err
+	}
+	defer s.unlock()
+
+	_, ok := s.data.Shards[id]
+	return ok, nil
+}
+
+func (s *localStore) CreateShard(ctx context.Context, title, content, shardType string, priority *int, labels []string, metadata json.RawMessage) (string, error) {
+	if err := s.lock(); err != nil {
+		return "", err
+	}
+	defer s.unlock()
+
+	if metadata != nil && !json.Valid(metadata) {
+		return "", invalidf("failed to create shard: invalid metadata JSON")
+	}
+
+	now := time.Now().UTC()
+	sh := &localShard{
+		ID:        s.newID(),
+		Project:   s.cfg.Project,
+		Title:     title,
+		Content:   content,
+		Type:      shardType,
+		Status:    "open",
+		Priority:  priority,
+		Creator:   s.cfg.Agent,
+		Labels:    sortedUnique(labels),
+		Metadata:  metadata,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.data.Shards[sh.ID] = sh
+
+	if err := s.save(); err != nil {
+		return "", err
+	}
+	return sh.ID, nil
+}
+
+func (s *localStore) UpdateShardFields(ctx context.Context, id string, title *string, content *string) (*UpdateShardResult, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[id]
+	if !ok || sh.Project != s.cfg.Project {
+		return nil, notFoundf("Shard %s not found", id)
+	}
+	if title != nil {
+		sh.Title = *title
+	}
+	if content != nil {
+		sh.Content = *content
+	}
+	sh.UpdatedAt = time.Now().UTC()
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &UpdateShardResult{
+		ID:             id,
+		UpdatedAt:      sh.UpdatedAt,
+		TitleChanged:   title != nil,
+		ContentChanged: content != nil,
+		ShardType:      sh.Type,
+	}, nil
+}
+
+func (s *localStore) UpdateShardStatus(ctx context.Context, id, status string) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[id]
+	if !ok {
+		return notFoundf("shard not found: %s", id)
+	}
+	sh.Status = status
+	sh.UpdatedAt = time.Now().UTC()
+	return s.save()
+}
+
+func (s *localStore) AddProgress(ctx context.Context, id, note string) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[id]
+	if !ok || sh.Type == "epic" || sh.Type == "memory" || sh.Type == "message" {
+		return notFoundf("task not found: %s", id)
+	}
+	sh.Content += progressNote(s.cfg.Agent, note)
+	sh.UpdatedAt = time.Now().UTC()
+	if sh.Status == "in_progress" && sh.Owner != nil && *sh.Owner == s.cfg.Agent {
+		lease := s.cfg.LeaseDuration()
+		var meta struct {
+			LeaseExpiresAt *time.Time `json:"lease_expires_at"`
+		}
+		if json.Unmarshal(sh.metadata(), &meta) == nil && meta.LeaseExpiresAt != nil {
+			lease = max(lease, meta.LeaseExpiresAt.Sub(sh.UpdatedAt))
+		}
+		sh.renewLease(sh.UpdatedAt, lease)
+	}
+	return s.save()
+}
+
+func (s *localStore) ListShardsByType(ctx context.Context, shardType string, statusFilter string, limit int) ([]Shard, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	var matched []*localShard
+	for _, sh := range s.projectShards() {
+		if sh.Type != shardType || (statusFilter != "" && sh.Status != statusFilter) {
+			continue
+		}
+		matched = append(matched, sh)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if (a.Priority == nil) != (b.Priority == nil) || (a.Priority != nil && *a.Priority != *b.Priority) {
+			return priorityLess(a, b)
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+
+	var shards []Shard
+	for i, sh := range matched {
+		if i >= limit {
+			break
+		}
+		out := sh.toShard()
+		out.Content = snippet(out.Content)
+		out.Labels = nil
+		shards = append(shards, *out)
+	}
+	return shards, nil
+}
+
+// filterShards applies ListShardsOpts filters, newest first
+func (s *localStore) filterShards(opts ListShardsOpts) []*localShard {
+	var matched []*localShard
+	for _, sh := range s.projectShards() {
+		if opts.Types != nil && !containsString(opts.Types, sh.Type) {
+			continue
+		}
+		if opts.Status != nil && !containsString(opts.Status, sh.Status) {
+			continue
+		}
+		if opts.Labels != nil {
+			hasAll := true
+			for _, l := range opts.Labels {
+				if !sh.hasLabel(l) {
+					hasAll = false
+					break
+				}
+			}
+			if !hasAll {
+				continue
+			}
+		}
+		if opts.Creator != "" && sh.Creator != opts.Creator {
+			continue
+		}
+		if opts.Search != "" && textRank(sh, opts.Search) == 0 {
+			continue
+		}
+		if opts.Since != nil && sh.CreatedAt.Before(*opts.Since) {
+			continue
+		}
+		if opts.RootsOnly && sh.ParentID != nil {
+			continue
+		}
+		matched = append(matched, sh)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+	return matched
+}
+
+func (s *localStore) ListShardsFiltered(ctx context.Context, opts ListShardsOpts) ([]ShardListResult, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	limit := opts.Limit
+	if limit == 0 {
+		limit = 20
+	}
+
+	matched := s.filterShards(opts)
+	var results []ShardListResult
+	for i := opts.Offset; i < len(matched) && len(results) < limit; i++ {
+		sh := matched[i]
+		results = append(results, ShardListResult{
+			ID:        sh.ID,
+			Title:     sh.Title,
+			Type:      sh.Type,
+			Status:    sh.Status,
+			Creator:   sh.Creator,
+			Labels:    append([]string{}, sh.Labels...),
+			CreatedAt: sh.CreatedAt,
+			UpdatedAt: sh.UpdatedAt,
+			Snippet:   snippet(sh.Content),
+		})
+	}
+	return results, nil
+}
+
+func (s *localStore) ListShardsCount(ctx context.Context, opts ListShardsOpts) (int, error) {
+	if err := s.lock(); err != nil {
+		return 0, err
+	}
+	defer s.unlock()
+
+	return len(s.filterShards(opts)), nil
+}
+
+func (s *localStore) SearchShards(ctx context.Context, query string, shardType string, limit int) ([]Shard, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	type ranked struct {
+		sh   *localShard
+		rank int
+	}
+	var matched []ranked
+	for _, sh := range s.projectShards() {
+		if shardType != "" && sh.Type != shardType {
+			continue
+		}
+		if r := textRank(sh, query); r > 0 {
+			matched = append(matched, ranked{sh, r})
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].rank > matched[j].rank })
+
+	var shards []Shard
+	for i, m := range matched {
+		if i >= limit {
+			break
+		}
+		out := m.sh.toShard()
+		out.Content = snippet(out.Content)
+		out.Labels = nil
+		shards = append(shards, *out)
+	}
+	return shards, nil
+}
+
+// -- Edges and labels --
+
+func (s *localStore) CreateEdge(ctx context.Context, fromID, toID, edgeType string, metadata json.RawMessage) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	if _, ok := s.data.Shards[fromID]; !ok {
+		return notFoundf("Shard %s not found", fromID)
+	}
+	if _, ok := s.data.Shards[toID]; !ok {
+		return notFoundf("Shard %s not found", toID)
+	}
+	if fromID == toID {
+		return invalidf("Cannot create edge from a shard to itself")
+	}
+	for _, e := range s.data.Edges {
+		if e.FromID == fromID && e.ToID == toID && e.EdgeType == edgeType {
+			return conflictf("Edge already exists: %s --%s--> %s", fromID, edgeType, toID)
+		}
+	}
+	if metadata == nil {
+		metadata = json.RawMessage("{}")
+	}
+
+	s.data.Edges = append(s.data.Edges, localEdge{
+		FromID:    fromID,
+		ToID:      toID,
+		EdgeType:  edgeType,
+		Metadata:  metadata,
+		CreatedAt: time.Now().UTC(),
+	})
+	return s.save()
+}
+
+func (s *localStore) DeleteEdge(ctx context.Context, fromID, toID, edgeType string) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	for i, e := range s.data.Edges {
+		if e.FromID == fromID && e.ToID == toID && e.EdgeType == edgeType {
+			s.data.Edges = append(s.data.Edges[:i], s.data.Edges[i+1:]...)
+			return s.save()
+		}
+	}
+	return notFoundf("No edge of type '%s' from %s to %s", edgeType, fromID, toID)
+}
+
+func (s *localStore) GetShardEdges(ctx context.Context, shardID string, direction string, edgeTypes []string) ([]EdgeInfo, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	var edges []EdgeInfo
+	for _, e := range s.data.Edges {
+		if edgeTypes != nil && !containsString(edgeTypes, e.EdgeType) {
+			continue
+		}
+		var dir, linked string
+		switch {
+		case e.FromID == shardID && (direction == "" || direction == "outgoing"):
+			dir, linked = "outgoing", e.ToID
+		case e.ToID == shardID && (direction == "" || direction == "incoming"):
+			dir, linked = "incoming", e.FromID
+		default:
+			continue
+		}
+		sh, ok := s.data.Shards[linked]
+		if !ok {
+			continue
+		}
+		edges = append(edges, EdgeInfo{
+			Direction:    dir,
+			EdgeType:     e.EdgeType,
+			ShardID:      sh.ID,
+			Title:        sh.Title,
+			Type:         sh.Type,
+			Status:       sh.Status,
+			EdgeMetadata: e.Metadata,
+		})
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].EdgeType != edges[j].EdgeType {
+			return edges[i].EdgeType < edges[j].EdgeType
+		}
+		return edges[i].Direction < edges[j].Direction
+	})
+	return edges, nil
+}
+
+func (s *localStore) HasCircularDependency(ctx context.Context, fromID, toID string, edgeTypes []string) (bool, error) {
+	if err := s.lock(); err != nil {
+		return false, err
+	}
+	defer s.unlock()
+
+	if fromID == toID {
+		return true, nil
+	}
+	visited := map[string]bool{}
+	queue := []string{toID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, e := range s.data.Edges {
+			if e.FromID != current || !containsString(edgeTypes, e.EdgeType) || visited[e.ToID] {
+				continue
+			}
+			if e.ToID == fromID {
+				return true, nil
+			}
+			visited[e.ToID] = true
+			queue = append(queue, e.ToID)
+		}
+	}
+	return false, nil
+}
+
+// -- Agent registry --
+
+func (s *localStore) ListAgents(ctx context.Context) ([]Agent, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	agents := append([]Agent(nil), s.data.Agents...)
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	return agents, nil
+}
+
+func (s *localStore) GetAgent(ctx context.Context, id string) (*Agent, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	for _, a := range s.data.Agents {
+		if a.ID == id {
+			return &a, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *localStore) SaveAgent(ctx context.Context, a Agent) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	a.Presence = ""
+	for i, existing := range s.data.Agents {
+		if existing.ID == a.ID {
+			s.data.Agents[i] = a
+			return s.save()
+		}
+	}
+	s.data.Agents = append(s.data.Agents, a)
+	return s.save()
+}
+
+func (s *localStore) TouchAgent(ctx context.Context, id string) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	for i := range s.data.Agents {
+		if s.data.Agents[i].ID == id {
+			now := time.Now().UTC()
+			s.data.Agents[i].LastSeen = &now
+			return s.save()
+		}
+	}
+	return notFoundf("agent %s is not registered (run cp agent register)", id)
+}
+
+func (s *localStore) ListEdgeTypes(ctx context.Context) ([]EdgeType, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	var types []EdgeType
+	for _, t := range s.data.Types {
+		if t.Project == s.cfg.Project {
+			types = append(types, t.EdgeType)
+		}
+	}
+	return types, nil
+}
+
+func (s *localStore) SaveEdgeType(ctx context.Context, t EdgeType) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	entry := localEdgeType{Project: s.cfg.Project, EdgeType: t}
+	replaced := false
+	for i, existing := range s.data.Types {
+		if existing.Project == s.cfg.Project && existing.Name == t.Name {
+			s.data.Types[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		s.data.Types = append(s.data.Types, entry)
+	}
+	return s.save()
+}
+
+func (s *localStore) DeleteEdgeType(ctx context.Context, name string, builtIn bool) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	if !builtIn {
+		used := 0
+		for _, e := range s.data.Edges {
+			if sh, ok := s.data.Shards[e.FromID]; ok && sh.Project == s.cfg.Project && e.EdgeType == name {
+				used++
+			}
+		}
+		if used > 0 {
+			return conflictf("%d edges use type %s; unlink them first", used, name)
+		}
+	}
+
+	kept := s.data.Types[:0]
+	found := false
+	for _, t := range s.data.Types {
+		if t.Project == s.cfg.Project && t.Name == name {
+			found = true
+			continue
+		}
+		kept = append(kept, t)
+	}
+	if !found {
+		return notFoundf("edge type %s is not declared in project %s", name, s.cfg.Project)
+	}
+	s.data.Types = kept
+	return s.save()
+}
+
+func (s *localStore) AddShardLabels(ctx context.Context, shardID string, labels []string) ([]string, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[shardID]
+	if !ok {
+		return nil, notFoundf("Shard %s not found", shardID)
+	}
+	sh.Labels = sortedUnique(append(sh.Labels, labels...))
+	sh.UpdatedAt = time.Now().UTC()
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return append([]string{}, sh.Labels...), nil
+}
+
+func (s *localStore) RemoveShardLabels(ctx context.Context, shardID string, labels []string) ([]string, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[shardID]
+	if !ok {
+		return nil, notFoundf("Shard %s not found", shardID)
+	}
+	kept := []string{}
+	for _, l := range sh.Labels {
+		if !containsString(labels, l) {
+			kept = append(kept, l)
+		}
+	}
+	sh.Labels = sortedUnique(kept)
+	sh.UpdatedAt = time.Now().UTC()
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return append([]string{}, sh.Labels...), nil
+}
+
+func (s *localStore) LabelSummary(ctx context.Context) ([]LabelCount, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	counts := map[string]int{}
+	for _, sh := range s.projectShards() {
+		if sh.Status == "closed" {
+			continue
+		}
+		for _, l := range sh.Labels {
+			counts[l]++
+		}
+	}
+	var labels []LabelCount
+	for l, n := range counts {
+		labels = append(labels, LabelCount{Label: l, Count: n})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Count != labels[j].Count {
+			return labels[i].Count > labels[j].Count
+		}
+		return labels[i].Label < labels[j].Label
+	})
+	return labels, nil
+}
+
+// -- Messages --
+
+func (s *localStore) SendMessage(ctx context.Context, recipients []string, subject, body string, cc []string, kind string, replyTo string, extra []string, due *time.Time) (string, error) {
+	if err := s.lock(); err != nil {
+		return "", err
+	}
+	defer s.unlock()
+
+	if replyTo != "" {
+		if _, ok := s.data.Shards[replyTo]; !ok {
+			return "", notFoundf("failed to send message: shard %s not found", replyTo)
+		}
+	}
+
+	var labels []string
+	for _, r := range recipients {
+		labels = append(labels, "to:"+r)
+	}
+	for _, r := range cc {
+		labels = append(labels, "cc:"+r)
+	}
+	if kind != "" {
+		labels = append(labels, "kind:"+kind)
+	}
+	labels = append(labels, extra...)
+
+	now := time.Now().UTC()
+	msg := &localShard{
+		ID:        s.newID(),
+		Project:   s.cfg.Project,
+		Title:     subject,
+		Content:   body,
+		Type:      "message",
+		Status:    "open",
+		Creator:   s.cfg.Agent,
+		Labels:    sortedUnique(labels),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if kind == RequestKind {
+		msg.setMetadataKey("expects_reply", true)
+		if due != nil {
+			msg.setMetadataKey("due_at", due.UTC().Format(time.RFC3339))
+		}
+	}
+	s.data.Shards[msg.ID] = msg
+
+	if replyTo != "" {
+		s.data.Edges = append(s.data.Edges, localEdge{
+			FromID: msg.ID, ToID: replyTo, EdgeType: "replies-to", CreatedAt: now,
+		})
+		s.markRead(replyTo, s.cfg.Agent, now)
+	}
+
+	if err := s.save(); err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// markRead records a read receipt; returns false if one already existed
+func (s *localStore) markRead(shardID, agent string, at time.Time) bool {
+	for _, r := range s.data.Receipts {
+		if r.ShardID == shardID && r.AgentID == agent {
+			return false
+		}
+	}
+	s.data.Receipts = append(s.data.Receipts, localReceipt{ShardID: shardID, AgentID: agent, ReadAt: at})
+	return true
+}
+
+func (s *localStore) hasRead(shardID, agent string) bool {
+	for _, r := range s.data.Receipts {
+		if r.ShardID == shardID && r.AgentID == agent {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *localStore) GetInbox(ctx context.Context) ([]Message, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	agent := s.cfg.Agent
+	var msgs []*localShard
+	for _, sh := range s.projectShards() {
+		if sh.Type != "message" || sh.Status != "open" {
+			continue
+		}
+		if !sh.hasLabel("to:"+agent) && !sh.hasLabel("cc:"+agent) {
+			continue
+		}
+		if s.hasRead(sh.ID, agent) {
+			continue
+		}
+		msgs = append(msgs, sh)
+	}
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].CreatedAt.Before(msgs[j].CreatedAt) })
+
+	var messages []Message
+	for _, sh := range msgs {
+		m := Message{ID: sh.ID, Title: sh.Title, Creator: sh.Creator, CreatedAt: sh.CreatedAt}
+		for _, l := range sh.Labels {
+			if strings.HasPrefix(l, "kind:") {
+				kind := l
+				m.Kind = &kind
+				break
+			}
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+func (s *localStore) MarkRead(ctx context.Context, shardIDs []string) (int, error) {
+	if err := s.lock(); err != nil {
+		return 0, err
+	}
+	defer s.unlock()
+
+	now := time.Now().UTC()
+	for _, id := range shardIDs {
+		if _, ok := s.data.Shards[id]; !ok {
+			return 0, notFoundf("failed to mark as read: shard %s not found", id)
+		}
+		s.markRead(id, s.cfg.Agent, now)
+	}
+	if err := s.save(); err != nil {
+		return 0, err
+	}
+	return len(shardIDs), nil
+}
+
+func (s *localStore) ListSent(ctx context.Context, limit int) ([]SentMessage, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	var msgs []*localShard
+	for _, sh := range s.projectShards() {
+		if sh.Type == "message" && sh.Creator == s.cfg.Agent {
+			msgs = append(msgs, sh)
+		}
+	}
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].CreatedAt.After(msgs[j].CreatedAt) })
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+
+	var sent []SentMessage
+	for _, sh := range msgs {
+		m := SentMessage{Message: Message{ID: sh.ID, Title: sh.Title, Creator: sh.Creator, CreatedAt: sh.CreatedAt}}
+		m.setAddressing(sh.Labels)
+		for _, r := range s.data.Receipts {
+			if r.ShardID == sh.ID {
+				readAt := r.ReadAt
+				m.Receipts = append(m.Receipts, Receipt{Agent: r.AgentID, ReadAt: &readAt})
+			}
+		}
+		sent = append(sent, m)
+	}
+	return sent, nil
+}
+
+func (s *localStore) ClaimEscalation(ctx context.Context, id string, at time.Time) (bool, error) {
+	if err := s.lock(); err != nil {
+		return false, err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[id]
+	if !ok {
+		return false, notFoundf("failed to claim %s for escalation: shard not found", id)
+	}
+	if sh.metadataString("escalated_at") != "" {
+		return false, nil
+	}
+	sh.setMetadataKey("escalated_at", at.UTC().Format(time.RFC3339))
+	return true, s.save()
+}
+
+func (s *localStore) ReleaseEscalation(ctx context.Context, id string, at time.Time) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[id]
+	if !ok || sh.metadataString("escalated_at") != at.UTC().Format(time.RFC3339) {
+		return nil
+	}
+	sh.deleteMetadataKeys("escalated_at")
+	return s.save()
+}
+
+func (s *localStore) OpenRequests(ctx context.Context, agent string) ([]Request, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	// Replies to a request, or to its escalation follow-ups, by anyone but
+	// the escalations themselves
+	replies := map[string][]string{}
+	for _, e := range s.data.Edges {
+		if e.EdgeType == "replies-to" {
+			replies[e.ToID] = append(replies[e.ToID], e.FromID)
+		}
+	}
+	isEscalation := func(id string) bool {
+		sh, ok := s.data.Shards[id]
+		return ok && sh.hasLabel("kind:"+EscalationKind)
+	}
+
+	var reqs []Request
+	for _, sh := range s.projectShards() {
+		if sh.Type != "message" || sh.Status != "open" {
+			continue
+		}
+		var meta struct {
+			ExpectsReply bool       `json:"expects_reply"`
+			DueAt        *time.Time `json:"due_at"`
+			EscalatedAt  *time.Time `json:"escalated_at"`
+		}
+		if json.Unmarshal(sh.metadata(), &meta) != nil || !meta.ExpectsReply {
+			continue
+		}
+		if agent != "" && sh.Creator != agent && !sh.hasLabel("to:"+agent) {
+			continue
+		}
+
+		r := Request{
+			Message:     Message{ID: sh.ID, Title: sh.Title, Creator: sh.Creator, CreatedAt: sh.CreatedAt},
+			DueAt:       meta.DueAt,
+			EscalatedAt: meta.EscalatedAt,
+		}
+		r.setAddressing(sh.Labels)
+		answers := append([]string{}, replies[sh.ID]...)
+		for _, id := range replies[sh.ID] {
+			if isEscalation(id) {
+				answers = append(answers, replies[id]...)
+			}
+		}
+		for _, id := range answers {
+			if reply, ok := s.data.Shards[id]; ok && !isEscalation(id) && !containsString(r.Replied, reply.Creator) {
+				r.Replied = append(r.Replied, reply.Creator)
+			}
+		}
+		reqs = append(reqs, r)
+	}
+	sort.SliceStable(reqs, func(i, j int) bool {
+		a, b := reqs[i].DueAt, reqs[j].DueAt
+		switch {
+		case a != nil && b != nil && !a.Equal(*b):
+			return a.Before(*b)
+		case (a == nil) != (b == nil):
+			return a != nil
+		}
+		return reqs[i].CreatedAt.Before(reqs[j].CreatedAt)
+	})
+	return reqs, nil
+}
+
+func (s *localStore) GetThread(ctx context.Context, id string) ([]ThreadMessage, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	if sh, ok := s.data.Shards[id]; !ok || sh.Project != s.cfg.Project {
+		return nil, nil
+	}
+	parent := map[string]string{}
+	replies := map[string][]string{}
+	for _, e := range s.data.Edges {
+		if e.EdgeType != "replies-to" {
+			continue
+		}
+		if _, ok := parent[e.FromID]; !ok {
+			parent[e.FromID] = e.ToID
+		}
+		replies[e.ToID] = append(replies[e.ToID], e.FromID)
+	}
+
+	root := id
+	for hops := 0; hops < 100; hops++ {
+		p, ok := parent[root]
+		if !ok {
+			break
+		}
+		root = p
+	}
+
+	var msgs []ThreadMessage
+	seen := map[string]bool{}
+	queue := []string{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+		sh, ok := s.data.Shards[cur]
+		if !ok {
+			continue
+		}
+		m := ThreadMessage{
+			Message:  Message{ID: sh.ID, Title: sh.Title, Creator: sh.Creator, CreatedAt: sh.CreatedAt, Content: sh.Content},
+			ReplyTo:  parent[sh.ID],
+			Receipts: []Receipt{},
+		}
+		m.setAddressing(sh.Labels)
+		for _, r := range s.data.Receipts {
+			if r.ShardID == sh.ID {
+				readAt := r.ReadAt
+				m.Receipts = append(m.Receipts, Receipt{Agent: r.AgentID, ReadAt: &readAt})
+			}
+		}
+		msgs = append(msgs, m)
+		queue = append(queue, replies[cur]...)
+	}
+	return msgs, nil
+}
+
+// -- Lifecycle --
+
+func (s *localStore) AssignShard(ctx context.Context, shardID string, agent string, lease time.Duration) (*AssignResult, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	if agent == "" {
+		agent = s.cfg.Agent
+	}
+
+	sh, ok := s.data.Shards[shardID]
+	if !ok || sh.Project != s.cfg.Project {
+		return nil, notFoundf("Shard %s not found", shardID)
+	}
+	switch sh.Status {
+	case "in_progress":
+		owner := ""
+		if sh.Owner != nil {
+			owner = *sh.Owner
+		}
+		return nil, conflictf("Shard %s is already in_progress (owner: %s)", shardID, owner)
+	case "closed":
+		return nil, conflictf("Shard %s is already closed", shardID)
+	}
+	if len(s.openBlockers(shardID)) > 0 {
+		return nil, conflictf("Shard %s has unresolved blockers", shardID)
+	}
+
+	now := time.Now().UTC()
+	sh.Status = "in_progress"
+	sh.Owner = &agent
+	sh.UpdatedAt = now
+	sh.setMetadataKey("assigned_at", now.Format(time.RFC3339Nano))
+	expires := sh.renewLease(now, lease)
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &AssignResult{ID: shardID, Title: sh.Title, Owner: agent, LeaseExpiresAt: expires}, nil
+}
+
+func (s *localStore) CloseShard(ctx context.Context, shardID string, reason string) (*CloseResult, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[shardID]
+	if !ok || sh.Project != s.cfg.Project {
+		return nil, notFoundf("Shard %s not found", shardID)
+	}
+
+	result := &CloseResult{
+		ID:       shardID,
+		Title:    sh.Title,
+		Status:   "closed",
+		ClosedAt: time.Now(),
+		Reason:   reason,
+	}
+
+	// Idempotent: already closed = no-op
+	if sh.Status == "closed" {
+		return result, nil
+	}
+
+	now := time.Now().UTC()
+	agent := s.cfg.Agent
+	sh.Status = "closed"
+	sh.ClosedAt = &now
+	sh.ClosedBy = &agent
+	if reason != "" {
+		sh.ClosedReason = &reason
+	}
+	sh.UpdatedAt = now
+	if sh.ParentID != nil {
+		if parent, ok := s.data.Shards[*sh.ParentID]; ok {
+			parent.UpdatedAt = now
+		}
+	}
+
+	reg := s.registry()
+	for _, e := range s.data.Edges {
+		if e.ToID != shardID || !reg.IsBlocking(e.EdgeType) {
+			continue
+		}
+		dep, ok := s.data.Shards[e.FromID]
+		if ok && dep.Status == "open" && len(s.openBlockers(dep.ID)) == 0 {
+			result.Unblocked = append(result.Unblocked, UnblockedShard{ID: dep.ID, Title: dep.Title})
+		}
+	}
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *localStore) GetNextShards(ctx context.Context, epicID *string, capabilities []string, limit int) ([]NextShard, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	if limit <= 0 {
+		limit = 1
+	}
+	if limit > 10 {
+		limit = 10
+	}
+
+	var candidates []*localShard
+	for _, sh := range s.projectShards() {
+		if sh.Status != "open" || sh.Type == "epic" || sh.Type == "memory" || sh.Type == "message" {
+			continue
+		}
+		if epicID != nil && (sh.ParentID == nil || *sh.ParentID != *epicID) {
+			continue
+		}
+		if capabilities != nil && !sh.capableOf(capabilities) {
+			continue
+		}
+		if len(s.openBlockers(sh.ID)) > 0 {
+			continue
+		}
+		candidates = append(candidates, sh)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return priorityLess(candidates[i], candidates[j]) })
+
+	var shards []NextShard
+	for i, sh := range candidates {
+		if i >= limit {
+			break
+		}
+		shards = append(shards, NextShard{
+			ID:        sh.ID,
+			Title:     sh.Title,
+			Kind:      sh.kind(),
+			Priority:  sh.Priority,
+			EpicID:    sh.ParentID,
+			EpicTitle: s.epicTitle(sh.ParentID),
+		})
+	}
+	return shards, nil
+}
+
+// TakeShard selects and assigns in one critical section: the file lock keeps
+// other processes out between reading the candidates and saving the claim.
+func (s *localStore) TakeShard(ctx context.Context, epicID *string, kind *string, agent string, lease time.Duration) (*NextShard, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	if agent == "" {
+		agent = s.cfg.Agent
+	}
+
+	var candidates []*localShard
+	for _, sh := range s.projectShards() {
+		if sh.Status != "open" || sh.Type == "epic" || sh.Type == "memory" || sh.Type == "message" {
+			continue
+		}
+		if epicID != nil && (sh.ParentID == nil || *sh.ParentID != *epicID) {
+			continue
+		}
+		if kind != nil && sh.kind() != *kind {
+			continue
+		}
+		if len(s.openBlockers(sh.ID)) > 0 {
+			continue
+		}
+		candidates = append(candidates, sh)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	sort.Slice(candidates, func(i, j int) bool { return priorityLess(candidates[i], candidates[j]) })
+
+	sh := candidates[0]
+	now := time.Now().UTC()
+	sh.Status = "in_progress"
+	sh.Owner = &agent
+	sh.UpdatedAt = now
+	sh.setMetadataKey("assigned_at", now.Format(time.RFC3339Nano))
+	expires := sh.renewLease(now, lease)
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &NextShard{
+		ID:             sh.ID,
+		Title:          sh.Title,
+		Kind:           sh.kind(),
+		Priority:       sh.Priority,
+		EpicID:         sh.ParentID,
+		EpicTitle:      s.epicTitle(sh.ParentID),
+		LeaseExpiresAt: &expires,
+	}, nil
+}
+
+func (s *localStore) RenewLease(ctx context.Context, shardID string, lease time.Duration) (*Lease, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[shardID]
+	if !ok || sh.Project != s.cfg.Project {
+		return nil, notFoundf("Shard %s not found", shardID)
+	}
+	if sh.Status != "in_progress" {
+		return nil, conflictf("Shard %s is %s (lease expired or released); claim it again", shardID, sh.Status)
+	}
+	if sh.Owner == nil || *sh.Owner != s.cfg.Agent {
+		owner := "nobody"
+		if sh.Owner != nil {
+			owner = *sh.Owner
+		}
+		return nil, conflictf("Shard %s is owned by %s", shardID, owner)
+	}
+
+	now := time.Now().UTC()
+	expires := sh.renewLease(now, lease)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &Lease{ID: shardID, Owner: s.cfg.Agent, HeartbeatAt: now, ExpiresAt: expires}, nil
+}
+
+func (s *localStore) ReapLeases(ctx context.Context, dryRun bool) ([]ReapedShard, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	now := time.Now().UTC()
+	reaped := []ReapedShard{}
+	for _, sh := range s.projectShards() {
+		if sh.Status != "in_progress" {
+			continue
+		}
+		var meta struct {
+			LeaseExpiresAt *time.Time `json:"lease_expires_at"`
+		}
+		if json.Unmarshal(sh.metadata(), &meta) != nil || meta.LeaseExpiresAt == nil || !meta.LeaseExpiresAt.Before(now) {
+			continue
+		}
+		reaped = append(reaped, ReapedShard{ID: sh.ID, Title: sh.Title, Owner: sh.Owner, ExpiredAt: *meta.LeaseExpiresAt})
+		if dryRun {
+			continue
+		}
+
+		owner := "nobody"
+		if sh.Owner != nil {
+			owner = *sh.Owner
+		}
+		sh.Content += progressNote(s.cfg.Agent, fmt.Sprintf("Lease held by %s expired at %s; returned to open.",
+			owner, meta.LeaseExpiresAt.Local().Format("2006-01-02 15:04:05")))
+		sh.Status = "open"
+		sh.Owner = nil
+		sh.UpdatedAt = now
+		sh.deleteMetadataKeys("assigned_at", "heartbeat_at", "lease_expires_at")
+	}
+	sort.Slice(reaped, func(i, j int) bool { return reaped[i].ExpiredAt.Before(reaped[j].ExpiredAt) })
+
+	if len(reaped) > 0 && !dryRun {
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+	return reaped, nil
+}
+
+func (s *localStore) GetShardBoard(ctx context.Context, epicID *string, agent *string) ([]BoardShard, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	statusOrder := map[string]int{"in_progress": 0, "open": 1, "closed": 2}
+	recent := time.Now().Add(-24 * time.Hour)
+
+	var matched []*localShard
+	for _, sh := range s.projectShards() {
+		if sh.Type == "epic" || sh.Type == "memory" || sh.Type == "message" {
+			continue
+		}
+		if epicID != nil && (sh.ParentID == nil || *sh.ParentID != *epicID) {
+			continue
+		}
+		if agent != nil && (sh.Owner == nil || *sh.Owner != *agent) {
+			continue
+		}
+		if agent == nil && epicID == nil && sh.Status == "closed" &&
+			(sh.ClosedAt == nil || sh.ClosedAt.Before(recent)) {
+			continue
+		}
+		matched = append(matched, sh)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if statusOrder[a.Status] != statusOrder[b.Status] {
+			return statusOrder[a.Status] < statusOrder[b.Status]
+		}
+		return priorityLess(a, b)
+	})
+
+	var shards []BoardShard
+	for _, sh := range matched {
+		b := BoardShard{
+			ID:        sh.ID,
+			Title:     sh.Title,
+			Status:    sh.Status,
+			Kind:      sh.kind(),
+			Owner:     sh.Owner,
+			Priority:  sh.Priority,
+			EpicID:    sh.ParentID,
+			EpicTitle: s.epicTitle(sh.ParentID),
+			ClosedAt:  sh.ClosedAt,
+			BlockedBy: s.openBlockers(sh.ID),
+		}
+		var meta struct {
+			AssignedAt     *time.Time `json:"assigned_at"`
+			HeartbeatAt    *time.Time `json:"heartbeat_at"`
+			LeaseExpiresAt *time.Time `json:"lease_expires_at"`
+		}
+		if json.Unmarshal(sh.metadata(), &meta) == nil {
+			b.AssignedAt = meta.AssignedAt
+			b.HeartbeatAt = meta.HeartbeatAt
+			b.LeaseExpiresAt = meta.LeaseExpiresAt
+		}
+		if b.BlockedBy == nil {
+			b.BlockedBy = []string{}
+		}
+		shards = append(shards, b)
+	}
+	return shards, nil
+}
+
+// -- Embeddings --
+
+// cosineSimilarity returns the cosine similarity of two equal-length vectors
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func (s *localStore) SemanticSearch(ctx context.Context, queryEmbedding []float32, types []string, labels []string, status []string, limit int, minSimilarity float64, since *time.Time) ([]RecallResult, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	var results []RecallResult
+	for _, sh := range s.projectShards() {
+		if len(sh.Embedding) == 0 {
+			continue
+		}
+		if len(sh.Embedding) != len(queryEmbedding) {
+			return nil, fmt.Errorf("semantic search failed: embedding dimension mismatch: stored %d, query %d", len(sh.Embedding), len(queryEmbedding))
+		}
+		if types != nil && !containsString(types, sh.Type) {
+			continue
+		}
+		if labels != nil {
+			matched := false
+			for _, l := range labels {
+				if sh.hasLabel(l) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		if status != nil && !containsString(status, sh.Status) {
+			continue
+		}
+		if since != nil && sh.CreatedAt.Before(*since) {
+			continue
+		}
+		r := RecallResult{
+			ID:         sh.ID,
+			Title:      sh.Title,
+			Type:       sh.Type,
+			Status:     sh.Status,
+			Similarity: cosineSimilarity(queryEmbedding, sh.Embedding),
+			Snippet:    snippet(sh.Content),
+			Labels:     append([]string{}, sh.Labels...),
+			CreatedAt:  sh.CreatedAt,
+		}
+		// Best chunk wins over the whole-shard vector
+		for _, ch := range sh.Chunks {
+			if sim := cosineSimilarity(queryEmbedding, ch.Embedding); sim > r.Similarity {
+				index, heading := ch.Index, ch.Heading
+				r.Similarity = sim
+				r.Snippet = snippet(ch.Content)
+				r.ChunkIndex = &index
+				r.ChunkHeading = nil
+				if heading != "" {
+					r.ChunkHeading = &heading
+				}
+			}
+		}
+		if r.Similarity < minSimilarity {
+			continue
+		}
+		results = append(results, r)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Similarity > results[j].Similarity })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// KeywordSearch mirrors keyword_search(): term-frequency rank plus boosts for
+// an exact ID, title or content match.
+func (s *localStore) KeywordSearch(ctx context.Context, query string, types []string, labels []string, status []string, limit int, since *time.Time) ([]RecallResult, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	lq := strings.ToLower(query)
+	var results []RecallResult
+	for _, sh := range s.projectShards() {
+		if types != nil && !containsString(types, sh.Type) {
+			continue
+		}
+		if labels != nil {
+			matched := false
+			for _, l := range labels {
+				if sh.hasLabel(l) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		if status != nil && !containsString(status, sh.Status) {
+			continue
+		}
+		if since != nil && sh.CreatedAt.Before(*since) {
+			continue
+		}
+
+		rank := textRank(sh, query)
+		score := float64(rank) / float64(rank+10)
+		if sh.ID == query {
+			score += 1.0
+		}
+		if strings.Contains(strings.ToLower(sh.Title), lq) {
+			score += 0.5
+		}
+		snip := snippet(sh.Content)
+		if pos := strings.Index(sh.Content, query); pos >= 0 {
+			score += 0.3
+			start := pos - 60
+			if start < 0 {
+				start = 0
+			}
+			for start > 0 && !utf8.RuneStart(sh.Content[start]) {
+				start--
+			}
+			snip = snippet(sh.Content[start:])
+		}
+		if score == 0 {
+			continue
+		}
+		results = append(results, RecallResult{
+			ID:         sh.ID,
+			Title:      sh.Title,
+			Type:       sh.Type,
+			Status:     sh.Status,
+			Similarity: score,
+			Snippet:    snip,
+			Labels:     append([]string{}, sh.Labels...),
+			CreatedAt:  sh.CreatedAt,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Similarity != results[j].Similarity {
+			return results[i].Similarity > results[j].Similarity
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (s *localStore) UpdateEmbedding(ctx context.Context, shardID string, emb []float32, model embedding.ModelInfo) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[shardID]
+	if !ok {
+		return notFoundf("shard not found: %s", shardID)
+	}
+	sh.Embedding = emb
+	sh.EmbeddingBy = &model
+	return s.save()
+}
+
+func (s *localStore) ReplaceChunks(ctx context.Context, shardID string, chunks []ChunkEmbedding, model embedding.ModelInfo) error {
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[shardID]
+	if !ok {
+		return notFoundf("shard not found: %s", shardID)
+	}
+	if len(chunks) == 0 && len(sh.Chunks) == 0 {
+		return nil
+	}
+	sh.Chunks = nil
+	for _, ch := range chunks {
+		sh.Chunks = append(sh.Chunks, localChunk{Index: ch.Index, Heading: ch.Heading, Content: ch.Content, Embedding: ch.Embedding})
+	}
+	return s.save()
+}
+
+func (s *localStore) EmbeddingModelCounts(ctx context.Context) ([]EmbeddingModelCount, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	counts := map[embedding.ModelInfo]int{}
+	untagged := 0
+	for _, sh := range s.projectShards() {
+		switch {
+		case len(sh.Embedding) == 0:
+		case sh.EmbeddingBy == nil:
+			untagged++
+		default:
+			counts[*sh.EmbeddingBy]++
+		}
+	}
+
+	var out []EmbeddingModelCount
+	for m, n := range counts {
+		m := m
+		out = append(out, EmbeddingModelCount{Model: &m, Count: n})
+	}
+	if untagged > 0 {
+		out = append(out, EmbeddingModelCount{Count: untagged})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	return out, nil
+}
+
+func (s *localStore) GetShardsWithStaleEmbedding(ctx context.Context, current embedding.ModelInfo, limit int) ([]ShardForEmbedding, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	var matched []*localShard
+	for _, sh := range s.projectShards() {
+		if len(sh.Embedding) > 0 && (sh.EmbeddingBy == nil || *sh.EmbeddingBy != current) {
+			matched = append(matched, sh)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].CreatedAt.After(matched[j].CreatedAt) })
+
+	var shards []ShardForEmbedding
+	for i, sh := range matched {
+		if i >= limit {
+			break
+		}
+		shards = append(shards, ShardForEmbedding{ID: sh.ID, Title: sh.Title, Type: sh.Type})
+	}
+	return shards, nil
+}
+
+func (s *localStore) GetShardsNeedingEmbedding(ctx context.Context, limit int) ([]ShardForEmbedding, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	var matched []*localShard
+	for _, sh := range s.projectShards() {
+		if len(sh.Embedding) == 0 || (embedding.NeedsChunking(sh.Content) && len(sh.Chunks) == 0) {
+			matched = append(matched, sh)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].CreatedAt.After(matched[j].CreatedAt) })
+
+	var shards []ShardForEmbedding
+	for i, sh := range matched {
+		if i >= limit {
+			break
+		}
+		shards = append(shards, ShardForEmbedding{ID: sh.ID, Title: sh.Title, Type: sh.Type})
+	}
+	return shards, nil
+}
+
+func (s *localStore) GetShardContentForEmbedding(ctx context.Context, id string) (shardType, title, content string, err error) {
+	if err := s.lock(); err != nil {
+		return "", "", "", err
+	}
+	defer s.unlock()
+
+	sh, ok := s.data.Shards[id]
+	if !ok {
+		return "", "", "", notFoundf("failed to fetch shard for embedding: shard not found: %s", id)
+	}
+	return sh.Type, sh.Title, sh.Content, nil
+}
@@ -0,0 +1,41 @@
+//go:build !unix
+
+package client
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// lockStale is how old a lock file must be before it is taken to be left
+// over from a process that died holding it
+const lockStale = time.Minute
+
+// lockFile takes an exclusive lock by creating path, waiting while another
+// process holds it
+func lockFile(path string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// unlockFile releases a lock taken by lockFile
+func unlockFile(f *os.File) {
+	if f == nil {
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
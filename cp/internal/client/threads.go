@@ -0,0 +1,351 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Receipt is one participant's read status for a message
+type Receipt struct {
+	Agent  string     `json:"agent"`
+	ReadAt *time.Time `json:"read_at,omitempty"` // nil: unread
+}
+
+// ThreadMessage is a message in a conversation, with its place in the
+// replies-to tree
+type ThreadMessage struct {
+	Message
+	ReplyTo  string    `json:"reply_to,omitempty"`
+	Depth    int       `json:"depth"`
+	Receipts []Receipt `json:"receipts"`
+}
+
+// Thread is a whole conversation, in reading order: each message is followed
+// by its replies, oldest first
+type Thread struct {
+	RootID       string          `json:"root_id"`
+	Subject      string          `json:"subject"`
+	Participants []string        `json:"participants"`
+	Messages     []ThreadMessage `json:"messages"`
+}
+
+// ReplyDraft is a reply addressed and quoted from the message it answers
+type ReplyDraft struct {
+	ReplyTo string   `json:"reply_to"`
+	To      []string `json:"to"`
+	Cc      []string `json:"cc,omitempty"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+// GetThread returns the conversation containing a message, from its root
+func (c *Client) GetThread(ctx context.Context, id string) (*Thread, error) {
+	msgs, err := c.store.GetThread(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, notFoundf("message %s not found", id)
+	}
+	return buildThread(msgs), nil
+}
+
+// buildThread orders a conversation's messages depth-first from the root and
+// fills in unread receipts for every addressed participant
+func buildThread(msgs []ThreadMessage) *Thread {
+	byID := map[string]bool{}
+	for _, m := range msgs {
+		byID[m.ID] = true
+	}
+	children := map[string][]ThreadMessage{}
+	var roots []ThreadMessage
+	for _, m := range msgs {
+		if m.ReplyTo == "" || !byID[m.ReplyTo] {
+			roots = append(roots, m)
+		} else {
+			children[m.ReplyTo] = append(children[m.ReplyTo], m)
+		}
+	}
+	byTime := func(ms []ThreadMessage) {
+		sort.SliceStable(ms, func(i, j int) bool { return ms[i].CreatedAt.Before(ms[j].CreatedAt) })
+	}
+	byTime(roots)
+
+	t := &Thread{Messages: []ThreadMessage{}}
+	seen := map[string]bool{}
+	participants := map[string]bool{}
+	var walk func(m ThreadMessage, depth int)
+	walk = func(m ThreadMessage, depth int) {
+		if seen[m.ID] {
+			return
+		}
+		seen[m.ID] = true
+		m.Depth = depth
+		m.Receipts = addressedReceipts(m.Message, m.Receipts)
+		participants[m.Creator] = true
+		for _, r := range m.Receipts {
+			participants[r.Agent] = true
+		}
+		t.Messages = append(t.Messages, m)
+		kids := children[m.ID]
+		byTime(kids)
+		for _, k := range kids {
+			walk(k, depth+1)
+		}
+	}
+	for _, r := range roots {
+		walk(r, 0)
+	}
+
+	t.RootID = t.Messages[0].ID
+	t.Subject = t.Messages[0].Title
+	for p := range participants {
+		t.Participants = append(t.Participants, p)
+	}
+	sort.Strings(t.Participants)
+	return t
+}
+
+// addressedReceipts lists read status for the message's to: and cc:
+// recipients, in that order, followed by anyone else who has read it. The
+// sender is left out.
+func addressedReceipts(m Message, receipts []Receipt) []Receipt {
+	readAt := map[string]*time.Time{}
+	for _, r := range receipts {
+		readAt[r.Agent] = r.ReadAt
+	}
+	out := []Receipt{}
+	listed := map[string]bool{m.Creator: true}
+	add := func(agent string) {
+		if listed[agent] {
+			return
+		}
+		listed[agent] = true
+		out = append(out, Receipt{Agent: agent, ReadAt: readAt[agent]})
+	}
+	for _, a := range m.To {
+		add(a)
+	}
+	for _, a := range m.Cc {
+		add(a)
+	}
+	for _, r := range receipts {
+		add(r.Agent)
+	}
+	return out
+}
+
+// Markdown renders the thread as a Markdown document for sharing or archiving
+func (t *Thread) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", t.Subject)
+	fmt.Fprintf(&b, "Thread `%s` · %d messages · participants: %s\n", t.RootID, len(t.Messages), strings.Join(t.Participants, ", "))
+
+	for _, m := range t.Messages {
+		fmt.Fprintf(&b, "\n---\n\n## %s\n\n", m.Title)
+		fmt.Fprintf(&b, "- **ID:** `%s`", m.ID)
+		if m.ReplyTo != "" {
+			fmt.Fprintf(&b, " (reply to `%s`)", m.ReplyTo)
+		}
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "- **From:** %s\n", m.Creator)
+		if len(m.To) > 0 {
+			fmt.Fprintf(&b, "- **To:** %s\n", strings.Join(m.To, ", "))
+		}
+		if len(m.Cc) > 0 {
+			fmt.Fprintf(&b, "- **Cc:** %s\n", strings.Join(m.Cc, ", "))
+		}
+		if len(m.Groups) > 0 {
+			fmt.Fprintf(&b, "- **Groups:** %s\n", strings.Join(m.Groups, ", "))
+		}
+		if m.Kind != nil {
+			fmt.Fprintf(&b, "- **Kind:** %s\n", strings.TrimPrefix(*m.Kind, "kind:"))
+		}
+		fmt.Fprintf(&b, "- **Date:** %s\n", m.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"))
+		if len(m.Receipts) > 0 {
+			fmt.Fprintf(&b, "- **Read:** %s\n", FormatReceipts(m.Receipts))
+		}
+		if m.Content != "" {
+			fmt.Fprintf(&b, "\n%s\n", strings.TrimRight(m.Content, "\n"))
+		}
+	}
+	return b.String()
+}
+
+// FormatReceipts renders read status as "agent-a (10-16 10:05), agent-b (unread)"
+func FormatReceipts(receipts []Receipt) string {
+	parts := make([]string, len(receipts))
+	for i, r := range receipts {
+		if r.ReadAt == nil {
+			parts[i] = r.Agent + " (unread)"
+		} else {
+			parts[i] = fmt.Sprintf("%s (%s)", r.Agent, r.ReadAt.Local().Format("01-02 15:04"))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PrepareReply addresses a reply to a message: to its sender (or, replying to
+// your own message, its recipients) with its cc list copied, less yourself.
+// all also copies the other recipients. The subject gets a "Re: " prefix and,
+// with quote, the original is quoted below body. Replying to an escalation
+// follow-up answers the request it chases.
+func (c *Client) PrepareReply(ctx context.Context, id, body string, all, quote bool) (*ReplyDraft, error) {
+	orig, err := c.GetMessage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if orig.Kind != nil && *orig.Kind == "kind:"+EscalationKind {
+		edges, err := c.GetShardEdges(ctx, orig.ID, "outgoing", []string{"replies-to"})
+		if err != nil {
+			return nil, err
+		}
+		if len(edges) == 1 {
+			if orig, err = c.GetMessage(ctx, edges[0].ShardID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	me := c.Config.Agent
+
+	var to, cc []string
+	if orig.Creator == me {
+		to = append(to, orig.To...)
+	} else {
+		to = append(to, orig.Creator)
+		if all {
+			cc = append(cc, orig.To...)
+		}
+	}
+	cc = append(cc, orig.Cc...)
+
+	d := &ReplyDraft{ReplyTo: orig.ID, Subject: orig.Title, Body: body}
+	for _, a := range to {
+		if a != me && !containsString(d.To, a) {
+			d.To = append(d.To, a)
+		}
+	}
+	for _, a := range cc {
+		if a != me && !containsString(d.To, a) && !containsString(d.Cc, a) {
+			d.Cc = append(d.Cc, a)
+		}
+	}
+	if len(d.To) == 0 {
+		return nil, invalidf("nobody to reply to: %s has no recipients besides you", id)
+	}
+
+	if !strings.HasPrefix(strings.ToLower(d.Subject), "re:") {
+		d.Subject = "Re: " + d.Subject
+	}
+	if quote && orig.Content != "" {
+		var q strings.Builder
+		if body != "" {
+			q.WriteString(strings.TrimRight(body, "\n"))
+			q.WriteString("\n\n")
+		}
+		fmt.Fprintf(&q, "On %s, %s wrote:\n", orig.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"), orig.Creator)
+		for _, line := range strings.Split(strings.TrimRight(orig.Content, "\n"), "\n") {
+			if line == "" {
+				q.WriteString(">\n")
+			} else {
+				q.WriteString("> " + line + "\n")
+			}
+		}
+		d.Body = q.String()
+	}
+	return d, nil
+}
+
+// Reply sends a prepared reply, linked to the original with replies-to
+func (c *Client) Reply(ctx context.Context, d *ReplyDraft, extraCc []string, kind string) (*SendResult, error) {
+	cc := append([]string{}, d.Cc...)
+	for _, a := range extraCc {
+		if !containsString(cc, a) && !containsString(d.To, a) {
+			cc = append(cc, a)
+		}
+	}
+	d.Cc = cc
+	return c.SendMessage(ctx, d.To, d.Subject, d.Body, d.Cc, kind, d.ReplyTo)
+}
+
+func (pg *pgStore) GetThread(ctx context.Context, id string) ([]ThreadMessage, error) {
+	conn, err := pg.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT id, title, creator, content, reply_to, created_at, labels
+		FROM message_thread($1, $2)
+	`, pg.cfg.Project, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	var msgs []ThreadMessage
+	for rows.Next() {
+		var m ThreadMessage
+		var replyTo *string
+		var labels []string
+		if err := rows.Scan(&m.ID, &m.Title, &m.Creator, &m.Content, &replyTo, &m.CreatedAt, &labels); err != nil {
+			return nil, fmt.Errorf("failed to scan thread message: %w", err)
+		}
+		if replyTo != nil {
+			m.ReplyTo = *replyTo
+		}
+		m.setAddressing(labels)
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("thread iteration error: %w", err)
+	}
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		ids = append(ids, m.ID)
+	}
+	receipts, err := readReceipts(ctx, conn, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range msgs {
+		msgs[i].Receipts = receipts[msgs[i].ID]
+	}
+	return msgs, nil
+}
+
+// readReceipts returns the read receipts for messages, keyed by message ID
+func readReceipts(ctx context.Context, conn *pgxpool.Conn, ids []string) (map[string][]Receipt, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT shard_id, agent_id, read_at FROM read_receipts
+		WHERE shard_id = ANY($1) ORDER BY read_at
+	`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read receipts: %w", pgError(err))
+	}
+	defer rows.Close()
+
+	receipts := map[string][]Receipt{}
+	for rows.Next() {
+		var shardID, agent string
+		var readAt time.Time
+		if err := rows.Scan(&shardID, &agent, &readAt); err != nil {
+			return nil, fmt.Errorf("failed to scan read receipt: %w", err)
+		}
+		receipts[shardID] = append(receipts[shardID], Receipt{Agent: agent, ReadAt: &readAt})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read receipt iteration error: %w", err)
+	}
+	return receipts, nil
+}
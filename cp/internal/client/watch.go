@@ -0,0 +1,335 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventChannel is the Postgres NOTIFY channel written by migration 012
+const EventChannel = "cp_events"
+
+// Reconnect backoff and liveness check for Watch
+const (
+	watchMinBackoff = time.Second
+	watchMaxBackoff = 30 * time.Second
+	watchPingEvery  = 30 * time.Second
+)
+
+// Notifications queued together are described with one query on the
+// listening connection: up to watchBatchMax of them, arriving no more than
+// watchBatchWait apart
+const (
+	watchBatchMax  = 100
+	watchBatchWait = 10 * time.Millisecond
+)
+
+// Event is a change to a shard, edge or label, as streamed by cp watch
+type Event struct {
+	Event      string    `json:"event"` // shard.created, shard.updated, shard.heartbeat, shard.closed, shard.deleted, edge.created, edge.deleted, label.added, label.removed
+	ID         string    `json:"id"`    // the shard changed; the source shard for edges
+	Type       string    `json:"type,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	PrevStatus string    `json:"prev_status,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	Creator    string    `json:"creator,omitempty"`
+	Owner      string    `json:"owner,omitempty"`
+	Labels     []string  `json:"labels,omitempty"`
+	EdgeType   string    `json:"edge_type,omitempty"`
+	To         string    `json:"to,omitempty"` // edge target
+	Label      string    `json:"label,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// WatchOptions filters and observes a Watch
+type WatchOptions struct {
+	Types  []string // only events on shards of these types
+	Labels []string // only events on shards with any of these labels
+	// Heartbeats includes shard.heartbeat events (lease renewals), which are
+	// left out by default
+	Heartbeats bool
+
+	// OnConnect runs after every successful LISTEN, including reconnects;
+	// use it to catch up on anything missed while disconnected.
+	OnConnect func(ctx context.Context) error
+	// OnDisconnect is told about a lost connection and the wait before retrying
+	OnDisconnect func(err error, retryIn time.Duration)
+}
+
+// notifyPayload is the JSON written by the notify_*_change() triggers
+type notifyPayload struct {
+	Table      string `json:"table"`
+	Op         string `json:"op"`
+	Project    string `json:"project"`
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Status     string `json:"status"`
+	PrevStatus string `json:"prev_status"`
+	ToID       string `json:"to_id"`
+	EdgeType   string `json:"edge_type"`
+	Label      string `json:"label"`
+	Heartbeat  bool   `json:"heartbeat"` // only the lease was renewed (migration 020)
+}
+
+// handlerError marks an error returned by the caller's handler, which ends
+// the watch instead of triggering a reconnect
+type handlerError struct{ err error }
+
+func (e *handlerError) Error() string { return e.err.Error() }
+
+// Watch streams change events for the configured project to fn until ctx is
+// cancelled or fn returns an error. Lost connections are re-established with
+// exponential backoff; events committed while disconnected are not replayed.
+// Requires the postgres backend and migration 012; lease heartbeats are told
+// apart from other updates from migration 020.
+func (c *Client) Watch(ctx context.Context, opts WatchOptions, fn func(Event) error) error {
+	// Fail fast on configuration problems; only established watches retry
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	backoff := watchMinBackoff
+	for {
+		err = c.listen(ctx, conn, opts, fn, func() { backoff = watchMinBackoff })
+		conn = nil
+		if ctx.Err() != nil {
+			return nil
+		}
+		var herr *handlerError
+		if errors.As(err, &herr) {
+			return herr.err
+		}
+
+		if opts.OnDisconnect != nil {
+			opts.OnDisconnect(err, backoff)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+	}
+}
+
+// listen runs one LISTEN session on conn (acquiring one if nil) until an error
+func (c *Client) listen(ctx context.Context, conn *pgxpool.Conn, opts WatchOptions, fn func(Event) error, connected func()) error {
+	if conn == nil {
+		var err error
+		if conn, err = c.Connect(ctx); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		// A connection still in LISTEN must not go back to the pool
+		if !conn.Conn().IsClosed() {
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+EventChannel); err != nil {
+		return fmt.Errorf("failed to listen for changes: %w", err)
+	}
+	if opts.OnConnect != nil {
+		if err := opts.OnConnect(ctx); err != nil {
+			return err
+		}
+	}
+	connected()
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, watchPingEvery)
+		n, err := conn.Conn().WaitForNotification(waitCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				// Idle: make sure the connection is still alive
+				if _, err := conn.Exec(ctx, "SELECT 1"); err != nil {
+					return fmt.Errorf("connection lost: %w", err)
+				}
+				continue
+			}
+			return fmt.Errorf("connection lost: %w", err)
+		}
+
+		batch, lost := collectNotifications(ctx, conn, n)
+		var events []Event
+		for _, n := range batch {
+			var p notifyPayload
+			if err := json.Unmarshal([]byte(n.Payload), &p); err != nil || p.Project != c.Config.Project {
+				continue
+			}
+			if ev, ok := eventFromPayload(p); ok {
+				events = append(events, ev)
+			}
+		}
+		if lost == nil {
+			lost = describeEvents(ctx, conn, events)
+		}
+		for _, ev := range events {
+			if !matchesWatch(ev, opts) {
+				continue
+			}
+			if err := fn(ev); err != nil {
+				return &handlerError{err}
+			}
+		}
+		if lost != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("connection lost: %v", lost)
+		}
+	}
+}
+
+// collectNotifications gathers the notifications queued behind first. An
+// error other than the wait running out means the connection is gone; the
+// notifications collected so far are still returned.
+func collectNotifications(ctx context.Context, conn *pgxpool.Conn, first *pgconn.Notification) ([]*pgconn.Notification, error) {
+	batch := []*pgconn.Notification{first}
+	for len(batch) < watchBatchMax {
+		waitCtx, cancel := context.WithTimeout(ctx, watchBatchWait)
+		n, err := conn.Conn().WaitForNotification(waitCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return batch, nil
+			}
+			return batch, err
+		}
+		batch = append(batch, n)
+	}
+	return batch, nil
+}
+
+// eventFromPayload names the event and copies what the payload carries
+func eventFromPayload(p notifyPayload) (Event, bool) {
+	ev := Event{ID: p.ID, Type: p.Type, Status: p.Status, PrevStatus: p.PrevStatus, At: time.Now().UTC()}
+	switch p.Table + "." + p.Op {
+	case "shards.INSERT":
+		ev.Event = "shard.created"
+	case "shards.UPDATE":
+		ev.Event = "shard.updated"
+		if p.Status == "closed" && p.PrevStatus != "" {
+			ev.Event = "shard.closed"
+		} else if p.Heartbeat {
+			ev.Event = "shard.heartbeat"
+		}
+	case "shards.DELETE":
+		ev.Event = "shard.deleted"
+	case "edges.INSERT":
+		ev.Event, ev.To, ev.EdgeType = "edge.created", p.ToID, p.EdgeType
+	case "edges.DELETE":
+		ev.Event, ev.To, ev.EdgeType = "edge.deleted", p.ToID, p.EdgeType
+	case "labels.INSERT":
+		ev.Event, ev.Label = "label.added", p.Label
+	case "labels.DELETE":
+		ev.Event, ev.Label = "label.removed", p.Label
+	default:
+		return ev, false
+	}
+	return ev, true
+}
+
+// describeEvents fills in title, owner and labels for a batch of events with
+// one query on the listening connection, which is free between waits; taking
+// a pooled connection per event would let a burst exhaust a small pool.
+// Deleted shards can no longer be looked up and keep only what the payload
+// carried. The error is returned only when the connection itself failed.
+func describeEvents(ctx context.Context, conn *pgxpool.Conn, events []Event) error {
+	var ids []string
+	for _, ev := range events {
+		if ev.Event != "shard.deleted" && !containsString(ids, ev.ID) {
+			ids = append(ids, ev.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT s.id, s.title, COALESCE(s.type, ''), s.status, s.creator, COALESCE(s.owner, ''),
+			COALESCE(array_agg(l.label ORDER BY l.label) FILTER (WHERE l.label IS NOT NULL), '{}')
+		FROM shards s
+		LEFT JOIN labels l ON l.shard_id = s.id
+		WHERE s.id = ANY($1)
+		GROUP BY s.id
+	`, ids)
+	if err != nil {
+		return lookupConnError(conn, err)
+	}
+	defer rows.Close()
+
+	found := map[string]Event{}
+	for rows.Next() {
+		var d Event
+		if err := rows.Scan(&d.ID, &d.Title, &d.Type, &d.Status, &d.Creator, &d.Owner, &d.Labels); err != nil {
+			return lookupConnError(conn, err)
+		}
+		found[d.ID] = d
+	}
+	if err := rows.Err(); err != nil {
+		return lookupConnError(conn, err)
+	}
+
+	for i, ev := range events {
+		d, ok := found[ev.ID]
+		if !ok || ev.Event == "shard.deleted" {
+			continue
+		}
+		events[i].Title, events[i].Type, events[i].Status = d.Title, d.Type, d.Status
+		events[i].Creator, events[i].Owner, events[i].Labels = d.Creator, d.Owner, d.Labels
+	}
+	return nil
+}
+
+// lookupConnError keeps a failed lookup from ending the watch unless the
+// connection went with it
+func lookupConnError(conn *pgxpool.Conn, err error) error {
+	if conn.Conn().IsClosed() {
+		return err
+	}
+	return nil
+}
+
+// matchesWatch applies the type and label filters (each is any-of)
+func matchesWatch(ev Event, opts WatchOptions) bool {
+	if ev.Event == "shard.heartbeat" && !opts.Heartbeats {
+		return false
+	}
+	if len(opts.Types) > 0 && !containsString(opts.Types, ev.Type) {
+		return false
+	}
+	if len(opts.Labels) > 0 {
+		for _, l := range ev.Labels {
+			if containsString(opts.Labels, l) {
+				return true
+			}
+		}
+		// A removed label is no longer on the shard but still concerns it
+		return ev.Event == "label.removed" && containsString(opts.Labels, ev.Label)
+	}
+	return true
+}
+
+// MessageKind returns the kind:* label value from labels, if any
+func MessageKind(labels []string) string {
+	for _, l := range labels {
+		if k, ok := strings.CutPrefix(l, "kind:"); ok {
+			return k
+		}
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+package embedding
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunking limits, in characters (~4 chars per token).
+const (
+	// ChunkThreshold is the content length above which a shard is also embedded
+	// in chunks. Keep in sync with shards_needing_embedding() (migration 010).
+	ChunkThreshold = 6000
+	// ChunkSize is the target maximum size of one chunk.
+	ChunkSize = 4000
+)
+
+// Chunk is one heading-aware slice of a long shard.
+type Chunk struct {
+	Index   int
+	Heading string // markdown heading path, e.g. "Design > Storage"; empty before the first heading
+	Text    string
+}
+
+// NeedsChunking reports whether content is long enough to be chunked.
+func NeedsChunking(content string) bool {
+	return len(content) > ChunkThreshold
+}
+
+// ChunkText splits markdown content into chunks of at most maxChars, breaking
+// at headings first, then at paragraphs, then at lines, and only as a last
+// resort mid-line. Consecutive small sections under the same parent are merged.
+func ChunkText(content string, maxChars int) []Chunk {
+	if maxChars <= 0 {
+		maxChars = ChunkSize
+	}
+
+	var chunks []Chunk
+	emit := func(heading, text string) {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return
+		}
+		chunks = append(chunks, Chunk{Index: len(chunks), Heading: heading, Text: text})
+	}
+
+	// Pending chunk being filled with whole sections
+	var pendingHeading string
+	var pending strings.Builder
+	flush := func() {
+		emit(pendingHeading, pending.String())
+		pending.Reset()
+	}
+
+	for _, sec := range splitSections(content) {
+		if len(sec.text) > maxChars {
+			flush()
+			for _, part := range splitLong(sec.text, maxChars) {
+				emit(sec.heading, part)
+			}
+			continue
+		}
+		if pending.Len() > 0 && pending.Len()+len(sec.text)+2 > maxChars {
+			flush()
+		}
+		if pending.Len() == 0 {
+			pendingHeading = sec.heading
+		} else {
+			pendingHeading = commonHeading(pendingHeading, sec.heading)
+			pending.WriteString("\n\n")
+		}
+		pending.WriteString(sec.text)
+	}
+	flush()
+
+	return chunks
+}
+
+// BuildChunkText constructs the text to embed for one chunk. The shard type,
+// title and heading path are included so each chunk carries its context.
+func BuildChunkText(shardType, title string, c Chunk) string {
+	heading := title
+	if c.Heading != "" {
+		heading = title + " > " + c.Heading
+	}
+	return BuildEmbeddingText(shardType, heading, c.Text)
+}
+
+type section struct {
+	heading string
+	text    string
+}
+
+// splitSections splits markdown at ATX headings, tracking the heading path.
+// Headings inside fenced code blocks are ignored.
+func splitSections(content string) []section {
+	var sections []section
+	var path []string // path[i] = heading at level i+1
+	var cur strings.Builder
+	curHeading := ""
+	inFence := false
+
+	flush := func() {
+		if strings.TrimSpace(cur.String()) != "" {
+			sections = append(sections, section{heading: curHeading, text: cur.String()})
+		}
+		cur.Reset()
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if level, title := headingLevel(line); level > 0 && !inFence {
+			flush()
+			if len(path) >= level {
+				path = path[:level-1]
+			}
+			for len(path) < level-1 {
+				path = append(path, "")
+			}
+			path = append(path, title)
+			curHeading = joinHeading(path)
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+	}
+	flush()
+	return sections
+}
+
+// headingLevel returns the level and text of an ATX heading line ("## Foo"), or 0
+func headingLevel(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || line[level] != ' ' {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(strings.TrimRight(line[level:], "#"))
+}
+
+func joinHeading(path []string) string {
+	var parts []string
+	for _, p := range path {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// commonHeading returns the shared prefix of two heading paths
+func commonHeading(a, b string) string {
+	pa, pb := strings.Split(a, " > "), strings.Split(b, " > ")
+	var common []string
+	for i := 0; i < len(pa) && i < len(pb) && pa[i] == pb[i]; i++ {
+		common = append(common, pa[i])
+	}
+	return strings.Join(common, " > ")
+}
+
+// splitLong splits text larger than maxChars at paragraph, then line, then
+// hard boundaries.
+func splitLong(text string, maxChars int) []string {
+	var parts []string
+	var cur strings.Builder
+
+	add := func(piece, sep string) {
+		if cur.Len() > 0 && cur.Len()+len(sep)+len(piece) > maxChars {
+			parts = append(parts, cur.String())
+			cur.Reset()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString(sep)
+		}
+		cur.WriteString(piece)
+	}
+
+	for _, para := range strings.Split(text, "\n\n") {
+		if len(para) <= maxChars {
+			add(para, "\n\n")
+			continue
+		}
+		for _, line := range strings.Split(para, "\n") {
+			for len(line) > maxChars {
+				cut := maxChars
+				for cut > 0 && !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+				add(line[:cut], "\n")
+				line = line[cut:]
+			}
+			add(line, "\n")
+		}
+	}
+	if cur.Len() > 0 {
+		parts = append(parts, cur.String())
+	}
+	return parts
+}
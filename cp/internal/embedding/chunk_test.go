@@ -0,0 +1,137 @@
+package embedding
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkText(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		maxChars int
+		want     []Chunk // nil: only the invariants are checked
+	}{
+		{
+			name:     "empty",
+			content:  "",
+			maxChars: 10,
+			want:     []Chunk{},
+		},
+		{
+			name:     "whitespace only",
+			content:  "\n\n   \n",
+			maxChars: 10,
+			want:     []Chunk{},
+		},
+		{
+			name:     "single oversize token",
+			content:  "abcdefghijklmnopqrstuvwxy",
+			maxChars: 10,
+			want: []Chunk{
+				{Index: 0, Text: "abcdefghij"},
+				{Index: 1, Text: "klmnopqrst"},
+				{Index: 2, Text: "uvwxy"},
+			},
+		},
+		{
+			name:     "oversize token cut on rune boundaries",
+			content:  strings.Repeat("é", 12), // 24 bytes
+			maxChars: 5,
+		},
+		{
+			name:     "exactly maxChars",
+			content:  "0123456789",
+			maxChars: 10,
+			want:     []Chunk{{Index: 0, Text: "0123456789"}},
+		},
+		{
+			name:     "paragraphs split at the boundary",
+			content:  "aaaa\n\nbbbb\n\ncccc",
+			maxChars: 10,
+			want: []Chunk{
+				{Index: 0, Text: "aaaa\n\nbbbb"},
+				{Index: 1, Text: "cccc"},
+			},
+		},
+		{
+			name:     "small sections merge under their common heading",
+			content:  "# Design\n## Storage\nrows\n## API\nroutes",
+			maxChars: 100,
+			want: []Chunk{
+				// Each section keeps its trailing newline before the blank separator line
+				{Index: 0, Heading: "Design", Text: "# Design\n\n\n## Storage\nrows\n\n\n## API\nroutes"},
+			},
+		},
+		{
+			name:     "sections that do not fit start a new chunk",
+			content:  "# A\none one one\n# B\ntwo two two",
+			maxChars: 20,
+			want: []Chunk{
+				{Index: 0, Heading: "A", Text: "# A\none one one"},
+				{Index: 1, Heading: "B", Text: "# B\ntwo two two"},
+			},
+		},
+		{
+			name:     "headings inside code fences are text",
+			content:  "# Real\n```\n# not a heading\n```",
+			maxChars: 100,
+			want: []Chunk{
+				{Index: 0, Heading: "Real", Text: "# Real\n```\n# not a heading\n```"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChunkText(tt.content, tt.maxChars)
+
+			var joined strings.Builder
+			for i, c := range got {
+				if c.Index != i {
+					t.Errorf("chunk %d has Index %d", i, c.Index)
+				}
+				if len(c.Text) > tt.maxChars {
+					t.Errorf("chunk %d is %d bytes, over %d", i, len(c.Text), tt.maxChars)
+				}
+				if !utf8.ValidString(c.Text) {
+					t.Errorf("chunk %d is not valid UTF-8: %q", i, c.Text)
+				}
+				joined.WriteString(c.Text)
+			}
+			// Chunks neither overlap nor drop text at their boundaries
+			if got, want := stripSpace(joined.String()), stripSpace(tt.content); got != want {
+				t.Errorf("chunks join to %q, want %q", got, want)
+			}
+
+			if tt.want == nil {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d chunks %q, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("chunk %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChunkTextDefaultSize(t *testing.T) {
+	content := strings.Repeat("word ", ChunkThreshold/5+100)
+	got := ChunkText(content, 0)
+	if len(got) < 2 {
+		t.Fatalf("got %d chunks, want the content split at ChunkSize", len(got))
+	}
+	for i, c := range got {
+		if len(c.Text) > ChunkSize {
+			t.Errorf("chunk %d is %d bytes, over ChunkSize", i, len(c.Text))
+		}
+	}
+}
+
+func stripSpace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
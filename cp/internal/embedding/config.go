@@ -5,11 +5,18 @@ import (
 	"os"
 )
 
+// DefaultDimensions matches the shards.embedding vector(768) column as first
+// created; cp admin embed-reset resizes it for other sizes.
+const DefaultDimensions = 768
+
 // EmbeddingConfig holds embedding provider configuration.
 type EmbeddingConfig struct {
-	Provider  string `yaml:"provider"`   // "google"
-	Model     string `yaml:"model"`      // "text-embedding-004"
-	APIKeyEnv string `yaml:"api_key_env"` // env var name containing API key
+	Provider   string `yaml:"provider"`              // "google", "openai", "ollama" or "hash"
+	Model      string `yaml:"model"`                 // "text-embedding-004"
+	APIKeyEnv  string `yaml:"api_key_env"`           // env var name containing API key
+	Dimensions int    `yaml:"dimensions,omitempty"`  // output dimensions (default 768); must match the embedding columns
+	BaseURL    string `yaml:"base_url,omitempty"`    // API base URL, e.g. "http://localhost:11434/v1"
+	AuthHeader string `yaml:"auth_header,omitempty"` // header carrying the API key (default "Authorization: Bearer <key>")
 }
 
 // NewProvider creates an embedding Provider from config.
@@ -19,14 +26,44 @@ func NewProvider(cfg *EmbeddingConfig) (Provider, error) {
 		return nil, nil
 	}
 
+	dims := cfg.Dimensions
+	if dims == 0 {
+		dims = DefaultDimensions
+	}
+	if dims < 0 {
+		return nil, fmt.Errorf("invalid embedding dimensions: %d", dims)
+	}
+
 	switch cfg.Provider {
 	case "google":
 		apiKey := os.Getenv(cfg.APIKeyEnv)
 		if apiKey == "" {
 			return nil, fmt.Errorf("embedding API key not found: set %s environment variable", cfg.APIKeyEnv)
 		}
-		return NewGoogleProvider(apiKey, cfg.Model), nil
+		return NewGoogleProvider(apiKey, cfg.Model, dims, cfg.BaseURL), nil
+	case "openai", "ollama":
+		// API key is optional: local servers (Ollama, llama.cpp, vLLM) usually need none
+		apiKey := ""
+		if cfg.APIKeyEnv != "" {
+			apiKey = os.Getenv(cfg.APIKeyEnv)
+			if apiKey == "" {
+				return nil, fmt.Errorf("embedding API key not found: set %s environment variable", cfg.APIKeyEnv)
+			}
+		}
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = defaultOpenAIBaseURL
+			if cfg.Provider == "ollama" {
+				baseURL = defaultOllamaBaseURL
+			}
+		}
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("embedding model is required for provider %q", cfg.Provider)
+		}
+		return NewOpenAIProvider(cfg.Provider, baseURL, cfg.Model, apiKey, cfg.AuthHeader, dims, cfg.Dimensions != 0), nil
+	case "hash":
+		return NewHashProvider(dims), nil
 	default:
-		return nil, fmt.Errorf("unsupported embedding provider: %q (supported: google)", cfg.Provider)
+		return nil, fmt.Errorf("unsupported embedding provider: %q (supported: google, openai, ollama, hash)", cfg.Provider)
 	}
 }
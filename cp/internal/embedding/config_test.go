@@ -0,0 +1,39 @@
+package embedding
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewProviderDimensions(t *testing.T) {
+	tests := []struct {
+		dims    int
+		want    int
+		wantErr bool
+	}{
+		{dims: 0, want: DefaultDimensions},
+		{dims: 768, want: 768},
+		{dims: 1536, want: 1536}, // the columns are resized with cp admin embed-reset
+		{dims: 256, want: 256},
+		{dims: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		p, err := NewProvider(&EmbeddingConfig{Provider: "hash", Dimensions: tt.dims})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("dimensions %d: want an error", tt.dims)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("dimensions %d: %v", tt.dims, err)
+			continue
+		}
+		if p.Dimensions() != tt.want {
+			t.Errorf("dimensions %d: provider has %d, want %d", tt.dims, p.Dimensions(), tt.want)
+		}
+		if v, err := p.Embed(context.Background(), "text"); err != nil || len(v) != tt.want {
+			t.Errorf("dimensions %d: Embed returned %d values, %v", tt.dims, len(v), err)
+		}
+	}
+}
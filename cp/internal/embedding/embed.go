@@ -10,6 +10,43 @@ import (
 type Provider interface {
 	Embed(ctx context.Context, text string) ([]float32, error)
 	Dimensions() int
+	Info() ModelInfo
+}
+
+// ModelInfo identifies what produced an embedding. Vectors from different
+// models are not comparable even when their dimensions match.
+type ModelInfo struct {
+	Provider   string `json:"provider" yaml:"provider"`
+	Model      string `json:"model" yaml:"model"`
+	Dimensions int    `json:"dimensions" yaml:"dimensions"`
+}
+
+// String formats the model as "provider/model@dims"
+func (m ModelInfo) String() string {
+	return fmt.Sprintf("%s/%s@%d", m.Provider, m.Model, m.Dimensions)
+}
+
+// BatchProvider is implemented by providers with a batch endpoint. EmbedBatch
+// returns one vector per input text, in input order.
+type BatchProvider interface {
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedBatch embeds texts with one batch call when the provider supports it,
+// falling back to one Embed call per text.
+func EmbedBatch(ctx context.Context, p Provider, texts []string) ([][]float32, error) {
+	if bp, ok := p.(BatchProvider); ok {
+		return bp.EmbedBatch(ctx, texts)
+	}
+	vecs := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec, err := p.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vecs[i] = vec
+	}
+	return vecs, nil
 }
 
 // BuildEmbeddingText constructs the text to embed from shard fields.
@@ -7,24 +7,38 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
+const defaultGoogleBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
 // GoogleProvider embeds text using Google's text-embedding-004 model via the Gemini API.
 type GoogleProvider struct {
 	apiKey     string
 	model      string
+	dims       int
+	baseURL    string
 	httpClient *http.Client
 }
 
-// NewGoogleProvider creates a GoogleProvider with the given API key and model.
-func NewGoogleProvider(apiKey, model string) *GoogleProvider {
+// NewGoogleProvider creates a GoogleProvider with the given API key, model,
+// output dimensions and base URL (empty = Gemini API).
+func NewGoogleProvider(apiKey, model string, dims int, baseURL string) *GoogleProvider {
 	if model == "" {
 		model = "gemini-embedding-001"
 	}
+	if dims <= 0 {
+		dims = DefaultDimensions
+	}
+	if baseURL == "" {
+		baseURL = defaultGoogleBaseURL
+	}
 	return &GoogleProvider{
-		apiKey: apiKey,
-		model:  model,
+		apiKey:  apiKey,
+		model:   model,
+		dims:    dims,
+		baseURL: strings.TrimRight(baseURL, "/"),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -32,11 +46,16 @@ func NewGoogleProvider(apiKey, model string) *GoogleProvider {
 }
 
 func (g *GoogleProvider) Dimensions() int {
-	return 768
+	return g.dims
+}
+
+func (g *GoogleProvider) Info() ModelInfo {
+	return ModelInfo{Provider: "google", Model: g.model, Dimensions: g.dims}
 }
 
 // googleEmbedRequest is the request body for the Gemini embedContent API.
 type googleEmbedRequest struct {
+	Model                string        `json:"model,omitempty"` // required per request in batchEmbedContents
 	Content              googleContent `json:"content"`
 	OutputDimensionality int           `json:"outputDimensionality,omitempty"`
 }
@@ -51,10 +70,23 @@ type googlePart struct {
 
 // googleEmbedResponse is the response from the Gemini embedContent API.
 type googleEmbedResponse struct {
-	Embedding struct {
-		Values []float32 `json:"values"`
-	} `json:"embedding"`
-	Error *googleError `json:"error,omitempty"`
+	Embedding googleEmbedding `json:"embedding"`
+	Error     *googleError    `json:"error,omitempty"`
+}
+
+// googleBatchRequest is the request body for the Gemini batchEmbedContents API.
+type googleBatchRequest struct {
+	Requests []googleEmbedRequest `json:"requests"`
+}
+
+// googleBatchResponse is the response from the Gemini batchEmbedContents API.
+type googleBatchResponse struct {
+	Embeddings []googleEmbedding `json:"embeddings"`
+	Error      *googleError      `json:"error,omitempty"`
+}
+
+type googleEmbedding struct {
+	Values []float32 `json:"values"`
 }
 
 type googleError struct {
@@ -67,10 +99,7 @@ func (g *GoogleProvider) Embed(ctx context.Context, text string) ([]float32, err
 		return nil, fmt.Errorf("cannot embed empty text")
 	}
 
-	url := fmt.Sprintf(
-		"https://generativelanguage.googleapis.com/v1beta/models/%s:embedContent?key=%s",
-		g.model, g.apiKey,
-	)
+	url := fmt.Sprintf("%s/models/%s:embedContent?key=%s", g.baseURL, g.model, g.apiKey)
 
 	reqBody := googleEmbedRequest{
 		Content: googleContent{
@@ -79,12 +108,82 @@ func (g *GoogleProvider) Embed(ctx context.Context, text string) ([]float32, err
 		OutputDimensionality: g.Dimensions(),
 	}
 
-	bodyBytes, err := json.Marshal(reqBody)
+	respBody, err := postWithRetry(ctx, g.httpClient, url, nil, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var result googleEmbedResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("API error %d: %s", result.Error.Code, result.Error.Message)
+	}
+
+	if len(result.Embedding.Values) == 0 {
+		return nil, fmt.Errorf("API returned empty embedding")
+	}
+	if len(result.Embedding.Values) != g.dims {
+		return nil, FormatDimensionError(g.dims, len(result.Embedding.Values))
+	}
+
+	return result.Embedding.Values, nil
+}
+
+// EmbedBatch embeds several texts in one batchEmbedContents call.
+func (g *GoogleProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	url := fmt.Sprintf("%s/models/%s:batchEmbedContents?key=%s", g.baseURL, g.model, g.apiKey)
+
+	reqBody := googleBatchRequest{}
+	for _, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("cannot embed empty text")
+		}
+		reqBody.Requests = append(reqBody.Requests, googleEmbedRequest{
+			Model:                "models/" + g.model,
+			Content:              googleContent{Parts: []googlePart{{Text: text}}},
+			OutputDimensionality: g.Dimensions(),
+		})
+	}
+
+	respBody, err := postWithRetry(ctx, g.httpClient, url, nil, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var result googleBatchResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("API error %d: %s", result.Error.Code, result.Error.Message)
+	}
+
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("API returned %d embeddings for %d texts", len(result.Embeddings), len(texts))
+	}
+
+	vecs := make([][]float32, len(texts))
+	for i, e := range result.Embeddings {
+		if len(e.Values) != g.dims {
+			return nil, FormatDimensionError(g.dims, len(e.Values))
+		}
+		vecs[i] = e.Values
+	}
+	return vecs, nil
+}
+
+// postWithRetry POSTs body as JSON and returns the 200 response body,
+// retrying with exponential backoff on 429/5xx and transport errors.
+func postWithRetry(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, body any) ([]byte, error) {
+	bodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	// Retry with exponential backoff on 429/5xx
 	delays := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
 	var lastErr error
 
@@ -102,8 +201,11 @@ func (g *GoogleProvider) Embed(ctx context.Context, text string) ([]float32, err
 			return nil, fmt.Errorf("failed to create request: %v", err)
 		}
 		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
 
-		resp, err := g.httpClient.Do(req)
+		resp, err := httpClient.Do(req)
 		if err != nil {
 			lastErr = fmt.Errorf("HTTP request failed: %v", err)
 			continue
@@ -125,20 +227,7 @@ func (g *GoogleProvider) Embed(ctx context.Context, text string) ([]float32, err
 			return nil, fmt.Errorf("API returned %d: %s", resp.StatusCode, string(respBody))
 		}
 
-		var result googleEmbedResponse
-		if err := json.Unmarshal(respBody, &result); err != nil {
-			return nil, fmt.Errorf("failed to parse response: %v", err)
-		}
-
-		if result.Error != nil {
-			return nil, fmt.Errorf("API error %d: %s", result.Error.Code, result.Error.Message)
-		}
-
-		if len(result.Embedding.Values) == 0 {
-			return nil, fmt.Errorf("API returned empty embedding")
-		}
-
-		return result.Embedding.Values, nil
+		return respBody, nil
 	}
 
 	return nil, fmt.Errorf("embedding failed after retries: %v", lastErr)
@@ -0,0 +1,77 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// HashProvider produces deterministic embeddings offline by feature-hashing
+// words and word bigrams into a fixed-size vector. Texts that share vocabulary
+// land close together, which is enough for tests and air-gapped demos; it has
+// no notion of meaning beyond that.
+type HashProvider struct {
+	dims int
+}
+
+// NewHashProvider creates a HashProvider with the given dimensions.
+func NewHashProvider(dims int) *HashProvider {
+	if dims <= 0 {
+		dims = DefaultDimensions
+	}
+	return &HashProvider{dims: dims}
+}
+
+func (h *HashProvider) Dimensions() int {
+	return h.dims
+}
+
+func (h *HashProvider) Info() ModelInfo {
+	return ModelInfo{Provider: "hash", Model: "fnv-v1", Dimensions: h.dims}
+}
+
+func (h *HashProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return nil, fmt.Errorf("cannot embed empty text")
+	}
+
+	vec := make([]float64, h.dims)
+	add := func(feature string, weight float64) {
+		f := fnv.New64a()
+		f.Write([]byte(feature))
+		sum := f.Sum64()
+		idx := int(sum % uint64(h.dims))
+		// High bit picks the sign so collisions tend to cancel out
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vec[idx] += weight
+	}
+	for i, w := range words {
+		add(w, 1)
+		if i > 0 {
+			add(words[i-1]+" "+w, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		norm = 1
+	}
+
+	out := make([]float32, h.dims)
+	for i, v := range vec {
+		out[i] = float32(v / norm)
+	}
+	return out, nil
+}
@@ -0,0 +1,156 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOllamaBaseURL = "http://localhost:11434/v1"
+)
+
+// OpenAIProvider embeds text via an OpenAI-compatible /v1/embeddings endpoint.
+// This covers OpenAI itself as well as Ollama, llama.cpp server and vLLM.
+type OpenAIProvider struct {
+	name       string // "openai" or "ollama", for ModelInfo
+	baseURL    string
+	model      string
+	apiKey     string
+	authHeader string
+	dims       int
+	sendDims   bool // send "dimensions" in the request (only when explicitly configured)
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates an OpenAIProvider. name is the configured provider
+// ("openai" or "ollama") recorded with each embedding. apiKey may be empty for local
+// servers; authHeader names the header carrying it (default Authorization,
+// sent as "Bearer <key>"). sendDims asks the server to truncate output to dims,
+// which only some models support.
+func NewOpenAIProvider(name, baseURL, model, apiKey, authHeader string, dims int, sendDims bool) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	if authHeader == "" {
+		authHeader = "Authorization"
+	}
+	if dims <= 0 {
+		dims = DefaultDimensions
+	}
+	if name == "" {
+		name = "openai"
+	}
+	return &OpenAIProvider{
+		name:       name,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		apiKey:     apiKey,
+		authHeader: authHeader,
+		dims:       dims,
+		sendDims:   sendDims,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+func (o *OpenAIProvider) Dimensions() int {
+	return o.dims
+}
+
+func (o *OpenAIProvider) Info() ModelInfo {
+	return ModelInfo{Provider: o.name, Model: o.model, Dimensions: o.dims}
+}
+
+// openAIEmbedRequest is the request body for POST /embeddings.
+type openAIEmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// openAIEmbedResponse is the response from POST /embeddings.
+type openAIEmbedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (o *OpenAIProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	vecs, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// EmbedBatch embeds several texts in one /embeddings call.
+func (o *OpenAIProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	for _, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("cannot embed empty text")
+		}
+	}
+
+	reqBody := openAIEmbedRequest{
+		Model: o.model,
+		Input: texts,
+	}
+	if o.sendDims {
+		reqBody.Dimensions = o.dims
+	}
+
+	headers := map[string]string{}
+	if o.apiKey != "" {
+		if strings.EqualFold(o.authHeader, "Authorization") {
+			headers[o.authHeader] = "Bearer " + o.apiKey
+		} else {
+			headers[o.authHeader] = o.apiKey
+		}
+	}
+
+	respBody, err := postWithRetry(ctx, o.httpClient, o.baseURL+"/embeddings", headers, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var result openAIEmbedResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("API error: %s", result.Error.Message)
+	}
+
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("API returned %d embeddings for %d texts", len(result.Data), len(texts))
+	}
+
+	// Results carry an index; don't assume they come back in order
+	vecs := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("API returned out-of-range embedding index %d", d.Index)
+		}
+		if len(d.Embedding) != o.dims {
+			return nil, FormatDimensionError(o.dims, len(d.Embedding))
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	for i, v := range vecs {
+		if v == nil {
+			return nil, fmt.Errorf("API returned no embedding for input %d", i)
+		}
+	}
+	return vecs, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 )
 
 // Generator generates text from a prompt using an LLM.
@@ -13,9 +14,29 @@ type Generator interface {
 
 // GenerationConfig holds generation provider configuration.
 type GenerationConfig struct {
-	Provider  string `yaml:"provider"`    // "google"
-	Model     string `yaml:"model"`       // "gemini-2.0-flash"
-	APIKeyEnv string `yaml:"api_key_env"` // env var name containing API key
+	Provider    string        `yaml:"provider"`              // "google", "openai", "ollama" or "mock"
+	Model       string        `yaml:"model"`                 // "gemini-2.0-flash"
+	APIKeyEnv   string        `yaml:"api_key_env"`           // env var name containing API key
+	BaseURL     string        `yaml:"base_url,omitempty"`    // API base URL (provider default when empty)
+	Temperature *float64      `yaml:"temperature,omitempty"` // sampling temperature (provider default when unset)
+	MaxTokens   int           `yaml:"max_tokens,omitempty"`  // max output tokens (provider default when 0)
+	Timeout     time.Duration `yaml:"timeout,omitempty"`     // request timeout, e.g. "60s" (default 30s)
+	Fixtures    string        `yaml:"fixtures,omitempty"`    // mock provider: path to fixtures YAML
+}
+
+// Options are the request settings shared by all HTTP generators.
+type Options struct {
+	BaseURL     string
+	Temperature *float64
+	MaxTokens   int
+	Timeout     time.Duration
+}
+
+func (o Options) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return 30 * time.Second
 }
 
 // NewGenerator creates a Generator from config.
@@ -24,14 +45,44 @@ func NewGenerator(cfg *GenerationConfig) (Generator, error) {
 		return nil, nil
 	}
 
+	opts := Options{
+		BaseURL:     cfg.BaseURL,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxTokens,
+		Timeout:     cfg.Timeout,
+	}
+
 	switch cfg.Provider {
 	case "google":
 		apiKey := os.Getenv(cfg.APIKeyEnv)
 		if apiKey == "" {
 			return nil, fmt.Errorf("generation API key not found: set %s environment variable", cfg.APIKeyEnv)
 		}
-		return NewGoogleGenerator(apiKey, cfg.Model), nil
+		return NewGoogleGenerator(apiKey, cfg.Model, opts), nil
+	case "openai":
+		// API key is optional: local OpenAI-compatible servers usually need none
+		apiKey := ""
+		if cfg.APIKeyEnv != "" {
+			apiKey = os.Getenv(cfg.APIKeyEnv)
+			if apiKey == "" {
+				return nil, fmt.Errorf("generation API key not found: set %s environment variable", cfg.APIKeyEnv)
+			}
+		}
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("generation model is required for provider %q", cfg.Provider)
+		}
+		return NewOpenAIGenerator(apiKey, cfg.Model, opts), nil
+	case "ollama":
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("generation model is required for provider %q", cfg.Provider)
+		}
+		return NewOllamaGenerator(cfg.Model, opts), nil
+	case "mock":
+		if cfg.Fixtures == "" {
+			return nil, fmt.Errorf("mock generation provider requires a fixtures file")
+		}
+		return LoadMockGenerator(cfg.Fixtures)
 	default:
-		return nil, fmt.Errorf("unsupported generation provider: %q (supported: google)", cfg.Provider)
+		return nil, fmt.Errorf("unsupported generation provider: %q (supported: google, openai, ollama, mock)", cfg.Provider)
 	}
 }
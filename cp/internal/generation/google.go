@@ -7,32 +7,46 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"time"
+	"strings"
 )
 
+const defaultGoogleBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
 // GoogleGenerator generates text using Google Gemini.
 type GoogleGenerator struct {
 	apiKey     string
 	model      string
+	opts       Options
 	httpClient *http.Client
 }
 
-// NewGoogleGenerator creates a GoogleGenerator with the given API key and model.
-func NewGoogleGenerator(apiKey, model string) *GoogleGenerator {
+// NewGoogleGenerator creates a GoogleGenerator with the given API key, model and options.
+func NewGoogleGenerator(apiKey, model string, opts Options) *GoogleGenerator {
 	if model == "" {
 		model = "gemini-2.0-flash"
 	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultGoogleBaseURL
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
 	return &GoogleGenerator{
 		apiKey: apiKey,
 		model:  model,
+		opts:   opts,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: opts.timeout(),
 		},
 	}
 }
 
 type generateRequest struct {
-	Contents []generateContent `json:"contents"`
+	Contents         []generateContent `json:"contents"`
+	GenerationConfig *generationConfig `json:"generationConfig,omitempty"`
+}
+
+type generationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
 }
 
 type generateContent struct {
@@ -62,16 +76,19 @@ func (g *GoogleGenerator) Generate(ctx context.Context, prompt string) (string,
 		return "", fmt.Errorf("cannot generate from empty prompt")
 	}
 
-	url := fmt.Sprintf(
-		"https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
-		g.model, g.apiKey,
-	)
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", g.opts.BaseURL, g.model, g.apiKey)
 
 	reqBody := generateRequest{
 		Contents: []generateContent{
 			{Parts: []generatePart{{Text: prompt}}},
 		},
 	}
+	if g.opts.Temperature != nil || g.opts.MaxTokens > 0 {
+		reqBody.GenerationConfig = &generationConfig{
+			Temperature:     g.opts.Temperature,
+			MaxOutputTokens: g.opts.MaxTokens,
+		}
+	}
 
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
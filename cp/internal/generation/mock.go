@@ -0,0 +1,98 @@
+package generation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MockGenerator returns canned responses from a fixtures file so flows that
+// depend on a Generator (summaries, parent review) are reproducible offline.
+//
+// Fixtures file format:
+//
+//	responses:
+//	  - match: "parent memory"     # used whenever the prompt contains this text
+//	    response: "UNCHANGED"
+//	  - response: "first reply"    # no match: consumed in order, one per call
+//	  - response: "second reply"
+//
+// Matching fixtures are checked first (in file order); otherwise the next
+// unmatched fixture in the script is returned. Running out of script is an error.
+type MockGenerator struct {
+	mu      sync.Mutex
+	matched []MockFixture
+	script  []MockFixture
+	next    int
+	prompts []string
+}
+
+// MockFixture is one canned response.
+type MockFixture struct {
+	Match    string `yaml:"match,omitempty"`
+	Response string `yaml:"response"`
+}
+
+type mockFixtures struct {
+	Responses []MockFixture `yaml:"responses"`
+}
+
+// LoadMockGenerator reads a fixtures YAML file into a MockGenerator.
+func LoadMockGenerator(path string) (*MockGenerator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read generation fixtures: %v", err)
+	}
+	var f mockFixtures
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid generation fixtures %s: %v", path, err)
+	}
+	return NewMockGenerator(f.Responses), nil
+}
+
+// NewMockGenerator creates a MockGenerator from fixtures.
+func NewMockGenerator(fixtures []MockFixture) *MockGenerator {
+	m := &MockGenerator{}
+	for _, f := range fixtures {
+		if f.Match != "" {
+			m.matched = append(m.matched, f)
+		} else {
+			m.script = append(m.script, f)
+		}
+	}
+	return m
+}
+
+func (m *MockGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	if prompt == "" {
+		return "", fmt.Errorf("cannot generate from empty prompt")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prompts = append(m.prompts, prompt)
+
+	for _, f := range m.matched {
+		if strings.Contains(prompt, f.Match) {
+			return f.Response, nil
+		}
+	}
+	if m.next < len(m.script) {
+		resp := m.script[m.next].Response
+		m.next++
+		return resp, nil
+	}
+	return "", fmt.Errorf("mock generator: no fixture for prompt (call %d)", len(m.prompts))
+}
+
+// Prompts returns every prompt received so far, in order.
+func (m *MockGenerator) Prompts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.prompts...)
+}
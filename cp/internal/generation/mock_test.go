@@ -0,0 +1,91 @@
+package generation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMockGenerator(t *testing.T) {
+	fixtures := []MockFixture{
+		{Response: "first"},
+		{Match: "parent memory", Response: "UNCHANGED"},
+		{Response: "second"},
+	}
+
+	tests := []struct {
+		prompt  string
+		want    string
+		wantErr bool
+	}{
+		{prompt: "summarise this", want: "first"},
+		{prompt: "review the parent memory", want: "UNCHANGED"}, // matches don't consume the script
+		{prompt: "another", want: "second"},
+		{prompt: "parent memory again", want: "UNCHANGED"},
+		{prompt: "one too many", wantErr: true},
+		{prompt: "", wantErr: true},
+	}
+
+	m := NewMockGenerator(fixtures)
+	for _, tt := range tests {
+		got, err := m.Generate(context.Background(), tt.prompt)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Generate(%q) = %q, want an error", tt.prompt, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Generate(%q) = %q, %v; want %q", tt.prompt, got, err, tt.want)
+		}
+	}
+
+	// Empty prompts are rejected before they are recorded
+	want := []string{"summarise this", "review the parent memory", "another", "parent memory again", "one too many"}
+	if got := m.Prompts(); !slices.Equal(got, want) {
+		t.Errorf("Prompts() = %q, want %q", got, want)
+	}
+}
+
+func TestNewGeneratorMock(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "fixtures.yaml")
+	if err := os.WriteFile(good, []byte("responses:\n  - match: \"Question:\"\n    response: \"From fixtures\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(bad, []byte("responses: [unclosed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		fixtures string
+		wantErr  bool
+	}{
+		{name: "fixtures file", fixtures: good},
+		{name: "no fixtures", fixtures: "", wantErr: true},
+		{name: "missing file", fixtures: filepath.Join(dir, "missing.yaml"), wantErr: true},
+		{name: "invalid YAML", fixtures: bad, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := NewGenerator(&GenerationConfig{Provider: "mock", Fixtures: tt.fixtures})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := gen.Generate(context.Background(), "Question: why?")
+			if err != nil || got != "From fixtures" {
+				t.Errorf("Generate = %q, %v; want the fixture response", got, err)
+			}
+		})
+	}
+}
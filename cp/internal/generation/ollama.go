@@ -0,0 +1,81 @@
+package generation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+// OllamaGenerator generates text via Ollama's native /api/generate endpoint.
+type OllamaGenerator struct {
+	model      string
+	opts       Options
+	httpClient *http.Client
+}
+
+// NewOllamaGenerator creates an OllamaGenerator for the given model.
+func NewOllamaGenerator(model string, opts Options) *OllamaGenerator {
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultOllamaBaseURL
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	return &OllamaGenerator{
+		model: model,
+		opts:  opts,
+		httpClient: &http.Client{
+			Timeout: opts.timeout(),
+		},
+	}
+}
+
+type ollamaRequest struct {
+	Model   string         `json:"model"`
+	Prompt  string         `json:"prompt"`
+	Stream  bool           `json:"stream"`
+	Options *ollamaOptions `json:"options,omitempty"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+type ollamaResponse struct {
+	Response string `json:"response"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (o *OllamaGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	if prompt == "" {
+		return "", fmt.Errorf("cannot generate from empty prompt")
+	}
+
+	reqBody := ollamaRequest{
+		Model:  o.model,
+		Prompt: prompt,
+	}
+	if o.opts.Temperature != nil || o.opts.MaxTokens > 0 {
+		reqBody.Options = &ollamaOptions{
+			Temperature: o.opts.Temperature,
+			NumPredict:  o.opts.MaxTokens,
+		}
+	}
+
+	var result ollamaResponse
+	if err := postJSON(ctx, o.httpClient, o.opts.BaseURL+"/api/generate", nil, reqBody, &result); err != nil {
+		return "", err
+	}
+
+	if result.Error != "" {
+		return "", fmt.Errorf("API error: %s", result.Error)
+	}
+
+	if result.Response == "" {
+		return "", fmt.Errorf("API returned no content")
+	}
+
+	return result.Response, nil
+}
@@ -0,0 +1,129 @@
+package generation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIGenerator generates text via an OpenAI-compatible /chat/completions
+// endpoint (OpenAI, vLLM, llama.cpp server, LM Studio, ...).
+type OpenAIGenerator struct {
+	apiKey     string
+	model      string
+	opts       Options
+	httpClient *http.Client
+}
+
+// NewOpenAIGenerator creates an OpenAIGenerator. apiKey may be empty for local servers.
+func NewOpenAIGenerator(apiKey, model string, opts Options) *OpenAIGenerator {
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultOpenAIBaseURL
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	return &OpenAIGenerator{
+		apiKey: apiKey,
+		model:  model,
+		opts:   opts,
+		httpClient: &http.Client{
+			Timeout: opts.timeout(),
+		},
+	}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (o *OpenAIGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	if prompt == "" {
+		return "", fmt.Errorf("cannot generate from empty prompt")
+	}
+
+	reqBody := chatRequest{
+		Model:       o.model,
+		Messages:    []chatMessage{{Role: "user", Content: prompt}},
+		Temperature: o.opts.Temperature,
+		MaxTokens:   o.opts.MaxTokens,
+	}
+
+	headers := map[string]string{}
+	if o.apiKey != "" {
+		headers["Authorization"] = "Bearer " + o.apiKey
+	}
+
+	var result chatResponse
+	if err := postJSON(ctx, o.httpClient, o.opts.BaseURL+"/chat/completions", headers, reqBody, &result); err != nil {
+		return "", err
+	}
+
+	if result.Error != nil {
+		return "", fmt.Errorf("API error: %s", result.Error.Message)
+	}
+
+	if len(result.Choices) == 0 || result.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("API returned no content")
+	}
+
+	return result.Choices[0].Message.Content, nil
+}
+
+// postJSON POSTs body as JSON and decodes a 200 response into out
+func postJSON(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, body, out any) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("generation request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Schema is the subset of JSON Schema used for tool inputs.
+type Schema struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Default     any                `json:"default,omitempty"`
+
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+}
+
+// Prop is a named property of an object schema.
+type Prop struct {
+	Name     string
+	Schema   *Schema
+	Required bool
+}
+
+// Object builds an object schema from properties. Unknown properties are rejected.
+func Object(props ...Prop) *Schema {
+	no := false
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: &no}
+	for _, p := range props {
+		s.Properties[p.Name] = p.Schema
+		if p.Required {
+			s.Required = append(s.Required, p.Name)
+		}
+	}
+	return s
+}
+
+// String is a string schema.
+func String(desc string) *Schema {
+	return &Schema{Type: "string", Description: desc}
+}
+
+// Enum is a string schema limited to values.
+func Enum(desc string, values ...string) *Schema {
+	return &Schema{Type: "string", Description: desc, Enum: values}
+}
+
+// Integer is an integer schema bounded by min and max.
+func Integer(desc string, min, max float64) *Schema {
+	return &Schema{Type: "integer", Description: desc, Minimum: &min, Maximum: &max}
+}
+
+// Number is a number schema bounded by min and max.
+func Number(desc string, min, max float64) *Schema {
+	return &Schema{Type: "number", Description: desc, Minimum: &min, Maximum: &max}
+}
+
+// Boolean is a boolean schema.
+func Boolean(desc string) *Schema {
+	return &Schema{Type: "boolean", Description: desc}
+}
+
+// StringArray is an array-of-strings schema.
+func StringArray(desc string) *Schema {
+	return &Schema{Type: "array", Description: desc, Items: &Schema{Type: "string"}}
+}
+
+// Typed adapts a handler taking a decoded argument struct. Unknown fields and
+// missing required properties (per schema) are reported as tool errors.
+func Typed[T any](schema *Schema, fn func(ctx context.Context, args T) (any, error)) Handler {
+	return func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var present map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &present); err != nil {
+			return nil, fmt.Errorf("arguments must be a JSON object: %v", err)
+		}
+		for _, name := range schema.Required {
+			if v, ok := present[name]; !ok || string(v) == "null" {
+				return nil, fmt.Errorf("missing required argument %q", name)
+			}
+		}
+		for name := range present {
+			if _, ok := schema.Properties[name]; !ok {
+				return nil, fmt.Errorf("unknown argument %q", name)
+			}
+		}
+
+		var args T
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %v", err)
+		}
+		return fn(ctx, args)
+	}
+}
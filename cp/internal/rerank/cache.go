@@ -0,0 +1,125 @@
+package rerank
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached expansions and rerank scores are reused.
+const DefaultCacheTTL = 7 * 24 * time.Hour
+
+// maxCacheEntries bounds the cache file; the oldest entries are dropped first.
+const maxCacheEntries = 500
+
+// Cache stores LLM expansions and rerank scores in a JSON file so repeating a
+// query doesn't call the generator again. A nil *Cache is valid and caches
+// nothing.
+type Cache struct {
+	path    string
+	ttl     time.Duration
+	entries map[string]CacheEntry
+	dirty   bool
+}
+
+// CacheEntry is one cached generator result.
+type CacheEntry struct {
+	CreatedAt  time.Time          `json:"created_at"`
+	Expansions []string           `json:"expansions,omitempty"`
+	Scores     map[string]float64 `json:"scores,omitempty"`
+}
+
+// LoadCache reads the cache file, starting empty if it doesn't exist or is
+// unreadable. Expired entries are dropped.
+func LoadCache(path string, ttl time.Duration) *Cache {
+	c := &Cache{path: path, ttl: ttl, entries: map[string]CacheEntry{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		c.entries = map[string]CacheEntry{}
+		return c
+	}
+	for k, e := range c.entries {
+		if time.Since(e.CreatedAt) > ttl {
+			delete(c.entries, k)
+			c.dirty = true
+		}
+	}
+	return c
+}
+
+// ExpandKey is the cache key for expanding query into n phrasings.
+func ExpandKey(project, query string, n int) string {
+	return cacheKey("expand", project, query, fmt.Sprint(n))
+}
+
+// RerankKey is the cache key for rescoring candidates against query. The key
+// covers candidate content, so edited shards are rescored.
+func RerankKey(project, query string, candidates []Candidate) string {
+	parts := []string{"rerank", project, query}
+	for _, c := range candidates {
+		parts = append(parts, c.ID, c.Title, c.Content)
+	}
+	return cacheKey(parts...)
+}
+
+func cacheKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the entry for key, if cached.
+func (c *Cache) Get(key string) (CacheEntry, bool) {
+	if c == nil {
+		return CacheEntry{}, false
+	}
+	e, ok := c.entries[key]
+	return e, ok
+}
+
+// Put stores an entry under key.
+func (c *Cache) Put(key string, e CacheEntry) {
+	if c == nil {
+		return
+	}
+	e.CreatedAt = time.Now()
+	c.entries[key] = e
+	c.dirty = true
+}
+
+// Save writes the cache file if anything changed.
+func (c *Cache) Save() error {
+	if c == nil || !c.dirty {
+		return nil
+	}
+	for len(c.entries) > maxCacheEntries {
+		var oldest string
+		for k, e := range c.entries {
+			if oldest == "" || e.CreatedAt.Before(c.entries[oldest].CreatedAt) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode recall cache: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("cannot write recall cache: %v", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("cannot write recall cache: %v", err)
+	}
+	c.dirty = false
+	return os.Rename(tmp, c.path)
+}
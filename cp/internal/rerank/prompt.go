@@ -0,0 +1,143 @@
+package rerank
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Candidate is one recall result offered to the model for rescoring.
+type Candidate struct {
+	ID      string
+	Type    string
+	Title   string
+	Content string
+}
+
+// MaxCandidateChars caps how much of each candidate's content goes into the
+// rerank prompt.
+const MaxCandidateChars = 800
+
+// BuildRerankPrompt creates the prompt asking the model to score each
+// candidate's relevance to the query from 0 to 10.
+func BuildRerankPrompt(query string, candidates []Candidate) string {
+	var b strings.Builder
+	for _, c := range candidates {
+		content := c.Content
+		if len(content) > MaxCandidateChars {
+			cut := MaxCandidateChars
+			for cut > 0 && !utf8.RuneStart(content[cut]) {
+				cut--
+			}
+			content = content[:cut] + "..."
+		}
+		fmt.Fprintf(&b, "[%s] (%s) %s\n%s\n---\n", c.ID, c.Type, c.Title, strings.TrimSpace(content))
+	}
+
+	return fmt.Sprintf(`You are ranking search results for an AI agent's memory system.
+
+QUERY: %s
+
+CANDIDATES:
+---
+%s
+Score how well each candidate answers or relates to the query, from 0 (irrelevant)
+to 10 (exactly what the agent is looking for). Judge by meaning, not word overlap.
+Score every candidate exactly once, using its ID in brackets.
+
+Respond as JSON:
+{
+  "scores": [{"id": "candidate id", "score": 7}]
+}`, query, b.String())
+}
+
+// ParseRerankResponse parses the model's scores into a map of shard ID to score.
+// IDs not in the candidate set are ignored.
+func ParseRerankResponse(response string, candidates []Candidate) (map[string]float64, error) {
+	var parsed struct {
+		Scores []struct {
+			ID    string  `json:"id"`
+			Score float64 `json:"score"`
+		} `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(stripFences(response)), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse rerank response as JSON: %w\nRaw response: %s", err, response)
+	}
+
+	known := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		known[c.ID] = true
+	}
+	scores := make(map[string]float64)
+	for _, s := range parsed.Scores {
+		if known[s.ID] {
+			scores[s.ID] = s.Score
+		}
+	}
+	if len(scores) == 0 {
+		return nil, fmt.Errorf("rerank response scored none of the candidates")
+	}
+	return scores, nil
+}
+
+// BuildExpandPrompt creates the prompt asking for n alternative phrasings of query.
+func BuildExpandPrompt(query string, n int) string {
+	return fmt.Sprintf(`You are helping an AI agent search its memory system (bugs, tasks, requirements,
+design docs, lessons learned). The agent's query may be vague or use different
+words than the stored notes.
+
+QUERY: %s
+
+Write %d alternative search queries that could find the same information:
+synonyms, likely technical terms, symptoms vs causes, more specific phrasings.
+Keep each under 12 words. Do not repeat the original query.
+
+Respond as JSON:
+{
+  "queries": ["alternative one", "alternative two"]
+}`, query, n)
+}
+
+// ParseExpandResponse parses the alternative phrasings, dropping blanks,
+// duplicates and the original query, and keeping at most n.
+func ParseExpandResponse(response, query string, n int) ([]string, error) {
+	var parsed struct {
+		Queries []string `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(stripFences(response)), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse expansion response as JSON: %w\nRaw response: %s", err, response)
+	}
+
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	var queries []string
+	for _, q := range parsed.Queries {
+		q = strings.TrimSpace(q)
+		key := strings.ToLower(q)
+		if q == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		queries = append(queries, q)
+		if len(queries) == n {
+			break
+		}
+	}
+	return queries, nil
+}
+
+// stripFences removes markdown code fences that models sometimes wrap around JSON.
+func stripFences(response string) string {
+	response = strings.TrimSpace(response)
+	if !strings.HasPrefix(response, "```") {
+		return response
+	}
+	lines := strings.Split(response, "\n")
+	if len(lines) > 2 {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "```" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
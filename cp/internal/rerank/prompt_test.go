@@ -0,0 +1,33 @@
+package rerank
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBuildRerankPromptTruncation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string // the content as it appears in the prompt
+	}{
+		{name: "short", content: "fits", want: "fits\n"},
+		{name: "exact", content: strings.Repeat("a", MaxCandidateChars), want: strings.Repeat("a", MaxCandidateChars) + "\n"},
+		{name: "ascii", content: strings.Repeat("a", MaxCandidateChars+5), want: strings.Repeat("a", MaxCandidateChars) + "...\n"},
+		// "é" is two bytes; the cap falls inside the last one, which is dropped whole
+		{name: "multibyte", content: strings.Repeat("a", MaxCandidateChars-1) + "ééé", want: strings.Repeat("a", MaxCandidateChars-1) + "...\n"},
+		{name: "emoji", content: strings.Repeat("a", MaxCandidateChars-2) + "🚀🚀", want: strings.Repeat("a", MaxCandidateChars-2) + "...\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := BuildRerankPrompt("q", []Candidate{{ID: "pf-1", Type: "memory", Title: "T", Content: tt.content}})
+			if !utf8.ValidString(prompt) {
+				t.Fatal("prompt is not valid UTF-8")
+			}
+			if !strings.Contains(prompt, "[pf-1] (memory) T\n"+tt.want+"---") {
+				t.Errorf("prompt does not carry %q", tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package summary
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/otherjamesbrown/context-palace/cp/internal/generation"
 	"github.com/otherjamesbrown/context-palace/cp/internal/pointer"
 )
 
@@ -93,3 +95,18 @@ func ParseSummaryResponse(response string) (*SummaryResult, error) {
 
 	return &result, nil
 }
+
+// GenerateTrigger asks gen for the trigger summary of a new child memory.
+// Callers that show the parent review to a human should use BuildSummaryPrompt
+// and ParseSummaryResponse directly.
+func GenerateTrigger(ctx context.Context, gen generation.Generator, parentID, parentContent, childTitle, childContent string) (string, error) {
+	response, err := gen.Generate(ctx, BuildSummaryPrompt(parentID, parentContent, childTitle, childContent))
+	if err != nil {
+		return "", fmt.Errorf("summary generation failed: %v", err)
+	}
+	parsed, err := ParseSummaryResponse(response)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Summary, nil
+}
@@ -0,0 +1,146 @@
+package summary
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otherjamesbrown/context-palace/cp/internal/generation"
+	"github.com/otherjamesbrown/context-palace/cp/internal/pointer"
+)
+
+const parentContent = `Deployment is straightforward: push to main and Nomad rolls it out.
+
+<!-- sub-memories -->
+[
+  {
+    "id": "pf-old",
+    "title": "Rollback",
+    "summary": "When a release must be reverted"
+  }
+]
+<!-- /sub-memories -->
+`
+
+const fixtures = `responses:
+  - match: "NEW CHILD MEMORY (title: Deploys that don't restart)"
+    response: |
+      ` + "```json" + `
+      {
+        "summary": "If deploy succeeds but allocations don't restart",
+        "parent_needs_update": true,
+        "parent_edits": "Soften 'straightforward': link the restart troubleshooting"
+      }
+      ` + "```" + `
+`
+
+// loadFixtures writes fixtures to a file and loads the mock generator from it,
+// as generation.provider: mock does
+func loadFixtures(t *testing.T, fixtures string) *generation.MockGenerator {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := os.WriteFile(path, []byte(fixtures), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gen, err := generation.LoadMockGenerator(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gen
+}
+
+func TestGenerateTrigger(t *testing.T) {
+	ctx := context.Background()
+	gen := loadFixtures(t, fixtures)
+
+	trigger, err := GenerateTrigger(ctx, gen, "pf-parent", parentContent, "Deploys that don't restart", "Check the job version.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "If deploy succeeds but allocations don't restart"; trigger != want {
+		t.Errorf("trigger = %q, want %q", trigger, want)
+	}
+
+	// The prompt carries the parent's prose but not its pointer block
+	prompts := gen.Prompts()
+	if len(prompts) != 1 {
+		t.Fatalf("prompts = %d, want 1", len(prompts))
+	}
+	if !strings.Contains(prompts[0], "Deployment is straightforward") || strings.Contains(prompts[0], "pf-old") {
+		t.Errorf("prompt does not carry just the parent prose:\n%s", prompts[0])
+	}
+
+	// add-sub writes the trigger into the parent's pointer block
+	updated, err := pointer.AppendSubMemory(parentContent, pointer.SubMemoryEntry{ID: "pf-new", Title: "Deploys that don't restart", Summary: trigger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prose, entries, err := pointer.ParseSubMemories(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prose != "Deployment is straightforward: push to main and Nomad rolls it out." {
+		t.Errorf("parent prose = %q", prose)
+	}
+	if len(entries) != 2 || entries[0].ID != "pf-old" || entries[1].ID != "pf-new" || entries[1].Summary != trigger {
+		t.Errorf("pointer block = %+v, want pf-old then pf-new with the trigger", entries)
+	}
+
+	// No fixture for another child: the generator's error surfaces
+	if _, err := GenerateTrigger(ctx, gen, "pf-parent", parentContent, "Something else", "body"); err == nil || !strings.Contains(err.Error(), "summary generation failed") {
+		t.Errorf("err = %v, want the generation failure", err)
+	}
+}
+
+func TestParentReview(t *testing.T) {
+	ctx := context.Background()
+	gen := loadFixtures(t, fixtures)
+
+	// memory add-sub shows the parent review before approval
+	response, err := gen.Generate(ctx, BuildSummaryPrompt("pf-parent", parentContent, "Deploys that don't restart", "Check the job version."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSummaryResponse(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.ParentNeedsUpdate || parsed.ParentEdits == nil || !strings.Contains(*parsed.ParentEdits, "Soften 'straightforward'") {
+		t.Errorf("parent review = %+v, want the suggested edit", parsed)
+	}
+}
+
+func TestParseSummaryResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    string
+		wantSummary string
+		wantUpdate  bool
+		wantErr     bool
+	}{
+		{name: "plain JSON", response: `{"summary": "When X", "parent_needs_update": false, "parent_edits": null}`, wantSummary: "When X"},
+		{name: "fenced JSON", response: "```json\n{\"summary\": \"When Y\", \"parent_needs_update\": true, \"parent_edits\": \"edit\"}\n```", wantSummary: "When Y", wantUpdate: true},
+		{name: "bare fence", response: "```\n{\"summary\": \"When Z\"}\n```", wantSummary: "When Z"},
+		{name: "empty summary", response: `{"summary": ""}`, wantErr: true},
+		{name: "not JSON", response: "Summary: when X", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSummaryResponse(tt.response)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Summary != tt.wantSummary || got.ParentNeedsUpdate != tt.wantUpdate {
+				t.Errorf("got %+v, want summary %q, update %v", got, tt.wantSummary, tt.wantUpdate)
+			}
+		})
+	}
+}
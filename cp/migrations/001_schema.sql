@@ -0,0 +1,424 @@
+-- Base schema: projects, shards, edges, labels, read receipts
+-- Everything later migrations build on. Previously only documented in
+-- specs/postgres-schema.md; existing databases that were set up by hand should
+-- be recorded with `cp admin migrate baseline` instead of running this.
+
+CREATE TABLE IF NOT EXISTS projects (
+    name       TEXT PRIMARY KEY,
+    prefix     TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Per-project IDs: <prefix>-<6 hex chars>
+CREATE OR REPLACE FUNCTION gen_shard_id(p_project TEXT) RETURNS TEXT AS $$
+    SELECT prefix || '-' || substr(md5(random()::text), 1, 6)
+    FROM projects WHERE name = p_project;
+$$ LANGUAGE sql;
+
+CREATE TABLE IF NOT EXISTS shards (
+    id            TEXT PRIMARY KEY,
+    project       TEXT NOT NULL,
+    title         TEXT NOT NULL CHECK (char_length(title) <= 500),
+    content       TEXT,
+    type          TEXT,
+    status        TEXT NOT NULL DEFAULT 'open',
+    priority      INTEGER CHECK (priority >= 0 AND priority <= 4),
+    creator       TEXT NOT NULL,
+    owner         TEXT,
+    parent_id     TEXT REFERENCES shards(id),
+    labels        TEXT[] NOT NULL DEFAULT '{}',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at     TIMESTAMPTZ,
+    closed_reason TEXT,
+    search_vector tsvector GENERATED ALWAYS AS
+        (to_tsvector('english', coalesce(title, '') || ' ' || coalesce(content, ''))) STORED
+);
+
+CREATE INDEX IF NOT EXISTS idx_shards_project ON shards(project);
+CREATE INDEX IF NOT EXISTS idx_shards_project_status ON shards(project, status);
+CREATE INDEX IF NOT EXISTS idx_shards_project_type ON shards(project, type);
+CREATE INDEX IF NOT EXISTS idx_shards_status ON shards(status);
+CREATE INDEX IF NOT EXISTS idx_shards_type ON shards(type);
+CREATE INDEX IF NOT EXISTS idx_shards_creator ON shards(creator);
+CREATE INDEX IF NOT EXISTS idx_shards_owner ON shards(owner);
+CREATE INDEX IF NOT EXISTS idx_shards_parent_id ON shards(parent_id);
+CREATE INDEX IF NOT EXISTS idx_shards_created_at ON shards(created_at);
+CREATE INDEX IF NOT EXISTS idx_shards_labels ON shards USING gin(labels);
+CREATE INDEX IF NOT EXISTS idx_shards_search ON shards USING gin(search_vector);
+
+CREATE TABLE IF NOT EXISTS edges (
+    from_id    TEXT NOT NULL REFERENCES shards(id) ON DELETE CASCADE,
+    to_id      TEXT NOT NULL REFERENCES shards(id) ON DELETE CASCADE,
+    edge_type  TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    metadata   JSONB,
+    PRIMARY KEY (from_id, to_id, edge_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_edges_to_id ON edges(to_id);
+CREATE INDEX IF NOT EXISTS idx_edges_type ON edges(edge_type);
+
+CREATE TABLE IF NOT EXISTS labels (
+    shard_id TEXT NOT NULL REFERENCES shards(id) ON DELETE CASCADE,
+    label    TEXT NOT NULL,
+    PRIMARY KEY (shard_id, label)
+);
+
+CREATE INDEX IF NOT EXISTS idx_labels_label ON labels(label);
+
+CREATE TABLE IF NOT EXISTS read_receipts (
+    shard_id TEXT NOT NULL REFERENCES shards(id) ON DELETE CASCADE,
+    agent_id TEXT NOT NULL,
+    read_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (shard_id, agent_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_read_receipts_agent ON read_receipts(agent_id);
+
+CREATE TABLE IF NOT EXISTS artifacts (
+    id            BIGSERIAL PRIMARY KEY,
+    shard_id      TEXT NOT NULL REFERENCES shards(id) ON DELETE CASCADE,
+    artifact_type TEXT NOT NULL,
+    reference     TEXT NOT NULL,
+    description   TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifacts_shard ON artifacts(shard_id);
+
+-- Auto-update updated_at
+CREATE OR REPLACE FUNCTION update_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS shards_updated_at ON shards;
+CREATE TRIGGER shards_updated_at
+    BEFORE UPDATE ON shards
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- Labels live both in shards.labels (filtered with && by list/search
+-- functions) and in the labels table (joined by messaging functions). These
+-- triggers keep the two in step whichever side is written; the depth check
+-- stops each from re-firing the other.
+CREATE OR REPLACE FUNCTION sync_labels_to_table()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF pg_trigger_depth() > 1 THEN
+        RETURN NULL;
+    END IF;
+    DELETE FROM labels
+    WHERE shard_id = NEW.id AND NOT (label = ANY(NEW.labels));
+    INSERT INTO labels (shard_id, label)
+    SELECT NEW.id, unnest(NEW.labels)
+    ON CONFLICT DO NOTHING;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS shards_sync_labels ON shards;
+CREATE TRIGGER shards_sync_labels
+    AFTER INSERT OR UPDATE OF labels ON shards
+    FOR EACH ROW EXECUTE FUNCTION sync_labels_to_table();
+
+CREATE OR REPLACE FUNCTION sync_labels_to_shard()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_shard_id TEXT;
+BEGIN
+    IF pg_trigger_depth() > 1 THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP = 'DELETE' THEN v_shard_id := OLD.shard_id; ELSE v_shard_id := NEW.shard_id; END IF;
+    UPDATE shards
+    SET labels = ARRAY(SELECT label FROM labels WHERE shard_id = v_shard_id ORDER BY label)
+    WHERE id = v_shard_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS labels_sync_shard ON labels;
+CREATE TRIGGER labels_sync_shard
+    AFTER INSERT OR DELETE ON labels
+    FOR EACH ROW EXECUTE FUNCTION sync_labels_to_shard();
+
+-- Shard creation (amended with labels and metadata in 002)
+CREATE OR REPLACE FUNCTION create_shard(
+    p_project TEXT,
+    p_title TEXT,
+    p_content TEXT,
+    p_type TEXT,
+    p_creator TEXT,
+    p_owner TEXT DEFAULT NULL,
+    p_priority INT DEFAULT NULL,
+    p_status TEXT DEFAULT 'open'
+) RETURNS TEXT AS $$
+DECLARE
+    new_id TEXT;
+BEGIN
+    new_id := gen_shard_id(p_project);
+    INSERT INTO shards (id, project, title, content, type, creator, owner, priority, status)
+    VALUES (new_id, p_project, p_title, p_content, p_type, p_creator, p_owner, p_priority, p_status);
+    RETURN new_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Shards with memorable IDs like pf-rules; creates or updates
+CREATE OR REPLACE FUNCTION create_named_shard(
+    p_project TEXT,
+    p_name TEXT,
+    p_title TEXT,
+    p_content TEXT,
+    p_type TEXT,
+    p_creator TEXT,
+    p_owner TEXT DEFAULT NULL,
+    p_priority INT DEFAULT NULL,
+    p_status TEXT DEFAULT 'open'
+) RETURNS TEXT AS $$
+DECLARE
+    new_id TEXT;
+BEGIN
+    SELECT prefix || '-' || p_name INTO new_id FROM projects WHERE name = p_project;
+
+    INSERT INTO shards (id, project, title, content, type, creator, owner, priority, status)
+    VALUES (new_id, p_project, p_title, p_content, p_type, p_creator, p_owner, p_priority, p_status)
+    ON CONFLICT (id) DO UPDATE SET
+        title = EXCLUDED.title,
+        content = EXCLUDED.content,
+        updated_at = NOW();
+
+    RETURN new_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Unread messages for an agent (to: or cc:)
+CREATE OR REPLACE FUNCTION unread_for(p_project TEXT, p_agent TEXT)
+RETURNS TABLE (id TEXT, title TEXT, creator TEXT, kind TEXT, created_at TIMESTAMPTZ) AS $$
+    SELECT
+        s.id, s.title, s.creator,
+        (SELECT label FROM labels WHERE shard_id = s.id AND label LIKE 'kind:%' LIMIT 1),
+        s.created_at
+    FROM shards s
+    JOIN labels l ON l.shard_id = s.id
+    WHERE s.project = p_project
+      AND s.type = 'message'
+      AND s.status = 'open'
+      AND l.label IN ('to:' || p_agent, 'cc:' || p_agent)
+      AND s.id NOT IN (SELECT shard_id FROM read_receipts WHERE agent_id = p_agent)
+    ORDER BY s.created_at;
+$$ LANGUAGE sql STABLE;
+
+-- Open tasks owned by an agent
+CREATE OR REPLACE FUNCTION tasks_for(p_project TEXT, p_agent TEXT)
+RETURNS TABLE (id TEXT, title TEXT, priority INT, status TEXT, created_at TIMESTAMPTZ) AS $$
+    SELECT id, title, priority, status, created_at
+    FROM shards
+    WHERE project = p_project AND type = 'task' AND owner = p_agent AND status != 'closed'
+    ORDER BY priority, created_at;
+$$ LANGUAGE sql STABLE;
+
+-- Open tasks with no open blockers
+CREATE OR REPLACE FUNCTION ready_tasks(p_project TEXT)
+RETURNS TABLE (id TEXT, title TEXT, priority INT, owner TEXT, created_at TIMESTAMPTZ) AS $$
+    SELECT s.id, s.title, s.priority, s.owner, s.created_at
+    FROM shards s
+    WHERE s.project = p_project AND s.type = 'task' AND s.status = 'open'
+      AND NOT EXISTS (
+          SELECT 1 FROM edges e JOIN shards blocker ON e.to_id = blocker.id
+          WHERE e.from_id = s.id AND e.edge_type = 'blocks' AND blocker.status != 'closed'
+      )
+    ORDER BY s.priority, s.created_at;
+$$ LANGUAGE sql STABLE;
+
+-- Conversation thread below a root message
+CREATE OR REPLACE FUNCTION get_thread(p_root_id TEXT)
+RETURNS TABLE (id TEXT, title TEXT, creator TEXT, content TEXT, depth INT, created_at TIMESTAMPTZ) AS $$
+    WITH RECURSIVE thread AS (
+        SELECT s.id, s.title, s.creator, s.content, 0 AS depth, s.created_at
+        FROM shards s WHERE s.id = p_root_id
+        UNION ALL
+        SELECT s.id, s.title, s.creator, s.content, t.depth + 1, s.created_at
+        FROM shards s
+        JOIN edges e ON e.from_id = s.id
+        JOIN thread t ON e.to_id = t.id
+        WHERE e.edge_type = 'replies-to'
+    )
+    SELECT * FROM thread ORDER BY depth, created_at;
+$$ LANGUAGE sql STABLE;
+
+-- Atomic message creation with recipient labels and reply edge
+CREATE OR REPLACE FUNCTION send_message(
+    p_project TEXT,
+    p_sender TEXT,
+    p_recipients TEXT[],
+    p_subject TEXT,
+    p_body TEXT,
+    p_cc TEXT[] DEFAULT NULL,
+    p_kind TEXT DEFAULT NULL,
+    p_reply_to TEXT DEFAULT NULL
+) RETURNS TEXT AS $$
+DECLARE
+    new_id TEXT;
+    recipient TEXT;
+BEGIN
+    new_id := gen_shard_id(p_project);
+    INSERT INTO shards (id, project, title, content, type, status, creator)
+    VALUES (new_id, p_project, p_subject, p_body, 'message', 'open', p_sender);
+
+    FOREACH recipient IN ARRAY p_recipients LOOP
+        INSERT INTO labels (shard_id, label) VALUES (new_id, 'to:' || recipient);
+    END LOOP;
+
+    IF p_cc IS NOT NULL THEN
+        FOREACH recipient IN ARRAY p_cc LOOP
+            INSERT INTO labels (shard_id, label) VALUES (new_id, 'cc:' || recipient)
+            ON CONFLICT DO NOTHING;
+        END LOOP;
+    END IF;
+
+    IF p_kind IS NOT NULL THEN
+        INSERT INTO labels (shard_id, label) VALUES (new_id, 'kind:' || p_kind);
+    END IF;
+
+    IF p_reply_to IS NOT NULL THEN
+        INSERT INTO edges (from_id, to_id, edge_type) VALUES (new_id, p_reply_to, 'replies-to');
+        INSERT INTO read_receipts (shard_id, agent_id) VALUES (p_reply_to, p_sender) ON CONFLICT DO NOTHING;
+    END IF;
+
+    RETURN new_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Task from a source message, linked with discovered-from
+CREATE OR REPLACE FUNCTION create_task_from(
+    p_project TEXT,
+    p_creator TEXT,
+    p_source_id TEXT,
+    p_title TEXT,
+    p_description TEXT,
+    p_priority INT DEFAULT 2,
+    p_owner TEXT DEFAULT NULL,
+    p_labels TEXT[] DEFAULT NULL
+) RETURNS TEXT AS $$
+DECLARE
+    new_id TEXT;
+BEGIN
+    new_id := gen_shard_id(p_project);
+    INSERT INTO shards (id, project, title, content, type, status, creator, owner, priority)
+    VALUES (new_id, p_project, p_title, p_description, 'task', 'open', p_creator, p_owner, p_priority);
+
+    INSERT INTO edges (from_id, to_id, edge_type) VALUES (new_id, p_source_id, 'discovered-from');
+
+    -- Copy component labels from the source, plus any given
+    INSERT INTO labels (shard_id, label)
+    SELECT new_id, label FROM labels
+    WHERE shard_id = p_source_id
+      AND label NOT LIKE 'to:%' AND label NOT LIKE 'cc:%' AND label NOT LIKE 'kind:%'
+    UNION
+    SELECT new_id, unnest(COALESCE(p_labels, '{}'))
+    ON CONFLICT DO NOTHING;
+
+    UPDATE shards SET status = 'closed' WHERE id = p_source_id AND type = 'message';
+
+    RETURN new_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Mark messages read
+CREATE OR REPLACE FUNCTION mark_read(p_shard_ids TEXT[], p_agent TEXT)
+RETURNS INT AS $$
+DECLARE
+    v_id TEXT;
+    v_count INT := 0;
+BEGIN
+    FOREACH v_id IN ARRAY p_shard_ids LOOP
+        INSERT INTO read_receipts (shard_id, agent_id) VALUES (v_id, p_agent) ON CONFLICT DO NOTHING;
+        v_count := v_count + 1;
+    END LOOP;
+    RETURN v_count;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION mark_all_read(p_project TEXT, p_agent TEXT)
+RETURNS INT AS $$
+DECLARE
+    v_count INT;
+BEGIN
+    INSERT INTO read_receipts (shard_id, agent_id)
+    SELECT id, p_agent FROM unread_for(p_project, p_agent)
+    ON CONFLICT DO NOTHING;
+
+    GET DIAGNOSTICS v_count = ROW_COUNT;
+    RETURN v_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Edge and label shortcuts
+CREATE OR REPLACE FUNCTION link(p_from_id TEXT, p_to_id TEXT, p_edge_type TEXT)
+RETURNS VOID AS $$
+    INSERT INTO edges (from_id, to_id, edge_type) VALUES (p_from_id, p_to_id, p_edge_type)
+    ON CONFLICT DO NOTHING;
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION add_labels(p_shard_id TEXT, p_labels TEXT[])
+RETURNS INT AS $$
+DECLARE
+    v_count INT;
+BEGIN
+    INSERT INTO labels (shard_id, label)
+    SELECT p_shard_id, unnest(p_labels)
+    ON CONFLICT DO NOTHING;
+    GET DIAGNOSTICS v_count = ROW_COUNT;
+    RETURN v_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Task claim/close (palace workflow). Claiming fails if another agent owns it.
+CREATE OR REPLACE FUNCTION claim_task(p_task_id TEXT, p_agent TEXT)
+RETURNS BOOLEAN AS $$
+BEGIN
+    UPDATE shards
+    SET owner = p_agent, status = 'in_progress'
+    WHERE id = p_task_id
+      AND type IN ('task', 'backlog')
+      AND status != 'closed'
+      AND (owner IS NULL OR owner = p_agent);
+    RETURN FOUND;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION close_task(p_task_id TEXT, p_summary TEXT)
+RETURNS VOID AS $$
+BEGIN
+    UPDATE shards
+    SET status = 'closed', closed_at = NOW(), closed_reason = p_summary
+    WHERE id = p_task_id AND type IN ('task', 'backlog');
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Task % not found', p_task_id;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Artifacts (commits, PRs, files) recorded against a task
+CREATE OR REPLACE FUNCTION add_artifact(
+    p_shard_id TEXT,
+    p_type TEXT,
+    p_reference TEXT,
+    p_description TEXT DEFAULT NULL
+) RETURNS BIGINT AS $$
+    INSERT INTO artifacts (shard_id, artifact_type, reference, description)
+    VALUES (p_shard_id, p_type, p_reference, p_description)
+    RETURNING id;
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION get_artifacts(p_shard_id TEXT)
+RETURNS TABLE (artifact_type TEXT, reference TEXT, description TEXT, created_at TIMESTAMPTZ) AS $$
+    SELECT a.artifact_type, a.reference, COALESCE(a.description, ''), a.created_at
+    FROM artifacts a
+    WHERE a.shard_id = p_shard_id
+    ORDER BY a.created_at;
+$$ LANGUAGE sql STABLE;
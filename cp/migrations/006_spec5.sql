@@ -1,8 +1,6 @@
 -- SPEC-5: Unified Search & Graph CLI
 -- Depends on: 004_pgvector.sql (SPEC-1), 002_metadata.sql (SPEC-2), 003_requirements.sql (SPEC-3)
 
-BEGIN;
-
 -- Deduplicate edges before adding unique constraint
 DELETE FROM edges WHERE ctid NOT IN (
     SELECT min(ctid) FROM edges GROUP BY from_id, to_id, edge_type
@@ -387,5 +385,3 @@ CREATE OR REPLACE FUNCTION memory_recall(
     ORDER BY s.embedding <=> p_query_embedding
     LIMIT p_limit;
 $$ LANGUAGE sql STABLE;
-
-COMMIT;
@@ -0,0 +1,6 @@
+-- Revert 009: drop embedding model tracking. Embeddings themselves are kept.
+
+DROP INDEX IF EXISTS idx_shards_embedding_model;
+ALTER TABLE shards DROP COLUMN IF EXISTS embedding_dims;
+ALTER TABLE shards DROP COLUMN IF EXISTS embedding_model;
+ALTER TABLE shards DROP COLUMN IF EXISTS embedding_provider;
@@ -0,0 +1,13 @@
+-- Embedding model tracking
+-- Tags each stored embedding with the provider, model and dimensions that
+-- produced it, so recall can detect mixed vectors and `cp admin reembed` can
+-- find stale ones. Existing embeddings stay untagged (NULL) until re-embedded.
+
+ALTER TABLE shards ADD COLUMN IF NOT EXISTS embedding_provider TEXT;
+ALTER TABLE shards ADD COLUMN IF NOT EXISTS embedding_model TEXT;
+ALTER TABLE shards ADD COLUMN IF NOT EXISTS embedding_dims INT;
+
+-- Supports the per-model counts and stale-embedding scans
+CREATE INDEX IF NOT EXISTS idx_shards_embedding_model
+    ON shards (project, embedding_provider, embedding_model, embedding_dims)
+    WHERE embedding IS NOT NULL;
@@ -0,0 +1,110 @@
+-- Chunked embeddings for long shards
+-- Long shards (knowledge docs, session logs) are split into heading-aware
+-- chunks, each with its own vector, so recall can match any section instead of
+-- only the first 32k chars. semantic_search() aggregates chunk hits back to the
+-- owning shard and returns the best-matching chunk as the snippet.
+
+CREATE TABLE IF NOT EXISTS shard_chunks (
+    shard_id TEXT NOT NULL REFERENCES shards(id) ON DELETE CASCADE,
+    chunk_index INT NOT NULL,
+    heading TEXT,
+    content TEXT NOT NULL,
+    embedding vector(768),
+    embedding_provider TEXT,
+    embedding_model TEXT,
+    embedding_dims INT,
+    PRIMARY KEY (shard_id, chunk_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shard_chunks_embedding ON shard_chunks
+    USING ivfflat (embedding vector_cosine_ops)
+    WITH (lists = 50);
+
+-- AMENDED: shards_needing_embedding() also returns long shards that have no
+-- chunks yet. 6000 must match embedding.ChunkThreshold.
+CREATE OR REPLACE FUNCTION shards_needing_embedding(p_project TEXT, p_limit INT DEFAULT 100)
+RETURNS TABLE (id TEXT, title TEXT, type TEXT) AS $$
+    SELECT s.id, s.title, s.type
+    FROM shards s
+    WHERE s.project = p_project
+      AND (s.content IS NOT NULL OR s.title IS NOT NULL)
+      AND (s.embedding IS NULL
+           OR (length(s.content) > 6000
+               AND NOT EXISTS (SELECT 1 FROM shard_chunks c WHERE c.shard_id = s.id)))
+    ORDER BY s.created_at DESC
+    LIMIT p_limit;
+$$ LANGUAGE sql STABLE;
+
+-- AMENDED: semantic_search() searches shard and chunk vectors, keeps the best
+-- hit per shard, and reports which chunk matched. Return type changes, so the
+-- old function must be dropped first.
+DROP FUNCTION IF EXISTS semantic_search(TEXT, vector, TEXT[], TEXT[], TEXT[], INT, FLOAT, TIMESTAMPTZ);
+
+CREATE OR REPLACE FUNCTION semantic_search(
+    p_project TEXT,
+    p_query_embedding vector(768),
+    p_types TEXT[] DEFAULT NULL,
+    p_labels TEXT[] DEFAULT NULL,
+    p_status TEXT[] DEFAULT NULL,
+    p_limit INT DEFAULT 20,
+    p_min_similarity FLOAT DEFAULT 0.3,
+    p_since TIMESTAMPTZ DEFAULT NULL
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    type TEXT,
+    status TEXT,
+    similarity FLOAT,
+    snippet TEXT,
+    labels TEXT[],
+    created_at TIMESTAMPTZ,
+    chunk_index INT,
+    chunk_heading TEXT
+) AS $$
+    WITH shard_hits AS (
+        SELECT s.id AS shard_id,
+               1 - (s.embedding <=> p_query_embedding) AS similarity,
+               LEFT(s.content, 200) AS snippet,
+               NULL::INT AS chunk_index,
+               NULL::TEXT AS chunk_heading
+        FROM shards s
+        WHERE s.project = p_project
+          AND s.embedding IS NOT NULL
+          AND (p_types IS NULL OR s.type = ANY(p_types))
+          AND (p_labels IS NULL OR s.labels && p_labels)
+          AND (p_status IS NULL OR s.status = ANY(p_status))
+          AND (p_since IS NULL OR s.created_at >= p_since)
+        ORDER BY s.embedding <=> p_query_embedding
+        LIMIT p_limit * 4
+    ),
+    chunk_hits AS (
+        SELECT c.shard_id,
+               1 - (c.embedding <=> p_query_embedding) AS similarity,
+               LEFT(c.content, 200) AS snippet,
+               c.chunk_index,
+               c.heading AS chunk_heading
+        FROM shard_chunks c
+        JOIN shards s ON s.id = c.shard_id
+        WHERE s.project = p_project
+          AND c.embedding IS NOT NULL
+          AND (p_types IS NULL OR s.type = ANY(p_types))
+          AND (p_labels IS NULL OR s.labels && p_labels)
+          AND (p_status IS NULL OR s.status = ANY(p_status))
+          AND (p_since IS NULL OR s.created_at >= p_since)
+        ORDER BY c.embedding <=> p_query_embedding
+        LIMIT p_limit * 4
+    ),
+    best AS (
+        SELECT DISTINCT ON (h.shard_id) h.*
+        FROM (SELECT * FROM shard_hits UNION ALL SELECT * FROM chunk_hits) h
+        WHERE h.similarity >= p_min_similarity
+        ORDER BY h.shard_id, h.similarity DESC
+    )
+    SELECT s.id, s.title, s.type, s.status,
+           b.similarity, b.snippet, s.labels, s.created_at,
+           b.chunk_index, b.chunk_heading
+    FROM best b
+    JOIN shards s ON s.id = b.shard_id
+    ORDER BY b.similarity DESC
+    LIMIT p_limit;
+$$ LANGUAGE sql STABLE;
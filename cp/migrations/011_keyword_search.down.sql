@@ -0,0 +1,3 @@
+-- Revert 011: remove keyword_search(); recall must then use --mode semantic
+
+DROP FUNCTION IF EXISTS keyword_search(TEXT, TEXT, TEXT[], TEXT[], TEXT[], INT, TIMESTAMPTZ);
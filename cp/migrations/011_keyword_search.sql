@@ -0,0 +1,52 @@
+-- Keyword search for hybrid recall
+-- Full-text rank plus exact-match boosts, so identifiers that tokenize badly
+-- (error codes, function names, shard IDs) still rank first. Filters mirror
+-- semantic_search() so both rankings can be fused over the same candidate set.
+
+CREATE OR REPLACE FUNCTION keyword_search(
+    p_project TEXT,
+    p_query TEXT,
+    p_types TEXT[] DEFAULT NULL,
+    p_labels TEXT[] DEFAULT NULL,
+    p_status TEXT[] DEFAULT NULL,
+    p_limit INT DEFAULT 20,
+    p_since TIMESTAMPTZ DEFAULT NULL
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    type TEXT,
+    status TEXT,
+    score FLOAT,
+    snippet TEXT,
+    labels TEXT[],
+    created_at TIMESTAMPTZ
+) AS $$
+    WITH q AS (
+        SELECT plainto_tsquery('english', p_query) AS tsq, lower(p_query) AS lq
+    )
+    SELECT
+        s.id, s.title, s.type, s.status,
+        (ts_rank(s.search_vector, q.tsq)
+         + CASE WHEN s.id = p_query THEN 1.0 ELSE 0 END
+         + CASE WHEN strpos(lower(s.title), q.lq) > 0 THEN 0.5 ELSE 0 END
+         + CASE WHEN strpos(s.content, p_query) > 0 THEN 0.3 ELSE 0 END)::FLOAT AS score,
+        -- Center the snippet on an exact match when there is one
+        CASE WHEN strpos(s.content, p_query) > 0
+             THEN substr(s.content, greatest(strpos(s.content, p_query) - 60, 1), 200)
+             ELSE LEFT(s.content, 200)
+        END AS snippet,
+        s.labels,
+        s.created_at
+    FROM shards s, q
+    WHERE s.project = p_project
+      AND (s.search_vector @@ q.tsq
+           OR s.id = p_query
+           OR strpos(lower(s.title), q.lq) > 0
+           OR strpos(s.content, p_query) > 0)
+      AND (p_types IS NULL OR s.type = ANY(p_types))
+      AND (p_labels IS NULL OR s.labels && p_labels)
+      AND (p_status IS NULL OR s.status = ANY(p_status))
+      AND (p_since IS NULL OR s.created_at >= p_since)
+    ORDER BY score DESC, s.created_at DESC
+    LIMIT p_limit;
+$$ LANGUAGE sql STABLE;
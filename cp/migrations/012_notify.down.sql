@@ -0,0 +1,8 @@
+-- Revert 012: stop publishing change notifications
+
+DROP TRIGGER IF EXISTS shards_notify ON shards;
+DROP TRIGGER IF EXISTS edges_notify ON edges;
+DROP TRIGGER IF EXISTS labels_notify ON labels;
+DROP FUNCTION IF EXISTS notify_shard_change();
+DROP FUNCTION IF EXISTS notify_edge_change();
+DROP FUNCTION IF EXISTS notify_label_change();
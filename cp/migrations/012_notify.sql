@@ -0,0 +1,91 @@
+-- Change notifications for cp watch / cp message watch
+-- Shard, edge and label changes are published on the cp_events channel with
+-- pg_notify(). Payloads carry only identifiers (NOTIFY payloads are capped at
+-- 8000 bytes); listeners look up titles and labels themselves. Notifications
+-- are delivered on commit, so a message's to:/cc: labels exist by the time
+-- the listener sees them.
+
+CREATE OR REPLACE FUNCTION notify_shard_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        payload := jsonb_build_object(
+            'table', 'shards', 'op', TG_OP, 'project', OLD.project,
+            'id', OLD.id, 'type', OLD.type, 'status', OLD.status);
+    ELSE
+        -- Embedding backfills and timestamp-only touches are not news
+        IF TG_OP = 'UPDATE'
+           AND NEW.title IS NOT DISTINCT FROM OLD.title
+           AND NEW.content IS NOT DISTINCT FROM OLD.content
+           AND NEW.status IS NOT DISTINCT FROM OLD.status
+           AND NEW.owner IS NOT DISTINCT FROM OLD.owner
+           AND NEW.priority IS NOT DISTINCT FROM OLD.priority
+           AND NEW.metadata IS NOT DISTINCT FROM OLD.metadata THEN
+            RETURN NULL;
+        END IF;
+        payload := jsonb_build_object(
+            'table', 'shards', 'op', TG_OP, 'project', NEW.project,
+            'id', NEW.id, 'type', NEW.type, 'status', NEW.status);
+        IF TG_OP = 'UPDATE' AND NEW.status IS DISTINCT FROM OLD.status THEN
+            payload := payload || jsonb_build_object('prev_status', OLD.status);
+        END IF;
+    END IF;
+    PERFORM pg_notify('cp_events', payload::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS shards_notify ON shards;
+CREATE TRIGGER shards_notify
+    AFTER INSERT OR UPDATE OR DELETE ON shards
+    FOR EACH ROW EXECUTE FUNCTION notify_shard_change();
+
+CREATE OR REPLACE FUNCTION notify_edge_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    e RECORD;
+    v_project TEXT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN e := OLD; ELSE e := NEW; END IF;
+    -- Edges removed by a cascading shard delete have no project left; the
+    -- shard's own DELETE event covers them.
+    SELECT project INTO v_project FROM shards WHERE id = e.from_id;
+    IF v_project IS NULL THEN
+        RETURN NULL;
+    END IF;
+    PERFORM pg_notify('cp_events', jsonb_build_object(
+        'table', 'edges', 'op', TG_OP, 'project', v_project,
+        'id', e.from_id, 'to_id', e.to_id, 'edge_type', e.edge_type)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS edges_notify ON edges;
+CREATE TRIGGER edges_notify
+    AFTER INSERT OR DELETE ON edges
+    FOR EACH ROW EXECUTE FUNCTION notify_edge_change();
+
+CREATE OR REPLACE FUNCTION notify_label_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    l RECORD;
+    v_project TEXT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN l := OLD; ELSE l := NEW; END IF;
+    SELECT project INTO v_project FROM shards WHERE id = l.shard_id;
+    IF v_project IS NULL THEN
+        RETURN NULL;
+    END IF;
+    PERFORM pg_notify('cp_events', jsonb_build_object(
+        'table', 'labels', 'op', TG_OP, 'project', v_project,
+        'id', l.shard_id, 'label', l.label)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS labels_notify ON labels;
+CREATE TRIGGER labels_notify
+    AFTER INSERT OR DELETE ON labels
+    FOR EACH ROW EXECUTE FUNCTION notify_label_change();
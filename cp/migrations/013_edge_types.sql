@@ -0,0 +1,375 @@
+-- Per-project edge type registry
+-- Built-in edge types (blocked-by, child-of, implements, ...) are defined in
+-- the client. A project may declare its own types here, or redeclare a
+-- built-in to change its properties:
+--   inverse    name of the relation read from the other end; linking with it
+--              stores the edge reversed under the canonical name
+--   blocks     from_id cannot start until to_id is closed
+--   acyclic    edges of this type (of any blocking type, if it blocks) may
+--              not form a cycle
+--   from_types / to_types  shard types the edge may connect (empty = any)
+--
+-- Every blocking check now goes through the blocking_edges view instead of
+-- naming 'blocked-by', so a project-declared blocking type really blocks.
+
+CREATE TABLE IF NOT EXISTS edge_types (
+    project     TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    inverse     TEXT,
+    blocks      BOOLEAN NOT NULL DEFAULT false,
+    acyclic     BOOLEAN NOT NULL DEFAULT false,
+    from_types  TEXT[] NOT NULL DEFAULT '{}',
+    to_types    TEXT[] NOT NULL DEFAULT '{}',
+    description TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (project, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_edge_types_inverse
+    ON edge_types(project, inverse) WHERE inverse IS NOT NULL;
+
+-- Edges that make from_id wait on to_id. blocked-by blocks unless the project
+-- redeclared it; other types block only when declared with blocks = true.
+--
+-- 'blocks' and 'parent' are now inverse names: linking with them stores a
+-- blocked-by or child-of edge reversed. Edges stored under those names before
+-- this migration are left as they are and read as aliases in their stored
+-- direction, which is what ready_tasks() in 001 did: from_id waits on to_id.
+CREATE OR REPLACE VIEW blocking_edges AS
+SELECT e.from_id, e.to_id, e.edge_type
+FROM edges e
+JOIN shards s ON s.id = e.from_id
+LEFT JOIN edge_types t ON t.project = s.project
+    AND t.name = CASE e.edge_type
+                     WHEN 'blocks' THEN 'blocked-by'
+                     WHEN 'parent' THEN 'child-of'
+                     ELSE e.edge_type
+                 END
+WHERE COALESCE(t.blocks, e.edge_type IN ('blocked-by', 'blocks'));
+
+-- Cycle check over a set of edge types: would from_id -> to_id close a loop?
+CREATE OR REPLACE FUNCTION has_circular_dependency(
+    p_from TEXT,
+    p_to TEXT,
+    p_edge_types TEXT[]
+) RETURNS BOOLEAN AS $$
+    WITH RECURSIVE chain AS (
+        SELECT to_id FROM edges WHERE from_id = p_to AND edge_type = ANY(p_edge_types)
+        UNION
+        SELECT e.to_id FROM edges e
+        JOIN chain c ON e.from_id = c.to_id
+        WHERE e.edge_type = ANY(p_edge_types)
+    )
+    SELECT EXISTS (SELECT 1 FROM chain WHERE to_id = p_from)
+           OR p_from = p_to;
+$$ LANGUAGE sql STABLE;
+
+-- The functions below are unchanged from 001/007 apart from reading
+-- blocking_edges.
+
+CREATE OR REPLACE FUNCTION ready_tasks(p_project TEXT)
+RETURNS TABLE (id TEXT, title TEXT, priority INT, owner TEXT, created_at TIMESTAMPTZ) AS $$
+    SELECT s.id, s.title, s.priority, s.owner, s.created_at
+    FROM shards s
+    WHERE s.project = p_project AND s.type = 'task' AND s.status = 'open'
+      AND NOT EXISTS (
+          SELECT 1 FROM blocking_edges e JOIN shards blocker ON e.to_id = blocker.id
+          WHERE e.from_id = s.id AND blocker.status != 'closed'
+      )
+    ORDER BY s.priority, s.created_at;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION epic_progress(
+    p_project TEXT,
+    p_epic_id TEXT
+) RETURNS TABLE (
+    total INT,
+    completed INT,
+    in_progress INT,
+    open INT,
+    blocked INT
+) AS $$
+    SELECT
+        count(*)::int AS total,
+        count(*) FILTER (WHERE s.status = 'closed')::int AS completed,
+        count(*) FILTER (WHERE s.status = 'in_progress')::int AS in_progress,
+        count(*) FILTER (WHERE s.status = 'open'
+            AND NOT EXISTS (
+                SELECT 1 FROM blocking_edges e
+                JOIN shards blocker ON blocker.id = e.to_id
+                WHERE e.from_id = s.id
+                  AND blocker.status != 'closed'
+            ))::int AS open,
+        count(*) FILTER (WHERE s.status = 'open'
+            AND EXISTS (
+                SELECT 1 FROM blocking_edges e
+                JOIN shards blocker ON blocker.id = e.to_id
+                WHERE e.from_id = s.id
+                  AND blocker.status != 'closed'
+            ))::int AS blocked
+    FROM shards s
+    WHERE s.project = p_project
+      AND s.parent_id = p_epic_id
+      AND s.type != 'epic';
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION epic_children(
+    p_project TEXT,
+    p_epic_id TEXT
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    status TEXT,
+    kind TEXT,
+    owner TEXT,
+    priority INT,
+    assigned_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    closed_by TEXT,
+    closed_reason TEXT,
+    blocked_by TEXT[]
+) AS $$
+    SELECT
+        s.id, s.title, s.status,
+        COALESCE(
+            (SELECT replace(l.label, 'kind:', '')
+             FROM labels l
+             WHERE l.shard_id = s.id AND l.label LIKE 'kind:%'
+             LIMIT 1),
+            'task'
+        ) AS kind,
+        s.owner,
+        s.priority,
+        (s.metadata->>'assigned_at')::timestamptz,
+        s.closed_at,
+        s.closed_by,
+        s.closed_reason,
+        COALESCE(
+            (SELECT array_agg(e.to_id)
+             FROM blocking_edges e
+             JOIN shards blocker ON blocker.id = e.to_id
+             WHERE e.from_id = s.id
+               AND blocker.status != 'closed'),
+            '{}'::text[]
+        )
+    FROM shards s
+    WHERE s.project = p_project
+      AND s.parent_id = p_epic_id
+      AND s.type != 'epic'
+    ORDER BY
+        CASE s.status
+            WHEN 'in_progress' THEN 0
+            WHEN 'open' THEN 1
+            WHEN 'closed' THEN 2
+        END,
+        s.priority,
+        s.created_at;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION shard_next(
+    p_project TEXT,
+    p_epic_id TEXT DEFAULT NULL,
+    p_limit INT DEFAULT 5
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    kind TEXT,
+    priority INT,
+    epic_id TEXT,
+    epic_title TEXT
+) AS $$
+    SELECT
+        s.id, s.title,
+        COALESCE(
+            (SELECT replace(l.label, 'kind:', '')
+             FROM labels l
+             WHERE l.shard_id = s.id AND l.label LIKE 'kind:%'
+             LIMIT 1),
+            'task'
+        ),
+        s.priority,
+        s.parent_id,
+        p.title
+    FROM shards s
+    LEFT JOIN shards p ON p.id = s.parent_id AND p.type = 'epic'
+    WHERE s.project = p_project
+      AND s.status = 'open'
+      AND s.type NOT IN ('epic', 'memory', 'message')
+      AND (p_epic_id IS NULL OR s.parent_id = p_epic_id)
+      AND NOT EXISTS (
+          SELECT 1 FROM blocking_edges e
+          JOIN shards blocker ON blocker.id = e.to_id
+          WHERE e.from_id = s.id
+            AND blocker.status != 'closed'
+      )
+    ORDER BY s.priority, s.created_at
+    LIMIT p_limit;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION shard_board(
+    p_project TEXT,
+    p_epic_id TEXT DEFAULT NULL,
+    p_agent TEXT DEFAULT NULL
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    status TEXT,
+    kind TEXT,
+    owner TEXT,
+    priority INT,
+    epic_id TEXT,
+    epic_title TEXT,
+    assigned_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    blocked_by TEXT[]
+) AS $$
+    SELECT
+        s.id, s.title, s.status,
+        COALESCE(
+            (SELECT replace(l.label, 'kind:', '')
+             FROM labels l
+             WHERE l.shard_id = s.id AND l.label LIKE 'kind:%'
+             LIMIT 1),
+            'task'
+        ),
+        s.owner, s.priority, s.parent_id, p.title,
+        (s.metadata->>'assigned_at')::timestamptz,
+        s.closed_at,
+        COALESCE(
+            (SELECT array_agg(e.to_id)
+             FROM blocking_edges e
+             JOIN shards blocker ON blocker.id = e.to_id
+             WHERE e.from_id = s.id
+               AND blocker.status != 'closed'),
+            '{}'::text[]
+        )
+    FROM shards s
+    LEFT JOIN shards p ON p.id = s.parent_id AND p.type = 'epic'
+    WHERE s.project = p_project
+      AND s.type NOT IN ('epic', 'memory', 'message')
+      AND (p_epic_id IS NULL OR s.parent_id = p_epic_id)
+      AND (p_agent IS NULL OR s.owner = p_agent)
+      AND (p_agent IS NOT NULL
+           OR p_epic_id IS NOT NULL
+           OR s.status != 'closed'
+           OR s.closed_at > NOW() - INTERVAL '24 hours')
+    ORDER BY
+        CASE s.status
+            WHEN 'in_progress' THEN 0
+            WHEN 'open' THEN 1
+            WHEN 'closed' THEN 2
+        END,
+        s.priority,
+        s.created_at;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION shard_assign(
+    p_project TEXT,
+    p_shard_id TEXT,
+    p_agent TEXT
+) RETURNS TEXT AS $$
+DECLARE
+    v_status TEXT;
+    v_owner TEXT;
+    v_title TEXT;
+BEGIN
+    SELECT status, owner, title INTO v_status, v_owner, v_title
+    FROM shards WHERE id = p_shard_id AND project = p_project FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Shard % not found', p_shard_id;
+    END IF;
+
+    IF v_status = 'in_progress' THEN
+        RAISE EXCEPTION 'Shard % is already in_progress (owner: %)', p_shard_id, v_owner;
+    END IF;
+
+    IF v_status = 'closed' THEN
+        RAISE EXCEPTION 'Shard % is already closed', p_shard_id;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM blocking_edges e
+        JOIN shards blocker ON blocker.id = e.to_id
+        WHERE e.from_id = p_shard_id
+          AND blocker.status != 'closed'
+    ) THEN
+        RAISE EXCEPTION 'Shard % has unresolved blockers', p_shard_id;
+    END IF;
+
+    UPDATE shards
+    SET status = 'in_progress',
+        owner = p_agent,
+        updated_at = NOW(),
+        metadata = jsonb_set(
+            COALESCE(metadata, '{}'::jsonb),
+            '{assigned_at}',
+            to_jsonb(NOW()::text)
+        )
+    WHERE id = p_shard_id AND project = p_project;
+
+    RETURN v_title;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+CREATE OR REPLACE FUNCTION shard_close(
+    p_project TEXT,
+    p_shard_id TEXT,
+    p_agent TEXT,
+    p_reason TEXT DEFAULT NULL
+) RETURNS TABLE (
+    closed_title TEXT,
+    unblocked_id TEXT,
+    unblocked_title TEXT
+) AS $$
+DECLARE
+    v_status TEXT;
+    v_title TEXT;
+    v_parent_id TEXT;
+BEGIN
+    SELECT status, title, parent_id INTO v_status, v_title, v_parent_id
+    FROM shards WHERE id = p_shard_id AND project = p_project FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Shard % not found', p_shard_id;
+    END IF;
+
+    -- Idempotent: already closed = no-op
+    IF v_status = 'closed' THEN
+        RETURN QUERY SELECT v_title, NULL::TEXT, NULL::TEXT;
+        RETURN;
+    END IF;
+
+    UPDATE shards
+    SET status = 'closed',
+        closed_at = NOW(),
+        closed_by = p_agent,
+        closed_reason = p_reason,
+        updated_at = NOW()
+    WHERE id = p_shard_id;
+
+    -- Update parent epic's updated_at
+    IF v_parent_id IS NOT NULL THEN
+        UPDATE shards SET updated_at = NOW() WHERE id = v_parent_id;
+    END IF;
+
+    -- Return the closed shard + any newly unblocked shards
+    RETURN QUERY
+    SELECT v_title, NULL::TEXT, NULL::TEXT
+    UNION ALL
+    SELECT NULL::TEXT, s.id, s.title
+    FROM shards s
+    WHERE s.status = 'open'
+      AND EXISTS (
+          SELECT 1 FROM blocking_edges e
+          WHERE e.from_id = s.id
+            AND e.to_id = p_shard_id
+      )
+      AND NOT EXISTS (
+          SELECT 1 FROM blocking_edges e
+          JOIN shards blocker ON blocker.id = e.to_id
+          WHERE e.from_id = s.id
+            AND blocker.status != 'closed'
+      );
+END;
+$$ LANGUAGE plpgsql VOLATILE;
@@ -0,0 +1,78 @@
+-- Atomic claim-next for cp shard take
+-- shard_next followed by shard_assign is two round-trips, so parallel agents
+-- race for the same shard. shard_take picks and assigns in one statement:
+-- FOR UPDATE SKIP LOCKED lets concurrent callers pass over a candidate another
+-- transaction is claiming and take the next one instead of waiting on it.
+
+CREATE OR REPLACE FUNCTION shard_take(
+    p_project TEXT,
+    p_agent TEXT,
+    p_epic_id TEXT DEFAULT NULL,
+    p_kind TEXT DEFAULT NULL
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    kind TEXT,
+    priority INT,
+    epic_id TEXT,
+    epic_title TEXT
+) AS $$
+#variable_conflict use_column
+DECLARE
+    v_id TEXT;
+BEGIN
+    SELECT s.id INTO v_id
+    FROM shards s
+    WHERE s.project = p_project
+      AND s.status = 'open'
+      AND s.type NOT IN ('epic', 'memory', 'message')
+      AND (p_epic_id IS NULL OR s.parent_id = p_epic_id)
+      AND (p_kind IS NULL OR COALESCE(
+              (SELECT replace(l.label, 'kind:', '')
+               FROM labels l
+               WHERE l.shard_id = s.id AND l.label LIKE 'kind:%'
+               LIMIT 1),
+              'task') = p_kind)
+      AND NOT EXISTS (
+          SELECT 1 FROM blocking_edges e
+          JOIN shards blocker ON blocker.id = e.to_id
+          WHERE e.from_id = s.id
+            AND blocker.status != 'closed'
+      )
+    ORDER BY s.priority, s.created_at
+    LIMIT 1
+    FOR UPDATE OF s SKIP LOCKED;
+
+    IF v_id IS NULL THEN
+        RETURN;
+    END IF;
+
+    UPDATE shards
+    SET status = 'in_progress',
+        owner = p_agent,
+        updated_at = NOW(),
+        metadata = jsonb_set(
+            COALESCE(metadata, '{}'::jsonb),
+            '{assigned_at}',
+            to_jsonb(NOW()::text)
+        )
+    WHERE shards.id = v_id;
+
+    RETURN QUERY
+    SELECT
+        s.id, s.title,
+        COALESCE(
+            (SELECT replace(l.label, 'kind:', '')
+             FROM labels l
+             WHERE l.shard_id = s.id AND l.label LIKE 'kind:%'
+             LIMIT 1),
+            'task'
+        ),
+        s.priority,
+        s.parent_id,
+        p.title
+    FROM shards s
+    LEFT JOIN shards p ON p.id = s.parent_id AND p.type = 'epic'
+    WHERE s.id = v_id;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
@@ -0,0 +1,303 @@
+-- Claim leases for shard assign/take and task claim
+-- A claim records heartbeat_at and lease_expires_at in shard metadata next to
+-- assigned_at. The owner renews the lease with shard_heartbeat (cp task
+-- heartbeat, cp task progress); shard_reap returns in_progress shards whose
+-- lease has run out to open, so a crashed agent does not strand its work.
+-- Shards claimed before this migration have no lease and are never reaped.
+
+DROP FUNCTION IF EXISTS shard_assign(TEXT, TEXT, TEXT);
+
+CREATE OR REPLACE FUNCTION shard_assign(
+    p_project TEXT,
+    p_shard_id TEXT,
+    p_agent TEXT,
+    p_lease INTERVAL
+) RETURNS TABLE (
+    title TEXT,
+    lease_expires_at TIMESTAMPTZ
+) AS $$
+#variable_conflict use_column
+DECLARE
+    v_status TEXT;
+    v_owner TEXT;
+    v_title TEXT;
+BEGIN
+    SELECT s.status, s.owner, s.title INTO v_status, v_owner, v_title
+    FROM shards s WHERE s.id = p_shard_id AND s.project = p_project FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Shard % not found', p_shard_id;
+    END IF;
+
+    IF v_status = 'in_progress' THEN
+        RAISE EXCEPTION 'Shard % is already in_progress (owner: %)', p_shard_id, v_owner;
+    END IF;
+
+    IF v_status = 'closed' THEN
+        RAISE EXCEPTION 'Shard % is already closed', p_shard_id;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM blocking_edges e
+        JOIN shards blocker ON blocker.id = e.to_id
+        WHERE e.from_id = p_shard_id
+          AND blocker.status != 'closed'
+    ) THEN
+        RAISE EXCEPTION 'Shard % has unresolved blockers', p_shard_id;
+    END IF;
+
+    UPDATE shards
+    SET status = 'in_progress',
+        owner = p_agent,
+        updated_at = NOW(),
+        metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+            'assigned_at', NOW()::text,
+            'heartbeat_at', NOW()::text,
+            'lease_expires_at', (NOW() + p_lease)::text)
+    WHERE shards.id = p_shard_id AND shards.project = p_project;
+
+    RETURN QUERY SELECT v_title, NOW() + p_lease;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+DROP FUNCTION IF EXISTS shard_take(TEXT, TEXT, TEXT, TEXT);
+
+CREATE OR REPLACE FUNCTION shard_take(
+    p_project TEXT,
+    p_agent TEXT,
+    p_lease INTERVAL,
+    p_epic_id TEXT DEFAULT NULL,
+    p_kind TEXT DEFAULT NULL
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    kind TEXT,
+    priority INT,
+    epic_id TEXT,
+    epic_title TEXT,
+    lease_expires_at TIMESTAMPTZ
+) AS $$
+#variable_conflict use_column
+DECLARE
+    v_id TEXT;
+BEGIN
+    SELECT s.id INTO v_id
+    FROM shards s
+    WHERE s.project = p_project
+      AND s.status = 'open'
+      AND s.type NOT IN ('epic', 'memory', 'message')
+      AND (p_epic_id IS NULL OR s.parent_id = p_epic_id)
+      AND (p_kind IS NULL OR COALESCE(
+              (SELECT replace(l.label, 'kind:', '')
+               FROM labels l
+               WHERE l.shard_id = s.id AND l.label LIKE 'kind:%'
+               LIMIT 1),
+              'task') = p_kind)
+      AND NOT EXISTS (
+          SELECT 1 FROM blocking_edges e
+          JOIN shards blocker ON blocker.id = e.to_id
+          WHERE e.from_id = s.id
+            AND blocker.status != 'closed'
+      )
+    ORDER BY s.priority, s.created_at
+    LIMIT 1
+    FOR UPDATE OF s SKIP LOCKED;
+
+    IF v_id IS NULL THEN
+        RETURN;
+    END IF;
+
+    UPDATE shards
+    SET status = 'in_progress',
+        owner = p_agent,
+        updated_at = NOW(),
+        metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+            'assigned_at', NOW()::text,
+            'heartbeat_at', NOW()::text,
+            'lease_expires_at', (NOW() + p_lease)::text)
+    WHERE shards.id = v_id;
+
+    RETURN QUERY
+    SELECT
+        s.id, s.title,
+        COALESCE(
+            (SELECT replace(l.label, 'kind:', '')
+             FROM labels l
+             WHERE l.shard_id = s.id AND l.label LIKE 'kind:%'
+             LIMIT 1),
+            'task'
+        ),
+        s.priority,
+        s.parent_id,
+        p.title,
+        NOW() + p_lease
+    FROM shards s
+    LEFT JOIN shards p ON p.id = s.parent_id AND p.type = 'epic'
+    WHERE s.id = v_id;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+DROP FUNCTION IF EXISTS claim_task(TEXT, TEXT);
+
+CREATE OR REPLACE FUNCTION claim_task(p_task_id TEXT, p_agent TEXT, p_lease INTERVAL)
+RETURNS BOOLEAN AS $$
+BEGIN
+    UPDATE shards
+    SET owner = p_agent, status = 'in_progress',
+        metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+            'heartbeat_at', NOW()::text,
+            'lease_expires_at', (NOW() + p_lease)::text)
+    WHERE id = p_task_id
+      AND type IN ('task', 'backlog')
+      AND status != 'closed'
+      AND (owner IS NULL OR owner = p_agent);
+    RETURN FOUND;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Renew the caller's lease on an in_progress shard
+CREATE OR REPLACE FUNCTION shard_heartbeat(
+    p_project TEXT,
+    p_shard_id TEXT,
+    p_agent TEXT,
+    p_lease INTERVAL
+) RETURNS TIMESTAMPTZ AS $$
+DECLARE
+    v_status TEXT;
+    v_owner TEXT;
+BEGIN
+    SELECT status, owner INTO v_status, v_owner
+    FROM shards WHERE id = p_shard_id AND project = p_project FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Shard % not found', p_shard_id;
+    END IF;
+
+    IF v_status != 'in_progress' THEN
+        RAISE EXCEPTION 'Shard % is % (lease expired or released); claim it again', p_shard_id, v_status;
+    END IF;
+
+    IF v_owner IS DISTINCT FROM p_agent THEN
+        RAISE EXCEPTION 'Shard % is owned by %', p_shard_id, COALESCE(v_owner, 'nobody');
+    END IF;
+
+    UPDATE shards
+    SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+            'heartbeat_at', NOW()::text,
+            'lease_expires_at', (NOW() + p_lease)::text)
+    WHERE id = p_shard_id;
+
+    RETURN NOW() + p_lease;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+-- Return in_progress shards with an expired lease to open, noting why in the
+-- content. With p_dry_run the candidates are only listed.
+CREATE OR REPLACE FUNCTION shard_reap(
+    p_project TEXT,
+    p_agent TEXT,
+    p_dry_run BOOLEAN DEFAULT FALSE
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    owner TEXT,
+    lease_expires_at TIMESTAMPTZ
+) AS $$
+#variable_conflict use_column
+DECLARE
+    r RECORD;
+BEGIN
+    FOR r IN
+        SELECT s.id, s.title, s.owner, (s.metadata->>'lease_expires_at')::timestamptz AS expires_at
+        FROM shards s
+        WHERE s.project = p_project
+          AND s.status = 'in_progress'
+          AND s.metadata ? 'lease_expires_at'
+          AND (s.metadata->>'lease_expires_at')::timestamptz < NOW()
+        ORDER BY expires_at
+        FOR UPDATE OF s SKIP LOCKED
+    LOOP
+        IF NOT p_dry_run THEN
+            UPDATE shards
+            SET status = 'open',
+                owner = NULL,
+                updated_at = NOW(),
+                content = COALESCE(content, '') || format(
+                    E'\n\n---\n**[%s] %s:** Lease held by %s expired at %s; returned to open.',
+                    to_char(NOW(), 'YYYY-MM-DD HH24:MI:SS'), p_agent, COALESCE(r.owner, 'nobody'),
+                    to_char(r.expires_at, 'YYYY-MM-DD HH24:MI:SS')),
+                metadata = metadata - 'assigned_at' - 'heartbeat_at' - 'lease_expires_at'
+            WHERE shards.id = r.id;
+        END IF;
+
+        id := r.id;
+        title := r.title;
+        owner := r.owner;
+        lease_expires_at := r.expires_at;
+        RETURN NEXT;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+DROP FUNCTION IF EXISTS shard_board(TEXT, TEXT, TEXT);
+
+CREATE OR REPLACE FUNCTION shard_board(
+    p_project TEXT,
+    p_epic_id TEXT DEFAULT NULL,
+    p_agent TEXT DEFAULT NULL
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    status TEXT,
+    kind TEXT,
+    owner TEXT,
+    priority INT,
+    epic_id TEXT,
+    epic_title TEXT,
+    assigned_at TIMESTAMPTZ,
+    heartbeat_at TIMESTAMPTZ,
+    lease_expires_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    blocked_by TEXT[]
+) AS $$
+    SELECT
+        s.id, s.title, s.status,
+        COALESCE(
+            (SELECT replace(l.label, 'kind:', '')
+             FROM labels l
+             WHERE l.shard_id = s.id AND l.label LIKE 'kind:%'
+             LIMIT 1),
+            'task'
+        ),
+        s.owner, s.priority, s.parent_id, p.title,
+        (s.metadata->>'assigned_at')::timestamptz,
+        (s.metadata->>'heartbeat_at')::timestamptz,
+        (s.metadata->>'lease_expires_at')::timestamptz,
+        s.closed_at,
+        COALESCE(
+            (SELECT array_agg(e.to_id)
+             FROM blocking_edges e
+             JOIN shards blocker ON blocker.id = e.to_id
+             WHERE e.from_id = s.id
+               AND blocker.status != 'closed'),
+            '{}'::text[]
+        )
+    FROM shards s
+    LEFT JOIN shards p ON p.id = s.parent_id AND p.type = 'epic'
+    WHERE s.project = p_project
+      AND s.type NOT IN ('epic', 'memory', 'message')
+      AND (p_epic_id IS NULL OR s.parent_id = p_epic_id)
+      AND (p_agent IS NULL OR s.owner = p_agent)
+      AND (p_agent IS NOT NULL
+           OR p_epic_id IS NOT NULL
+           OR s.status != 'closed'
+           OR s.closed_at > NOW() - INTERVAL '24 hours')
+    ORDER BY
+        CASE s.status
+            WHEN 'in_progress' THEN 0
+            WHEN 'open' THEN 1
+            WHEN 'closed' THEN 2
+        END,
+        s.priority,
+        s.created_at;
+$$ LANGUAGE sql STABLE;
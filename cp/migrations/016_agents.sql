@@ -0,0 +1,66 @@
+-- Agent registry
+-- Agent identities used to be free-form strings. Registered agents carry a
+-- display name, capabilities and the projects they serve, plus a last-seen
+-- time kept fresh by cp agent heartbeat. The registry is shared by every
+-- project; message send warns about recipients missing from it.
+-- Shards name the capabilities they need with needs:<capability> labels, and
+-- shard_next can offer only work an agent's capabilities cover.
+
+CREATE TABLE IF NOT EXISTS agents (
+    id TEXT PRIMARY KEY,
+    display_name TEXT,
+    capabilities TEXT[] NOT NULL DEFAULT '{}',
+    projects TEXT[] NOT NULL DEFAULT '{}',   -- empty = serves every project
+    description TEXT,
+    registered_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen TIMESTAMPTZ
+);
+
+DROP FUNCTION IF EXISTS shard_next(TEXT, TEXT, INT);
+
+CREATE OR REPLACE FUNCTION shard_next(
+    p_project TEXT,
+    p_epic_id TEXT DEFAULT NULL,
+    p_limit INT DEFAULT 5,
+    p_capabilities TEXT[] DEFAULT NULL
+) RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    kind TEXT,
+    priority INT,
+    epic_id TEXT,
+    epic_title TEXT
+) AS $$
+    SELECT
+        s.id, s.title,
+        COALESCE(
+            (SELECT replace(l.label, 'kind:', '')
+             FROM labels l
+             WHERE l.shard_id = s.id AND l.label LIKE 'kind:%'
+             LIMIT 1),
+            'task'
+        ),
+        s.priority,
+        s.parent_id,
+        p.title
+    FROM shards s
+    LEFT JOIN shards p ON p.id = s.parent_id AND p.type = 'epic'
+    WHERE s.project = p_project
+      AND s.status = 'open'
+      AND s.type NOT IN ('epic', 'memory', 'message')
+      AND (p_epic_id IS NULL OR s.parent_id = p_epic_id)
+      AND (p_capabilities IS NULL OR NOT EXISTS (
+          SELECT 1 FROM labels l
+          WHERE l.shard_id = s.id
+            AND l.label LIKE 'needs:%'
+            AND NOT (substr(l.label, 7) = ANY(p_capabilities))
+      ))
+      AND NOT EXISTS (
+          SELECT 1 FROM blocking_edges e
+          JOIN shards blocker ON blocker.id = e.to_id
+          WHERE e.from_id = s.id
+            AND blocker.status != 'closed'
+      )
+    ORDER BY s.priority, s.created_at
+    LIMIT p_limit;
+$$ LANGUAGE sql STABLE;
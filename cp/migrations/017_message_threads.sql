@@ -0,0 +1,52 @@
+-- Message threads
+-- get_thread() only walks down from a root it is given and drops recipients.
+-- message_thread() finds the root of the conversation containing any message,
+-- then returns every message below it with its parent and its to:/cc:/kind:
+-- labels, so cp message thread can rebuild the replies-to tree and show who
+-- has read what. Read receipts are fetched separately.
+
+CREATE OR REPLACE FUNCTION message_thread(p_project TEXT, p_id TEXT)
+RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    creator TEXT,
+    content TEXT,
+    reply_to TEXT,
+    created_at TIMESTAMPTZ,
+    labels TEXT[]
+) AS $$
+    WITH RECURSIVE up AS (
+        SELECT s.id, 0 AS hops
+        FROM shards s
+        WHERE s.id = p_id AND s.project = p_project
+        UNION
+        SELECT e.to_id, up.hops + 1
+        FROM up
+        JOIN edges e ON e.from_id = up.id AND e.edge_type = 'replies-to'
+        WHERE up.hops < 100
+    ),
+    root AS (
+        SELECT up.id FROM up ORDER BY up.hops DESC LIMIT 1
+    ),
+    down AS (
+        SELECT root.id, 0 AS depth FROM root
+        UNION
+        SELECT e.from_id, down.depth + 1
+        FROM down
+        JOIN edges e ON e.to_id = down.id AND e.edge_type = 'replies-to'
+        WHERE down.depth < 100
+    )
+    SELECT
+        s.id, s.title, s.creator, COALESCE(s.content, ''),
+        (SELECT e.to_id FROM edges e
+         WHERE e.from_id = s.id AND e.edge_type = 'replies-to'
+         ORDER BY e.created_at LIMIT 1),
+        s.created_at,
+        ARRAY(SELECT l.label FROM labels l
+              WHERE l.shard_id = s.id
+                AND (l.label LIKE 'to:%' OR l.label LIKE 'cc:%' OR l.label LIKE 'kind:%')
+              ORDER BY l.label)
+    FROM shards s
+    WHERE s.id IN (SELECT down.id FROM down)
+    ORDER BY s.created_at;
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,74 @@
+-- Message groups
+-- Agents join named groups (cp agent register --group implementers) and a
+-- message addressed to @implementers, or @all for every registered agent
+-- serving the project, is expanded to one to:/cc: label per member at send
+-- time, so each member has its own inbox entry and read receipt. The group
+-- itself is kept as a group:<name> label on the message.
+
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS groups TEXT[] NOT NULL DEFAULT '{}';
+
+-- message_thread() now also returns group: labels
+CREATE OR REPLACE FUNCTION message_thread(p_project TEXT, p_id TEXT)
+RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    creator TEXT,
+    content TEXT,
+    reply_to TEXT,
+    created_at TIMESTAMPTZ,
+    labels TEXT[]
+) AS $$
+    WITH RECURSIVE up AS (
+        SELECT s.id, 0 AS hops
+        FROM shards s
+        WHERE s.id = p_id AND s.project = p_project
+        UNION
+        SELECT e.to_id, up.hops + 1
+        FROM up
+        JOIN edges e ON e.from_id = up.id AND e.edge_type = 'replies-to'
+        WHERE up.hops < 100
+    ),
+    root AS (
+        SELECT up.id FROM up ORDER BY up.hops DESC LIMIT 1
+    ),
+    down AS (
+        SELECT root.id, 0 AS depth FROM root
+        UNION
+        SELECT e.from_id, down.depth + 1
+        FROM down
+        JOIN edges e ON e.to_id = down.id AND e.edge_type = 'replies-to'
+        WHERE down.depth < 100
+    )
+    SELECT
+        s.id, s.title, s.creator, COALESCE(s.content, ''),
+        (SELECT e.to_id FROM edges e
+         WHERE e.from_id = s.id AND e.edge_type = 'replies-to'
+         ORDER BY e.created_at LIMIT 1),
+        s.created_at,
+        ARRAY(SELECT l.label FROM labels l
+              WHERE l.shard_id = s.id
+                AND (l.label LIKE 'to:%' OR l.label LIKE 'cc:%'
+                     OR l.label LIKE 'kind:%' OR l.label LIKE 'group:%')
+              ORDER BY l.label)
+    FROM shards s
+    WHERE s.id IN (SELECT down.id FROM down)
+    ORDER BY s.created_at;
+$$ LANGUAGE sql STABLE;
+
+-- Messages an agent has sent, newest first, with their addressing labels
+CREATE OR REPLACE FUNCTION sent_by(p_project TEXT, p_agent TEXT, p_limit INT DEFAULT 20)
+RETURNS TABLE (id TEXT, title TEXT, created_at TIMESTAMPTZ, labels TEXT[]) AS $$
+    SELECT
+        s.id, s.title, s.created_at,
+        ARRAY(SELECT l.label FROM labels l
+              WHERE l.shard_id = s.id
+                AND (l.label LIKE 'to:%' OR l.label LIKE 'cc:%'
+                     OR l.label LIKE 'kind:%' OR l.label LIKE 'group:%')
+              ORDER BY l.label)
+    FROM shards s
+    WHERE s.project = p_project
+      AND s.type = 'message'
+      AND s.creator = p_agent
+    ORDER BY s.created_at DESC
+    LIMIT p_limit;
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,54 @@
+-- Request/response messages
+-- A message sent with kind request expects a reply from each to: recipient.
+-- The marker lives in metadata: expects_reply, an optional due_at deadline,
+-- and escalated_at once an overdue request has been chased. A recipient has
+-- answered once they send a message that replies-to the request (or to one of
+-- its kind:escalation follow-ups); the follow-ups and notices themselves do
+-- not count.
+
+CREATE INDEX IF NOT EXISTS idx_shards_expects_reply
+    ON shards (project) WHERE type = 'message' AND (metadata->>'expects_reply') = 'true';
+
+-- Open requests in a project, optionally only those an agent sent or is a
+-- to: recipient of, with the agents that have replied
+CREATE OR REPLACE FUNCTION open_requests(p_project TEXT, p_agent TEXT DEFAULT NULL)
+RETURNS TABLE (
+    id TEXT,
+    title TEXT,
+    creator TEXT,
+    created_at TIMESTAMPTZ,
+    labels TEXT[],
+    due_at TIMESTAMPTZ,
+    escalated_at TIMESTAMPTZ,
+    replied TEXT[]
+) AS $$
+    SELECT
+        s.id, s.title, s.creator, s.created_at,
+        ARRAY(SELECT l.label FROM labels l
+              WHERE l.shard_id = s.id
+                AND (l.label LIKE 'to:%' OR l.label LIKE 'cc:%'
+                     OR l.label LIKE 'kind:%' OR l.label LIKE 'group:%')
+              ORDER BY l.label),
+        (s.metadata->>'due_at')::timestamptz,
+        (s.metadata->>'escalated_at')::timestamptz,
+        ARRAY(SELECT DISTINCT r.creator
+              FROM edges e
+              JOIN shards r ON r.id = e.from_id
+              WHERE e.edge_type = 'replies-to'
+                AND (e.to_id = s.id OR e.to_id IN (
+                    SELECT f.from_id FROM edges f
+                    JOIN labels fl ON fl.shard_id = f.from_id AND fl.label = 'kind:escalation'
+                    WHERE f.to_id = s.id AND f.edge_type = 'replies-to'))
+                AND NOT EXISTS (
+                    SELECT 1 FROM labels rl
+                    WHERE rl.shard_id = r.id AND rl.label = 'kind:escalation'))
+    FROM shards s
+    WHERE s.project = p_project
+      AND s.type = 'message'
+      AND s.status = 'open'
+      AND (s.metadata->>'expects_reply') = 'true'
+      AND (p_agent IS NULL
+           OR s.creator = p_agent
+           OR EXISTS (SELECT 1 FROM labels l WHERE l.shard_id = s.id AND l.label = 'to:' || p_agent))
+    ORDER BY (s.metadata->>'due_at')::timestamptz NULLS LAST, s.created_at;
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,33 @@
+-- Revert 020: notify lease heartbeats as plain shard updates again
+
+CREATE OR REPLACE FUNCTION notify_shard_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        payload := jsonb_build_object(
+            'table', 'shards', 'op', TG_OP, 'project', OLD.project,
+            'id', OLD.id, 'type', OLD.type, 'status', OLD.status);
+    ELSE
+        -- Embedding backfills and timestamp-only touches are not news
+        IF TG_OP = 'UPDATE'
+           AND NEW.title IS NOT DISTINCT FROM OLD.title
+           AND NEW.content IS NOT DISTINCT FROM OLD.content
+           AND NEW.status IS NOT DISTINCT FROM OLD.status
+           AND NEW.owner IS NOT DISTINCT FROM OLD.owner
+           AND NEW.priority IS NOT DISTINCT FROM OLD.priority
+           AND NEW.metadata IS NOT DISTINCT FROM OLD.metadata THEN
+            RETURN NULL;
+        END IF;
+        payload := jsonb_build_object(
+            'table', 'shards', 'op', TG_OP, 'project', NEW.project,
+            'id', NEW.id, 'type', NEW.type, 'status', NEW.status);
+        IF TG_OP = 'UPDATE' AND NEW.status IS DISTINCT FROM OLD.status THEN
+            payload := payload || jsonb_build_object('prev_status', OLD.status);
+        END IF;
+    END IF;
+    PERFORM pg_notify('cp_events', payload::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
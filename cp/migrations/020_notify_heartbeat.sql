@@ -0,0 +1,47 @@
+-- Mark lease heartbeats in change notifications
+-- shard_heartbeat (migration 015) rewrites heartbeat_at and lease_expires_at
+-- in the owner's claim every few minutes. Those updates still notify, but
+-- carry "heartbeat": true so cp watch can leave them out of its default
+-- stream; any other change in the same update clears the flag.
+
+CREATE OR REPLACE FUNCTION notify_shard_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        payload := jsonb_build_object(
+            'table', 'shards', 'op', TG_OP, 'project', OLD.project,
+            'id', OLD.id, 'type', OLD.type, 'status', OLD.status);
+    ELSE
+        -- Embedding backfills and timestamp-only touches are not news
+        IF TG_OP = 'UPDATE'
+           AND NEW.title IS NOT DISTINCT FROM OLD.title
+           AND NEW.content IS NOT DISTINCT FROM OLD.content
+           AND NEW.status IS NOT DISTINCT FROM OLD.status
+           AND NEW.owner IS NOT DISTINCT FROM OLD.owner
+           AND NEW.priority IS NOT DISTINCT FROM OLD.priority
+           AND NEW.metadata IS NOT DISTINCT FROM OLD.metadata THEN
+            RETURN NULL;
+        END IF;
+        payload := jsonb_build_object(
+            'table', 'shards', 'op', TG_OP, 'project', NEW.project,
+            'id', NEW.id, 'type', NEW.type, 'status', NEW.status);
+        IF TG_OP = 'UPDATE' AND NEW.status IS DISTINCT FROM OLD.status THEN
+            payload := payload || jsonb_build_object('prev_status', OLD.status);
+        END IF;
+        IF TG_OP = 'UPDATE'
+           AND NEW.title IS NOT DISTINCT FROM OLD.title
+           AND NEW.content IS NOT DISTINCT FROM OLD.content
+           AND NEW.status IS NOT DISTINCT FROM OLD.status
+           AND NEW.owner IS NOT DISTINCT FROM OLD.owner
+           AND NEW.priority IS NOT DISTINCT FROM OLD.priority
+           AND (NEW.metadata - 'heartbeat_at' - 'lease_expires_at')
+               IS NOT DISTINCT FROM (OLD.metadata - 'heartbeat_at' - 'lease_expires_at') THEN
+            payload := payload || jsonb_build_object('heartbeat', true);
+        END IF;
+    END IF;
+    PERFORM pg_notify('cp_events', payload::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
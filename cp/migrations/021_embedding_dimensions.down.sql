@@ -0,0 +1,5 @@
+-- Revert 021: drop the resize functions. Columns keep whatever size they were
+-- resized to; run cp admin embed-reset 768 first to restore the original size.
+
+DROP FUNCTION IF EXISTS resize_embeddings(INT);
+DROP FUNCTION IF EXISTS embedding_dimensions();
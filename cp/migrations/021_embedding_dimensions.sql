@@ -0,0 +1,42 @@
+-- Configurable embedding dimensions
+-- shards.embedding and shard_chunks.embedding were created as vector(768).
+-- resize_embeddings() re-types both columns for a provider with a different
+-- output size (cp admin embed-reset). Vectors cannot be converted between
+-- sizes, so it clears every stored embedding and chunk; the columns are shared
+-- by all projects, so this is database-wide. The search functions declare
+-- vector(768) arguments, but Postgres ignores argument sizes, so they accept
+-- any size unchanged.
+
+-- embedding_dimensions() is the size of the embedding columns (NULL if unsized)
+CREATE OR REPLACE FUNCTION embedding_dimensions()
+RETURNS INT AS $$
+    SELECT NULLIF(atttypmod, -1) FROM pg_attribute
+    WHERE attrelid = 'shards'::regclass AND attname = 'embedding';
+$$ LANGUAGE sql STABLE;
+
+-- resize_embeddings() returns how many shard embeddings it cleared
+CREATE OR REPLACE FUNCTION resize_embeddings(p_dims INT)
+RETURNS INT AS $$
+DECLARE
+    v_cleared INT;
+BEGIN
+    -- pgvector's ivfflat and hnsw indexes support at most 2000 dimensions
+    IF p_dims IS NULL OR p_dims < 1 OR p_dims > 2000 THEN
+        RAISE EXCEPTION 'Embedding dimensions must be between 1 and 2000, not %', p_dims;
+    END IF;
+    IF embedding_dimensions() = p_dims THEN
+        RETURN 0;
+    END IF;
+
+    UPDATE shards
+    SET embedding = NULL, embedding_provider = NULL, embedding_model = NULL, embedding_dims = NULL
+    WHERE embedding IS NOT NULL;
+    GET DIAGNOSTICS v_cleared = ROW_COUNT;
+    DELETE FROM shard_chunks;
+
+    -- Rebuilds idx_shards_embedding and idx_shard_chunks_embedding
+    EXECUTE format('ALTER TABLE shards ALTER COLUMN embedding TYPE vector(%s)', p_dims);
+    EXECUTE format('ALTER TABLE shard_chunks ALTER COLUMN embedding TYPE vector(%s)', p_dims);
+    RETURN v_cleared;
+END;
+$$ LANGUAGE plpgsql;
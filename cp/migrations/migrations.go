@@ -0,0 +1,9 @@
+// Package migrations embeds the versioned SQL schema applied by
+// `cp admin migrate`. Files are named NNN_name.sql; an optional
+// NNN_name.down.sql reverts it. Each file runs in its own transaction.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS